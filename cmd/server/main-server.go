@@ -23,6 +23,7 @@ import (
 	"github.com/wavetermdev/waveterm/pkg/service"
 	"github.com/wavetermdev/waveterm/pkg/telemetry"
 	"github.com/wavetermdev/waveterm/pkg/telemetry/telemetrydata"
+	"github.com/wavetermdev/waveterm/pkg/util/fileutil"
 	"github.com/wavetermdev/waveterm/pkg/util/shellutil"
 	"github.com/wavetermdev/waveterm/pkg/util/sigutil"
 	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
@@ -89,10 +90,19 @@ func stdinReadWatch() {
 func startConfigWatcher() {
 	watcher := wconfig.GetWatcher()
 	if watcher != nil {
+		watcher.AddUpdateHandler(updateMimeTypeOverrides)
 		watcher.Start()
 	}
 }
 
+func updateMimeTypeOverrides(fullConfig wconfig.FullConfigType) {
+	overrides := make(map[string]fileutil.MimeTypeOverride)
+	for ext, override := range fullConfig.Settings.PreviewMimeTypes {
+		overrides[ext] = fileutil.MimeTypeOverride{MimeType: override.MimeType, Force: override.Force}
+	}
+	fileutil.SetMimeTypeOverrides(overrides)
+}
+
 func telemetryLoop() {
 	var nextSend int64
 	time.Sleep(InitialTelemetryWait)
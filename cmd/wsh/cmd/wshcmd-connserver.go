@@ -38,10 +38,12 @@ var serverCmd = &cobra.Command{
 
 var connServerRouter bool
 var singleServerRouter bool
+var connServerJailRoot string
 
 func init() {
 	serverCmd.Flags().BoolVar(&connServerRouter, "router", false, "run in local router mode")
 	serverCmd.Flags().BoolVar(&singleServerRouter, "single", false, "run in local single mode")
+	serverCmd.Flags().StringVar(&connServerJailRoot, "jailroot", "", "restrict file commands to paths within this directory")
 	rootCmd.AddCommand(serverCmd)
 }
 
@@ -84,6 +86,7 @@ func handleNewListenerConn(conn net.Conn, router *wshutil.WshRouter) {
 			routeIdPtr := routeIdContainer.Load()
 			if routeIdPtr != nil && *routeIdPtr != "" {
 				router.UnregisterRoute(*routeIdPtr)
+				wshremote.ReleaseFileLocks(*routeIdPtr)
 				disposeMsg := &wshutil.RpcMessage{
 					Command: wshrpc.Command_Dispose,
 					Data: wshrpc.CommandDisposeData{
@@ -138,7 +141,7 @@ func setupConnServerRpcClientWithRouter(router *wshutil.WshRouter, jwtToken stri
 	}
 	inputCh := make(chan []byte, wshutil.DefaultInputChSize)
 	outputCh := make(chan []byte, wshutil.DefaultOutputChSize)
-	connServerClient := wshutil.MakeWshRpc(inputCh, outputCh, *rpcCtx, &wshremote.ServerImpl{LogWriter: os.Stdout}, authRtn.RouteId)
+	connServerClient := wshutil.MakeWshRpc(inputCh, outputCh, *rpcCtx, &wshremote.ServerImpl{LogWriter: os.Stdout, JailRoot: connServerJailRoot}, authRtn.RouteId)
 	connServerClient.SetAuthToken(authRtn.AuthToken)
 	router.RegisterRoute(authRtn.RouteId, connServerClient, false)
 	wshclient.RouteAnnounceCommand(connServerClient, nil)
@@ -208,7 +211,7 @@ func checkForUpdate() error {
 }
 
 func serverRunSingle(jwtToken string) error {
-	err := setupRpcClient(&wshremote.ServerImpl{LogWriter: os.Stdout}, jwtToken)
+	err := setupRpcClient(&wshremote.ServerImpl{LogWriter: os.Stdout, JailRoot: connServerJailRoot}, jwtToken)
 	if err != nil {
 		return err
 	}
@@ -223,7 +226,7 @@ func serverRunSingle(jwtToken string) error {
 }
 
 func serverRunNormal(jwtToken string) error {
-	err := setupRpcClient(&wshremote.ServerImpl{LogWriter: os.Stdout}, jwtToken)
+	err := setupRpcClient(&wshremote.ServerImpl{LogWriter: os.Stdout, JailRoot: connServerJailRoot}, jwtToken)
 	if err != nil {
 		return err
 	}
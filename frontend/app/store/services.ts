@@ -47,6 +47,46 @@ class ClientServiceType {
 
 export const ClientService = new ClientServiceType();
 
+// fileservice.FileService (file)
+class FileServiceType {
+    // copy a file or directory, within or across connections.  the copy runs server to server, the data never goes through the frontend
+    CopyFile(srcConnection: string, srcPath: string, destConnection: string, destPath: string, opts: FileCopyOpts): Promise<void> {
+        return WOS.callBackendService("file", "CopyFile", Array.from(arguments))
+    }
+
+    // check whether a path exists.  false is only returned for a clean not found, other failures (permissions, connection) are errors
+    FileExists(connection: string, path: string): Promise<boolean> {
+        return WOS.callBackendService("file", "FileExists", Array.from(arguments))
+    }
+
+    // get the total size and file count of a directory tree, huge trees return a partial (truncated) total
+    GetDirectorySize(connection: string, path: string): Promise<DirectorySize> {
+        return WOS.callBackendService("file", "GetDirectorySize", Array.from(arguments))
+    }
+
+    // move or rename a file or directory within a connection, an existing destination is only replaced with overwrite set
+    MoveFile(connection: string, srcPath: string, destPath: string, overwrite: boolean): Promise<void> {
+        return WOS.callBackendService("file", "MoveFile", Array.from(arguments))
+    }
+
+    // read a whole file (as data64), or the entries of a directory.  files larger than preview:maxfilesize are rejected
+    ReadFile(connection: string, path: string): Promise<FileData> {
+        return WOS.callBackendService("file", "ReadFile", Array.from(arguments))
+    }
+
+    // get the file info for a path, a missing file is returned with notfound set (not as an error)
+    StatFile(connection: string, path: string): Promise<FileInfo> {
+        return WOS.callBackendService("file", "StatFile", Array.from(arguments))
+    }
+
+    // stat several paths on a connection in one call, results are in the order of paths and missing paths have notfound set
+    StatFiles(connection: string, paths: string[]): Promise<FileInfo[]> {
+        return WOS.callBackendService("file", "StatFiles", Array.from(arguments))
+    }
+}
+
+export const FileService = new FileServiceType();
+
 // objectservice.ObjectService (object)
 class ObjectServiceType {
     // @returns blockId (and object updates)
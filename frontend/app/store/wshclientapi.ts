@@ -292,16 +292,66 @@ class RpcApiType {
         return client.wshRpcCall("recordtevent", data, opts);
     }
 
+    // command "remoteclosefile" [call]
+    RemoteCloseFileCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<void> {
+        return client.wshRpcCall("remoteclosefile", data, opts);
+    }
+
+    // command "remotediskusage" [call]
+    RemoteDiskUsageCommand(client: WshClient, data: CommandRemoteDiskUsageData, opts?: RpcOpts): Promise<CommandRemoteDiskUsageRtnData> {
+        return client.wshRpcCall("remotediskusage", data, opts);
+    }
+
+    // command "remotedownload" [responsestream]
+	RemoteDownloadCommand(client: WshClient, data: CommandRemoteDownloadData, opts?: RpcOpts): AsyncGenerator<CommandRemoteDownloadProgress, void, boolean> {
+        return client.wshRpcStream("remotedownload", data, opts);
+    }
+
+    // command "remoteensuredir" [call]
+    RemoteEnsureDirCommand(client: WshClient, data: CommandRemoteMkdirData, opts?: RpcOpts): Promise<void> {
+        return client.wshRpcCall("remoteensuredir", data, opts);
+    }
+
+    // command "remotefileappend" [call]
+    RemoteFileAppendCommand(client: WshClient, data: FileData, opts?: RpcOpts): Promise<FileInfo> {
+        return client.wshRpcCall("remotefileappend", data, opts);
+    }
+
+    // command "remotefilechown" [call]
+    RemoteFileChownCommand(client: WshClient, data: CommandRemoteFileChownData, opts?: RpcOpts): Promise<void> {
+        return client.wshRpcCall("remotefilechown", data, opts);
+    }
+
+    // command "remotefilecompare" [call]
+    RemoteFileCompareCommand(client: WshClient, data: CommandRemoteFileCompareData, opts?: RpcOpts): Promise<CommandRemoteFileCompareRtnData> {
+        return client.wshRpcCall("remotefilecompare", data, opts);
+    }
+
     // command "remotefilecopy" [call]
     RemoteFileCopyCommand(client: WshClient, data: CommandFileCopyData, opts?: RpcOpts): Promise<boolean> {
         return client.wshRpcCall("remotefilecopy", data, opts);
     }
 
+    // command "remotefilecopyplan" [responsestream]
+	RemoteFileCopyPlanCommand(client: WshClient, data: CommandFileCopyData, opts?: RpcOpts): AsyncGenerator<FileCopyPlanEntry, void, boolean> {
+        return client.wshRpcStream("remotefilecopyplan", data, opts);
+    }
+
+    // command "remotefilecopystream" [responsestream]
+	RemoteFileCopyStreamCommand(client: WshClient, data: CommandFileCopyData, opts?: RpcOpts): AsyncGenerator<CommandRemoteFileCopyProgress, void, boolean> {
+        return client.wshRpcStream("remotefilecopystream", data, opts);
+    }
+
     // command "remotefiledelete" [call]
     RemoteFileDeleteCommand(client: WshClient, data: CommandDeleteFileData, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("remotefiledelete", data, opts);
     }
 
+    // command "remotefileexists" [call]
+    RemoteFileExistsCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<string> {
+        return client.wshRpcCall("remotefileexists", data, opts);
+    }
+
     // command "remotefileinfo" [call]
     RemoteFileInfoCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<FileInfo> {
         return client.wshRpcCall("remotefileinfo", data, opts);
@@ -312,21 +362,81 @@ class RpcApiType {
         return client.wshRpcCall("remotefilejoin", data, opts);
     }
 
+    // command "remotefilelock" [call]
+    RemoteFileLockCommand(client: WshClient, data: CommandRemoteFileLockData, opts?: RpcOpts): Promise<CommandRemoteFileLockRtnData> {
+        return client.wshRpcCall("remotefilelock", data, opts);
+    }
+
     // command "remotefilemove" [call]
     RemoteFileMoveCommand(client: WshClient, data: CommandFileCopyData, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("remotefilemove", data, opts);
     }
 
+    // command "remotefilemovestream" [responsestream]
+	RemoteFileMoveStreamCommand(client: WshClient, data: CommandFileCopyData, opts?: RpcOpts): AsyncGenerator<CommandRemoteFileCopyProgress, void, boolean> {
+        return client.wshRpcStream("remotefilemovestream", data, opts);
+    }
+
+    // command "remotefilepreview" [call]
+    RemoteFilePreviewCommand(client: WshClient, data: CommandRemoteFilePreviewData, opts?: RpcOpts): Promise<CommandRemoteFilePreviewRtnData> {
+        return client.wshRpcCall("remotefilepreview", data, opts);
+    }
+
+    // command "remotefilesystemstats" [call]
+    RemoteFileSystemStatsCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<FileSystemStats> {
+        return client.wshRpcCall("remotefilesystemstats", data, opts);
+    }
+
     // command "remotefiletouch" [call]
-    RemoteFileTouchCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<void> {
+    RemoteFileTouchCommand(client: WshClient, data: CommandRemoteFileTouchData, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("remotefiletouch", data, opts);
     }
 
+    // command "remotefiletruncate" [call]
+    RemoteFileTruncateCommand(client: WshClient, data: CommandRemoteFileTruncateData, opts?: RpcOpts): Promise<number> {
+        return client.wshRpcCall("remotefiletruncate", data, opts);
+    }
+
+    // command "remotefileunlock" [call]
+    RemoteFileUnlockCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<void> {
+        return client.wshRpcCall("remotefileunlock", data, opts);
+    }
+
+    // command "remotefileuploadclose" [call]
+    RemoteFileUploadCloseCommand(client: WshClient, data: CommandRemoteFileUploadCloseData, opts?: RpcOpts): Promise<FileInfo> {
+        return client.wshRpcCall("remotefileuploadclose", data, opts);
+    }
+
+    // command "remotefileuploadopen" [call]
+    RemoteFileUploadOpenCommand(client: WshClient, data: CommandRemoteFileUploadOpenData, opts?: RpcOpts): Promise<string> {
+        return client.wshRpcCall("remotefileuploadopen", data, opts);
+    }
+
+    // command "remotefileuploadwrite" [call]
+    RemoteFileUploadWriteCommand(client: WshClient, data: CommandRemoteFileUploadWriteData, opts?: RpcOpts): Promise<void> {
+        return client.wshRpcCall("remotefileuploadwrite", data, opts);
+    }
+
     // command "remotegetinfo" [call]
     RemoteGetInfoCommand(client: WshClient, opts?: RpcOpts): Promise<RemoteInfo> {
         return client.wshRpcCall("remotegetinfo", null, opts);
     }
 
+    // command "remoteglob" [responsestream]
+	RemoteGlobCommand(client: WshClient, data: string, opts?: RpcOpts): AsyncGenerator<CommandRemoteListEntriesRtnData, void, boolean> {
+        return client.wshRpcStream("remoteglob", data, opts);
+    }
+
+    // command "remotegrep" [responsestream]
+	RemoteGrepCommand(client: WshClient, data: CommandRemoteGrepData, opts?: RpcOpts): AsyncGenerator<GrepMatch, void, boolean> {
+        return client.wshRpcStream("remotegrep", data, opts);
+    }
+
+    // command "remotehardlink" [call]
+    RemoteHardlinkCommand(client: WshClient, data: CommandRemoteLinkData, opts?: RpcOpts): Promise<FileInfo> {
+        return client.wshRpcCall("remotehardlink", data, opts);
+    }
+
     // command "remoteinstallrcfiles" [call]
     RemoteInstallRcFilesCommand(client: WshClient, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("remoteinstallrcfiles", null, opts);
@@ -337,11 +447,66 @@ class RpcApiType {
         return client.wshRpcStream("remotelistentries", data, opts);
     }
 
+    // command "remotemanifest" [responsestream]
+	RemoteManifestCommand(client: WshClient, data: CommandRemoteManifestData, opts?: RpcOpts): AsyncGenerator<ManifestEntry, void, boolean> {
+        return client.wshRpcStream("remotemanifest", data, opts);
+    }
+
     // command "remotemkdir" [call]
-    RemoteMkdirCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<void> {
+    RemoteMkdirCommand(client: WshClient, data: CommandRemoteMkdirData, opts?: RpcOpts): Promise<void> {
         return client.wshRpcCall("remotemkdir", data, opts);
     }
 
+    // command "remotemkdirtemp" [call]
+    RemoteMkdirTempCommand(client: WshClient, data: CommandRemoteMkdirTempData, opts?: RpcOpts): Promise<FileInfo> {
+        return client.wshRpcCall("remotemkdirtemp", data, opts);
+    }
+
+    // command "remoteopenfile" [call]
+    RemoteOpenFileCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<CommandRemoteOpenFileRtnData> {
+        return client.wshRpcCall("remoteopenfile", data, opts);
+    }
+
+    // command "remotereadat" [call]
+    RemoteReadAtCommand(client: WshClient, data: CommandRemoteReadAtData, opts?: RpcOpts): Promise<FileData> {
+        return client.wshRpcCall("remotereadat", data, opts);
+    }
+
+    // command "remotereadfilerange" [responsestream]
+	RemoteReadFileRangeCommand(client: WshClient, data: CommandRemoteReadFileRangeData, opts?: RpcOpts): AsyncGenerator<Packet, void, boolean> {
+        return client.wshRpcStream("remotereadfilerange", data, opts);
+    }
+
+    // command "remotereadlines" [responsestream]
+	RemoteReadLinesCommand(client: WshClient, data: CommandRemoteReadLinesData, opts?: RpcOpts): AsyncGenerator<FileData, void, boolean> {
+        return client.wshRpcStream("remotereadlines", data, opts);
+    }
+
+    // command "remotereadlink" [call]
+    RemoteReadLinkCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<string> {
+        return client.wshRpcCall("remotereadlink", data, opts);
+    }
+
+    // command "remotereadtext" [call]
+    RemoteReadTextCommand(client: WshClient, data: CommandRemoteReadTextData, opts?: RpcOpts): Promise<CommandRemoteReadTextRtnData> {
+        return client.wshRpcCall("remotereadtext", data, opts);
+    }
+
+    // command "remoterealpath" [call]
+    RemoteRealPathCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<CommandRemoteRealPathRtnData> {
+        return client.wshRpcCall("remoterealpath", data, opts);
+    }
+
+    // command "remotesed" [call]
+    RemoteSedCommand(client: WshClient, data: CommandRemoteSedData, opts?: RpcOpts): Promise<CommandRemoteSedRtnData> {
+        return client.wshRpcCall("remotesed", data, opts);
+    }
+
+    // command "remotestatvfs" [call]
+    RemoteStatVFSCommand(client: WshClient, data: string, opts?: RpcOpts): Promise<StatVFSInfo> {
+        return client.wshRpcCall("remotestatvfs", data, opts);
+    }
+
     // command "remotestreamcpudata" [responsestream]
 	RemoteStreamCpuDataCommand(client: WshClient, opts?: RpcOpts): AsyncGenerator<TimeSeriesData, void, boolean> {
         return client.wshRpcStream("remotestreamcpudata", null, opts);
@@ -352,6 +517,21 @@ class RpcApiType {
         return client.wshRpcStream("remotestreamfile", data, opts);
     }
 
+    // command "remotesymlink" [call]
+    RemoteSymlinkCommand(client: WshClient, data: CommandRemoteLinkData, opts?: RpcOpts): Promise<FileInfo> {
+        return client.wshRpcCall("remotesymlink", data, opts);
+    }
+
+    // command "remotesync" [responsestream]
+	RemoteSyncCommand(client: WshClient, data: CommandRemoteSyncData, opts?: RpcOpts): AsyncGenerator<CommandRemoteSyncProgress, void, boolean> {
+        return client.wshRpcStream("remotesync", data, opts);
+    }
+
+    // command "remotetailfile" [responsestream]
+	RemoteTailFileCommand(client: WshClient, data: CommandRemoteTailFileData, opts?: RpcOpts): AsyncGenerator<FileData, void, boolean> {
+        return client.wshRpcStream("remotetailfile", data, opts);
+    }
+
     // command "remotetarstream" [responsestream]
 	RemoteTarStreamCommand(client: WshClient, data: CommandRemoteStreamTarData, opts?: RpcOpts): AsyncGenerator<Packet, void, boolean> {
         return client.wshRpcStream("remotetarstream", data, opts);
@@ -362,6 +542,11 @@ class RpcApiType {
         return client.wshRpcCall("remotewritefile", data, opts);
     }
 
+    // command "remotezipstream" [responsestream]
+	RemoteZipStreamCommand(client: WshClient, data: CommandRemoteStreamZipData, opts?: RpcOpts): AsyncGenerator<Packet, void, boolean> {
+        return client.wshRpcStream("remotezipstream", data, opts);
+    }
+
     // command "resolveids" [call]
     ResolveIdsCommand(client: WshClient, data: CommandResolveIdsData, opts?: RpcOpts): Promise<CommandResolveIdsRtnData> {
         return client.wshRpcCall("resolveids", data, opts);
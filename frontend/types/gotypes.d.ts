@@ -204,6 +204,150 @@ declare global {
         message: string;
     };
 
+    // wshrpc.CommandRemoteDiskUsageData
+    type CommandRemoteDiskUsageData = {
+        path: string;
+        topn?: number;
+        timeoutms?: number;
+    };
+
+    // wshrpc.CommandRemoteDiskUsageRtnData
+    type CommandRemoteDiskUsageRtnData = {
+        totalbytes: number;
+        filecount: number;
+        dircount: number;
+        largestfiles?: FileInfo[];
+        truncated?: boolean;
+    };
+
+    // wshrpc.CommandRemoteDownloadData
+    type CommandRemoteDownloadData = {
+        url: string;
+        path: string;
+        sha256?: string;
+        overwrite?: boolean;
+        timeout?: number;
+    };
+
+    // wshrpc.CommandRemoteDownloadProgress
+    type CommandRemoteDownloadProgress = {
+        bytesdone: number;
+        totalbytes?: number;
+        done?: boolean;
+        contenttype?: string;
+        sha256?: string;
+        info?: FileInfo;
+    };
+
+    // wshrpc.CommandRemoteFileChownData
+    type CommandRemoteFileChownData = {
+        path: string;
+        owner?: string;
+        group?: string;
+        recursive?: boolean;
+        strict?: boolean;
+    };
+
+    // wshrpc.CommandRemoteFileCompareData
+    type CommandRemoteFileCompareData = {
+        path1: string;
+        path2: string;
+        quickcheck?: boolean;
+    };
+
+    // wshrpc.CommandRemoteFileCompareRtnData
+    type CommandRemoteFileCompareRtnData = {
+        result: string;
+        diffoffset: number;
+        missingpath?: string;
+    };
+
+    // wshrpc.CommandRemoteFileCopyProgress
+    type CommandRemoteFileCopyProgress = {
+        filesdone: number;
+        bytesdone: number;
+        currentfile?: string;
+        totalbytes?: number;
+    };
+
+    // wshrpc.CommandRemoteFileLockData
+    type CommandRemoteFileLockData = {
+        path: string;
+        shared?: boolean;
+        trylock?: boolean;
+    };
+
+    // wshrpc.CommandRemoteFileLockRtnData
+    type CommandRemoteFileLockRtnData = {
+        locked: boolean;
+        handleid?: string;
+    };
+
+    // wshrpc.CommandRemoteFilePreviewData
+    type CommandRemoteFilePreviewData = {
+        path: string;
+        maxsize?: number;
+        format?: string;
+    };
+
+    // wshrpc.CommandRemoteFilePreviewRtnData
+    type CommandRemoteFilePreviewRtnData = {
+        data64?: string;
+        mimetype: string;
+        width?: number;
+        height?: number;
+        origwidth?: number;
+        origheight?: number;
+        nopreview?: boolean;
+    };
+
+    // wshrpc.CommandRemoteFileTouchData
+    type CommandRemoteFileTouchData = {
+        path: string;
+        nocreate?: boolean;
+    };
+
+    // wshrpc.CommandRemoteFileTruncateData
+    type CommandRemoteFileTruncateData = {
+        path: string;
+        size: number;
+        create?: boolean;
+    };
+
+    // wshrpc.CommandRemoteFileUploadCloseData
+    type CommandRemoteFileUploadCloseData = {
+        uploadid: string;
+        checksum?: string;
+        cancel?: boolean;
+    };
+
+    // wshrpc.CommandRemoteFileUploadOpenData
+    type CommandRemoteFileUploadOpenData = {
+        path: string;
+        mode?: number;
+    };
+
+    // wshrpc.CommandRemoteFileUploadWriteData
+    type CommandRemoteFileUploadWriteData = {
+        uploadid: string;
+        packet: Packet;
+    };
+
+    // wshrpc.CommandRemoteGrepData
+    type CommandRemoteGrepData = {
+        path: string;
+        pattern: string;
+        opts?: GrepOpts;
+    };
+
+    // wshrpc.CommandRemoteLinkData
+    type CommandRemoteLinkData = {
+        path: string;
+        target: string;
+        targetmustexist?: boolean;
+        makeparents?: boolean;
+    };
+
     // wshrpc.CommandRemoteListEntriesData
     type CommandRemoteListEntriesData = {
         path: string;
@@ -213,12 +357,102 @@ declare global {
     // wshrpc.CommandRemoteListEntriesRtnData
     type CommandRemoteListEntriesRtnData = {
         fileinfo?: FileInfo[];
+        done?: boolean;
+        totalcount?: number;
+        totalcountismin?: boolean;
+        skippedcount?: number;
+    };
+
+    // wshrpc.CommandRemoteManifestData
+    type CommandRemoteManifestData = {
+        path: string;
+        opts?: ManifestOpts;
+    };
+
+    // wshrpc.CommandRemoteMkdirData
+    type CommandRemoteMkdirData = {
+        path: string;
+        mode?: number;
+    };
+
+    // wshrpc.CommandRemoteMkdirTempData
+    type CommandRemoteMkdirTempData = {
+        dir?: string;
+        pattern?: string;
+    };
+
+    // wshrpc.CommandRemoteOpenFileRtnData
+    type CommandRemoteOpenFileRtnData = {
+        handleid: string;
+        info: FileInfo;
+    };
+
+    // wshrpc.CommandRemoteReadAtData
+    type CommandRemoteReadAtData = {
+        handleid: string;
+        offset: number;
+        size: number;
+    };
+
+    // wshrpc.CommandRemoteReadFileRangeData
+    type CommandRemoteReadFileRangeData = {
+        path: string;
+        offset?: number;
+        size: number;
+    };
+
+    // wshrpc.CommandRemoteReadLinesData
+    type CommandRemoteReadLinesData = {
+        path: string;
+        startline?: number;
+        endline?: number;
+    };
+
+    // wshrpc.CommandRemoteReadTextData
+    type CommandRemoteReadTextData = {
+        path: string;
+        byterange?: string;
+        encoding?: string;
+    };
+
+    // wshrpc.CommandRemoteReadTextRtnData
+    type CommandRemoteReadTextRtnData = {
+        text: string;
+        encoding?: string;
+        isbinary?: boolean;
+        bytesread: number;
+        eof?: boolean;
+    };
+
+    // wshrpc.CommandRemoteRealPathRtnData
+    type CommandRemoteRealPathRtnData = {
+        path: string;
+        exists?: boolean;
+    };
+
+    // wshrpc.CommandRemoteSedData
+    type CommandRemoteSedData = {
+        path: string;
+        pattern: string;
+        replacement: string;
+        opts?: SedOpts;
+    };
+
+    // wshrpc.CommandRemoteSedRtnData
+    type CommandRemoteSedRtnData = {
+        files?: SedFileResult[];
+        totalcount: number;
+        skippedcount?: number;
+        dryrun?: boolean;
     };
 
     // wshrpc.CommandRemoteStreamFileData
     type CommandRemoteStreamFileData = {
         path: string;
         byterange?: string;
+        headonly?: boolean;
+        filechunksize?: number;
+        dirchunksize?: number;
     };
 
     // wshrpc.CommandRemoteStreamTarData
@@ -227,6 +461,39 @@ declare global {
         opts?: FileCopyOpts;
     };
 
+    // wshrpc.CommandRemoteStreamZipData
+    type CommandRemoteStreamZipData = {
+        path: string;
+        opts?: FileCopyOpts;
+        compressionlevel?: number;
+    };
+
+    // wshrpc.CommandRemoteSyncData
+    type CommandRemoteSyncData = {
+        srcuri: string;
+        desturi: string;
+        opts?: SyncOpts;
+    };
+
+    // wshrpc.CommandRemoteSyncProgress
+    type CommandRemoteSyncProgress = {
+        path?: string;
+        action?: string;
+        filesdone: number;
+        bytesdone: number;
+        done?: boolean;
+        deleted?: number;
+        unchanged?: number;
+        dryrun?: boolean;
+    };
+
+    // wshrpc.CommandRemoteTailFileData
+    type CommandRemoteTailFileData = {
+        path: string;
+        lines?: number;
+        pollms?: number;
+    };
+
     // wshrpc.CommandResolveIdsData
     type CommandResolveIdsData = {
         blockid: string;
@@ -300,6 +567,7 @@ declare global {
         "conn:wshpath"?: string;
         "conn:shellpath"?: string;
         "conn:ignoresshconfig"?: boolean;
+        "conn:jailroot"?: string;
         "display:hidden"?: boolean;
         "display:order"?: number;
         "term:*"?: boolean;
@@ -357,6 +625,13 @@ declare global {
         count: number;
     };
 
+    // fileservice.DirectorySize
+    type DirectorySize = {
+        size: number;
+        filecount: number;
+        truncated?: boolean;
+    };
+
     // vdom.DomRect
     type DomRect = {
         top: number;
@@ -388,8 +663,34 @@ declare global {
     type FileCopyOpts = {
         overwrite?: boolean;
         recursive?: boolean;
+        makeparents?: boolean;
         merge?: boolean;
         timeout?: number;
+        preservetimestamps?: boolean;
+        preservemode?: boolean;
+        preserveowner?: boolean;
+        followsymlinks?: boolean;
+        hardlink?: boolean;
+        dedupe?: boolean;
+        respectgitignore?: boolean;
+        excludepatterns?: string[];
+        resume?: boolean;
+        resumeoffset?: number;
+        resumechecksum?: string;
+        dryrun?: boolean;
+        bufferdepth?: number;
+        parallelwrites?: number;
+        stripcomponents?: number;
+        filechunksize?: number;
+    };
+
+    // wshrpc.FileCopyPlanEntry
+    type FileCopyPlanEntry = {
+        path: string;
+        action: string;
+        isdir?: boolean;
+        size?: number;
+        reason?: string;
     };
 
     // wshrpc.FileData
@@ -398,6 +699,7 @@ declare global {
         data64?: string;
         entries?: FileInfo[];
         at?: FileDataAt;
+        totallines?: number;
     };
 
     // wshrpc.FileDataAt
@@ -420,6 +722,7 @@ declare global {
         notfound?: boolean;
         opts?: FileOpts;
         size?: number;
+        numentries?: number;
         meta?: {[key: string]: any};
         mode?: number;
         modestr?: string;
@@ -428,6 +731,12 @@ declare global {
         supportsmkdir?: boolean;
         mimetype?: string;
         readonly?: boolean;
+        linktarget?: string;
+        uid?: number;
+        gid?: number;
+        owner?: string;
+        group?: string;
+        xattrs?: {[key: string]: string};
     };
 
     // wshrpc.FileListData
@@ -441,6 +750,12 @@ declare global {
         all?: boolean;
         offset?: number;
         limit?: number;
+        sortby?: string;
+        sortdesc?: boolean;
+        dirsfirst?: boolean;
+        respectgitignore?: boolean;
+        showhidden?: boolean;
+        dirchunksize?: number;
     };
 
     // wshrpc.FileOpts
@@ -451,6 +766,10 @@ declare global {
         ijsonbudget?: number;
         truncate?: boolean;
         append?: boolean;
+        atomic?: boolean;
+        expectedmodtime?: number;
+        expectedsize?: number;
+        sha256?: string;
     };
 
     // wshrpc.FileShareCapability
@@ -459,6 +778,15 @@ declare global {
         canmkdir: boolean;
     };
 
+    // wshrpc.FileSystemStats
+    type FileSystemStats = {
+        path: string;
+        total: number;
+        used: number;
+        available: number;
+        fstype?: string;
+    };
+
     // wconfig.FullConfigType
     type FullConfigType = {
         settings: SettingsType;
@@ -472,6 +800,22 @@ declare global {
         configerrors: ConfigError[];
     };
 
+    // wshrpc.GrepMatch
+    type GrepMatch = {
+        path: string;
+        linenum: number;
+        line: string;
+    };
+
+    // wshrpc.GrepOpts
+    type GrepOpts = {
+        regex?: boolean;
+        ignorecase?: boolean;
+        wholeword?: boolean;
+        maxmatches?: number;
+        glob?: string;
+    };
+
     // waveobj.LayoutActionData
     type LayoutActionData = {
         actiontype: string;
@@ -500,6 +844,23 @@ declare global {
         blockid: string;
     };
 
+    // wshrpc.ManifestEntry
+    type ManifestEntry = {
+        path: string;
+        size?: number;
+        modtime: number;
+        mode: number;
+        isdir?: boolean;
+        linktarget?: string;
+        sha256?: string;
+    };
+
+    // wshrpc.ManifestOpts
+    type ManifestOpts = {
+        hash?: boolean;
+        excludepatterns?: string[];
+    };
+
     // waveobj.MetaTSType
     type MetaType = {
         view?: string;
@@ -608,6 +969,12 @@ declare global {
         color: string;
     };
 
+    // wconfig.MimeTypeOverrideType
+    type MimeTypeOverrideType = {
+        mimetype: string;
+        force?: boolean;
+    };
+
     // waveobj.ORef
     type ORef = string;
 
@@ -615,6 +982,8 @@ declare global {
     type Packet = {
         Data: string;
         Checksum: string;
+        ChunkChecksum: string;
+        Offset: number;
     };
 
     // wshrpc.PathCommandData
@@ -668,6 +1037,22 @@ declare global {
         winsize?: WinSize;
     };
 
+    // wshrpc.SedFileResult
+    type SedFileResult = {
+        path: string;
+        count: number;
+        error?: string;
+    };
+
+    // wshrpc.SedOpts
+    type SedOpts = {
+        regex?: boolean;
+        ignorecase?: boolean;
+        wholeword?: boolean;
+        glob?: string;
+        dryrun?: boolean;
+    };
+
     // webcmd.SetBlockTermSizeWSCommand
     type SetBlockTermSizeWSCommand = {
         wscommand: "setblocktermsize";
@@ -723,6 +1108,10 @@ declare global {
         "markdown:fontsize"?: number;
         "markdown:fixedfontsize"?: number;
         "preview:showhiddenfiles"?: boolean;
+        "preview:mimetypes"?: {[key: string]: MimeTypeOverrideType};
+        "preview:dirsizetimeoutms"?: number;
+        "preview:maxfilesize"?: number;
+        "preview:filetimeoutms"?: number;
         "tab:preset"?: string;
         "widget:*"?: boolean;
         "widget:showhelp"?: boolean;
@@ -750,6 +1139,16 @@ declare global {
         "conn:*"?: boolean;
         "conn:askbeforewshinstall"?: boolean;
         "conn:wshenabled"?: boolean;
+        "conn:maxfileops"?: number;
+    };
+
+    // wshrpc.StatVFSInfo
+    type StatVFSInfo = {
+        path: string;
+        mountpoint?: string;
+        fstype?: string;
+        readonly?: boolean;
+        network?: boolean;
     };
 
     // waveobj.StickerClickOptsType
@@ -798,6 +1197,15 @@ declare global {
         "url:url"?: string;
     };
 
+    // wshrpc.SyncOpts
+    type SyncOpts = {
+        delete?: boolean;
+        checksum?: boolean;
+        excludepatterns?: string[];
+        dryrun?: boolean;
+        timeout?: number;
+    };
+
     // telemetrydata.TEvent
     type TEvent = {
         uuid?: string;
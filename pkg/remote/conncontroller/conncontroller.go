@@ -259,6 +259,14 @@ func (conn *SSHConn) getWshPath() string {
 	return wavebase.RemoteFullWshBinPath
 }
 
+func (conn *SSHConn) getJailRoot() string {
+	config, ok := conn.getConnectionConfig()
+	if !ok {
+		return ""
+	}
+	return config.ConnJailRoot
+}
+
 func (conn *SSHConn) GetConfigShellPath() string {
 	config, ok := conn.getConnectionConfig()
 	if !ok {
@@ -302,6 +310,9 @@ func (conn *SSHConn) StartConnServer(ctx context.Context, afterUpdate bool) (boo
 		return false, "", "", fmt.Errorf("unable to get stdin pipe: %w", err)
 	}
 	cmdStr := fmt.Sprintf(ConnServerCmdTemplate, wshPath, wshPath)
+	if jailRoot := conn.getJailRoot(); jailRoot != "" {
+		cmdStr += " --jailroot " + shellutil.HardQuote(jailRoot)
+	}
 	log.Printf("starting conn controller: %q\n", cmdStr)
 	shWrappedCmdStr := fmt.Sprintf("sh -c %s", shellutil.HardQuote(cmdStr))
 	blocklogger.Debugf(ctx, "[conndebug] wrapped command:\n%s\n", shWrappedCmdStr)
@@ -0,0 +1,171 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fileshare
+
+import (
+	"context"
+	"sync"
+
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
+	"github.com/wavetermdev/waveterm/pkg/util/iterfn"
+	"github.com/wavetermdev/waveterm/pkg/wconfig"
+)
+
+// used when conn:maxfileops is not set
+const DefaultConnMaxFileOps = 16
+
+// connLimiter caps the number of in-flight file operations on a single connection.
+// excess callers queue (FIFO) until a slot frees up or their context is done.
+type connLimiter struct {
+	lock    sync.Mutex
+	limit   int
+	active  int
+	waiters []chan struct{}
+}
+
+var connLimitersLock sync.Mutex
+var connLimiters = make(map[string]*connLimiter)
+
+func getConnMaxFileOps() int {
+	maxOps := wconfig.GetWatcher().GetFullConfig().Settings.ConnMaxFileOps
+	if maxOps <= 0 {
+		return DefaultConnMaxFileOps
+	}
+	return maxOps
+}
+
+func getConnLimiterKey(conn *connparse.Connection) string {
+	return conn.Scheme + "://" + conn.Host
+}
+
+func getConnLimiter(conn *connparse.Connection) *connLimiter {
+	key := getConnLimiterKey(conn)
+	connLimitersLock.Lock()
+	defer connLimitersLock.Unlock()
+	limiter := connLimiters[key]
+	if limiter == nil {
+		limiter = &connLimiter{}
+		connLimiters[key] = limiter
+	}
+	return limiter
+}
+
+// acquireConnSlot blocks until the connection has a free slot.  the returned func must be called to release it.
+// wave (local filestore) operations are not limited.
+func acquireConnSlot(ctx context.Context, conn *connparse.Connection) (func(), error) {
+	if conn.GetType() == connparse.ConnectionTypeWave {
+		return func() {}, nil
+	}
+	return getConnLimiter(conn).acquire(ctx, getConnMaxFileOps())
+}
+
+// AcquireConnSlots takes a slot on each distinct connection (see acquireConnSlot).  the slots are taken in key order,
+// so two copies running in opposite directions can't each hold one slot while waiting for the other.
+func AcquireConnSlots(ctx context.Context, conns ...*connparse.Connection) (func(), error) {
+	connsByKey := make(map[string]*connparse.Connection)
+	for _, conn := range conns {
+		connsByKey[getConnLimiterKey(conn)] = conn
+	}
+	var releases []func()
+	releaseAll := func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+	for _, key := range iterfn.MapKeysToSorted(connsByKey) {
+		release, err := acquireConnSlot(ctx, connsByKey[key])
+		if err != nil {
+			releaseAll()
+			return nil, err
+		}
+		releases = append(releases, release)
+	}
+	return releaseAll, nil
+}
+
+// the limit is re-read on every acquire so config changes apply to new operations
+func (l *connLimiter) acquire(ctx context.Context, limit int) (func(), error) {
+	l.lock.Lock()
+	l.limit = limit
+	l.wakeLocked()
+	if l.active < l.limit {
+		l.active++
+		l.lock.Unlock()
+		return l.releaseFunc(), nil
+	}
+	waiter := make(chan struct{})
+	l.waiters = append(l.waiters, waiter)
+	l.lock.Unlock()
+	select {
+	case <-waiter:
+		return l.releaseFunc(), nil
+	case <-ctx.Done():
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		for i, w := range l.waiters {
+			if w == waiter {
+				l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+				return nil, ctx.Err()
+			}
+		}
+		// the slot was handed to us as the context finished, pass it on
+		l.releaseLocked()
+		return nil, ctx.Err()
+	}
+}
+
+func (l *connLimiter) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.lock.Lock()
+			defer l.lock.Unlock()
+			l.releaseLocked()
+		})
+	}
+}
+
+func (l *connLimiter) releaseLocked() {
+	l.active--
+	l.wakeLocked()
+}
+
+// hands free slots to waiters in FIFO order.  if the limit was lowered, waiters stay queued until active drops below it.
+func (l *connLimiter) wakeLocked() {
+	for l.active < l.limit && len(l.waiters) > 0 {
+		waiter := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.active++
+		close(waiter)
+	}
+}
+
+// limitStream holds a connection slot until ch is closed (or ctx is done)
+func limitStream[T any](ctx context.Context, release func(), ch <-chan T) <-chan T {
+	rtn := make(chan T)
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("fileshare:limitStream", recover())
+		}()
+		defer close(rtn)
+		defer release()
+		for resp := range ch {
+			select {
+			case rtn <- resp:
+			case <-ctx.Done():
+				// drain so the producer can exit
+				go func() {
+					defer func() {
+						panichandler.PanicHandler("fileshare:limitStream:drain", recover())
+					}()
+					for range ch {
+					}
+				}()
+				return
+			}
+		}
+	}()
+	return rtn
+}
@@ -0,0 +1,193 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fileshare
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fstype"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// slowOps simulates a slow remote: each op sleeps while tracking how many run at once
+type slowOps struct {
+	running    atomic.Int32
+	maxRunning atomic.Int32
+}
+
+func (s *slowOps) do() {
+	cur := s.running.Add(1)
+	for {
+		prev := s.maxRunning.Load()
+		if cur <= prev || s.maxRunning.CompareAndSwap(prev, cur) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	s.running.Add(-1)
+}
+
+func TestConnLimiter_MaxConcurrent(t *testing.T) {
+	const limit = 3
+	limiter := &connLimiter{}
+	ops := &slowOps{}
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := limiter.acquire(context.Background(), limit)
+			if err != nil {
+				t.Errorf("acquire: %v", err)
+				return
+			}
+			defer release()
+			ops.do()
+		}()
+	}
+	wg.Wait()
+	if maxRunning := ops.maxRunning.Load(); maxRunning > limit {
+		t.Errorf("expected at most %d concurrent ops, got %d", limit, maxRunning)
+	} else if maxRunning < limit {
+		t.Errorf("expected ops to use all %d slots, got %d", limit, maxRunning)
+	}
+	if limiter.active != 0 || len(limiter.waiters) != 0 {
+		t.Errorf("limiter not drained: active=%d waiters=%d", limiter.active, len(limiter.waiters))
+	}
+}
+
+func TestConnLimiter_CanceledWaiter(t *testing.T) {
+	limiter := &connLimiter{}
+	release, err := limiter.acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error for queued op, got %v", err)
+	}
+	release()
+	// releasing twice must not free a second slot
+	release()
+	release2, err := limiter.acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	if limiter.active != 1 {
+		t.Errorf("expected 1 active op, got %d", limiter.active)
+	}
+	release2()
+}
+
+func TestLimitStream_HoldsSlot(t *testing.T) {
+	limiter := &connLimiter{}
+	release, err := limiter.acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	src := make(chan int)
+	out := limitStream(context.Background(), release, src)
+	go func() {
+		src <- 1
+		src <- 2
+		close(src)
+	}()
+	<-out
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(ctx, 1); err == nil {
+		t.Fatalf("slot should be held while the stream is open")
+	}
+	for range out {
+	}
+	release2, err := limiter.acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("slot should be released when the stream closes: %v", err)
+	}
+	release2()
+}
+
+// slotCheckClient records how many slots are held on each connection while a copy runs
+type slotCheckClient struct {
+	fstype.FileShareClient
+	srcActive  int
+	destActive int
+}
+
+func getLimiterActive(conn *connparse.Connection) int {
+	limiter := getConnLimiter(conn)
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+	return limiter.active
+}
+
+func (c *slotCheckClient) CopyRemote(ctx context.Context, srcConn, destConn *connparse.Connection, srcClient fstype.FileShareClient, opts *wshrpc.FileCopyOpts) (bool, error) {
+	c.srcActive = getLimiterActive(srcConn)
+	c.destActive = getLimiterActive(destConn)
+	return false, nil
+}
+
+func (c *slotCheckClient) CopyInternal(ctx context.Context, srcConn, destConn *connparse.Connection, opts *wshrpc.FileCopyOpts) (bool, error) {
+	return c.CopyRemote(ctx, srcConn, destConn, nil, opts)
+}
+
+func TestCopyWithClients_HoldsConnSlots(t *testing.T) {
+	srcConn := &connparse.Connection{Scheme: connparse.ConnectionTypeWsh, Host: "limit-src", Path: "a.txt"}
+	destConn := &connparse.Connection{Scheme: connparse.ConnectionTypeWsh, Host: "limit-dest", Path: "b.txt"}
+	client := &slotCheckClient{}
+	if err := CopyWithClients(context.Background(), client, srcConn, client, destConn, nil); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if client.srcActive != 1 || client.destActive != 1 {
+		t.Errorf("expected one slot on each connection during the copy, got src=%d dest=%d", client.srcActive, client.destActive)
+	}
+	if getLimiterActive(srcConn) != 0 || getLimiterActive(destConn) != 0 {
+		t.Errorf("slots should be released after the copy")
+	}
+
+	// a copy within one connection takes a single slot
+	sameConn := &connparse.Connection{Scheme: connparse.ConnectionTypeWsh, Host: "limit-src", Path: "c.txt"}
+	if err := CopyWithClients(context.Background(), client, srcConn, client, sameConn, nil); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if client.srcActive != 1 {
+		t.Errorf("expected one slot for a same-connection copy, got %d", client.srcActive)
+	}
+}
+
+// streamClient streams whatever is sent on ch
+type streamClient struct {
+	fstype.FileShareClient
+	ch chan wshrpc.RespOrErrorUnion[wshrpc.FileData]
+}
+
+func (c *streamClient) ReadStream(ctx context.Context, conn *connparse.Connection, data wshrpc.FileData) <-chan wshrpc.RespOrErrorUnion[wshrpc.FileData] {
+	return c.ch
+}
+
+func TestReadStreamWithClient_HoldsConnSlot(t *testing.T) {
+	conn := &connparse.Connection{Scheme: connparse.ConnectionTypeWsh, Host: "limit-stream", Path: "a.txt"}
+	client := &streamClient{ch: make(chan wshrpc.RespOrErrorUnion[wshrpc.FileData])}
+	out := ReadStreamWithClient(context.Background(), client, conn, wshrpc.FileData{})
+	go func() {
+		client.ch <- wshrpc.RespOrErrorUnion[wshrpc.FileData]{Response: wshrpc.FileData{Data64: "eA=="}}
+	}()
+	<-out
+	if active := getLimiterActive(conn); active != 1 {
+		t.Errorf("expected the open stream to hold a slot, got %d", active)
+	}
+	close(client.ch)
+	for range out {
+	}
+	if active := getLimiterActive(conn); active != 0 {
+		t.Errorf("expected the slot to be released when the stream closes, got %d", active)
+	}
+}
@@ -52,6 +52,11 @@ func Read(ctx context.Context, data wshrpc.FileData) (*wshrpc.FileData, error) {
 	if conn == nil || client == nil {
 		return nil, fmt.Errorf(ErrorParsingConnection, data.Info.Path)
 	}
+	release, err := acquireConnSlot(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	return client.Read(ctx, conn, data)
 }
 
@@ -61,7 +66,17 @@ func ReadStream(ctx context.Context, data wshrpc.FileData) <-chan wshrpc.RespOrE
 	if conn == nil || client == nil {
 		return wshutil.SendErrCh[wshrpc.FileData](fmt.Errorf(ErrorParsingConnection, data.Info.Path))
 	}
-	return client.ReadStream(ctx, conn, data)
+	return ReadStreamWithClient(ctx, client, conn, data)
+}
+
+// ReadStreamWithClient streams using a client that was already created for conn, the stream holds a slot on conn
+// until it closes
+func ReadStreamWithClient(ctx context.Context, client fstype.FileShareClient, conn *connparse.Connection, data wshrpc.FileData) <-chan wshrpc.RespOrErrorUnion[wshrpc.FileData] {
+	release, err := acquireConnSlot(ctx, conn)
+	if err != nil {
+		return wshutil.SendErrCh[wshrpc.FileData](err)
+	}
+	return limitStream(ctx, release, client.ReadStream(ctx, conn, data))
 }
 
 func ReadTarStream(ctx context.Context, data wshrpc.CommandRemoteStreamTarData) <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
@@ -79,6 +94,11 @@ func ListEntries(ctx context.Context, path string, opts *wshrpc.FileListOpts) ([
 	if conn == nil || client == nil {
 		return nil, fmt.Errorf(ErrorParsingConnection, path)
 	}
+	release, err := acquireConnSlot(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	return client.ListEntries(ctx, conn, opts)
 }
 
@@ -88,7 +108,11 @@ func ListEntriesStream(ctx context.Context, path string, opts *wshrpc.FileListOp
 	if conn == nil || client == nil {
 		return wshutil.SendErrCh[wshrpc.CommandRemoteListEntriesRtnData](fmt.Errorf(ErrorParsingConnection, path))
 	}
-	return client.ListEntriesStream(ctx, conn, opts)
+	release, err := acquireConnSlot(ctx, conn)
+	if err != nil {
+		return wshutil.SendErrCh[wshrpc.CommandRemoteListEntriesRtnData](err)
+	}
+	return limitStream(ctx, release, client.ListEntriesStream(ctx, conn, opts))
 }
 
 func Stat(ctx context.Context, path string) (*wshrpc.FileInfo, error) {
@@ -97,6 +121,11 @@ func Stat(ctx context.Context, path string) (*wshrpc.FileInfo, error) {
 	if conn == nil || client == nil {
 		return nil, fmt.Errorf(ErrorParsingConnection, path)
 	}
+	release, err := acquireConnSlot(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	return client.Stat(ctx, conn)
 }
 
@@ -106,6 +135,11 @@ func PutFile(ctx context.Context, data wshrpc.FileData) error {
 	if conn == nil || client == nil {
 		return fmt.Errorf(ErrorParsingConnection, data.Info.Path)
 	}
+	release, err := acquireConnSlot(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer release()
 	return client.PutFile(ctx, conn, data)
 }
 
@@ -115,6 +149,11 @@ func Mkdir(ctx context.Context, path string) error {
 	if conn == nil || client == nil {
 		return fmt.Errorf(ErrorParsingConnection, path)
 	}
+	release, err := acquireConnSlot(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer release()
 	return client.Mkdir(ctx, conn)
 }
 
@@ -132,10 +171,25 @@ func Move(ctx context.Context, data wshrpc.CommandFileCopyData) error {
 	if destConn == nil || destClient == nil {
 		return fmt.Errorf("error creating fileshare client, could not parse destination connection %s", data.DestUri)
 	}
+	return MoveWithClients(ctx, srcClient, srcConn, destClient, destConn, opts)
+}
+
+// MoveWithClients moves using clients that were already created for the source and destination.  within a
+// connection the source does a move, across connections the destination pulls a copy and the source is deleted.
+// the move holds a slot on both connections.
+func MoveWithClients(ctx context.Context, srcClient fstype.FileShareClient, srcConn *connparse.Connection, destClient fstype.FileShareClient, destConn *connparse.Connection, opts *wshrpc.FileCopyOpts) error {
+	release, err := AcquireConnSlots(ctx, srcConn, destConn)
+	if err != nil {
+		return err
+	}
+	defer release()
 	if srcConn.Host != destConn.Host {
-		isDir, err := destClient.CopyRemote(ctx, srcConn, destConn, srcClient, opts)
+		// a move keeps the exact modes, the same as a rename would
+		copyOpts := *opts
+		copyOpts.PreserveMode = true
+		isDir, err := destClient.CopyRemote(ctx, srcConn, destConn, srcClient, &copyOpts)
 		if err != nil {
-			return fmt.Errorf("cannot copy %q to %q: %w", data.SrcUri, data.DestUri, err)
+			return fmt.Errorf("cannot copy %q to %q: %w", srcConn.GetFullURI(), destConn.GetFullURI(), err)
 		}
 		return srcClient.Delete(ctx, srcConn, opts.Recursive && isDir)
 	} else {
@@ -158,6 +212,18 @@ func Copy(ctx context.Context, data wshrpc.CommandFileCopyData) error {
 	if destConn == nil || destClient == nil {
 		return fmt.Errorf("error creating fileshare client, could not parse destination connection %s", data.DestUri)
 	}
+	return CopyWithClients(ctx, srcClient, srcConn, destClient, destConn, opts)
+}
+
+// CopyWithClients copies using clients that were already created for the source and destination.  within a
+// connection the copy is done by the source, across connections the destination pulls the data from the source.
+// the copy holds a slot on both connections.
+func CopyWithClients(ctx context.Context, srcClient fstype.FileShareClient, srcConn *connparse.Connection, destClient fstype.FileShareClient, destConn *connparse.Connection, opts *wshrpc.FileCopyOpts) error {
+	release, err := AcquireConnSlots(ctx, srcConn, destConn)
+	if err != nil {
+		return err
+	}
+	defer release()
 	if srcConn.Host != destConn.Host {
 		_, err := destClient.CopyRemote(ctx, srcConn, destConn, srcClient, opts)
 		return err
@@ -173,6 +239,11 @@ func Delete(ctx context.Context, data wshrpc.CommandDeleteFileData) error {
 	if conn == nil || client == nil {
 		return fmt.Errorf(ErrorParsingConnection, data.Path)
 	}
+	release, err := acquireConnSlot(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer release()
 	return client.Delete(ctx, conn, data.Recursive)
 }
 
@@ -182,6 +253,11 @@ func Join(ctx context.Context, path string, parts ...string) (*wshrpc.FileInfo,
 	if conn == nil || client == nil {
 		return nil, fmt.Errorf(ErrorParsingConnection, path)
 	}
+	release, err := acquireConnSlot(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	return client.Join(ctx, conn, parts...)
 }
 
@@ -191,6 +267,11 @@ func Append(ctx context.Context, data wshrpc.FileData) error {
 	if conn == nil || client == nil {
 		return fmt.Errorf(ErrorParsingConnection, data.Info.Path)
 	}
+	release, err := acquireConnSlot(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer release()
 	return client.AppendFile(ctx, conn, data)
 }
 
@@ -20,6 +20,7 @@ const (
 	RecursiveRequiredError             = "recursive flag must be set for directory operations"
 	MergeRequiredError                 = "directory already exists at %q, set overwrite flag to delete the existing contents or set merge flag to merge the contents"
 	OverwriteRequiredError             = "file already exists at %q, set overwrite flag to delete the existing file"
+	WriteConflictError                 = "file at %q was modified since it was read"
 )
 
 type FileShareClient interface {
@@ -51,6 +52,8 @@ type FileShareClient interface {
 	Delete(ctx context.Context, conn *connparse.Connection, recursive bool) error
 	// Join joins the given parts to the connection path
 	Join(ctx context.Context, conn *connparse.Connection, parts ...string) (*wshrpc.FileInfo, error)
+	// DiskUsage returns the total size and file count of the tree at the given path. If computing it takes longer than timeout, the partial totals are returned with Truncated set
+	DiskUsage(ctx context.Context, conn *connparse.Connection, timeout time.Duration) (*wshrpc.CommandRemoteDiskUsageRtnData, error)
 	// GetConnectionType returns the type of connection for the fileshare
 	GetConnectionType() string
 	// GetCapability returns the capability of the fileshare
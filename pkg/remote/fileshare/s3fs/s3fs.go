@@ -232,8 +232,9 @@ func (c S3Client) ReadTarStream(ctx context.Context, conn *connparse.Connection,
 
 	rtn, writeHeader, fileWriter, tarClose := tarcopy.TarCopySrc(readerCtx, tarPathPrefix)
 	go func() {
+		var streamErr error
 		defer func() {
-			tarClose()
+			tarClose(streamErr)
 			cancel()
 		}()
 
@@ -301,12 +302,12 @@ func (c S3Client) ReadTarStream(ctx context.Context, conn *connparse.Connection,
 				go getObjectAndFileInfo(obj)
 				return true, nil
 			}); err != nil {
-				rtn <- wshutil.RespErr[iochantypes.Packet](err)
+				streamErr = err
 				return
 			}
 			wg.Wait()
 			if len(errs) > 0 {
-				rtn <- wshutil.RespErr[iochantypes.Packet](errors.Join(errs...))
+				streamErr = errors.Join(errs...)
 				return
 			}
 		}
@@ -348,7 +349,7 @@ func (c S3Client) ReadTarStream(ctx context.Context, conn *connparse.Connection,
 			return nil
 		}); err != nil {
 			log.Printf("error walking tree: %v", err)
-			rtn <- wshutil.RespErr[iochantypes.Packet](err)
+			streamErr = err
 			return
 		}
 	}()
@@ -804,6 +805,10 @@ func (c S3Client) GetConnectionType() string {
 	return connparse.ConnectionTypeS3
 }
 
+func (c S3Client) DiskUsage(ctx context.Context, conn *connparse.Connection, timeout time.Duration) (*wshrpc.CommandRemoteDiskUsageRtnData, error) {
+	return nil, errors.Join(errors.ErrUnsupported, fmt.Errorf("disk usage not supported"))
+}
+
 func (c S3Client) GetCapability() wshrpc.FileShareCapability {
 	return wshrpc.FileShareCapability{
 		CanAppend: false,
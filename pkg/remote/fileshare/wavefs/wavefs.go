@@ -163,19 +163,20 @@ func (c WaveClient) ReadTarStream(ctx context.Context, conn *connparse.Connectio
 	rtn, writeHeader, fileWriter, tarClose := tarcopy.TarCopySrc(readerCtx, pathPrefix)
 
 	go func() {
+		var streamErr error
 		defer func() {
-			tarClose()
+			tarClose(streamErr)
 			cancel()
 		}()
 		for _, file := range entries {
 			if readerCtx.Err() != nil {
-				rtn <- wshutil.RespErr[iochantypes.Packet](context.Cause(readerCtx))
+				streamErr = context.Cause(readerCtx)
 				return
 			}
 			file.Mode = 0644
 
 			if err = writeHeader(fileutil.ToFsFileInfo(file), file.Path, singleFile); err != nil {
-				rtn <- wshutil.RespErr[iochantypes.Packet](fmt.Errorf("error writing tar header: %w", err))
+				streamErr = fmt.Errorf("error writing tar header: %w", err)
 				return
 			}
 			if file.IsDir {
@@ -188,11 +189,11 @@ func (c WaveClient) ReadTarStream(ctx context.Context, conn *connparse.Connectio
 
 			_, dataBuf, err := filestore.WFS.ReadFile(ctx, conn.Host, internalPath)
 			if err != nil {
-				rtn <- wshutil.RespErr[iochantypes.Packet](fmt.Errorf("error reading blockfile: %w", err))
+				streamErr = fmt.Errorf("error reading blockfile: %w", err)
 				return
 			}
 			if _, err = fileWriter.Write(dataBuf); err != nil {
-				rtn <- wshutil.RespErr[iochantypes.Packet](fmt.Errorf("error writing tar data: %w", err))
+				streamErr = fmt.Errorf("error writing tar data: %w", err)
 				return
 			}
 		}
@@ -603,6 +604,33 @@ func (c WaveClient) Join(ctx context.Context, conn *connparse.Connection, parts
 	return c.Stat(ctx, conn)
 }
 
+// DiskUsage sums the wave files under the path. they are all listed from the db at once, so timeout doesn't apply
+func (c WaveClient) DiskUsage(ctx context.Context, conn *connparse.Connection, timeout time.Duration) (*wshrpc.CommandRemoteDiskUsageRtnData, error) {
+	prefix, err := cleanPath(conn.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error cleaning path: %w", err)
+	}
+	if prefix != "" {
+		prefix += fspath.Separator
+	}
+	rtn := &wshrpc.CommandRemoteDiskUsageRtnData{}
+	dirs := make(map[string]bool)
+	err = listFilesPrefix(ctx, conn.Host, prefix, func(wf *filestore.WaveFile) error {
+		rtn.TotalBytes += wf.Size
+		rtn.FileCount++
+		// wave files have no directory entries, directories are the parents of the file names
+		for dir := fspath.Dir(strings.TrimPrefix(wf.Name, prefix)); dir != "." && dir != "" && !dirs[dir]; dir = fspath.Dir(dir) {
+			dirs[dir] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	rtn.DirCount = len(dirs)
+	return rtn, nil
+}
+
 func (c WaveClient) GetCapability() wshrpc.FileShareCapability {
 	return wshrpc.FileShareCapability{
 		CanAppend: true,
@@ -6,6 +6,7 @@ package wshfs
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
 	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fstype"
@@ -97,7 +98,7 @@ func (c WshClient) AppendFile(ctx context.Context, conn *connparse.Connection, d
 }
 
 func (c WshClient) Mkdir(ctx context.Context, conn *connparse.Connection) error {
-	return wshclient.RemoteMkdirCommand(RpcClient, conn.Path, &wshrpc.RpcOpts{Route: wshutil.MakeConnectionRouteId(conn.Host)})
+	return wshclient.RemoteMkdirCommand(RpcClient, wshrpc.CommandRemoteMkdirData{Path: conn.Path}, &wshrpc.RpcOpts{Route: wshutil.MakeConnectionRouteId(conn.Host)})
 }
 
 func (c WshClient) MoveInternal(ctx context.Context, srcConn, destConn *connparse.Connection, opts *wshrpc.FileCopyOpts) error {
@@ -137,6 +138,12 @@ func (c WshClient) Join(ctx context.Context, conn *connparse.Connection, parts .
 	return wshclient.RemoteFileJoinCommand(RpcClient, append([]string{conn.Path}, parts...), &wshrpc.RpcOpts{Route: wshutil.MakeConnectionRouteId(conn.Host)})
 }
 
+func (c WshClient) DiskUsage(ctx context.Context, conn *connparse.Connection, timeout time.Duration) (*wshrpc.CommandRemoteDiskUsageRtnData, error) {
+	// the remote stops walking at timeout, leave it time to send back the partial totals
+	rpcTimeout := (timeout + fstype.DefaultTimeout).Milliseconds()
+	return wshclient.RemoteDiskUsageCommand(RpcClient, wshrpc.CommandRemoteDiskUsageData{Path: conn.Path, TimeoutMs: int(timeout.Milliseconds())}, &wshrpc.RpcOpts{Route: wshutil.MakeConnectionRouteId(conn.Host), Timeout: rpcTimeout})
+}
+
 func (c WshClient) GetConnectionType() string {
 	return connparse.ConnectionTypeWsh
 }
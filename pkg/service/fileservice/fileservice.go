@@ -0,0 +1,373 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fileservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fstype"
+	"github.com/wavetermdev/waveterm/pkg/tsgen/tsgenmeta"
+	"github.com/wavetermdev/waveterm/pkg/wconfig"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+)
+
+// used when preview:dirsizetimeoutms is not set
+const DefaultDirSizeTimeout = 10 * time.Second
+
+// used when preview:maxfilesize is not set
+const DefaultMaxFileSize = wshrpc.MaxFileSize
+
+// used when preview:filetimeoutms is not set
+const DefaultFileTimeout = fstype.DefaultTimeout
+
+// reads get an extra second of timeout for every readTimeoutBytesPerSec bytes of the file
+const readTimeoutBytesPerSec = 1024 * 1024
+
+type FileService struct{}
+
+// overridden in tests
+var createFileShareClient = fileshare.CreateFileShareClient
+
+// formatRemoteUri matches formatRemoteUri in the frontend (waveutil.ts)
+func formatRemoteUri(connection string, path string) string {
+	if connection == "" {
+		connection = wshrpc.LocalConnName
+	}
+	if strings.HasPrefix(connection, "aws:") {
+		return connection + ":s3://" + path
+	}
+	return "wsh://" + connection + "/" + path
+}
+
+func getClient(ctx context.Context, connection string, path string) (fstype.FileShareClient, *connparse.Connection, error) {
+	uri := formatRemoteUri(connection, path)
+	client, conn := createFileShareClient(ctx, uri)
+	if conn == nil || client == nil {
+		return nil, nil, fmt.Errorf(fileshare.ErrorParsingConnection, uri)
+	}
+	return client, conn, nil
+}
+
+func getMaxFileSize() int64 {
+	maxSize := wconfig.GetWatcher().GetFullConfig().Settings.PreviewMaxFileSize
+	if maxSize <= 0 {
+		return DefaultMaxFileSize
+	}
+	return maxSize
+}
+
+// getFileTimeout returns the timeout for stats and reads, 0 means no timeout
+func getFileTimeout() time.Duration {
+	timeoutMs := wconfig.GetWatcher().GetFullConfig().Settings.PreviewFileTimeoutMs
+	if timeoutMs == nil {
+		return DefaultFileTimeout
+	}
+	return time.Duration(max(*timeoutMs, 0)) * time.Millisecond
+}
+
+// readTimeout scales timeout with the size of the file being read
+func readTimeout(timeout time.Duration, size int64) time.Duration {
+	if timeout <= 0 || size <= 0 {
+		return timeout
+	}
+	return timeout + time.Duration(size/readTimeoutBytesPerSec)*time.Second
+}
+
+// withTimeout runs fn with a deadline of timeout (0 means no deadline).  fn runs on its own goroutine so a call that
+// doesn't watch its context (e.g. an rpc to a hung connection) still returns at the deadline, its late result is dropped.
+func withTimeout[T any](ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancelFn := context.WithTimeout(ctx, timeout)
+	defer cancelFn()
+	type result struct {
+		val T
+		err error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		defer func() {
+			panichandler.PanicHandler("fileservice:withTimeout", recover())
+		}()
+		val, err := fn(ctx)
+		resultCh <- result{val: val, err: err}
+	}()
+	select {
+	case res := <-resultCh:
+		return res.val, res.err
+	case <-ctx.Done():
+		var zero T
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return zero, fmt.Errorf("timed out after %v: %w", timeout, ctx.Err())
+		}
+		return zero, ctx.Err()
+	}
+}
+
+func getDirSizeTimeout() time.Duration {
+	timeoutMs := wconfig.GetWatcher().GetFullConfig().Settings.PreviewDirSizeTimeoutMs
+	if timeoutMs <= 0 {
+		return DefaultDirSizeTimeout
+	}
+	return time.Duration(timeoutMs) * time.Millisecond
+}
+
+func (svc *FileService) StatFile_Meta() tsgenmeta.MethodMeta {
+	return tsgenmeta.MethodMeta{
+		Desc:     "get the file info for a path, a missing file is returned with notfound set (not as an error)",
+		ArgNames: []string{"ctx", "connection", "path"},
+	}
+}
+
+// StatFile stats path, giving up after preview:filetimeoutms
+func (svc *FileService) StatFile(ctx context.Context, connection string, path string) (*wshrpc.FileInfo, error) {
+	client, conn, err := getClient(ctx, connection, path)
+	if err != nil {
+		return nil, err
+	}
+	return statFile(ctx, client, conn, getFileTimeout())
+}
+
+func statFile(ctx context.Context, client fstype.FileShareClient, conn *connparse.Connection, timeout time.Duration) (*wshrpc.FileInfo, error) {
+	info, err := withTimeout(ctx, timeout, func(ctx context.Context) (*wshrpc.FileInfo, error) {
+		// the slot is taken inside the timeout, so waiting on a busy connection counts against it
+		release, err := fileshare.AcquireConnSlots(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return client.Stat(ctx, conn)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot stat %q: %w", conn.GetFullURI(), err)
+	}
+	return info, nil
+}
+
+func (svc *FileService) StatFiles_Meta() tsgenmeta.MethodMeta {
+	return tsgenmeta.MethodMeta{
+		Desc:     "stat several paths on a connection in one call, results are in the order of paths and missing paths have notfound set",
+		ArgNames: []string{"ctx", "connection", "paths"},
+	}
+}
+
+// StatFiles stats every path with a single fileshare client.  a missing path doesn't fail the batch, its entry has
+// NotFound set (the same as StatFile).  any other error does, the message names the path.  each stat has its own
+// preview:filetimeoutms timeout.
+func (svc *FileService) StatFiles(ctx context.Context, connection string, paths []string) ([]*wshrpc.FileInfo, error) {
+	return statFiles(ctx, connection, paths, getFileTimeout())
+}
+
+func statFiles(ctx context.Context, connection string, paths []string, timeout time.Duration) ([]*wshrpc.FileInfo, error) {
+	rtn := make([]*wshrpc.FileInfo, 0, len(paths))
+	if len(paths) == 0 {
+		return rtn, nil
+	}
+	client, _, err := getClient(ctx, connection, paths[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		uri := formatRemoteUri(connection, path)
+		conn, err := connparse.ParseURIAndReplaceCurrentHost(ctx, uri)
+		if err != nil {
+			return nil, fmt.Errorf(fileshare.ErrorParsingConnection, uri)
+		}
+		info, err := statFile(ctx, client, conn, timeout)
+		if err != nil {
+			return nil, err
+		}
+		rtn = append(rtn, info)
+	}
+	return rtn, nil
+}
+
+func (svc *FileService) FileExists_Meta() tsgenmeta.MethodMeta {
+	return tsgenmeta.MethodMeta{
+		Desc:     "check whether a path exists.  false is only returned for a clean not found, other failures (permissions, connection) are errors",
+		ArgNames: []string{"ctx", "connection", "path"},
+	}
+}
+
+// FileExists wraps StatFile, a path that is cleanly not found (FileInfo.NotFound) is false with no error
+func (svc *FileService) FileExists(ctx context.Context, connection string, path string) (bool, error) {
+	return fileExists(ctx, connection, path, getFileTimeout())
+}
+
+func fileExists(ctx context.Context, connection string, path string, timeout time.Duration) (bool, error) {
+	client, conn, err := getClient(ctx, connection, path)
+	if err != nil {
+		return false, err
+	}
+	info, err := statFile(ctx, client, conn, timeout)
+	if err != nil {
+		return false, err
+	}
+	return !info.NotFound, nil
+}
+
+func (svc *FileService) ReadFile_Meta() tsgenmeta.MethodMeta {
+	return tsgenmeta.MethodMeta{
+		Desc:     "read a whole file (as data64), or the entries of a directory.  files larger than preview:maxfilesize are rejected",
+		ArgNames: []string{"ctx", "connection", "path"},
+	}
+}
+
+// ReadFile reads the file at path.  the size is checked against preview:maxfilesize (defaults to wshrpc.MaxFileSize)
+// before any data is transferred, larger files can be read with ReadFileStream.  the read times out after
+// preview:filetimeoutms plus a second per MB of the file.
+func (svc *FileService) ReadFile(ctx context.Context, connection string, path string) (*wshrpc.FileData, error) {
+	return readFile(ctx, connection, path, getMaxFileSize(), getFileTimeout())
+}
+
+func readFile(ctx context.Context, connection string, path string, maxSize int64, timeout time.Duration) (*wshrpc.FileData, error) {
+	client, conn, err := getClient(ctx, connection, path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := statFile(ctx, client, conn, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if info.NotFound {
+		return nil, fmt.Errorf("cannot read %q: %w", conn.GetFullURI(), fs.ErrNotExist)
+	}
+	if !info.IsDir && info.Size > maxSize {
+		return nil, fmt.Errorf("cannot read %q: file is %d bytes, over the %d byte limit (preview:maxfilesize)", conn.GetFullURI(), info.Size, maxSize)
+	}
+	rtn, err := withTimeout(ctx, readTimeout(timeout, info.Size), func(ctx context.Context) (*wshrpc.FileData, error) {
+		release, err := fileshare.AcquireConnSlots(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return client.Read(ctx, conn, wshrpc.FileData{Info: info})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", conn.GetFullURI(), err)
+	}
+	return rtn, nil
+}
+
+func (svc *FileService) ReadFileStream_Meta() tsgenmeta.MethodMeta {
+	return tsgenmeta.MethodMeta{
+		Desc:     "stream a file in chunks with no size limit (for go callers, stream methods can't be called over http)",
+		ArgNames: []string{"ctx", "connection", "path"},
+	}
+}
+
+// ReadFileStream streams the file at path, for wsh connections over RemoteStreamFileCommand.  unlike ReadFile there is
+// no size limit.  the first response carries the file info, the rest carry the data in chunks (Data64, with At set).
+func (svc *FileService) ReadFileStream(ctx context.Context, connection string, path string) <-chan wshrpc.RespOrErrorUnion[wshrpc.FileData] {
+	client, conn, err := getClient(ctx, connection, path)
+	if err != nil {
+		return wshutil.SendErrCh[wshrpc.FileData](err)
+	}
+	return fileshare.ReadStreamWithClient(ctx, client, conn, wshrpc.FileData{})
+}
+
+func (svc *FileService) GetDirectorySize_Meta() tsgenmeta.MethodMeta {
+	return tsgenmeta.MethodMeta{
+		Desc:     "get the total size and file count of a directory tree, huge trees return a partial (truncated) total",
+		ArgNames: []string{"ctx", "connection", "path"},
+	}
+}
+
+// DirectorySize is what GetDirectorySize returns, service methods only send back a single value
+type DirectorySize struct {
+	Size      int64 `json:"size"`
+	FileCount int   `json:"filecount"`
+	Truncated bool  `json:"truncated,omitempty"` // the walk timed out, Size and FileCount only cover part of the tree
+}
+
+// GetDirectorySize returns the total size and file count of the tree at path.  the walk gives up after
+// preview:dirsizetimeoutms and returns what it counted so far with Truncated set.
+func (svc *FileService) GetDirectorySize(ctx context.Context, connection string, path string) (*DirectorySize, error) {
+	size, fileCount, truncated, err := getDirectorySize(ctx, connection, path, getDirSizeTimeout())
+	if err != nil {
+		return nil, err
+	}
+	return &DirectorySize{Size: size, FileCount: fileCount, Truncated: truncated}, nil
+}
+
+func getDirectorySize(ctx context.Context, connection string, path string, timeout time.Duration) (size int64, fileCount int, truncated bool, err error) {
+	client, conn, err := getClient(ctx, connection, path)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	release, err := fileshare.AcquireConnSlots(ctx, conn)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer release()
+	rtn, err := client.DiskUsage(ctx, conn, timeout)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("cannot get size of %q: %w", conn.GetFullURI(), err)
+	}
+	return rtn.TotalBytes, rtn.FileCount, rtn.Truncated, nil
+}
+
+func (svc *FileService) MoveFile_Meta() tsgenmeta.MethodMeta {
+	return tsgenmeta.MethodMeta{
+		Desc:     "move or rename a file or directory within a connection, an existing destination is only replaced with overwrite set",
+		ArgNames: []string{"ctx", "connection", "srcPath", "destPath", "overwrite"},
+	}
+}
+
+// MoveFile moves srcPath to destPath on connection.  for progress on moves that fall back to copy+delete (across
+// filesystems), use the RemoteFileMoveStreamCommand rpc instead.
+func (svc *FileService) MoveFile(ctx context.Context, connection string, srcPath string, destPath string, overwrite bool) error {
+	srcClient, srcConn, err := getClient(ctx, connection, srcPath)
+	if err != nil {
+		return err
+	}
+	destClient, destConn, err := getClient(ctx, connection, destPath)
+	if err != nil {
+		return err
+	}
+	opts := &wshrpc.FileCopyOpts{Overwrite: overwrite, Recursive: true}
+	if err := fileshare.MoveWithClients(ctx, srcClient, srcConn, destClient, destConn, opts); err != nil {
+		return fmt.Errorf("cannot move %q to %q: %w", srcConn.GetFullURI(), destConn.GetFullURI(), err)
+	}
+	return nil
+}
+
+func (svc *FileService) CopyFile_Meta() tsgenmeta.MethodMeta {
+	return tsgenmeta.MethodMeta{
+		Desc:     "copy a file or directory, within or across connections.  the copy runs server to server, the data never goes through the frontend",
+		ArgNames: []string{"ctx", "srcConnection", "srcPath", "destConnection", "destPath", "opts"},
+	}
+}
+
+// CopyFile copies srcPath on srcConnection to destPath on destConnection, directories are always copied recursively.
+// an existing destination is only replaced with opts.Overwrite (or merged into with opts.Merge), opts may be nil.
+func (svc *FileService) CopyFile(ctx context.Context, srcConnection string, srcPath string, destConnection string, destPath string, opts *wshrpc.FileCopyOpts) error {
+	srcClient, srcConn, err := getClient(ctx, srcConnection, srcPath)
+	if err != nil {
+		return err
+	}
+	destClient, destConn, err := getClient(ctx, destConnection, destPath)
+	if err != nil {
+		return err
+	}
+	copyOpts := wshrpc.FileCopyOpts{}
+	if opts != nil {
+		copyOpts = *opts
+	}
+	copyOpts.Recursive = true
+	if err := fileshare.CopyWithClients(ctx, srcClient, srcConn, destClient, destConn, &copyOpts); err != nil {
+		return fmt.Errorf("cannot copy %q to %q: %w", srcConn.GetFullURI(), destConn.GetFullURI(), err)
+	}
+	return nil
+}
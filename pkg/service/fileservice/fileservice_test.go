@@ -0,0 +1,419 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fileservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/fstype"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// fakeDiskUsageClient only implements DiskUsage, it reports a fixed tree and records what it was asked for
+type fakeDiskUsageClient struct {
+	fstype.FileShareClient
+	tree    *wshrpc.CommandRemoteDiskUsageRtnData
+	conn    *connparse.Connection
+	timeout time.Duration
+}
+
+func (c *fakeDiskUsageClient) DiskUsage(ctx context.Context, conn *connparse.Connection, timeout time.Duration) (*wshrpc.CommandRemoteDiskUsageRtnData, error) {
+	c.conn = conn
+	c.timeout = timeout
+	return c.tree, nil
+}
+
+// memFileClient keeps files in memory, keyed by host and path, and records which copy method was used.  statErr
+// fails every stat.
+type memFileClient struct {
+	fstype.FileShareClient
+	files    map[string]string
+	statErr  error
+	lastCall string
+}
+
+func memFileKey(conn *connparse.Connection) string {
+	return conn.Host + ":" + conn.Path
+}
+
+func (c *memFileClient) copyFile(srcConn, destConn *connparse.Connection, opts *wshrpc.FileCopyOpts) error {
+	data, ok := c.files[memFileKey(srcConn)]
+	if !ok {
+		return fmt.Errorf("%q not found", srcConn.Path)
+	}
+	if _, exists := c.files[memFileKey(destConn)]; exists && !opts.Overwrite {
+		return fmt.Errorf(fstype.OverwriteRequiredError, destConn.Path)
+	}
+	c.files[memFileKey(destConn)] = data
+	return nil
+}
+
+func (c *memFileClient) Stat(ctx context.Context, conn *connparse.Connection) (*wshrpc.FileInfo, error) {
+	if c.statErr != nil {
+		return nil, c.statErr
+	}
+	data, ok := c.files[memFileKey(conn)]
+	if !ok {
+		return &wshrpc.FileInfo{Path: conn.Path, NotFound: true}, nil
+	}
+	return &wshrpc.FileInfo{Path: conn.Path, Size: int64(len(data))}, nil
+}
+
+func (c *memFileClient) Read(ctx context.Context, conn *connparse.Connection, data wshrpc.FileData) (*wshrpc.FileData, error) {
+	c.lastCall = "Read"
+	return &wshrpc.FileData{Info: data.Info, Data64: base64.StdEncoding.EncodeToString([]byte(c.files[memFileKey(conn)]))}, nil
+}
+
+func (c *memFileClient) CopyInternal(ctx context.Context, srcConn, destConn *connparse.Connection, opts *wshrpc.FileCopyOpts) (bool, error) {
+	c.lastCall = "CopyInternal"
+	return false, c.copyFile(srcConn, destConn, opts)
+}
+
+func (c *memFileClient) CopyRemote(ctx context.Context, srcConn, destConn *connparse.Connection, srcClient fstype.FileShareClient, opts *wshrpc.FileCopyOpts) (bool, error) {
+	c.lastCall = "CopyRemote"
+	return false, c.copyFile(srcConn, destConn, opts)
+}
+
+func (c *memFileClient) MoveInternal(ctx context.Context, srcConn, destConn *connparse.Connection, opts *wshrpc.FileCopyOpts) error {
+	c.lastCall = "MoveInternal"
+	if err := c.copyFile(srcConn, destConn, opts); err != nil {
+		return err
+	}
+	delete(c.files, memFileKey(srcConn))
+	return nil
+}
+
+// slowFileClient answers stats and reads after a delay, ignoring its context the way a hung connection would
+type slowFileClient struct {
+	*memFileClient
+	statDelay time.Duration
+	readDelay time.Duration
+}
+
+func (c *slowFileClient) Stat(ctx context.Context, conn *connparse.Connection) (*wshrpc.FileInfo, error) {
+	time.Sleep(c.statDelay)
+	return c.memFileClient.Stat(ctx, conn)
+}
+
+func (c *slowFileClient) Read(ctx context.Context, conn *connparse.Connection, data wshrpc.FileData) (*wshrpc.FileData, error) {
+	time.Sleep(c.readDelay)
+	return c.memFileClient.Read(ctx, conn, data)
+}
+
+const bigFileChunkSize = 1024 * 1024
+
+// bigFileClient serves a file of size bytes, streamed in bigFileChunkSize chunks without holding it in memory
+type bigFileClient struct {
+	fstype.FileShareClient
+	size int64
+}
+
+func (c *bigFileClient) Stat(ctx context.Context, conn *connparse.Connection) (*wshrpc.FileInfo, error) {
+	return &wshrpc.FileInfo{Path: conn.Path, Size: c.size}, nil
+}
+
+func (c *bigFileClient) ReadStream(ctx context.Context, conn *connparse.Connection, data wshrpc.FileData) <-chan wshrpc.RespOrErrorUnion[wshrpc.FileData] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.FileData], 16)
+	go func() {
+		defer close(ch)
+		ch <- wshrpc.RespOrErrorUnion[wshrpc.FileData]{Response: wshrpc.FileData{Info: &wshrpc.FileInfo{Path: conn.Path, Size: c.size}}}
+		fullChunk := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("x"), bigFileChunkSize))
+		for offset := int64(0); offset < c.size; offset += bigFileChunkSize {
+			chunkSize := min(c.size-offset, bigFileChunkSize)
+			data64 := fullChunk
+			if chunkSize < bigFileChunkSize {
+				data64 = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("x"), int(chunkSize)))
+			}
+			ch <- wshrpc.RespOrErrorUnion[wshrpc.FileData]{Response: wshrpc.FileData{Data64: data64, At: &wshrpc.FileDataAt{Offset: offset, Size: int(chunkSize)}}}
+		}
+	}()
+	return ch
+}
+
+func useFakeClient(t *testing.T, client fstype.FileShareClient) {
+	orig := createFileShareClient
+	t.Cleanup(func() { createFileShareClient = orig })
+	createFileShareClient = func(ctx context.Context, connection string) (fstype.FileShareClient, *connparse.Connection) {
+		conn, err := connparse.ParseURI(connection)
+		if err != nil {
+			return nil, nil
+		}
+		return client, conn
+	}
+}
+
+func TestGetDirectorySize(t *testing.T) {
+	client := &fakeDiskUsageClient{tree: &wshrpc.CommandRemoteDiskUsageRtnData{TotalBytes: 12345, FileCount: 7, DirCount: 2}}
+	useFakeClient(t, client)
+	size, fileCount, truncated, err := getDirectorySize(context.Background(), "user@host", "/home/user/src", 5*time.Second)
+	if err != nil {
+		t.Fatalf("getDirectorySize: %v", err)
+	}
+	if size != 12345 || fileCount != 7 || truncated {
+		t.Errorf("got %d bytes in %d files (truncated %v), want 12345 bytes in 7 files", size, fileCount, truncated)
+	}
+	if client.conn.Host != "user@host" || client.conn.Path != "/home/user/src" {
+		t.Errorf("got connection %q path %q", client.conn.Host, client.conn.Path)
+	}
+	if client.timeout != 5*time.Second {
+		t.Errorf("got timeout %v, want 5s", client.timeout)
+	}
+
+	client.tree = &wshrpc.CommandRemoteDiskUsageRtnData{TotalBytes: 100, FileCount: 1, Truncated: true}
+	size, fileCount, truncated, err = getDirectorySize(context.Background(), "", "~/huge", time.Millisecond)
+	if err != nil {
+		t.Fatalf("getDirectorySize: %v", err)
+	}
+	if !truncated || size != 100 || fileCount != 1 {
+		t.Errorf("expected the partial total to be returned, got %d bytes in %d files (truncated %v)", size, fileCount, truncated)
+	}
+	if client.conn.Host != wshrpc.LocalConnName {
+		t.Errorf("expected an empty connection to mean %q, got %q", wshrpc.LocalConnName, client.conn.Host)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	client := &memFileClient{files: map[string]string{"local:/src/a.txt": "hello"}}
+	useFakeClient(t, client)
+	svc := &FileService{}
+	ctx := context.Background()
+
+	if err := svc.CopyFile(ctx, "", "/src/a.txt", "", "/dest/a.txt", nil); err != nil {
+		t.Fatalf("CopyFile: %v", err)
+	}
+	if client.files["local:/dest/a.txt"] != "hello" || client.lastCall != "CopyInternal" {
+		t.Errorf("same connection: got %q via %s, want a CopyInternal copy", client.files["local:/dest/a.txt"], client.lastCall)
+	}
+
+	// across connections the destination pulls the file from the source
+	if err := svc.CopyFile(ctx, "", "/src/a.txt", "user@host", "/home/user/a.txt", &wshrpc.FileCopyOpts{}); err != nil {
+		t.Fatalf("CopyFile: %v", err)
+	}
+	if client.files["user@host:/home/user/a.txt"] != "hello" || client.lastCall != "CopyRemote" {
+		t.Errorf("cross connection: got %q via %s, want a CopyRemote copy", client.files["user@host:/home/user/a.txt"], client.lastCall)
+	}
+
+	client.files["local:/src/a.txt"] = "changed"
+	err := svc.CopyFile(ctx, "", "/src/a.txt", "", "/dest/a.txt", nil)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected an overwrite error copying onto an existing file, got %v", err)
+	}
+	if err := svc.CopyFile(ctx, "", "/src/a.txt", "", "/dest/a.txt", &wshrpc.FileCopyOpts{Overwrite: true}); err != nil {
+		t.Fatalf("CopyFile with overwrite: %v", err)
+	}
+	if client.files["local:/dest/a.txt"] != "changed" {
+		t.Errorf("overwrite: got %q", client.files["local:/dest/a.txt"])
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	client := &memFileClient{files: map[string]string{"local:/src/a.txt": "new", "local:/dest/a.txt": "old"}}
+	useFakeClient(t, client)
+	svc := &FileService{}
+	ctx := context.Background()
+
+	err := svc.MoveFile(ctx, "", "/src/a.txt", "/dest/a.txt", false)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected an overwrite error moving onto an existing file, got %v", err)
+	}
+	if client.files["local:/src/a.txt"] != "new" || client.files["local:/dest/a.txt"] != "old" {
+		t.Errorf("a failed move changed the files: %v", client.files)
+	}
+
+	if err := svc.MoveFile(ctx, "", "/src/a.txt", "/dest/a.txt", true); err != nil {
+		t.Fatalf("MoveFile with overwrite: %v", err)
+	}
+	if client.lastCall != "MoveInternal" {
+		t.Errorf("got %s, want a MoveInternal move", client.lastCall)
+	}
+	if _, ok := client.files["local:/src/a.txt"]; ok {
+		t.Errorf("source still exists after the move")
+	}
+	if client.files["local:/dest/a.txt"] != "new" {
+		t.Errorf("overwrite: got %q, want %q", client.files["local:/dest/a.txt"], "new")
+	}
+}
+
+func TestStatFiles(t *testing.T) {
+	client := &memFileClient{files: map[string]string{
+		"local:/dir/a.txt": "aaa",
+		"local:/dir/c.txt": "c",
+	}}
+	clientsCreated := 0
+	useFakeClient(t, client)
+	createClient := createFileShareClient
+	createFileShareClient = func(ctx context.Context, connection string) (fstype.FileShareClient, *connparse.Connection) {
+		clientsCreated++
+		return createClient(ctx, connection)
+	}
+
+	paths := []string{"/dir/c.txt", "/dir/missing.txt", "/dir/a.txt"}
+	rtn, err := statFiles(context.Background(), "", paths, time.Second)
+	if err != nil {
+		t.Fatalf("StatFiles: %v", err)
+	}
+	if clientsCreated != 1 {
+		t.Errorf("created %d fileshare clients, expected one for the batch", clientsCreated)
+	}
+	if len(rtn) != len(paths) {
+		t.Fatalf("got %d results for %d paths", len(rtn), len(paths))
+	}
+	for idx, path := range paths {
+		if rtn[idx].Path != path {
+			t.Errorf("result %d is for %q, expected %q", idx, rtn[idx].Path, path)
+		}
+	}
+	if rtn[0].NotFound || rtn[0].Size != 1 || rtn[2].NotFound || rtn[2].Size != 3 {
+		t.Errorf("got %+v and %+v for the existing files", rtn[0], rtn[2])
+	}
+	if !rtn[1].NotFound {
+		t.Errorf("expected the missing file to have NotFound set, got %+v", rtn[1])
+	}
+}
+
+func TestFileExists(t *testing.T) {
+	client := &memFileClient{files: map[string]string{"local:/here.txt": "data"}}
+	useFakeClient(t, client)
+	ctx := context.Background()
+
+	if exists, err := fileExists(ctx, "", "/here.txt", time.Second); err != nil || !exists {
+		t.Errorf("existing file: got %v, %v", exists, err)
+	}
+	if exists, err := fileExists(ctx, "", "/gone.txt", time.Second); err != nil || exists {
+		t.Errorf("missing file: got %v, %v, want false with no error", exists, err)
+	}
+	client.statErr = fs.ErrPermission
+	exists, err := fileExists(ctx, "", "/here.txt", time.Second)
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected the permission error to be returned, got %v, %v", exists, err)
+	}
+}
+
+func TestReadFile_MaxSize(t *testing.T) {
+	client := &memFileClient{files: map[string]string{
+		"local:/small.txt": "0123456789",
+		"local:/big.txt":   strings.Repeat("x", 11),
+	}}
+	useFakeClient(t, client)
+	ctx := context.Background()
+
+	rtn, err := readFile(ctx, "", "/small.txt", 10, time.Second)
+	if err != nil {
+		t.Fatalf("readFile: %v", err)
+	}
+	if rtn.Data64 != base64.StdEncoding.EncodeToString([]byte("0123456789")) {
+		t.Errorf("got data64 %q", rtn.Data64)
+	}
+
+	client.lastCall = ""
+	_, err = readFile(ctx, "", "/big.txt", 10, time.Second)
+	if err == nil {
+		t.Fatal("expected reading a file over the limit to fail")
+	}
+	if !strings.Contains(err.Error(), "11 bytes") || !strings.Contains(err.Error(), "10 byte limit") {
+		t.Errorf("expected the error to state the size and the limit, got %v", err)
+	}
+	if client.lastCall == "Read" {
+		t.Error("the file was read even though it is over the limit")
+	}
+	// a raised limit lets it through
+	if _, err := readFile(ctx, "", "/big.txt", 11, time.Second); err != nil {
+		t.Errorf("readFile with a raised limit: %v", err)
+	}
+
+	if _, err := readFile(ctx, "", "/missing.txt", 10, time.Second); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestFileTimeouts(t *testing.T) {
+	// a fresh client per case, a timed out call is still running against the previous one
+	useSlowClient := func(statDelay time.Duration, readDelay time.Duration) {
+		useFakeClient(t, &slowFileClient{
+			memFileClient: &memFileClient{files: map[string]string{"local:/slow.txt": "data"}},
+			statDelay:     statDelay,
+			readDelay:     readDelay,
+		})
+	}
+	ctx := context.Background()
+
+	useSlowClient(time.Second, 0)
+	start := time.Now()
+	_, err := statFiles(ctx, "", []string{"/slow.txt"}, 20*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timed out after 20ms") {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("a hung stat took %v to time out", elapsed)
+	}
+
+	// a timeout of 0 waits as long as it takes
+	useSlowClient(30*time.Millisecond, 0)
+	if _, err := statFiles(ctx, "", []string{"/slow.txt"}, 0); err != nil {
+		t.Errorf("statFiles without a timeout: %v", err)
+	}
+
+	// the read has its own deadline, after a stat that made it in time
+	useSlowClient(0, time.Second)
+	if _, err := readFile(ctx, "", "/slow.txt", 1024, 20*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the read to time out, got %v", err)
+	}
+	useSlowClient(0, 0)
+	if _, err := readFile(ctx, "", "/slow.txt", 1024, 20*time.Millisecond); err != nil {
+		t.Errorf("readFile: %v", err)
+	}
+
+	if got := readTimeout(30*time.Second, 10*readTimeoutBytesPerSec); got != 40*time.Second {
+		t.Errorf("got a read timeout of %v for a 10MB file, want 40s", got)
+	}
+	if got := readTimeout(0, 10*readTimeoutBytesPerSec); got != 0 {
+		t.Errorf("no timeout should stay no timeout, got %v", got)
+	}
+}
+
+func TestReadFileStream_OverMaxFileSize(t *testing.T) {
+	size := int64(wshrpc.MaxFileSize + 1000)
+	useFakeClient(t, &bigFileClient{size: size})
+	ctx := context.Background()
+
+	if _, err := readFile(ctx, "", "/big.bin", DefaultMaxFileSize, time.Second); err == nil {
+		t.Fatal("expected ReadFile to reject a file over MaxFileSize")
+	}
+	var total int64
+	gotInfo := false
+	for resp := range (&FileService{}).ReadFileStream(ctx, "", "/big.bin") {
+		if resp.Error != nil {
+			t.Fatalf("ReadFileStream: %v", resp.Error)
+		}
+		if resp.Response.Info != nil {
+			gotInfo = resp.Response.Info.Size == size
+		}
+		if resp.Response.Data64 == "" {
+			continue
+		}
+		if resp.Response.At == nil || resp.Response.At.Offset != total {
+			t.Fatalf("chunk at %+v, expected offset %d", resp.Response.At, total)
+		}
+		data, err := base64.StdEncoding.DecodeString(resp.Response.Data64)
+		if err != nil {
+			t.Fatalf("chunk at offset %d: %v", total, err)
+		}
+		total += int64(len(data))
+	}
+	if !gotInfo {
+		t.Error("expected the stream to start with the file info")
+	}
+	if total != size {
+		t.Errorf("streamed %d bytes, want %d", total, size)
+	}
+}
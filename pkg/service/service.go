@@ -11,6 +11,7 @@ import (
 
 	"github.com/wavetermdev/waveterm/pkg/service/blockservice"
 	"github.com/wavetermdev/waveterm/pkg/service/clientservice"
+	"github.com/wavetermdev/waveterm/pkg/service/fileservice"
 	"github.com/wavetermdev/waveterm/pkg/service/objectservice"
 	"github.com/wavetermdev/waveterm/pkg/service/userinputservice"
 	"github.com/wavetermdev/waveterm/pkg/service/windowservice"
@@ -25,6 +26,7 @@ var ServiceMap = map[string]any{
 	"block":     blockservice.BlockServiceInstance,
 	"object":    &objectservice.ObjectService{},
 	"client":    &clientservice.ClientService{},
+	"file":      &fileservice.FileService{},
 	"window":    &windowservice.WindowService{},
 	"workspace": &workspaceservice.WorkspaceService{},
 	"userinput": &userinputservice.UserInputService{},
@@ -308,6 +310,12 @@ func convertReturnValues(rtnVals []reflect.Value) *WebReturnType {
 	return rtn
 }
 
+// IsStreamMethod reports whether a service method returns its result as a channel.  those are for go callers, the
+// http service can't stream, so CallService rejects them and tsgen leaves them out of the frontend services.
+func IsStreamMethod(methodType reflect.Type) bool {
+	return methodType.NumOut() == 1 && methodType.Out(0).Kind() == reflect.Chan
+}
+
 func webErrorRtn(err error) *WebReturnType {
 	return &WebReturnType{
 		Error: err.Error(),
@@ -323,6 +331,9 @@ func CallService(ctx context.Context, webCall WebCallType) *WebReturnType {
 	if !method.IsValid() {
 		return webErrorRtn(fmt.Errorf("invalid method: %s.%s", webCall.Service, webCall.Method))
 	}
+	if IsStreamMethod(method.Type()) {
+		return webErrorRtn(fmt.Errorf("cannot call stream method %s.%s as a service", webCall.Service, webCall.Method))
+	}
 	var valueArgs []reflect.Value
 	argIdx := 0
 	for idx := 0; idx < method.Type().NumIn(); idx++ {
@@ -400,7 +411,12 @@ func validateMethodArg(argType reflect.Type) error {
 }
 
 func validateServiceMethod(service string, method reflect.Method) error {
-	for idx := 0; idx < method.Type.NumOut(); idx++ {
+	// stream methods are never serialized by CallService, and their RespOrErrorUnion elements carry an error
+	numOut := method.Type.NumOut()
+	if IsStreamMethod(method.Type) {
+		numOut = 0
+	}
+	for idx := 0; idx < numOut; idx++ {
 		if err := validateMethodReturnArg(method.Type.Out(idx)); err != nil {
 			return fmt.Errorf("invalid return type %s.%s %s: %v", service, method.Name, method.Type.Out(idx), err)
 		}
@@ -410,7 +410,7 @@ func GenerateServiceClass(serviceName string, serviceObj any, tsTypesMap map[ref
 	isFirst := true
 	for midx := 0; midx < serviceType.NumMethod(); midx++ {
 		method := serviceType.Method(midx)
-		if strings.HasSuffix(method.Name, "_Meta") {
+		if strings.HasSuffix(method.Name, "_Meta") || service.IsStreamMethod(method.Type) {
 			continue
 		}
 		var meta tsgenmeta.MethodMeta
@@ -504,6 +504,9 @@ func GenerateServiceTypes(tsTypesMap map[reflect.Type]string) error {
 		serviceType := reflect.TypeOf(serviceObj)
 		for midx := 0; midx < serviceType.NumMethod(); midx++ {
 			method := serviceType.Method(midx)
+			if service.IsStreamMethod(method.Type) {
+				continue
+			}
 			err := generateTSMethodTypes(method, tsTypesMap, true)
 			if err != nil {
 				return fmt.Errorf("error generating TS method types for %s.%s: %v", serviceType, method.Name, err)
@@ -4,6 +4,7 @@
 package fileutil
 
 import (
+	"bytes"
 	"io"
 	"io/fs"
 	"mime"
@@ -12,6 +13,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/wavetermdev/waveterm/pkg/wavebase"
@@ -61,10 +63,143 @@ func WinSymlinkDir(path string, bits os.FileMode) bool {
 	}
 }
 
+type MimeTypeOverride struct {
+	MimeType string
+	// by default an override yields to content sniffing when the content clearly isn't what the
+	// override says (e.g. a binary file for a text/* override).  Force makes it win unconditionally.
+	Force bool
+}
+
+var mimeTypeOverridesLock sync.Mutex
+var mimeTypeOverrides map[string]MimeTypeOverride
+
+// SetMimeTypeOverrides replaces the extension -> mimetype overrides consulted by DetectMimeType.
+// extensions are matched case-insensitively, with or without the leading "."
+func SetMimeTypeOverrides(overrides map[string]MimeTypeOverride) {
+	normalized := make(map[string]MimeTypeOverride, len(overrides))
+	for ext, override := range overrides {
+		if override.MimeType == "" {
+			continue
+		}
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		normalized[ext] = override
+	}
+	mimeTypeOverridesLock.Lock()
+	defer mimeTypeOverridesLock.Unlock()
+	mimeTypeOverrides = normalized
+}
+
+func getMimeTypeOverride(ext string) (MimeTypeOverride, bool) {
+	if ext == "" {
+		return MimeTypeOverride{}, false
+	}
+	mimeTypeOverridesLock.Lock()
+	defer mimeTypeOverridesLock.Unlock()
+	override, ok := mimeTypeOverrides[strings.ToLower(ext)]
+	return override, ok
+}
+
+func isTextMimeType(mimeType string) bool {
+	if strings.HasPrefix(mimeType, "text/") || strings.HasSuffix(mimeType, "+json") || strings.HasSuffix(mimeType, "+xml") {
+		return true
+	}
+	switch mimeType {
+	case "application/json", "application/javascript", "application/xml", "application/x-sh":
+		return true
+	}
+	return false
+}
+
+// well-known files that have no extension, matched on the exact base name
+var StaticFileNameMimeTypeMap = map[string]string{
+	"Dockerfile":    "text/x-dockerfile",
+	"Containerfile": "text/x-dockerfile",
+	"Makefile":      "text/x-makefile",
+	"makefile":      "text/x-makefile",
+	"GNUmakefile":   "text/x-makefile",
+	"Jenkinsfile":   "text/x-groovy",
+	"Gemfile":       "text/x-ruby",
+	"Rakefile":      "text/x-ruby",
+	"Vagrantfile":   "text/x-ruby",
+}
+
+// keyed by interpreter name (after stripping any version suffix, e.g. python3 -> python)
+var shebangMimeTypeMap = map[string]string{
+	"sh":     "text/x-shellscript",
+	"bash":   "text/x-shellscript",
+	"zsh":    "text/x-shellscript",
+	"dash":   "text/x-shellscript",
+	"ksh":    "text/x-shellscript",
+	"fish":   "text/x-shellscript",
+	"python": "text/x-python",
+	"ruby":   "text/x-ruby",
+	"perl":   "text/x-perl",
+	"node":   "text/javascript",
+	"deno":   "text/javascript",
+	"php":    "application/x-httpd-php",
+	"lua":    "text/x-lua",
+}
+
+var interpreterVersionRe = regexp.MustCompile(`[0-9.]+$`)
+
+// returns the mimetype for a "#!" line (e.g. "#!/usr/bin/env python3"), or "" if the interpreter isn't known
+func shebangMimeType(head []byte) string {
+	if !bytes.HasPrefix(head, []byte("#!")) {
+		return ""
+	}
+	line, _, _ := bytes.Cut(head[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+	interp := filepath.Base(fields[0])
+	if interp == "env" {
+		interp = ""
+		for _, field := range fields[1:] {
+			// skip env flags (-S, -i) and VAR=value assignments
+			if strings.HasPrefix(field, "-") || strings.Contains(field, "=") {
+				continue
+			}
+			interp = filepath.Base(field)
+			break
+		}
+	}
+	return shebangMimeTypeMap[interpreterVersionRe.ReplaceAllString(interp, "")]
+}
+
+// readFileHead returns up to the first 512 bytes of the file (nil on error or empty file)
+func readFileHead(path string) []byte {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer fd.Close()
+	buf := make([]byte, 512)
+	// ignore the error (EOF / UnexpectedEOF is fine, just process how much we got back)
+	n, _ := io.ReadAtLeast(fd, buf, 512)
+	if n == 0 {
+		return nil
+	}
+	return buf[:n]
+}
+
+// sniffMimeType returns http.DetectContentType for the first 512 bytes of the file ("" on error or empty file)
+func sniffMimeType(path string) string {
+	head := readFileHead(path)
+	if head == nil {
+		return ""
+	}
+	return http.DetectContentType(head)
+}
+
 // on error just returns ""
 // does not return "application/octet-stream" as this is considered a detection failure
 // can pass an existing fileInfo to avoid re-statting the file
 // falls back to text/plain for 0 byte files
+// user overrides (SetMimeTypeOverrides) are checked before the built-in extension tables
 func DetectMimeType(path string, fileInfo fs.FileInfo, extended bool) string {
 	if fileInfo == nil {
 		statRtn, err := os.Stat(path)
@@ -88,6 +223,20 @@ func DetectMimeType(path string, fileInfo fs.FileInfo, extended bool) string {
 		return "block-special"
 	}
 	ext := filepath.Ext(path)
+	if override, ok := getMimeTypeOverride(ext); ok {
+		// the fast path can't look at the content, so the override always wins there
+		if override.Force || !extended || fileInfo.Size() == 0 || !isTextMimeType(override.MimeType) {
+			return override.MimeType
+		}
+		if sniffed := sniffMimeType(path); sniffed == "" || strings.HasPrefix(sniffed, "text/") {
+			return override.MimeType
+		}
+	}
+	if ext == "" {
+		if mimeType, ok := StaticFileNameMimeTypeMap[filepath.Base(path)]; ok {
+			return mimeType
+		}
+	}
 	if mimeType, ok := StaticMimeTypeMap[ext]; ok {
 		return mimeType
 	}
@@ -97,22 +246,18 @@ func DetectMimeType(path string, fileInfo fs.FileInfo, extended bool) string {
 	if fileInfo.Size() == 0 {
 		return "text/plain"
 	}
+	// only the extended path reads the file's content
 	if !extended {
 		return ""
 	}
-	fd, err := os.Open(path)
-	if err != nil {
+	head := readFileHead(path)
+	if head == nil {
 		return ""
 	}
-	defer fd.Close()
-	buf := make([]byte, 512)
-	// ignore the error (EOF / UnexpectedEOF is fine, just process how much we got back)
-	n, _ := io.ReadAtLeast(fd, buf, 512)
-	if n == 0 {
-		return ""
+	if mimeType := shebangMimeType(head); mimeType != "" {
+		return mimeType
 	}
-	buf = buf[:n]
-	rtn := http.DetectContentType(buf)
+	rtn := http.DetectContentType(head)
 	if rtn == "application/octet-stream" {
 		return ""
 	}
@@ -137,6 +282,14 @@ func DetectMimeTypeWithDirEnt(path string, dirEnt fs.DirEntry) string {
 		}
 	}
 	ext := filepath.Ext(path)
+	if override, ok := getMimeTypeOverride(ext); ok {
+		return override.MimeType
+	}
+	if ext == "" {
+		if mimeType, ok := StaticFileNameMimeTypeMap[filepath.Base(path)]; ok {
+			return mimeType
+		}
+	}
 	if mimeType, ok := StaticMimeTypeMap[ext]; ok {
 		return mimeType
 	}
@@ -0,0 +1,80 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir string, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDetectMimeType_Overrides(t *testing.T) {
+	SetMimeTypeOverrides(map[string]MimeTypeOverride{
+		".ts":   {MimeType: "text/x-custom-ts"},
+		"CONF":  {MimeType: "text/x-conf"},
+		".blob": {MimeType: "text/x-blob", Force: true},
+	})
+	defer SetMimeTypeOverrides(nil)
+	dir := t.TempDir()
+	binary := []byte{0x00, 0x01, 0x02, 0xff, 0xfe, 0x00, 0x47, 0x40}
+	tests := []struct {
+		name     string
+		content  []byte
+		extended bool
+		want     string
+	}{
+		{"app.ts", []byte("const x: number = 1;\n"), true, "text/x-custom-ts"},
+		{"APP.TS", []byte("const x: number = 1;\n"), false, "text/x-custom-ts"},
+		{"site.conf", []byte("key = value\n"), true, "text/x-conf"},
+		// a binary file contradicts a text override, so the built-in detection is used
+		{"video.ts", binary, true, StaticMimeTypeMap[".ts"]},
+		// the fast path can't see the content
+		{"video2.ts", binary, false, "text/x-custom-ts"},
+		{"data.blob", binary, true, "text/x-blob"},
+		// no override, passes through to the built-in tables
+		{"main.go", []byte("package main\n"), true, StaticMimeTypeMap[".go"]},
+		{"image.png", binary, false, StaticMimeTypeMap[".png"]},
+	}
+	for _, tc := range tests {
+		path := writeFile(t, dir, tc.name, tc.content)
+		if got := DetectMimeType(path, nil, tc.extended); got != tc.want {
+			t.Errorf("%s (extended=%v): got %q, expected %q", tc.name, tc.extended, got, tc.want)
+		}
+	}
+}
+
+func TestDetectMimeType_Extensionless(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		content  []byte
+		extended bool
+		want     string
+	}{
+		{"deploy", []byte("#!/bin/bash\nset -e\necho hi\n"), true, "text/x-shellscript"},
+		{"tool", []byte("#!/usr/bin/env -S python3 -u\nprint('hi')\n"), true, "text/x-python"},
+		{"server", []byte("#!/usr/bin/env node\nconsole.log(1)\n"), true, "text/javascript"},
+		// the fast path never opens the file
+		{"deploy-fast", []byte("#!/bin/bash\necho hi\n"), false, ""},
+		{"Dockerfile", []byte("FROM alpine\n"), false, "text/x-dockerfile"},
+		{"Makefile", []byte("all:\n\tgo build\n"), true, "text/x-makefile"},
+		{"notes", []byte("just some text\n"), true, "text/plain; charset=utf-8"},
+		{"program", []byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00}, true, ""},
+	}
+	for _, tc := range tests {
+		path := writeFile(t, dir, tc.name, tc.content)
+		if got := DetectMimeType(path, nil, tc.extended); got != tc.want {
+			t.Errorf("%s (extended=%v): got %q, expected %q", tc.name, tc.extended, got, tc.want)
+		}
+	}
+}
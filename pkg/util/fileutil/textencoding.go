@@ -0,0 +1,184 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package fileutil
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	TextEncoding_UTF8    = "utf-8"
+	TextEncoding_UTF16LE = "utf-16le"
+	TextEncoding_UTF16BE = "utf-16be"
+	TextEncoding_Latin1  = "iso-8859-1"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// isBinaryControl is true for control bytes that don't show up in text files (tab, newlines, form feed, backspace and escape do)
+func isBinaryControl(b byte) bool {
+	if b == 0x7f {
+		return true
+	}
+	if b >= 0x20 {
+		return false
+	}
+	switch b {
+	case '\t', '\n', '\r', '\f', '\v', '\b', 0x1b:
+		return false
+	}
+	return true
+}
+
+// looksBinary is true if buf has a NUL byte or more than 10% control bytes
+func looksBinary(buf []byte) bool {
+	numControl := 0
+	for _, b := range buf {
+		if b == 0 {
+			return true
+		}
+		if isBinaryControl(b) {
+			numControl++
+		}
+	}
+	return numControl*10 > len(buf)
+}
+
+// detectUTF16 recognizes BOM-less UTF-16 by its NUL bytes: mostly-ascii text has a zero high byte in (nearly) every code unit
+func detectUTF16(buf []byte) string {
+	if len(buf) < 4 {
+		return ""
+	}
+	var evenZeros, oddZeros int
+	for i := 0; i+1 < len(buf); i += 2 {
+		if buf[i] == 0 {
+			evenZeros++
+		}
+		if buf[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	numUnits := len(buf) / 2
+	if oddZeros*10 > numUnits*4 && evenZeros*20 < numUnits {
+		return TextEncoding_UTF16LE
+	}
+	if evenZeros*10 > numUnits*4 && oddZeros*20 < numUnits {
+		return TextEncoding_UTF16BE
+	}
+	return ""
+}
+
+// skipContinuationBytes returns the number of leading UTF-8 continuation bytes (at most 3), the tail of a rune split at the start of buf
+func skipContinuationBytes(buf []byte) int {
+	n := 0
+	for n < len(buf) && n < utf8.UTFMax-1 && !utf8.RuneStart(buf[n]) {
+		n++
+	}
+	return n
+}
+
+// trimIncompleteRune returns the length of buf without a multi-byte rune split at its end
+func trimIncompleteRune(buf []byte) int {
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if utf8.FullRune(buf[i:]) {
+				return len(buf)
+			}
+			return i
+		}
+	}
+	return len(buf)
+}
+
+// DetectTextEncoding guesses the encoding of buf, a chunk of a file.  atStart and atEOF tell whether the chunk begins at the
+// start of the file and runs to its end, runes split at the other edges are ignored.
+// it returns "" if the content looks binary.  a BOM is trusted, otherwise UTF-16 is recognized by its NUL bytes, valid
+// UTF-8 is UTF-8 and anything else that isn't binary is Latin-1.
+func DetectTextEncoding(buf []byte, atStart bool, atEOF bool) string {
+	switch {
+	case bytes.HasPrefix(buf, bomUTF8):
+		return TextEncoding_UTF8
+	case bytes.HasPrefix(buf, bomUTF16LE):
+		return TextEncoding_UTF16LE
+	case bytes.HasPrefix(buf, bomUTF16BE):
+		return TextEncoding_UTF16BE
+	}
+	if encoding := detectUTF16(buf); encoding != "" {
+		return encoding
+	}
+	if looksBinary(buf) {
+		return ""
+	}
+	start, end := 0, len(buf)
+	if !atStart {
+		start = skipContinuationBytes(buf)
+	}
+	if !atEOF {
+		end = max(trimIncompleteRune(buf), start)
+	}
+	if utf8.Valid(buf[start:end]) {
+		return TextEncoding_UTF8
+	}
+	return TextEncoding_Latin1
+}
+
+// DecodeText transcodes buf from encoding to UTF-8, dropping a BOM.  unless atEOF is set, a character split at the end
+// of buf is left out so it can be read whole with the next chunk, and unless atStart is set the tail of a UTF-8 rune split
+// at the start is skipped.  n is the number of bytes of buf that were consumed.  invalid sequences are replaced with U+FFFD.
+func DecodeText(buf []byte, encoding string, atStart bool, atEOF bool) (text string, n int, err error) {
+	switch encoding {
+	case TextEncoding_UTF8:
+		end := len(buf)
+		if !atEOF {
+			end = trimIncompleteRune(buf)
+		}
+		start := 0
+		if !atStart {
+			start = skipContinuationBytes(buf[:end])
+		}
+		if bytes.HasPrefix(buf[start:end], bomUTF8) {
+			start += len(bomUTF8)
+		}
+		return strings.ToValidUTF8(string(buf[start:end]), string(utf8.RuneError)), end, nil
+	case TextEncoding_UTF16LE, TextEncoding_UTF16BE:
+		bigEndian := encoding == TextEncoding_UTF16BE
+		units := make([]uint16, 0, len(buf)/2)
+		for i := 0; i+1 < len(buf); i += 2 {
+			if bigEndian {
+				units = append(units, uint16(buf[i])<<8|uint16(buf[i+1]))
+			} else {
+				units = append(units, uint16(buf[i+1])<<8|uint16(buf[i]))
+			}
+		}
+		n = len(units) * 2
+		if atEOF {
+			// a dangling odd byte can't be decoded
+			n = len(buf)
+		} else if len(units) > 0 && utf16.IsSurrogate(rune(units[len(units)-1])) && units[len(units)-1] < 0xdc00 {
+			// high surrogate, its pair is in the next chunk
+			units = units[:len(units)-1]
+			n -= 2
+		}
+		if len(units) > 0 && units[0] == 0xfeff {
+			units = units[1:]
+		}
+		return string(utf16.Decode(units)), n, nil
+	case TextEncoding_Latin1:
+		// latin-1 bytes are the first 256 code points
+		runes := make([]rune, len(buf))
+		for i, b := range buf {
+			runes[i] = rune(b)
+		}
+		return string(runes), len(buf), nil
+	}
+	return "", 0, fmt.Errorf("unsupported text encoding %q", encoding)
+}
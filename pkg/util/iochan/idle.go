@@ -0,0 +1,71 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package iochan
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrIdleTimeout is returned (wrapped) when a stream goes longer than its IdleTimeout without data
+var ErrIdleTimeout = errors.New("stream idle timeout")
+
+// idleTimer fires when it isn't reset within timeout, a zero timeout never fires
+type idleTimer struct {
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func newIdleTimer(timeout time.Duration) *idleTimer {
+	if timeout <= 0 {
+		return &idleTimer{}
+	}
+	return &idleTimer{timer: time.NewTimer(timeout), timeout: timeout}
+}
+
+// C returns nil (blocks forever in a select) for a disabled timer
+func (t *idleTimer) C() <-chan time.Time {
+	if t.timer == nil {
+		return nil
+	}
+	return t.timer.C
+}
+
+func (t *idleTimer) reset() {
+	if t.timer != nil {
+		t.timer.Reset(t.timeout)
+	}
+}
+
+func (t *idleTimer) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+// readWithIdleTimeout returns ErrIdleTimeout if read doesn't return within the timer's timeout.  read runs in its own
+// goroutine so a stalled reader can be abandoned, it is left to finish once the reader is closed (so after a timeout
+// the caller must not reuse the read buffer).
+func readWithIdleTimeout(timer *idleTimer, read func() (int, error)) (int, error) {
+	if timer.timer == nil {
+		return read()
+	}
+	timer.reset()
+	resultCh := make(chan readResult, 1)
+	go func() {
+		n, err := read()
+		resultCh <- readResult{n: n, err: err}
+	}()
+	select {
+	case res := <-resultCh:
+		return res.n, res.err
+	case <-timer.C():
+		return 0, ErrIdleTimeout
+	}
+}
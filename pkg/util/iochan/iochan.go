@@ -10,25 +10,216 @@ import (
 	"crypto/sha256"
 	"errors"
 	"fmt"
+	"hash"
+	"hash/crc32"
 	"io"
 	"log"
+	"net"
+	"sync"
+	"time"
 
 	"github.com/wavetermdev/waveterm/pkg/util/iochan/iochantypes"
 	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
 	"github.com/wavetermdev/waveterm/pkg/wshrpc"
 	"github.com/wavetermdev/waveterm/pkg/wshutil"
+	"golang.org/x/time/rate"
 )
 
+// ErrChecksumMismatch is passed (wrapped) to the WriterChan cancel func when the stream fails verification
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+const (
+	// DefaultBufferDepth is the number of chunks ReaderChan reads ahead of the consumer
+	DefaultBufferDepth = 32
+	MaxBufferDepth     = 1024
+)
+
+const (
+	HashAlgo_None   = "none" // no checksum is computed or verified
+	HashAlgo_Crc32  = "crc32"
+	HashAlgo_Sha256 = "sha256" // default
+)
+
+// ReaderChanOpts are the options for ReaderChanWithOpts
+type ReaderChanOpts struct {
+	ChunkSize   int64
+	BytesPerSec int64  // limits the throughput of the stream, <= 0 means unlimited
+	HashAlgo    string // hash used for the final checksum packet, defaults to sha256
+
+	// ChunkChecksums adds a checksum of each chunk (using HashAlgo) to every data packet,
+	// so WriterChan can fail on the first corrupted chunk instead of at the end of the stream
+	ChunkChecksums   bool
+	NoStreamChecksum bool // skip the final whole-stream checksum packet
+
+	// BufferDepth is the channel buffer size, i.e. how many chunks are read ahead of the consumer
+	// (defaults to DefaultBufferDepth, capped at MaxBufferDepth).  Deeper buffers keep high-latency
+	// links busy, but up to BufferDepth * ChunkSize bytes can be held in memory per stream.
+	BufferDepth int
+
+	// Retry retries transient read errors instead of failing the stream, nil means no retries
+	Retry *ReadRetryPolicy
+
+	// IdleTimeout fails the stream with ErrIdleTimeout if a single read (including its retries) takes longer than this,
+	// 0 means no timeout.  The stalled Read is abandoned, the callback should close the reader to release it.
+	IdleTimeout time.Duration
+
+	// Stats, if set, counts the data bytes as they are sent to the channel
+	Stats *TransferStats
+}
+
+// ReadRetryPolicy retries failed reads in ReaderChan.  The reader must be able to continue from its
+// current position after a failed Read (e.g. by reconnecting and seeking to the offset it has already returned),
+// otherwise data will be lost or duplicated.
+type ReadRetryPolicy struct {
+	MaxAttempts int                  // total attempts per read, including the first
+	Backoff     time.Duration        // wait before the first retry, doubled after each failed retry
+	MaxBackoff  time.Duration        // caps the wait between retries, 0 for no cap
+	Retryable   func(err error) bool // defaults to IsTransientReadError
+}
+
+// IsTransientReadError is the default ReadRetryPolicy predicate, it retries unexpected EOFs and network timeouts
+func IsTransientReadError(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// readWithRetry returns the error from the last attempt once the policy is exhausted
+func readWithRetry(ctx context.Context, r io.Reader, buf []byte, policy *ReadRetryPolicy) (int, error) {
+	backoff := time.Duration(0)
+	for attempt := 1; ; attempt++ {
+		n, err := r.Read(buf)
+		if err == nil || errors.Is(err, io.EOF) || policy == nil || attempt >= policy.MaxAttempts {
+			return n, err
+		}
+		retryable := policy.Retryable
+		if retryable == nil {
+			retryable = IsTransientReadError
+		}
+		if !retryable(err) {
+			return n, err
+		}
+		if n > 0 {
+			// deliver what was read, the next read picks up the retry
+			return n, nil
+		}
+		if attempt == 1 {
+			backoff = policy.Backoff
+		} else {
+			backoff *= 2
+		}
+		if policy.MaxBackoff > 0 {
+			backoff = min(backoff, policy.MaxBackoff)
+		}
+		log.Printf("ReaderChan: read error (attempt %d of %d), retrying in %v: %v\n", attempt, policy.MaxAttempts, backoff, err)
+		select {
+		case <-ctx.Done():
+			return 0, err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// WriterChanOpts are the options for WriterChanWithOpts
+type WriterChanOpts struct {
+	HashAlgo    string         // must match the HashAlgo of the ReaderChan, defaults to sha256
+	IdleTimeout time.Duration  // cancel with ErrIdleTimeout if no packet arrives for this long, 0 means no timeout
+	Stats       *TransferStats // if set, counts the data bytes as they are written
+}
+
+// bufFreeLists holds a free list (chan []byte) of chunk buffers per chunk size.  ReaderChan takes its read
+// buffers from here and WriterChan returns them once written (io.Writer implementations must not retain the slice).
+// Packets that are never passed to WriterChan (e.g. sent over rpc) are simply left for the GC.
+// A channel is used rather than a sync.Pool because putting a slice into a sync.Pool allocates.
+var bufFreeLists sync.Map
+
+const bufFreeListSize = 64
+
+func getFreeList(size int64) chan []byte {
+	freeList, ok := bufFreeLists.Load(size)
+	if !ok {
+		freeList, _ = bufFreeLists.LoadOrStore(size, make(chan []byte, bufFreeListSize))
+	}
+	return freeList.(chan []byte)
+}
+
+func getBuffer(size int64) []byte {
+	select {
+	case buf := <-getFreeList(size):
+		return buf
+	default:
+		return make([]byte, size)
+	}
+}
+
+func putBuffer(buf []byte) {
+	buf = buf[:cap(buf)]
+	freeList, ok := bufFreeLists.Load(int64(len(buf)))
+	if !ok {
+		return
+	}
+	select {
+	case freeList.(chan []byte) <- buf:
+	default:
+	}
+}
+
+// makeHash returns nil for HashAlgo_None
+func makeHash(algo string) (hash.Hash, error) {
+	switch algo {
+	case "", HashAlgo_Sha256:
+		return sha256.New(), nil
+	case HashAlgo_Crc32:
+		return crc32.NewIEEE(), nil
+	case HashAlgo_None:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %q", algo)
+	}
+}
+
 // ReaderChan reads from an io.Reader and sends the data to a channel
 func ReaderChan(ctx context.Context, r io.Reader, chunkSize int64, callback func()) chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
-	ch := make(chan wshrpc.RespOrErrorUnion[iochantypes.Packet], 32)
+	return ReaderChanWithOpts(ctx, r, ReaderChanOpts{ChunkSize: chunkSize}, callback)
+}
+
+// ReaderChanWithOpts reads from an io.Reader and sends the data to a channel, see ReaderChanOpts for the available options
+func ReaderChanWithOpts(ctx context.Context, r io.Reader, opts ReaderChanOpts, callback func()) chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
+	chunkSize := opts.ChunkSize
+	bufferDepth := opts.BufferDepth
+	if bufferDepth <= 0 {
+		bufferDepth = DefaultBufferDepth
+	}
+	bufferDepth = min(bufferDepth, MaxBufferDepth)
+	ch := make(chan wshrpc.RespOrErrorUnion[iochantypes.Packet], bufferDepth)
+	var limiter *rate.Limiter
+	if opts.BytesPerSec > 0 {
+		// the burst must allow a full chunk, otherwise WaitN will fail
+		limiter = rate.NewLimiter(rate.Limit(opts.BytesPerSec), int(chunkSize))
+	}
 	go func() {
 		defer func() {
 			log.Printf("Closing ReaderChan\n")
 			close(ch)
 			callback()
 		}()
-		sha256Hash := sha256.New()
+		hashFn, err := makeHash(opts.HashAlgo)
+		if err != nil {
+			ch <- wshutil.RespErr[iochantypes.Packet](fmt.Errorf("ReaderChan: %w", err))
+			return
+		}
+		var chunkHashFn hash.Hash
+		if opts.ChunkChecksums {
+			chunkHashFn, _ = makeHash(opts.HashAlgo)
+		}
+		if opts.NoStreamChecksum {
+			hashFn = nil
+		}
+		idle := newIdleTimer(opts.IdleTimeout)
+		defer idle.stop()
+		var offset int64
 		for {
 			select {
 			case <-ctx.Done():
@@ -37,20 +228,48 @@ func ReaderChan(ctx context.Context, r io.Reader, chunkSize int64, callback func
 				}
 				return
 			default:
-				buf := make([]byte, chunkSize)
-				if n, err := r.Read(buf); err != nil {
-					if errors.Is(err, io.EOF) {
-						ch <- wshrpc.RespOrErrorUnion[iochantypes.Packet]{Response: iochantypes.Packet{Checksum: sha256Hash.Sum(nil)}} // send the checksum
-						return
+				buf := getBuffer(chunkSize)
+				n, err := readWithIdleTimeout(idle, func() (int, error) { return readWithRetry(ctx, r, buf, opts.Retry) })
+				if errors.Is(err, ErrIdleTimeout) {
+					// the abandoned read may still write to buf, leave it for the GC
+					ch <- wshutil.RespErr[iochantypes.Packet](fmt.Errorf("ReaderChan: no data for %v after reading %d bytes: %w", opts.IdleTimeout, offset, err))
+					return
+				}
+				// a reader may return the last data along with io.EOF (or an error), it is sent before the error is handled
+				if n > 0 {
+					if limiter != nil {
+						// WaitN returns early with an error if the context is canceled
+						if err := limiter.WaitN(ctx, n); err != nil {
+							return
+						}
+					}
+					if hashFn != nil {
+						if _, err := hashFn.Write(buf[:n]); err != nil {
+							ch <- wshutil.RespErr[iochantypes.Packet](fmt.Errorf("ReaderChan: error writing to hash: %v", err))
+							return
+						}
 					}
+					pk := iochantypes.Packet{Data: buf[:n], Offset: offset}
+					if chunkHashFn != nil {
+						chunkHashFn.Reset()
+						chunkHashFn.Write(buf[:n])
+						pk.ChunkChecksum = chunkHashFn.Sum(nil)
+					}
+					offset += int64(n)
+					ch <- wshrpc.RespOrErrorUnion[iochantypes.Packet]{Response: pk}
+					opts.Stats.add(n)
+				} else {
+					putBuffer(buf)
+				}
+				if errors.Is(err, io.EOF) {
+					if hashFn != nil {
+						ch <- wshrpc.RespOrErrorUnion[iochantypes.Packet]{Response: iochantypes.Packet{Checksum: hashFn.Sum(nil)}} // send the checksum
+					}
+					return
+				}
+				if err != nil {
 					ch <- wshutil.RespErr[iochantypes.Packet](fmt.Errorf("ReaderChan: read error: %v", err))
 					return
-				} else if n > 0 {
-					if _, err := sha256Hash.Write(buf[:n]); err != nil {
-						ch <- wshutil.RespErr[iochantypes.Packet](fmt.Errorf("ReaderChan: error writing to sha256 hash: %v", err))
-						return
-					}
-					ch <- wshrpc.RespOrErrorUnion[iochantypes.Packet]{Response: iochantypes.Packet{Data: buf[:n]}}
 				}
 			}
 		}
@@ -58,19 +277,45 @@ func ReaderChan(ctx context.Context, r io.Reader, chunkSize int64, callback func
 	return ch
 }
 
-// WriterChan reads from a channel and writes the data to an io.Writer
-func WriterChan(ctx context.Context, w io.Writer, ch <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet], callback func(), cancel context.CancelCauseFunc) {
+// WriterChan reads from a channel and writes the data to an io.Writer.
+// cancel is called with the error if the stream fails or ctx is canceled before the stream completes.
+// callback is always called last, with the number of bytes written (so a stream that ended early without its
+// checksum packet can still be caught by comparing against the expected size).
+// WriterChan takes ownership of the packet data, the buffers are recycled for ReaderChan once written.
+func WriterChan(ctx context.Context, w io.Writer, ch <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet], callback func(written int64), cancel context.CancelCauseFunc) {
+	WriterChanWithOpts(ctx, w, ch, WriterChanOpts{}, callback, cancel)
+}
+
+// WriterChanWithOpts reads from a channel and writes the data to an io.Writer, see WriterChanOpts for the available options
+func WriterChanWithOpts(ctx context.Context, w io.Writer, ch <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet], opts WriterChanOpts, callback func(written int64), cancel context.CancelCauseFunc) {
 	go func() {
+		idle := newIdleTimer(opts.IdleTimeout)
+		defer idle.stop()
+		timedOut := false
+		var offset int64
 		defer func() {
-			if ctx.Err() != nil {
+			if ctx.Err() != nil || timedOut {
 				utilfn.DrainChannelSafe(ch, "WriterChan")
 			}
-			callback()
+			callback(offset)
 		}()
-		sha256Hash := sha256.New()
+		hashFn, err := makeHash(opts.HashAlgo)
+		if err != nil {
+			cancel(fmt.Errorf("WriterChan: %w", err))
+			return
+		}
+		chunkHashFn, _ := makeHash(opts.HashAlgo)
 		for {
+			// only time spent waiting for a packet counts, not slow writes
+			idle.reset()
 			select {
 			case <-ctx.Done():
+				// report the cancellation (and how much was written) so callers don't mistake a partial write for success
+				cancel(fmt.Errorf("WriterChan: canceled after writing %d bytes: %w", offset, ctx.Err()))
+				return
+			case <-idle.C():
+				timedOut = true
+				cancel(fmt.Errorf("WriterChan: no data for %v after writing %d bytes: %w", opts.IdleTimeout, offset, ErrIdleTimeout))
 				return
 			case resp, ok := <-ch:
 				if !ok {
@@ -80,15 +325,29 @@ func WriterChan(ctx context.Context, w io.Writer, ch <-chan wshrpc.RespOrErrorUn
 					cancel(resp.Error)
 					return
 				}
-				if _, err := sha256Hash.Write(resp.Response.Data); err != nil {
-					cancel(fmt.Errorf("WriterChan: error writing to sha256 hash: %v", err))
-					return
+				// verify the chunk before writing it, so a corrupted stream fails as early as possible
+				if chunkHashFn != nil && len(resp.Response.ChunkChecksum) > 0 {
+					chunkHashFn.Reset()
+					chunkHashFn.Write(resp.Response.Data)
+					if localChecksum := chunkHashFn.Sum(nil); !bytes.Equal(localChecksum, resp.Response.ChunkChecksum) {
+						cancel(fmt.Errorf("WriterChan: %w: chunk at offset %d, expected %x, got %x", ErrChecksumMismatch, offset, resp.Response.ChunkChecksum, localChecksum))
+						return
+					}
+				}
+				if hashFn != nil {
+					if _, err := hashFn.Write(resp.Response.Data); err != nil {
+						cancel(fmt.Errorf("WriterChan: error writing to hash: %v", err))
+						return
+					}
 				}
-				// The checksum is sent as the last packet
+				// The checksum is sent as the last packet, an empty checksum means no verification was requested
 				if resp.Response.Checksum != nil {
-					localChecksum := sha256Hash.Sum(nil)
+					if hashFn == nil || len(resp.Response.Checksum) == 0 {
+						return
+					}
+					localChecksum := hashFn.Sum(nil)
 					if !bytes.Equal(localChecksum, resp.Response.Checksum) {
-						cancel(fmt.Errorf("WriterChan: checksum mismatch"))
+						cancel(fmt.Errorf("WriterChan: %w: expected %x, got %x", ErrChecksumMismatch, resp.Response.Checksum, localChecksum))
 					}
 					return
 				}
@@ -96,6 +355,9 @@ func WriterChan(ctx context.Context, w io.Writer, ch <-chan wshrpc.RespOrErrorUn
 					cancel(fmt.Errorf("WriterChan: write error: %v", err))
 					return
 				}
+				offset += int64(len(resp.Response.Data))
+				opts.Stats.add(len(resp.Response.Data))
+				putBuffer(resp.Response.Data)
 			}
 		}
 	}()
@@ -4,12 +4,20 @@
 package iochan_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"slices"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/wavetermdev/waveterm/pkg/util/iochan"
+	"github.com/wavetermdev/waveterm/pkg/util/iochan/iochantypes"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
 )
 
 const (
@@ -37,12 +45,12 @@ func TestIochan_Basic(t *testing.T) {
 	// Initialize the destination pipe and the writer channel
 	destPipeReader, destPipeWriter := io.Pipe()
 	writerChanCallbackCalled := false
-	writerChanCallback := func() {
+	writerChanCallback := func(int64) {
 		destPipeReader.Close()
 		destPipeWriter.Close()
 		writerChanCallbackCalled = true
 	}
-	defer writerChanCallback() // Ensure the callback is called
+	defer writerChanCallback(0) // Ensure the callback is called
 	iochan.WriterChan(context.TODO(), destPipeWriter, ioch, writerChanCallback, func(err error) {})
 
 	// Read the packet from the destination pipe and compare it to the original packet
@@ -67,3 +75,659 @@ func TestIochan_Basic(t *testing.T) {
 		t.Fatalf("WriterChan callback not called")
 	}
 }
+
+func TestIochan_RateLimit(t *testing.T) {
+	const chunkSize = 1024
+	const bytesPerSec = 10 * 1024
+	data := make([]byte, 5*chunkSize)
+	start := time.Now()
+	ioch := iochan.ReaderChanWithOpts(context.TODO(), bytes.NewReader(data), iochan.ReaderChanOpts{ChunkSize: chunkSize, BytesPerSec: bytesPerSec}, func() {})
+	total := 0
+	for resp := range ioch {
+		if resp.Error != nil {
+			t.Fatalf("ReaderChan error: %v", resp.Error)
+		}
+		total += len(resp.Response.Data)
+	}
+	elapsed := time.Since(start)
+	if total != len(data) {
+		t.Fatalf("Read length mismatch: %d != %d", total, len(data))
+	}
+	// the first chunk is sent immediately (burst), the remaining 4 chunks are paced at 10 chunks/sec
+	expected := time.Duration(len(data)-chunkSize) * time.Second / bytesPerSec
+	if elapsed < expected*8/10 || elapsed > expected*3 {
+		t.Fatalf("elapsed time %v outside of tolerance, expected ~%v", elapsed, expected)
+	}
+}
+
+func TestIochan_RateLimitCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	data := make([]byte, 1024*1024)
+	ioch := iochan.ReaderChanWithOpts(ctx, bytes.NewReader(data), iochan.ReaderChanOpts{ChunkSize: 1024, BytesPerSec: 1024}, func() {})
+	<-ioch
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		for range ioch {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("ReaderChan did not exit after cancel")
+	}
+}
+
+// runCorruptedStream streams data through ReaderChan and WriterChan with the given hash algorithm,
+// optionally flipping a byte in the first data packet, and returns the error passed to cancel
+func runCorruptedStream(t *testing.T, hashAlgo string, corrupt bool) ([]byte, error) {
+	t.Helper()
+	corruptIdx := -1
+	if corrupt {
+		corruptIdx = 0
+	}
+	return streamWithCorruption(t, iochan.ReaderChanOpts{ChunkSize: buflen, HashAlgo: hashAlgo}, iochan.WriterChanOpts{HashAlgo: hashAlgo}, corruptIdx)
+}
+
+// streamWithCorruption flips a byte in data packet corruptIdx (-1 for none) between ReaderChan and WriterChan
+func streamWithCorruption(t *testing.T, readerOpts iochan.ReaderChanOpts, writerOpts iochan.WriterChanOpts, corruptIdx int) ([]byte, error) {
+	t.Helper()
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256)
+	readerCh := iochan.ReaderChanWithOpts(context.TODO(), bytes.NewReader(data), readerOpts, func() {})
+	corruptCh := make(chan wshrpc.RespOrErrorUnion[iochantypes.Packet])
+	go func() {
+		defer close(corruptCh)
+		dataIdx := 0
+		for resp := range readerCh {
+			if len(resp.Response.Data) > 0 {
+				if dataIdx == corruptIdx {
+					resp.Response.Data[0] ^= 0xff
+				}
+				dataIdx++
+			}
+			corruptCh <- resp
+		}
+	}()
+	var out bytes.Buffer
+	var cancelErr error
+	done := make(chan struct{})
+	iochan.WriterChanWithOpts(context.TODO(), &out, corruptCh, writerOpts, func(int64) { close(done) }, func(err error) { cancelErr = err })
+	<-done
+	return out.Bytes(), cancelErr
+}
+
+func TestIochan_HashAlgos(t *testing.T) {
+	for _, algo := range []string{"", iochan.HashAlgo_Sha256, iochan.HashAlgo_Crc32, iochan.HashAlgo_None} {
+		out, err := runCorruptedStream(t, algo, false)
+		if err != nil {
+			t.Errorf("algo %q: unexpected error: %v", algo, err)
+		}
+		if len(out) != 16*256 {
+			t.Errorf("algo %q: got %d bytes, expected %d", algo, len(out), 16*256)
+		}
+		_, err = runCorruptedStream(t, algo, true)
+		if algo == iochan.HashAlgo_None {
+			if err != nil {
+				t.Errorf("algo %q: corrupted stream should not be verified, got error: %v", algo, err)
+			}
+		} else if err == nil {
+			t.Errorf("algo %q: expected checksum mismatch for corrupted stream", algo)
+		}
+	}
+}
+
+func TestIochan_UnsupportedHashAlgo(t *testing.T) {
+	ioch := iochan.ReaderChanWithOpts(context.TODO(), bytes.NewReader([]byte("hello")), iochan.ReaderChanOpts{ChunkSize: buflen, HashAlgo: "md4"}, func() {})
+	resp := <-ioch
+	if resp.Error == nil {
+		t.Fatalf("expected error for unsupported hash algorithm")
+	}
+}
+
+func TestIochan_ChecksumMismatchError(t *testing.T) {
+	_, err := runCorruptedStream(t, iochan.HashAlgo_Sha256, true)
+	if !errors.Is(err, iochan.ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "expected ") {
+		t.Fatalf("expected digests in error message, got %q", err.Error())
+	}
+}
+
+func TestIochan_WrittenCount(t *testing.T) {
+	// not a multiple of the chunk size, so the last packet is short
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1000)[:10*buflen+17]
+	stream := func(dropAfter int) (int64, error) {
+		readerCh := iochan.ReaderChan(context.Background(), bytes.NewReader(data), buflen, func() {})
+		lossyCh := make(chan wshrpc.RespOrErrorUnion[iochantypes.Packet])
+		go func() {
+			defer close(lossyCh)
+			sent := 0
+			for resp := range readerCh {
+				// the rest of the stream (including the checksum packet) is lost
+				if dropAfter >= 0 && sent >= dropAfter {
+					continue
+				}
+				sent++
+				lossyCh <- resp
+			}
+		}()
+		var written int64
+		var streamErr error
+		done := make(chan struct{})
+		iochan.WriterChan(context.Background(), io.Discard, lossyCh, func(n int64) {
+			written = n
+			close(done)
+		}, func(err error) { streamErr = err })
+		<-done
+		return written, streamErr
+	}
+	written, err := stream(-1)
+	if err != nil || written != int64(len(data)) {
+		t.Errorf("full stream: got %d bytes and %v, want %d", written, err, len(data))
+	}
+	// without its checksum packet a truncated stream ends cleanly, only the count shows the missing data
+	written, err = stream(4)
+	if err != nil || written != 4*buflen {
+		t.Errorf("truncated stream: got %d bytes and %v, want %d", written, err, 4*buflen)
+	}
+
+	atCh := iochan.ReaderAtChan(context.Background(), bytes.NewReader(data), int64(len(data)), buflen, 4, func() {})
+	done := make(chan struct{})
+	var atWritten int64
+	iochan.WriterAtChan(context.Background(), &memWriterAt{}, atCh, func(n int64) {
+		atWritten = n
+		close(done)
+	}, func(err error) { t.Errorf("WriterAtChan: %v", err) })
+	<-done
+	if atWritten != int64(len(data)) {
+		t.Errorf("WriterAtChan: got %d bytes, want %d", atWritten, len(data))
+	}
+}
+
+func TestIochan_ChunkChecksums(t *testing.T) {
+	readerOpts := iochan.ReaderChanOpts{ChunkSize: buflen, ChunkChecksums: true}
+	out, err := streamWithCorruption(t, readerOpts, iochan.WriterChanOpts{}, -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out) != 16*256 {
+		t.Fatalf("got %d bytes, expected %d", len(out), 16*256)
+	}
+
+	// corrupt the second chunk, the writer must stop before writing it
+	out, err = streamWithCorruption(t, readerOpts, iochan.WriterChanOpts{}, 1)
+	if !errors.Is(err, iochan.ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("offset %d", buflen)) {
+		t.Fatalf("expected chunk offset %d in error, got %q", buflen, err.Error())
+	}
+	if len(out) != buflen {
+		t.Fatalf("expected writer to abort after the first chunk, wrote %d bytes", len(out))
+	}
+
+	// per-chunk checksums without the final stream checksum
+	readerOpts.NoStreamChecksum = true
+	if _, err = streamWithCorruption(t, readerOpts, iochan.WriterChanOpts{}, -1); err != nil {
+		t.Fatalf("unexpected error without stream checksum: %v", err)
+	}
+	if _, err = streamWithCorruption(t, readerOpts, iochan.WriterChanOpts{}, 2); !errors.Is(err, iochan.ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch without stream checksum, got %v", err)
+	}
+}
+
+func TestIochan_WriterChanCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan wshrpc.RespOrErrorUnion[iochantypes.Packet])
+	var out bytes.Buffer
+	var cancelErr error
+	done := make(chan struct{})
+	iochan.WriterChan(ctx, &out, ch, func(int64) { close(done) }, func(err error) { cancelErr = err })
+	ch <- wshrpc.RespOrErrorUnion[iochantypes.Packet]{Response: iochantypes.Packet{Data: []byte("partial")}}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("WriterChan did not exit after cancel")
+	}
+	if !errors.Is(cancelErr, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", cancelErr)
+	}
+	if !strings.Contains(cancelErr.Error(), "after writing 7 bytes") {
+		t.Fatalf("expected partial write size in error, got %q", cancelErr.Error())
+	}
+	if out.String() != "partial" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+// latencyReader pauses for delay every n reads, simulating a source that arrives in bursts
+type latencyReader struct {
+	r     io.Reader
+	n     int
+	delay time.Duration
+	count int
+}
+
+func (lr *latencyReader) Read(p []byte) (int, error) {
+	lr.count++
+	if lr.count%lr.n == 0 {
+		time.Sleep(lr.delay)
+	}
+	return lr.r.Read(p)
+}
+
+// latencyWriter pauses for delay every n writes, simulating round trips on a high-latency link
+type latencyWriter struct {
+	n     int
+	delay time.Duration
+	count int
+}
+
+func (lw *latencyWriter) Write(p []byte) (int, error) {
+	lw.count++
+	if lw.count%lw.n == 0 {
+		time.Sleep(lw.delay)
+	}
+	return len(p), nil
+}
+
+func BenchmarkIochan_BufferDepth(b *testing.B) {
+	const chunkSize = 16 * 1024
+	data := make([]byte, 4*1024*1024)
+	for _, depth := range []int{8, 32, 128} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				reader := &latencyReader{r: bytes.NewReader(data), n: 16, delay: time.Millisecond}
+				ioch := iochan.ReaderChanWithOpts(context.Background(), reader, iochan.ReaderChanOpts{ChunkSize: chunkSize, BufferDepth: depth}, func() {})
+				done := make(chan struct{})
+				iochan.WriterChan(context.Background(), &latencyWriter{n: 64, delay: 4 * time.Millisecond}, ioch, func(int64) { close(done) }, func(err error) {
+					b.Errorf("stream error: %v", err)
+				})
+				<-done
+			}
+		})
+	}
+}
+
+func BenchmarkIochan_LargeStream(b *testing.B) {
+	const streamSize = 256 * 1024 * 1024
+	data := make([]byte, streamSize)
+	b.SetBytes(streamSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ioch := iochan.ReaderChanWithOpts(context.Background(), bytes.NewReader(data), iochan.ReaderChanOpts{ChunkSize: wshrpc.FileChunkSize, HashAlgo: iochan.HashAlgo_None}, func() {})
+		done := make(chan struct{})
+		iochan.WriterChanWithOpts(context.Background(), io.Discard, ioch, iochan.WriterChanOpts{HashAlgo: iochan.HashAlgo_None}, func(int64) { close(done) }, func(err error) {
+			b.Errorf("stream error: %v", err)
+		})
+		<-done
+	}
+}
+
+// flakyReader fails with err on the first failures reads, then reads from r
+type flakyReader struct {
+	r        io.Reader
+	failures int
+	err      error
+	calls    int
+}
+
+func (fr *flakyReader) Read(p []byte) (int, error) {
+	fr.calls++
+	if fr.calls <= fr.failures {
+		return 0, fr.err
+	}
+	return fr.r.Read(p)
+}
+
+func readAll(t *testing.T, r io.Reader, opts iochan.ReaderChanOpts) ([]byte, error) {
+	t.Helper()
+	var out bytes.Buffer
+	for resp := range iochan.ReaderChanWithOpts(context.Background(), r, opts, func() {}) {
+		if resp.Error != nil {
+			return out.Bytes(), resp.Error
+		}
+		out.Write(resp.Response.Data)
+	}
+	return out.Bytes(), nil
+}
+
+func TestIochan_ReadRetry(t *testing.T) {
+	data := []byte("hello world")
+	retry := &iochan.ReadRetryPolicy{MaxAttempts: 4, Backoff: time.Millisecond}
+
+	reader := &flakyReader{r: bytes.NewReader(data), failures: 3, err: io.ErrUnexpectedEOF}
+	out, err := readAll(t, reader, iochan.ReaderChanOpts{ChunkSize: buflen, Retry: retry})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Fatalf("got %q, expected %q", out, data)
+	}
+
+	// retries exhausted, the last error is sent to the channel
+	reader = &flakyReader{r: bytes.NewReader(data), failures: 4, err: io.ErrUnexpectedEOF}
+	if _, err = readAll(t, reader, iochan.ReaderChanOpts{ChunkSize: buflen, Retry: retry}); err == nil || !strings.Contains(err.Error(), io.ErrUnexpectedEOF.Error()) {
+		t.Fatalf("expected unexpected EOF error, got %v", err)
+	}
+	if reader.calls != 4 {
+		t.Fatalf("expected 4 read attempts, got %d", reader.calls)
+	}
+
+	// fatal errors are not retried
+	reader = &flakyReader{r: bytes.NewReader(data), failures: 1, err: errors.New("permission denied")}
+	if _, err = readAll(t, reader, iochan.ReaderChanOpts{ChunkSize: buflen, Retry: retry}); err == nil {
+		t.Fatalf("expected error for non-retryable read failure")
+	}
+	if reader.calls != 1 {
+		t.Fatalf("expected 1 read attempt, got %d", reader.calls)
+	}
+
+	// custom predicate
+	fatalErr := errors.New("temporary glitch")
+	retry.Retryable = func(err error) bool { return errors.Is(err, fatalErr) }
+	reader = &flakyReader{r: bytes.NewReader(data), failures: 2, err: fatalErr}
+	if out, err = readAll(t, reader, iochan.ReaderChanOpts{ChunkSize: buflen, Retry: retry}); err != nil || !bytes.Equal(out, data) {
+		t.Fatalf("custom predicate: got %q, %v", out, err)
+	}
+}
+
+// slowReaderAt sleeps on every ReadAt, like a disk (or network filesystem) with per-request latency
+type slowReaderAt struct {
+	r     io.ReaderAt
+	delay time.Duration
+}
+
+func (sr *slowReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	time.Sleep(sr.delay)
+	return sr.r.ReadAt(p, off)
+}
+
+func TestIochan_ReaderAtChan(t *testing.T) {
+	data := make([]byte, 100*buflen+17)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	for _, parallelism := range []int{1, 4, 16} {
+		reader := &slowReaderAt{r: bytes.NewReader(data), delay: time.Duration(parallelism%3) * time.Millisecond}
+		ioch := iochan.ReaderAtChanWithOpts(context.Background(), reader, int64(len(data)), parallelism, iochan.ReaderChanOpts{ChunkSize: buflen, ChunkChecksums: true}, func() {})
+		var out bytes.Buffer
+		done := make(chan struct{})
+		var streamErr error
+		iochan.WriterChan(context.Background(), &out, ioch, func(int64) { close(done) }, func(err error) { streamErr = err })
+		<-done
+		if streamErr != nil {
+			t.Fatalf("parallelism=%d: stream error: %v", parallelism, streamErr)
+		}
+		if !bytes.Equal(out.Bytes(), data) {
+			t.Fatalf("parallelism=%d: output differs from input (%d bytes, expected %d)", parallelism, out.Len(), len(data))
+		}
+	}
+
+	// a source shorter than the given size is an error rather than a truncated stream
+	var gotErr error
+	for resp := range iochan.ReaderAtChan(context.Background(), bytes.NewReader(data), int64(len(data))+buflen, buflen, 4, func() {}) {
+		if resp.Error != nil {
+			gotErr = resp.Error
+		}
+	}
+	if gotErr == nil || !strings.Contains(gotErr.Error(), "short read") {
+		t.Errorf("expected short read error, got %v", gotErr)
+	}
+}
+
+func BenchmarkIochan_ReaderAtChan(b *testing.B) {
+	const chunkSize = 64 * 1024
+	data := make([]byte, 16*1024*1024)
+	reader := &slowReaderAt{r: bytes.NewReader(data), delay: 200 * time.Microsecond}
+	b.Run("sequential", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			ioch := iochan.ReaderChan(context.Background(), io.NewSectionReader(reader, 0, int64(len(data))), chunkSize, func() {})
+			done := make(chan struct{})
+			iochan.WriterChan(context.Background(), io.Discard, ioch, func(int64) { close(done) }, func(err error) {
+				b.Errorf("stream error: %v", err)
+			})
+			<-done
+		}
+	})
+	for _, parallelism := range []int{4, 16} {
+		b.Run(fmt.Sprintf("parallel=%d", parallelism), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				ioch := iochan.ReaderAtChan(context.Background(), reader, int64(len(data)), chunkSize, parallelism, func() {})
+				done := make(chan struct{})
+				iochan.WriterChan(context.Background(), io.Discard, ioch, func(int64) { close(done) }, func(err error) {
+					b.Errorf("stream error: %v", err)
+				})
+				<-done
+			}
+		})
+	}
+}
+
+// memWriterAt is an in-memory io.WriterAt
+type memWriterAt struct {
+	buf []byte
+}
+
+func (mw *memWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(mw.buf) {
+		mw.buf = append(mw.buf, make([]byte, end-len(mw.buf))...)
+	}
+	return copy(mw.buf[off:], p), nil
+}
+
+// writeAtPackets sends copies of pks to WriterAtChan (which recycles the buffers it is given), returning the written bytes and the stream error
+func writeAtPackets(pks []wshrpc.RespOrErrorUnion[iochantypes.Packet]) ([]byte, error) {
+	ch := make(chan wshrpc.RespOrErrorUnion[iochantypes.Packet], len(pks))
+	for _, pk := range pks {
+		pk.Response.Data = bytes.Clone(pk.Response.Data)
+		ch <- pk
+	}
+	close(ch)
+	out := &memWriterAt{}
+	var streamErr error
+	done := make(chan struct{})
+	iochan.WriterAtChan(context.Background(), out, ch, func(int64) { close(done) }, func(err error) { streamErr = err })
+	<-done
+	return out.buf, streamErr
+}
+
+func TestIochan_WriterAtChan(t *testing.T) {
+	data := make([]byte, 20*buflen+17)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	var pks []wshrpc.RespOrErrorUnion[iochantypes.Packet]
+	for resp := range iochan.ReaderChanWithOpts(context.Background(), bytes.NewReader(data), iochan.ReaderChanOpts{ChunkSize: buflen, ChunkChecksums: true}, func() {}) {
+		// the buffers belong to ReaderChan's free list, keep copies since the packets are sent several times
+		resp.Response.Data = bytes.Clone(resp.Response.Data)
+		pks = append(pks, resp)
+	}
+	dataPks, checksumPk := pks[:len(pks)-1], pks[len(pks)-1]
+	if checksumPk.Response.Checksum == nil {
+		t.Fatalf("expected the last packet to be the checksum")
+	}
+
+	// reversed, with the checksum still last
+	shuffled := slices.Clone(dataPks)
+	slices.Reverse(shuffled)
+	out, err := writeAtPackets(append(shuffled, checksumPk))
+	if err != nil {
+		t.Fatalf("out of order stream: %v", err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Fatalf("output differs from input (%d bytes, expected %d)", len(out), len(data))
+	}
+
+	// a packet that never arrives
+	_, err = writeAtPackets(append(slices.Concat(dataPks[:3], dataPks[4:]), checksumPk))
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("missing at offset %d", 3*buflen)) {
+		t.Errorf("expected missing data error, got %v", err)
+	}
+
+	_, err = writeAtPackets(append(slices.Concat(dataPks[:3], dataPks[2:]), checksumPk))
+	if err == nil || !strings.Contains(err.Error(), "duplicate packet") {
+		t.Errorf("expected duplicate packet error, got %v", err)
+	}
+
+	// data at the wrong offset fails the stream checksum
+	moved := slices.Clone(dataPks)
+	moved[1].Response.Offset, moved[2].Response.Offset = moved[2].Response.Offset, moved[1].Response.Offset
+	_, err = writeAtPackets(append(moved, checksumPk))
+	if !errors.Is(err, iochan.ErrChecksumMismatch) {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestIochan_IdleTimeout(t *testing.T) {
+	// a source that never sends, the read is abandoned and the pipe closed by the callback
+	pipeReader, _ := io.Pipe()
+	var readErr error
+	start := time.Now()
+	for resp := range iochan.ReaderChanWithOpts(context.Background(), pipeReader, iochan.ReaderChanOpts{ChunkSize: buflen, IdleTimeout: 50 * time.Millisecond}, func() { pipeReader.Close() }) {
+		if resp.Error != nil {
+			readErr = resp.Error
+		}
+	}
+	if !errors.Is(readErr, iochan.ErrIdleTimeout) {
+		t.Fatalf("expected idle timeout from ReaderChan, got %v", readErr)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("ReaderChan took %v to time out", elapsed)
+	}
+
+	// a channel that stops sending after the first packet
+	ch := make(chan wshrpc.RespOrErrorUnion[iochantypes.Packet], 1)
+	ch <- wshrpc.RespOrErrorUnion[iochantypes.Packet]{Response: iochantypes.Packet{Data: []byte("partial")}}
+	var out bytes.Buffer
+	var writeErr error
+	done := make(chan struct{})
+	iochan.WriterChanWithOpts(context.Background(), &out, ch, iochan.WriterChanOpts{IdleTimeout: 50 * time.Millisecond}, func(int64) { close(done) }, func(err error) { writeErr = err })
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("WriterChan did not time out")
+	}
+	if !errors.Is(writeErr, iochan.ErrIdleTimeout) || !strings.Contains(writeErr.Error(), "after writing 7 bytes") {
+		t.Fatalf("expected idle timeout after 7 bytes from WriterChan, got %v", writeErr)
+	}
+
+	// a slow stream that keeps making progress doesn't time out
+	data := make([]byte, 10*buflen)
+	reader := &latencyReader{r: bytes.NewReader(data), n: 1, delay: 10 * time.Millisecond}
+	ioch := iochan.ReaderChanWithOpts(context.Background(), reader, iochan.ReaderChanOpts{ChunkSize: buflen, IdleTimeout: 500 * time.Millisecond}, func() {})
+	out.Reset()
+	writeErr = nil
+	done = make(chan struct{})
+	iochan.WriterChanWithOpts(context.Background(), &out, ioch, iochan.WriterChanOpts{IdleTimeout: 500 * time.Millisecond}, func(int64) { close(done) }, func(err error) { writeErr = err })
+	<-done
+	if writeErr != nil || out.Len() != len(data) {
+		t.Fatalf("slow stream: %v (%d of %d bytes)", writeErr, out.Len(), len(data))
+	}
+}
+
+func TestIochan_Stats(t *testing.T) {
+	data := make([]byte, 100*buflen+17)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	readStats := &iochan.TransferStats{}
+	writeStats := &iochan.TransferStats{}
+	// sample from another goroutine while the stream runs, as a progress ui would
+	stopSampling := make(chan struct{})
+	samplerDone := make(chan struct{})
+	go func() {
+		defer close(samplerDone)
+		var last int64
+		for {
+			select {
+			case <-stopSampling:
+				return
+			case <-time.After(time.Millisecond):
+			}
+			total, rate := writeStats.Sample()
+			if total < last || rate < 0 {
+				t.Errorf("sample went backwards: total %d (was %d), rate %f", total, last, rate)
+			}
+			last = total
+			readStats.Bytes()
+			readStats.AvgRate()
+		}
+	}()
+	reader := &latencyReader{r: bytes.NewReader(data), n: 10, delay: time.Millisecond}
+	ioch := iochan.ReaderChanWithOpts(context.Background(), reader, iochan.ReaderChanOpts{ChunkSize: buflen, Stats: readStats}, func() {})
+	var out bytes.Buffer
+	var writeErr error
+	done := make(chan struct{})
+	iochan.WriterChanWithOpts(context.Background(), &out, ioch, iochan.WriterChanOpts{Stats: writeStats}, func(int64) { close(done) }, func(err error) { writeErr = err })
+	<-done
+	close(stopSampling)
+	<-samplerDone
+	if writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	if got := readStats.Bytes(); got != int64(len(data)) {
+		t.Errorf("reader stats: got %d bytes, want %d", got, len(data))
+	}
+	if got := writeStats.Bytes(); got != int64(len(data)) {
+		t.Errorf("writer stats: got %d bytes, want %d", got, len(data))
+	}
+	if writeStats.Start().IsZero() || writeStats.AvgRate() <= 0 {
+		t.Errorf("expected a start time and a positive average rate, got %v and %f", writeStats.Start(), writeStats.AvgRate())
+	}
+
+	// the parallel reader and the offset writer count the same way
+	atReadStats := &iochan.TransferStats{}
+	atWriteStats := &iochan.TransferStats{}
+	atch := iochan.ReaderAtChanWithOpts(context.Background(), bytes.NewReader(data), int64(len(data)), 4, iochan.ReaderChanOpts{ChunkSize: buflen, Stats: atReadStats}, func() {})
+	wa := &memWriterAt{}
+	done = make(chan struct{})
+	iochan.WriterAtChanWithOpts(context.Background(), wa, atch, iochan.WriterChanOpts{Stats: atWriteStats}, func(int64) { close(done) }, func(err error) { writeErr = err })
+	<-done
+	if writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	if atReadStats.Bytes() != int64(len(data)) || atWriteStats.Bytes() != int64(len(data)) {
+		t.Errorf("ReaderAtChan/WriterAtChan stats: got %d and %d bytes, want %d", atReadStats.Bytes(), atWriteStats.Bytes(), len(data))
+	}
+}
+
+func TestTransferStats_Sample(t *testing.T) {
+	stats := &iochan.TransferStats{}
+	if total, rate := stats.Sample(); total != 0 || rate != 0 {
+		t.Errorf("empty stats: got %d bytes at %f", total, rate)
+	}
+	ioch := iochan.ReaderChanWithOpts(context.Background(), bytes.NewReader(make([]byte, 4*buflen)), iochan.ReaderChanOpts{ChunkSize: buflen, Stats: stats}, func() {})
+	for range ioch {
+	}
+	time.Sleep(20 * time.Millisecond)
+	total, rate := stats.Sample()
+	if total != 4*buflen || rate <= 0 {
+		t.Errorf("first sample: got %d bytes at %f, want %d bytes at a positive rate", total, rate, 4*buflen)
+	}
+	// nothing moved since the last sample
+	time.Sleep(5 * time.Millisecond)
+	if total, rate := stats.Sample(); total != 4*buflen || rate != 0 {
+		t.Errorf("idle sample: got %d bytes at %f, want %d at 0", total, rate, 4*buflen)
+	}
+}
+
+func TestIochan_DataWithEOF(t *testing.T) {
+	// the last read returns its data along with io.EOF, like an http response body
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	got, err := readAll(t, iotest.DataErrReader(bytes.NewReader(data)), iochan.ReaderChanOpts{ChunkSize: buflen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("got %d bytes, want %d", len(got), len(data))
+	}
+}
@@ -1,6 +1,8 @@
 package iochantypes
 
 type Packet struct {
-	Data     []byte
-	Checksum []byte
+	Data          []byte
+	Checksum      []byte
+	ChunkChecksum []byte // optional checksum of Data
+	Offset        int64  // position of Data in the stream, lets WriterAtChan write packets that arrive out of order
 }
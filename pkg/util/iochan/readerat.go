@@ -0,0 +1,154 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package iochan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/wavetermdev/waveterm/pkg/util/iochan/iochantypes"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+	"golang.org/x/time/rate"
+)
+
+type readAtResult struct {
+	offset int64
+	buf    []byte
+	n      int
+	err    error
+}
+
+type readAtJob struct {
+	offset int64
+	result chan readAtResult
+}
+
+// ReaderAtChan reads size bytes from ra using parallelism concurrent readers and sends the data to a channel
+func ReaderAtChan(ctx context.Context, ra io.ReaderAt, size int64, chunkSize int64, parallelism int, callback func()) chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
+	return ReaderAtChanWithOpts(ctx, ra, size, parallelism, ReaderChanOpts{ChunkSize: chunkSize}, callback)
+}
+
+// ReaderAtChanWithOpts is ReaderAtChan with ReaderChanOpts (Retry is not supported).
+// Chunks are read out of order but sent in order, so the stream (including the checksums) is identical to ReaderChan's and
+// WriterChan needs no changes.  Reads run at most parallelism chunks ahead of the next chunk to send, so at most
+// BufferDepth + 2*parallelism chunks are held in memory.  callback is called once all the readers have returned.
+func ReaderAtChanWithOpts(ctx context.Context, ra io.ReaderAt, size int64, parallelism int, opts ReaderChanOpts, callback func()) chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
+	chunkSize := opts.ChunkSize
+	parallelism = max(parallelism, 1)
+	bufferDepth := opts.BufferDepth
+	if bufferDepth <= 0 {
+		bufferDepth = DefaultBufferDepth
+	}
+	bufferDepth = min(bufferDepth, MaxBufferDepth)
+	ch := make(chan wshrpc.RespOrErrorUnion[iochantypes.Packet], bufferDepth)
+	var limiter *rate.Limiter
+	if opts.BytesPerSec > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.BytesPerSec), int(chunkSize))
+	}
+	readCtx, cancelRead := context.WithCancel(ctx)
+	jobs := make(chan readAtJob)
+	// results in stream order, the capacity bounds how far the readers get ahead of the sender
+	order := make(chan chan readAtResult, parallelism)
+	go func() {
+		defer close(jobs)
+		defer close(order)
+		for offset := int64(0); offset < size; offset += chunkSize {
+			job := readAtJob{offset: offset, result: make(chan readAtResult, 1)}
+			select {
+			case order <- job.result:
+			case <-readCtx.Done():
+				return
+			}
+			select {
+			case jobs <- job:
+			case <-readCtx.Done():
+				return
+			}
+		}
+	}()
+	var readers sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for job := range jobs {
+				length := min(chunkSize, size-job.offset)
+				buf := getBuffer(chunkSize)
+				n, err := ra.ReadAt(buf[:length], job.offset)
+				if int64(n) == length {
+					// ReadAt may return io.EOF along with the last full chunk
+					err = nil
+				} else if err == nil || errors.Is(err, io.EOF) {
+					err = fmt.Errorf("short read at offset %d (%d of %d bytes): %w", job.offset, n, length, io.ErrUnexpectedEOF)
+				}
+				job.result <- readAtResult{offset: job.offset, buf: buf, n: n, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer func() {
+			log.Printf("Closing ReaderAtChan\n")
+			cancelRead()
+			readers.Wait()
+			close(ch)
+			callback()
+		}()
+		hashFn, err := makeHash(opts.HashAlgo)
+		if err != nil {
+			ch <- wshutil.RespErr[iochantypes.Packet](fmt.Errorf("ReaderAtChan: %w", err))
+			return
+		}
+		var chunkHashFn hash.Hash
+		if opts.ChunkChecksums {
+			chunkHashFn, _ = makeHash(opts.HashAlgo)
+		}
+		if opts.NoStreamChecksum {
+			hashFn = nil
+		}
+		for result := range order {
+			var res readAtResult
+			select {
+			case res = <-result:
+			case <-ctx.Done():
+				return
+			}
+			if res.err != nil {
+				putBuffer(res.buf)
+				ch <- wshutil.RespErr[iochantypes.Packet](fmt.Errorf("ReaderAtChan: read error: %v", res.err))
+				return
+			}
+			data := res.buf[:res.n]
+			if limiter != nil {
+				if err := limiter.WaitN(ctx, res.n); err != nil {
+					return
+				}
+			}
+			if hashFn != nil {
+				hashFn.Write(data)
+			}
+			pk := iochantypes.Packet{Data: data, Offset: res.offset}
+			if chunkHashFn != nil {
+				chunkHashFn.Reset()
+				chunkHashFn.Write(data)
+				pk.ChunkChecksum = chunkHashFn.Sum(nil)
+			}
+			ch <- wshrpc.RespOrErrorUnion[iochantypes.Packet]{Response: pk}
+			opts.Stats.add(res.n)
+		}
+		// order is also closed early when ctx is canceled
+		if ctx.Err() != nil {
+			return
+		}
+		if hashFn != nil {
+			ch <- wshrpc.RespOrErrorUnion[iochantypes.Packet]{Response: iochantypes.Packet{Checksum: hashFn.Sum(nil)}}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,80 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package iochan
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TransferStats counts the bytes moved by a stream (set ReaderChanOpts.Stats or WriterChanOpts.Stats).
+// The stream updates it atomically, so it can be read from any goroutine while the transfer runs.
+// Several streams can share one TransferStats to get a combined total.
+type TransferStats struct {
+	bytes     atomic.Int64
+	startNano atomic.Int64 // time of the first add, 0 until then
+
+	sampleLock  sync.Mutex
+	sampleTime  time.Time
+	sampleBytes int64
+}
+
+func (s *TransferStats) add(n int) {
+	if s == nil || n <= 0 {
+		return
+	}
+	s.startNano.CompareAndSwap(0, time.Now().UnixNano())
+	s.bytes.Add(int64(n))
+}
+
+// Bytes returns the total bytes transferred so far
+func (s *TransferStats) Bytes() int64 {
+	return s.bytes.Load()
+}
+
+// Start returns when the first bytes were transferred, the zero time if none have been
+func (s *TransferStats) Start() time.Time {
+	startNano := s.startNano.Load()
+	if startNano == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, startNano)
+}
+
+// AvgRate returns the average bytes per second since the first bytes were transferred
+func (s *TransferStats) AvgRate() float64 {
+	start := s.Start()
+	if start.IsZero() {
+		return 0
+	}
+	elapsed := time.Since(start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes()) / elapsed
+}
+
+// Sample returns the total bytes and the bytes per second since the previous Sample (or since the
+// first bytes for the first sample), so polling it at an interval gives the current throughput
+func (s *TransferStats) Sample() (int64, float64) {
+	s.sampleLock.Lock()
+	defer s.sampleLock.Unlock()
+	total := s.Bytes()
+	since := s.sampleTime
+	if since.IsZero() {
+		since = s.Start()
+		if since.IsZero() {
+			return total, 0
+		}
+	}
+	now := time.Now()
+	var rate float64
+	if elapsed := now.Sub(since).Seconds(); elapsed > 0 {
+		rate = float64(total-s.sampleBytes) / elapsed
+	}
+	s.sampleTime = now
+	s.sampleBytes = total
+	return total, rate
+}
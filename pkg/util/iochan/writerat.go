@@ -0,0 +1,125 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package iochan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/wavetermdev/waveterm/pkg/util/iochan/iochantypes"
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// WriterAtChan reads from a channel and writes each packet at its Offset, so packets can arrive in any order
+// (e.g. merged from several streams, or a resumed transfer).
+// cancel is called with the error if the stream fails or ctx is canceled before the stream completes, callback is
+// always called last with the number of bytes written.
+func WriterAtChan(ctx context.Context, wa io.WriterAt, ch <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet], callback func(written int64), cancel context.CancelCauseFunc) {
+	WriterAtChanWithOpts(ctx, wa, ch, WriterChanOpts{}, callback, cancel)
+}
+
+// WriterAtChanWithOpts is WriterAtChan with WriterChanOpts.
+// The stream checksum covers the bytes in offset order, so packets that arrive ahead of a gap are written right away but
+// held until the gap is filled and they can be hashed.  The checksum packet must come last, a gap left at that point
+// (or a duplicate packet) fails the stream.
+func WriterAtChanWithOpts(ctx context.Context, wa io.WriterAt, ch <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet], opts WriterChanOpts, callback func(written int64), cancel context.CancelCauseFunc) {
+	go func() {
+		idle := newIdleTimer(opts.IdleTimeout)
+		defer idle.stop()
+		timedOut := false
+		var written int64
+		defer func() {
+			if ctx.Err() != nil || timedOut {
+				utilfn.DrainChannelSafe(ch, "WriterAtChan")
+			}
+			callback(written)
+		}()
+		hashFn, err := makeHash(opts.HashAlgo)
+		if err != nil {
+			cancel(fmt.Errorf("WriterAtChan: %w", err))
+			return
+		}
+		chunkHashFn, _ := makeHash(opts.HashAlgo)
+		// written but not yet hashed, keyed by offset
+		pending := make(map[int64][]byte)
+		var hashedOffset int64
+		for {
+			// only time spent waiting for a packet counts, not slow writes
+			idle.reset()
+			select {
+			case <-ctx.Done():
+				cancel(fmt.Errorf("WriterAtChan: canceled after writing %d bytes: %w", written, ctx.Err()))
+				return
+			case <-idle.C():
+				timedOut = true
+				cancel(fmt.Errorf("WriterAtChan: no data for %v after writing %d bytes: %w", opts.IdleTimeout, written, ErrIdleTimeout))
+				return
+			case resp, ok := <-ch:
+				if !ok {
+					return
+				}
+				if resp.Error != nil {
+					cancel(resp.Error)
+					return
+				}
+				pk := resp.Response
+				if pk.Checksum != nil {
+					if hashFn == nil || len(pk.Checksum) == 0 {
+						return
+					}
+					if len(pending) > 0 {
+						cancel(fmt.Errorf("WriterAtChan: stream ended with data missing at offset %d", hashedOffset))
+						return
+					}
+					localChecksum := hashFn.Sum(nil)
+					if !bytes.Equal(localChecksum, pk.Checksum) {
+						cancel(fmt.Errorf("WriterAtChan: %w: expected %x, got %x", ErrChecksumMismatch, pk.Checksum, localChecksum))
+					}
+					return
+				}
+				if len(pk.Data) == 0 {
+					continue
+				}
+				if chunkHashFn != nil && len(pk.ChunkChecksum) > 0 {
+					chunkHashFn.Reset()
+					chunkHashFn.Write(pk.Data)
+					if localChecksum := chunkHashFn.Sum(nil); !bytes.Equal(localChecksum, pk.ChunkChecksum) {
+						cancel(fmt.Errorf("WriterAtChan: %w: chunk at offset %d, expected %x, got %x", ErrChecksumMismatch, pk.Offset, pk.ChunkChecksum, localChecksum))
+						return
+					}
+				}
+				if hashFn != nil {
+					if _, dup := pending[pk.Offset]; dup || pk.Offset < hashedOffset {
+						cancel(fmt.Errorf("WriterAtChan: duplicate packet at offset %d", pk.Offset))
+						return
+					}
+				}
+				if _, err := wa.WriteAt(pk.Data, pk.Offset); err != nil {
+					cancel(fmt.Errorf("WriterAtChan: write error at offset %d: %v", pk.Offset, err))
+					return
+				}
+				written += int64(len(pk.Data))
+				opts.Stats.add(len(pk.Data))
+				if hashFn == nil {
+					putBuffer(pk.Data)
+					continue
+				}
+				pending[pk.Offset] = pk.Data
+				for {
+					data, ok := pending[hashedOffset]
+					if !ok {
+						break
+					}
+					hashFn.Write(data)
+					delete(pending, hashedOffset)
+					hashedOffset += int64(len(data))
+					putBuffer(data)
+				}
+			}
+		}
+	}()
+}
@@ -12,8 +12,10 @@ import (
 	"io"
 	"io/fs"
 	"log"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/wavetermdev/waveterm/pkg/util/iochan"
 	"github.com/wavetermdev/waveterm/pkg/util/iochan/iochantypes"
@@ -30,25 +32,48 @@ const (
 
 	// custom flag to indicate that the source is a single file
 	SingleFile = "singlefile"
+
+	// custom flag to indicate that a single file entry only contains the data after this offset
+	ResumeOffset = "resumeoffset"
 )
 
 // TarCopySrc creates a tar stream writer and returns a channel to send the tar stream to.
-// writeHeader is a function that writes the tar header for the file. If only a single file is being written, the singleFile flag should be set to true.
+// writeHeader is a function that writes the tar header for the file. If only a single file is being written, the singleFile flag should be set to true. Symlinks are written with their link target and no content.
 // writer is the tar writer to write the file data to.
-// close is a function that closes the tar writer and internal pipe writer.
-func TarCopySrc(ctx context.Context, pathPrefix string) (outputChan chan wshrpc.RespOrErrorUnion[iochantypes.Packet], writeHeader func(fi fs.FileInfo, file string, singleFile bool) error, writer io.Writer, close func()) {
+// close is a function that closes the tar writer and internal pipe writer, it returns once the stream has been fully read. Producer errors should be passed to close rather than
+// sent on outputChan: the pipe reader returns the error only after all the data written before it, so the consumer sees the data
+// packets followed by a single error packet (and no checksum packet), never an error interleaved with data.
+func TarCopySrc(ctx context.Context, pathPrefix string) (outputChan chan wshrpc.RespOrErrorUnion[iochantypes.Packet], writeHeader func(fi fs.FileInfo, file string, singleFile bool) error, writer io.Writer, close func(err error)) {
+	return TarCopySrcWithOpts(ctx, pathPrefix, iochan.ReaderChanOpts{ChunkSize: wshrpc.FileChunkSize})
+}
+
+// TarCopySrcWithOpts is TarCopySrc with the ReaderChan options (chunk size, buffer depth, etc.) used for the output stream.
+func TarCopySrcWithOpts(ctx context.Context, pathPrefix string, readerOpts iochan.ReaderChanOpts) (outputChan chan wshrpc.RespOrErrorUnion[iochantypes.Packet], writeHeader func(fi fs.FileInfo, file string, singleFile bool) error, writer io.Writer, close func(err error)) {
 	pipeReader, pipeWriter := io.Pipe()
 	tarWriter := tar.NewWriter(pipeWriter)
-	rtnChan := iochan.ReaderChan(ctx, pipeReader, wshrpc.FileChunkSize, func() {
+	var readerDone sync.WaitGroup
+	readerDone.Add(1)
+	rtnChan := iochan.ReaderChanWithOpts(ctx, pipeReader, readerOpts, func() {
 		log.Printf("Closing pipe reader\n")
 		utilfn.GracefulClose(pipeReader, tarCopySrcName, pipeReaderName)
+		readerDone.Done()
 	})
 
 	singleFileFlagSet := false
 
 	return rtnChan, func(fi fs.FileInfo, path string, singleFile bool) error {
+			// symlinks are written as-is, with the link target read from the local filesystem
+			var link string
+			if fi.Mode()&fs.ModeSymlink != 0 {
+				var err error
+				link, err = os.Readlink(path)
+				if err != nil {
+					return err
+				}
+			}
+
 			// generate tar header
-			header, err := tar.FileInfoHeader(fi, path)
+			header, err := tar.FileInfoHeader(fi, link)
 			if err != nil {
 				return err
 			}
@@ -58,7 +83,11 @@ func TarCopySrc(ctx context.Context, pathPrefix string) (outputChan chan wshrpc.
 					return errors.New("attempting to write multiple files to a single file tar stream")
 				}
 
-				header.PAXRecords = map[string]string{SingleFile: "true"}
+				// keep any records passed in via fi.Sys()
+				if header.PAXRecords == nil {
+					header.PAXRecords = make(map[string]string)
+				}
+				header.PAXRecords[SingleFile] = "true"
 				singleFileFlagSet = true
 			}
 
@@ -78,10 +107,18 @@ func TarCopySrc(ctx context.Context, pathPrefix string) (outputChan chan wshrpc.
 				return err
 			}
 			return nil
-		}, tarWriter, func() {
-			log.Printf("Closing tar writer\n")
-			utilfn.GracefulClose(tarWriter, tarCopySrcName, tarWriterName)
-			utilfn.GracefulClose(pipeWriter, tarCopySrcName, pipeWriterName)
+		}, tarWriter, func(err error) {
+			if err != nil {
+				// don't finish the archive, a partial tar with a valid trailer could be mistaken for a complete one
+				log.Printf("Aborting tar stream: %v\n", err)
+				pipeWriter.CloseWithError(err)
+			} else {
+				log.Printf("Closing tar writer\n")
+				utilfn.GracefulClose(tarWriter, tarCopySrcName, tarWriterName)
+				utilfn.GracefulClose(pipeWriter, tarCopySrcName, pipeWriterName)
+			}
+			// wait for the reader to drain the pipe, so callers can cancel ctx right after close without cutting off the end of the stream
+			readerDone.Wait()
 		}
 }
 
@@ -98,7 +135,7 @@ func fixPath(path, prefix string) (string, error) {
 // The function returns an error if the tar stream cannot be read.
 func TarCopyDest(ctx context.Context, cancel context.CancelCauseFunc, ch <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet], readNext func(next *tar.Header, reader *tar.Reader, singleFile bool) error) error {
 	pipeReader, pipeWriter := io.Pipe()
-	iochan.WriterChan(ctx, pipeWriter, ch, func() {
+	iochan.WriterChan(ctx, pipeWriter, ch, func(int64) {
 		utilfn.GracefulClose(pipeWriter, tarCopyDestName, pipeWriterName)
 	}, cancel)
 	tarReader := tar.NewReader(pipeReader)
@@ -0,0 +1,167 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package tarcopy_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/util/iochan"
+	"github.com/wavetermdev/waveterm/pkg/util/iochan/iochantypes"
+	"github.com/wavetermdev/waveterm/pkg/util/tarcopy"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+type tarEntry struct {
+	name    string
+	content string
+	isDir   bool
+}
+
+// tarStream encodes entries as a tar archive and streams it the same way a remote TarCopySrc would
+func tarStream(t *testing.T, ctx context.Context, entries []tarEntry) chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
+	t.Helper()
+	var buf bytes.Buffer
+	tarWriter := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		if entry.isDir {
+			header = &tar.Header{Name: entry.name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatalf("cannot write header %q: %v", entry.name, err)
+		}
+		if _, err := tarWriter.Write([]byte(entry.content)); err != nil {
+			t.Fatalf("cannot write content %q: %v", entry.name, err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatalf("cannot close tar writer: %v", err)
+	}
+	// small chunks so entries span several packets
+	return iochan.ReaderChan(ctx, &buf, 16, func() {})
+}
+
+func TestTarCopyDest_MultipleEntries(t *testing.T) {
+	entries := []tarEntry{
+		{name: "dir/", isDir: true},
+		{name: "dir/a.txt", content: "aaa"},
+		{name: "dir/b.txt", content: "a longer file that spans more than one packet"},
+		{name: "dir/sub/", isDir: true},
+		{name: "dir/sub/c.txt", content: ""},
+		{name: "d.txt", content: "ddd"},
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	seen := make(map[string]int)
+	contents := make(map[string]string)
+	err := tarcopy.TarCopyDest(ctx, cancel, tarStream(t, ctx, entries), func(next *tar.Header, reader *tar.Reader, singleFile bool) error {
+		seen[next.Name]++
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		contents[next.Name] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TarCopyDest: %v", err)
+	}
+	if len(seen) != len(entries) {
+		t.Errorf("expected %d entries, got %d: %v", len(entries), len(seen), seen)
+	}
+	for _, entry := range entries {
+		if seen[entry.name] != 1 {
+			t.Errorf("entry %q processed %d times, expected once", entry.name, seen[entry.name])
+		}
+		if contents[entry.name] != entry.content {
+			t.Errorf("entry %q: got content %q, expected %q", entry.name, contents[entry.name], entry.content)
+		}
+	}
+}
+
+func TestTarCopyDest_RejectsTraversal(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	entries := []tarEntry{
+		{name: "ok.txt", content: "ok"},
+		{name: "../../etc/passwd", content: "evil"},
+		{name: "after.txt", content: "never read"},
+	}
+	var seen []string
+	err := tarcopy.TarCopyDest(ctx, cancel, tarStream(t, ctx, entries), func(next *tar.Header, reader *tar.Reader, singleFile bool) error {
+		seen = append(seen, next.Name)
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("expected traversal error")
+	}
+	if len(seen) != 1 || seen[0] != "ok.txt" {
+		t.Errorf("expected only ok.txt to be processed, got %v", seen)
+	}
+}
+
+func TestTarCopySrc_ErrorAfterData(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	content := bytes.Repeat([]byte("a"), 100*1024)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	finfo, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputChan, writeHeader, writer, tarClose := tarcopy.TarCopySrcWithOpts(context.Background(), dir, iochan.ReaderChanOpts{ChunkSize: 4096, BufferDepth: 1})
+	producerErr := errors.New("source went away")
+	go func() {
+		if err := writeHeader(finfo, path, false); err != nil {
+			tarClose(err)
+			return
+		}
+		writer.Write(content)
+		tarClose(producerErr)
+	}()
+
+	var data bytes.Buffer
+	var gotErr error
+	numAfterErr := 0
+	for resp := range outputChan {
+		if gotErr != nil {
+			numAfterErr++
+			continue
+		}
+		if resp.Error != nil {
+			gotErr = resp.Error
+			continue
+		}
+		if resp.Response.Checksum != nil {
+			t.Errorf("aborted stream should not end with a checksum")
+		}
+		data.Write(resp.Response.Data)
+	}
+	if gotErr == nil || !strings.Contains(gotErr.Error(), producerErr.Error()) {
+		t.Fatalf("expected producer error, got %v", gotErr)
+	}
+	if numAfterErr != 0 {
+		t.Errorf("expected the error to be the last packet, got %d after it", numAfterErr)
+	}
+	// everything written before the error arrives first
+	tarReader := tar.NewReader(&data)
+	header, err := tarReader.Next()
+	if err != nil || header.Name != "a.txt" {
+		t.Fatalf("expected a.txt header before the error, got %v, %v", header, err)
+	}
+	got, _ := io.ReadAll(tarReader)
+	if !bytes.Equal(got, content) {
+		t.Errorf("expected all %d bytes before the error, got %d", len(content), len(got))
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utilfn
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// binary patches are a sequence of ops:
+//
+//	'C' uvarint(offset) uvarint(len)  -- copy len bytes from the old buffer at offset
+//	'I' uvarint(len) bytes            -- insert len literal bytes
+const (
+	binDiffOp_Copy   = 'C'
+	binDiffOp_Insert = 'I'
+
+	binDiffBlockSize = 8
+)
+
+func binDiffKey(buf []byte) uint64 {
+	return binary.LittleEndian.Uint64(buf[:binDiffBlockSize])
+}
+
+func matchLen(a []byte, b []byte) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// MakeBinaryDiff returns a patch that transforms oldBuf into newBuf.
+// It works on raw bytes (nulls and invalid UTF-8 are fine) and is compact when
+// the two buffers share long runs of bytes.  Apply it with ApplyBinaryDiff.
+func MakeBinaryDiff(oldBuf []byte, newBuf []byte) []byte {
+	index := make(map[uint64]int)
+	for i := 0; i+binDiffBlockSize <= len(oldBuf); i++ {
+		key := binDiffKey(oldBuf[i:])
+		if _, found := index[key]; !found {
+			index[key] = i
+		}
+	}
+	var rtn []byte
+	var literal []byte
+	flushLiteral := func() {
+		if len(literal) == 0 {
+			return
+		}
+		rtn = append(rtn, binDiffOp_Insert)
+		rtn = binary.AppendUvarint(rtn, uint64(len(literal)))
+		rtn = append(rtn, literal...)
+		literal = nil
+	}
+	nextOld := 0
+	pos := 0
+	for pos < len(newBuf) {
+		// prefer continuing the previous copy (cheap for in-place edits), then fall back to the index
+		copyOffset := nextOld
+		copyLen := 0
+		if nextOld < len(oldBuf) {
+			copyLen = matchLen(oldBuf[nextOld:], newBuf[pos:])
+		}
+		if copyLen < binDiffBlockSize && pos+binDiffBlockSize <= len(newBuf) {
+			if offset, found := index[binDiffKey(newBuf[pos:])]; found {
+				if mlen := matchLen(oldBuf[offset:], newBuf[pos:]); mlen > copyLen {
+					copyOffset, copyLen = offset, mlen
+				}
+			}
+		}
+		if copyLen < binDiffBlockSize {
+			literal = append(literal, newBuf[pos])
+			pos++
+			continue
+		}
+		flushLiteral()
+		rtn = append(rtn, binDiffOp_Copy)
+		rtn = binary.AppendUvarint(rtn, uint64(copyOffset))
+		rtn = binary.AppendUvarint(rtn, uint64(copyLen))
+		pos += copyLen
+		nextOld = copyOffset + copyLen
+	}
+	flushLiteral()
+	return rtn
+}
+
+// ApplyBinaryDiff applies a patch created by MakeBinaryDiff to oldBuf.
+func ApplyBinaryDiff(oldBuf []byte, patch []byte) ([]byte, error) {
+	var rtn []byte
+	pos := 0
+	readUvarint := func() (uint64, error) {
+		val, n := binary.Uvarint(patch[pos:])
+		if n <= 0 {
+			return 0, fmt.Errorf("invalid binary diff: bad length at offset %d", pos)
+		}
+		pos += n
+		return val, nil
+	}
+	for pos < len(patch) {
+		op := patch[pos]
+		pos++
+		switch op {
+		case binDiffOp_Copy:
+			offset, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			length, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			if offset > uint64(len(oldBuf)) || length > uint64(len(oldBuf))-offset {
+				return nil, fmt.Errorf("invalid binary diff: copy [%d:+%d] out of range (old size %d)", offset, length, len(oldBuf))
+			}
+			rtn = append(rtn, oldBuf[offset:offset+length]...)
+		case binDiffOp_Insert:
+			length, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			if length > uint64(len(patch)-pos) {
+				return nil, fmt.Errorf("invalid binary diff: insert of %d bytes past end of patch", length)
+			}
+			rtn = append(rtn, patch[pos:pos+int(length)]...)
+			pos += int(length)
+		default:
+			return nil, fmt.Errorf("invalid binary diff: unknown op %q at offset %d", op, pos-1)
+		}
+	}
+	if rtn == nil {
+		rtn = []byte{}
+	}
+	return rtn, nil
+}
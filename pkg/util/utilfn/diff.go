@@ -0,0 +1,365 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utilfn
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DiffOp_Equal  = " "
+	DiffOp_Delete = "-"
+	DiffOp_Insert = "+"
+)
+
+// Text includes the line's trailing newline (the last line of the input may not have one)
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// line numbers are 1-based.  when a side has no lines in the hunk, its start is
+// the line number *before* the hunk (same convention as unified diffs)
+type DiffHunk struct {
+	OldStart int        `json:"oldstart"`
+	OldLines int        `json:"oldlines"`
+	NewStart int        `json:"newstart"`
+	NewLines int        `json:"newlines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+func splitDiffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	rtn := strings.SplitAfter(s, "\n")
+	if rtn[len(rtn)-1] == "" {
+		rtn = rtn[:len(rtn)-1]
+	}
+	return rtn
+}
+
+// returns the full edit script (every line of both inputs) transforming a into b
+func diffLines(a []string, b []string) []DiffLine {
+	intern := make(map[string]int)
+	toIds := func(lines []string) []int {
+		ids := make([]int, len(lines))
+		for i, line := range lines {
+			id, found := intern[line]
+			if !found {
+				id = len(intern)
+				intern[line] = id
+			}
+			ids[i] = id
+		}
+		return ids
+	}
+	aIds, bIds := toIds(a), toIds(b)
+	prefix := 0
+	for prefix < len(aIds) && prefix < len(bIds) && aIds[prefix] == bIds[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(aIds)-prefix && suffix < len(bIds)-prefix && aIds[len(aIds)-1-suffix] == bIds[len(bIds)-1-suffix] {
+		suffix++
+	}
+	var rtn []DiffLine
+	for i := 0; i < prefix; i++ {
+		rtn = append(rtn, DiffLine{Op: DiffOp_Equal, Text: a[i]})
+	}
+	aMid := a[prefix : len(a)-suffix]
+	bMid := b[prefix : len(b)-suffix]
+	rtn = append(rtn, myersDiff(aMid, bMid, aIds[prefix:len(aIds)-suffix], bIds[prefix:len(bIds)-suffix])...)
+	for i := len(a) - suffix; i < len(a); i++ {
+		rtn = append(rtn, DiffLine{Op: DiffOp_Equal, Text: a[i]})
+	}
+	return rtn
+}
+
+// classic O((N+M)D) Myers diff.  the trace only keeps the live diagonals for each d,
+// so memory is O(D^2) rather than O((N+M)D)
+func myersDiff(a []string, b []string, aIds []int, bIds []int) []DiffLine {
+	n, m := len(aIds), len(bIds)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		done := false
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && aIds[x] == bIds[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+		if done {
+			break
+		}
+	}
+	// backtrack, building the script in reverse
+	var rev []DiffLine
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		tv := trace[d]
+		get := func(k int) int { return tv[k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && get(k-1) < get(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := get(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			rev = append(rev, DiffLine{Op: DiffOp_Equal, Text: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				rev = append(rev, DiffLine{Op: DiffOp_Insert, Text: b[y-1]})
+			} else {
+				rev = append(rev, DiffLine{Op: DiffOp_Delete, Text: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	rtn := make([]DiffLine, len(rev))
+	for i, line := range rev {
+		rtn[len(rev)-1-i] = line
+	}
+	return rtn
+}
+
+// groups an edit script into hunks with up to contextLines of unchanged lines around each change.
+// changes separated by no more than 2*contextLines unchanged lines share a hunk.
+func groupHunks(script []DiffLine, contextLines int) []DiffHunk {
+	if contextLines < 0 {
+		contextLines = 0
+	}
+	oldPos := make([]int, len(script)+1)
+	newPos := make([]int, len(script)+1)
+	for i, line := range script {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if line.Op != DiffOp_Insert {
+			oldPos[i+1]++
+		}
+		if line.Op != DiffOp_Delete {
+			newPos[i+1]++
+		}
+	}
+	var rtn []DiffHunk
+	i := 0
+	for i < len(script) {
+		if script[i].Op == DiffOp_Equal {
+			i++
+			continue
+		}
+		changeStart := i
+		changeEnd := i
+		for j := i; j < len(script); j++ {
+			if script[j].Op != DiffOp_Equal {
+				changeEnd = j + 1
+				continue
+			}
+			if j-changeEnd >= 2*contextLines {
+				break
+			}
+		}
+		lo := max(0, changeStart-contextLines)
+		hi := min(len(script), changeEnd+contextLines)
+		hunk := DiffHunk{
+			OldLines: oldPos[hi] - oldPos[lo],
+			NewLines: newPos[hi] - newPos[lo],
+			Lines:    append([]DiffLine(nil), script[lo:hi]...),
+		}
+		hunk.OldStart = oldPos[lo]
+		if hunk.OldLines > 0 {
+			hunk.OldStart++
+		}
+		hunk.NewStart = newPos[lo]
+		if hunk.NewLines > 0 {
+			hunk.NewStart++
+		}
+		rtn = append(rtn, hunk)
+		i = changeEnd
+	}
+	return rtn
+}
+
+// ComputeHunks returns the line-level changes between str1 and str2 as hunks with no context lines.
+// returns nil if the strings are identical.
+func ComputeHunks(str1 string, str2 string) []DiffHunk {
+	return groupHunks(diffLines(splitDiffLines(str1), splitDiffLines(str2)), 0)
+}
+
+func formatUnifiedRange(start int, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// FormatUnifiedDiff returns a GNU-style unified diff between str1 and str2, with
+// contextLines of unchanged lines around each change.  returns "" if the strings are identical.
+func FormatUnifiedDiff(oldName string, newName string, str1 string, str2 string, contextLines int) string {
+	hunks := groupHunks(diffLines(splitDiffLines(str1), splitDiffLines(str2)), contextLines)
+	if len(hunks) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", formatUnifiedRange(hunk.OldStart, hunk.OldLines), formatUnifiedRange(hunk.NewStart, hunk.NewLines))
+		for _, line := range hunk.Lines {
+			buf.WriteString(line.Op)
+			buf.WriteString(line.Text)
+			if !strings.HasSuffix(line.Text, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return buf.String()
+}
+
+// Merge3 diffs each side against base, so worst-case memory is quadratic in the input size
+const MaxMergeLines = 10000
+
+// a region where a and b both changed base differently.  Merge3 leaves the base
+// lines in the merged output at [MergedLine, MergedLine+MergedLines) (0-based) so
+// callers can replace them with their own conflict markers.
+type MergeConflict struct {
+	MergedLine  int    `json:"mergedline"`
+	MergedLines int    `json:"mergedlines"`
+	Base        string `json:"base"`
+	A           string `json:"a"`
+	B           string `json:"b"`
+}
+
+// a run of changed lines relative to base: base[baseStart:baseEnd] is replaced by lines
+type diffChunk struct {
+	baseStart int
+	baseEnd   int
+	lines     []string
+}
+
+func diffChunks(base []string, other []string) []diffChunk {
+	var rtn []diffChunk
+	basePos := 0
+	var cur *diffChunk
+	for _, line := range diffLines(base, other) {
+		if line.Op == DiffOp_Equal {
+			if cur != nil {
+				rtn = append(rtn, *cur)
+				cur = nil
+			}
+			basePos++
+			continue
+		}
+		if cur == nil {
+			cur = &diffChunk{baseStart: basePos, baseEnd: basePos}
+		}
+		if line.Op == DiffOp_Delete {
+			basePos++
+			cur.baseEnd = basePos
+		} else {
+			cur.lines = append(cur.lines, line.Text)
+		}
+	}
+	if cur != nil {
+		rtn = append(rtn, *cur)
+	}
+	return rtn
+}
+
+func applyChunks(base []string, start int, end int, chunks []diffChunk) []string {
+	var rtn []string
+	pos := start
+	for _, chunk := range chunks {
+		rtn = append(rtn, base[pos:chunk.baseStart]...)
+		rtn = append(rtn, chunk.lines...)
+		pos = chunk.baseEnd
+	}
+	return append(rtn, base[pos:end]...)
+}
+
+// Merge3 does a three-way line merge of a and b, which were both derived from base.
+// changes that don't overlap (or touch) are combined.  overlapping changes are merged
+// cleanly if both sides made the same edit, otherwise they are returned as conflicts.
+func Merge3(base string, a string, b string) (string, []MergeConflict, error) {
+	baseLines, aLines, bLines := splitDiffLines(base), splitDiffLines(a), splitDiffLines(b)
+	if numLines := max(len(baseLines), len(aLines), len(bLines)); numLines > MaxMergeLines {
+		return "", nil, fmt.Errorf("cannot merge: input has %d lines (max %d)", numLines, MaxMergeLines)
+	}
+	aChunks, bChunks := diffChunks(baseLines, aLines), diffChunks(baseLines, bLines)
+	var merged []string
+	var conflicts []MergeConflict
+	basePos := 0
+	ai, bi := 0, 0
+	for ai < len(aChunks) || bi < len(bChunks) {
+		var start int
+		if bi >= len(bChunks) || (ai < len(aChunks) && aChunks[ai].baseStart <= bChunks[bi].baseStart) {
+			start = aChunks[ai].baseStart
+		} else {
+			start = bChunks[bi].baseStart
+		}
+		end := start
+		aFrom, bFrom := ai, bi
+		for {
+			if ai < len(aChunks) && aChunks[ai].baseStart <= end {
+				end = max(end, aChunks[ai].baseEnd)
+				ai++
+				continue
+			}
+			if bi < len(bChunks) && bChunks[bi].baseStart <= end {
+				end = max(end, bChunks[bi].baseEnd)
+				bi++
+				continue
+			}
+			break
+		}
+		merged = append(merged, baseLines[basePos:start]...)
+		basePos = end
+		aRegion := applyChunks(baseLines, start, end, aChunks[aFrom:ai])
+		bRegion := applyChunks(baseLines, start, end, bChunks[bFrom:bi])
+		if bFrom == bi {
+			merged = append(merged, aRegion...)
+			continue
+		}
+		if aFrom == ai {
+			merged = append(merged, bRegion...)
+			continue
+		}
+		aText, bText := strings.Join(aRegion, ""), strings.Join(bRegion, "")
+		if aText == bText {
+			merged = append(merged, aRegion...)
+			continue
+		}
+		conflicts = append(conflicts, MergeConflict{
+			MergedLine:  len(merged),
+			MergedLines: end - start,
+			Base:        strings.Join(baseLines[start:end], ""),
+			A:           aText,
+			B:           bText,
+		})
+		merged = append(merged, baseLines[start:end]...)
+	}
+	merged = append(merged, baseLines[basePos:]...)
+	return strings.Join(merged, ""), conflicts, nil
+}
@@ -0,0 +1,278 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utilfn
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const diffStr1 = `line one
+line two
+line three
+line four
+line five
+line six
+`
+
+const diffStr2 = `line zero
+line one
+line two
+line 3
+line four
+line five
+`
+
+func checkBinaryRoundTrip(t *testing.T, oldBuf []byte, newBuf []byte) []byte {
+	t.Helper()
+	patch := MakeBinaryDiff(oldBuf, newBuf)
+	got, err := ApplyBinaryDiff(oldBuf, patch)
+	if err != nil {
+		t.Fatalf("ApplyBinaryDiff: %v", err)
+	}
+	if !bytes.Equal(got, newBuf) {
+		t.Fatalf("round trip mismatch: old=%q new=%q got=%q", oldBuf, newBuf, got)
+	}
+	return patch
+}
+
+func TestBinaryDiffSimple(t *testing.T) {
+	checkBinaryRoundTrip(t, nil, nil)
+	checkBinaryRoundTrip(t, nil, []byte("hello"))
+	checkBinaryRoundTrip(t, []byte("hello"), nil)
+	checkBinaryRoundTrip(t, []byte("\x00\xff\xfe invalid \xc3\x28 utf8"), []byte("\x00\xff\xfe invalid \xc3\x28 utf8\x00\x00"))
+}
+
+func TestBinaryDiffCompact(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	oldBuf := make([]byte, 64*1024)
+	rnd.Read(oldBuf)
+	newBuf := bytes.Clone(oldBuf)
+	newBuf[1000] ^= 0xff
+	newBuf = append(newBuf[:30000], append([]byte("inserted\x00"), newBuf[30000:]...)...)
+	patch := checkBinaryRoundTrip(t, oldBuf, newBuf)
+	if len(patch) > 100 {
+		t.Errorf("patch too large for a small edit: %d bytes", len(patch))
+	}
+}
+
+func TestBinaryDiffRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	randBytes := func(n int) []byte {
+		buf := make([]byte, n)
+		// small alphabet so there are plenty of partial matches
+		for i := range buf {
+			buf[i] = byte(rnd.Intn(4))
+		}
+		return buf
+	}
+	for i := 0; i < 500; i++ {
+		oldBuf := randBytes(rnd.Intn(300))
+		var newBuf []byte
+		switch rnd.Intn(3) {
+		case 0:
+			newBuf = randBytes(rnd.Intn(300))
+		default:
+			// mutate a copy of oldBuf
+			newBuf = bytes.Clone(oldBuf)
+			for j := rnd.Intn(5); j > 0; j-- {
+				pos := rnd.Intn(len(newBuf) + 1)
+				newBuf = append(newBuf[:pos], append(randBytes(rnd.Intn(10)), newBuf[pos:]...)...)
+				if len(newBuf) > 0 {
+					del := rnd.Intn(len(newBuf))
+					newBuf = append(newBuf[:del], newBuf[min(len(newBuf), del+rnd.Intn(10)):]...)
+				}
+			}
+		}
+		checkBinaryRoundTrip(t, oldBuf, newBuf)
+	}
+}
+
+func TestApplyBinaryDiffInvalid(t *testing.T) {
+	oldBuf := []byte("hello world")
+	bad := [][]byte{
+		{'X'},
+		{'C', 0},
+		{'C', 5, 20},
+		{'I', 10, 'a'},
+	}
+	for _, patch := range bad {
+		if _, err := ApplyBinaryDiff(oldBuf, patch); err == nil {
+			t.Errorf("expected error for patch %q", patch)
+		}
+	}
+}
+
+func TestComputeHunks(t *testing.T) {
+	if hunks := ComputeHunks(diffStr1, diffStr1); hunks != nil {
+		t.Fatalf("expected no hunks for identical input, got %v", hunks)
+	}
+	hunks := ComputeHunks(diffStr1, diffStr2)
+	expected := []DiffHunk{
+		{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1, Lines: []DiffLine{
+			{Op: DiffOp_Insert, Text: "line zero\n"},
+		}},
+		{OldStart: 3, OldLines: 1, NewStart: 4, NewLines: 1, Lines: []DiffLine{
+			{Op: DiffOp_Delete, Text: "line three\n"},
+			{Op: DiffOp_Insert, Text: "line 3\n"},
+		}},
+		{OldStart: 6, OldLines: 1, NewStart: 6, NewLines: 0, Lines: []DiffLine{
+			{Op: DiffOp_Delete, Text: "line six\n"},
+		}},
+	}
+	if !reflect.DeepEqual(hunks, expected) {
+		t.Fatalf("hunks mismatch:\n got: %+v\nwant: %+v", hunks, expected)
+	}
+}
+
+func TestComputeHunksNoTrailingNewline(t *testing.T) {
+	hunks := ComputeHunks("a\nb", "a\nb\n")
+	expected := []DiffHunk{
+		{OldStart: 2, OldLines: 1, NewStart: 2, NewLines: 1, Lines: []DiffLine{
+			{Op: DiffOp_Delete, Text: "b"},
+			{Op: DiffOp_Insert, Text: "b\n"},
+		}},
+	}
+	if !reflect.DeepEqual(hunks, expected) {
+		t.Fatalf("hunks mismatch:\n got: %+v\nwant: %+v", hunks, expected)
+	}
+}
+
+func TestDiffLinesRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(7))
+	randLines := func() []string {
+		lines := make([]string, rnd.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a'+rnd.Intn(5))) + "\n"
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := randLines(), randLines()
+		var gotA, gotB []string
+		for _, line := range diffLines(a, b) {
+			if line.Op != DiffOp_Insert {
+				gotA = append(gotA, line.Text)
+			}
+			if line.Op != DiffOp_Delete {
+				gotB = append(gotB, line.Text)
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("edit script does not reproduce inputs: a=%q b=%q", a, b)
+		}
+	}
+}
+
+func TestFormatUnifiedDiff(t *testing.T) {
+	if out := FormatUnifiedDiff("a", "b", diffStr1, diffStr1, 3); out != "" {
+		t.Fatalf("expected empty diff, got %q", out)
+	}
+	expected := `--- a/file.txt
++++ b/file.txt
+@@ -1,6 +1,6 @@
++line zero
+ line one
+ line two
+-line three
++line 3
+ line four
+ line five
+-line six
+`
+	if out := FormatUnifiedDiff("a/file.txt", "b/file.txt", diffStr1, diffStr2, 3); out != expected {
+		t.Fatalf("unified diff mismatch:\n%s\nwant:\n%s", out, expected)
+	}
+	expected = `--- old
++++ new
+@@ -0,0 +1 @@
++line zero
+@@ -3 +4 @@
+-line three
++line 3
+@@ -6 +6,0 @@
+-line six
+`
+	if out := FormatUnifiedDiff("old", "new", diffStr1, diffStr2, 0); out != expected {
+		t.Fatalf("unified diff mismatch:\n%s\nwant:\n%s", out, expected)
+	}
+	expected = `--- old
++++ new
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`
+	if out := FormatUnifiedDiff("old", "new", "a\nb", "a\nc", 1); out != expected {
+		t.Fatalf("unified diff mismatch:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestMerge3Clean(t *testing.T) {
+	base := "one\ntwo\nthree\nfour\nfive\nsix\n"
+	a := "zero\none\ntwo\nthree\nfour\nfive\nsix\n"
+	b := "one\ntwo\nthree\nfour\nfive\nSIX\nseven\n"
+	merged, conflicts, err := Merge3(base, a, b)
+	if err != nil {
+		t.Fatalf("Merge3: %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %+v", conflicts)
+	}
+	if expected := "zero\none\ntwo\nthree\nfour\nfive\nSIX\nseven\n"; merged != expected {
+		t.Fatalf("merged mismatch: got %q, want %q", merged, expected)
+	}
+}
+
+func TestMerge3SameEdit(t *testing.T) {
+	base := "one\ntwo\nthree\n"
+	edited := "one\n2\nthree\n"
+	merged, conflicts, err := Merge3(base, edited, edited)
+	if err != nil || len(conflicts) != 0 || merged != edited {
+		t.Fatalf("Merge3 identical edits: got %q, %+v, %v", merged, conflicts, err)
+	}
+	merged, conflicts, err = Merge3(base, base, base)
+	if err != nil || len(conflicts) != 0 || merged != base {
+		t.Fatalf("Merge3 identical inputs: got %q, %+v, %v", merged, conflicts, err)
+	}
+}
+
+func TestMerge3Conflict(t *testing.T) {
+	base := "one\ntwo\nthree\nfour\nfive\n"
+	a := "one\nTWO-a\nthree\nfour\nfive!\n"
+	b := "one\nTWO-b\nthree\nfour\nfive\n"
+	merged, conflicts, err := Merge3(base, a, b)
+	if err != nil {
+		t.Fatalf("Merge3: %v", err)
+	}
+	expectedConflicts := []MergeConflict{
+		{MergedLine: 1, MergedLines: 1, Base: "two\n", A: "TWO-a\n", B: "TWO-b\n"},
+	}
+	if !reflect.DeepEqual(conflicts, expectedConflicts) {
+		t.Fatalf("conflicts mismatch:\n got: %+v\nwant: %+v", conflicts, expectedConflicts)
+	}
+	// the non-conflicting edit from a still applies, the conflict keeps the base lines
+	if expected := "one\ntwo\nthree\nfour\nfive!\n"; merged != expected {
+		t.Fatalf("merged mismatch: got %q, want %q", merged, expected)
+	}
+}
+
+func TestMerge3InsertConflict(t *testing.T) {
+	base := "one\ntwo\n"
+	merged, conflicts, err := Merge3(base, "one\na\ntwo\n", "one\nb\ntwo\n")
+	if err != nil {
+		t.Fatalf("Merge3: %v", err)
+	}
+	expectedConflicts := []MergeConflict{
+		{MergedLine: 1, MergedLines: 0, Base: "", A: "a\n", B: "b\n"},
+	}
+	if !reflect.DeepEqual(conflicts, expectedConflicts) || merged != base {
+		t.Fatalf("got %q, %+v", merged, conflicts)
+	}
+}
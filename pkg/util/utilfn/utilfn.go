@@ -297,6 +297,70 @@ func AddIntSlice(vals ...int) (int, error) {
 	return rtn, nil
 }
 
+// Add two int64 values, returning an error if the result overflows.
+func AddInt64(left, right int64) (int64, error) {
+	if right > 0 {
+		if left > math.MaxInt64-right {
+			return 0, ErrOverflow
+		}
+	} else {
+		if left < math.MinInt64-right {
+			return 0, ErrOverflow
+		}
+	}
+	return left + right, nil
+}
+
+// Add a slice of int64s, returning an error if the result overflows.
+func AddInt64Slice(vals ...int64) (int64, error) {
+	var rtn int64
+	for _, v := range vals {
+		var err error
+		rtn, err = AddInt64(rtn, v)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return rtn, nil
+}
+
+// Add two uint64 values, returning an error if the result overflows.
+func AddUint64(left, right uint64) (uint64, error) {
+	if left > math.MaxUint64-right {
+		return 0, ErrOverflow
+	}
+	return left + right, nil
+}
+
+// Multiply two int values, returning an error if the result overflows.
+func MulInt(left, right int) (int, error) {
+	if left == 0 || right == 0 {
+		return 0, nil
+	}
+	if (left == -1 && right == math.MinInt) || (right == -1 && left == math.MinInt) {
+		return 0, ErrOverflow
+	}
+	rtn := left * right
+	if rtn/right != left {
+		return 0, ErrOverflow
+	}
+	return rtn, nil
+}
+
+// Multiply a slice of ints, returning an error if the result overflows.
+// An empty slice returns 1.
+func MulIntSlice(vals ...int) (int, error) {
+	rtn := 1
+	for _, v := range vals {
+		var err error
+		rtn, err = MulInt(rtn, v)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return rtn, nil
+}
+
 func StrsEqual(s1arr []string, s2arr []string) bool {
 	if len(s1arr) != len(s2arr) {
 		return false
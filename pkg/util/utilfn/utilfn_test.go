@@ -0,0 +1,121 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utilfn
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+type arithTest struct {
+	name    string
+	vals    []int
+	want    int
+	wantErr bool
+}
+
+func testArithmetic(t *testing.T, fn func(...int) (int, error), tests []arithTest) {
+	t.Helper()
+	for _, tc := range tests {
+		got, err := fn(tc.vals...)
+		if tc.wantErr {
+			if !errors.Is(err, ErrOverflow) {
+				t.Errorf("%s: expected ErrOverflow, got %d, %v", tc.name, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestAddIntSlice(t *testing.T) {
+	testArithmetic(t, AddIntSlice, []arithTest{
+		{"empty", nil, 0, false},
+		{"simple", []int{1, 2, 3}, 6, false},
+		{"negative", []int{-5, 3}, -2, false},
+		{"max", []int{math.MaxInt, 0}, math.MaxInt, false},
+		{"max overflow", []int{math.MaxInt, 1}, 0, true},
+		{"min overflow", []int{math.MinInt, -1}, 0, true},
+		{"cancel", []int{math.MaxInt, math.MinInt}, -1, false},
+	})
+}
+
+func TestMulIntSlice(t *testing.T) {
+	testArithmetic(t, MulIntSlice, []arithTest{
+		{"empty", nil, 1, false},
+		{"simple", []int{2, 3, 4}, 24, false},
+		{"zero", []int{math.MaxInt, 0, math.MaxInt}, 0, false},
+		{"negative", []int{-2, 3}, -6, false},
+		{"two negatives", []int{-2, -3}, 6, false},
+		{"max", []int{math.MaxInt, 1}, math.MaxInt, false},
+		{"max neg one", []int{math.MaxInt, -1}, -math.MaxInt, false},
+		{"max overflow", []int{math.MaxInt, 2}, 0, true},
+		{"min neg one", []int{math.MinInt, -1}, 0, true},
+		{"neg one min", []int{-1, math.MinInt}, 0, true},
+		{"min times two", []int{math.MinInt / 2, 2}, math.MinInt, false},
+		{"min overflow", []int{math.MinInt/2 - 1, 2}, 0, true},
+		{"large pair", []int{1 << 32, 1 << 31}, 0, true},
+	})
+}
+
+func TestMulInt(t *testing.T) {
+	got, err := MulInt(1<<20, 1<<10)
+	if err != nil || got != 1<<30 {
+		t.Fatalf("MulInt: got %d, %v", got, err)
+	}
+	if _, err := MulInt(math.MaxInt/3+1, 3); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("expected overflow, got %v", err)
+	}
+}
+
+func TestAddInt64Slice(t *testing.T) {
+	tests := []struct {
+		name    string
+		vals    []int64
+		want    int64
+		wantErr bool
+	}{
+		{"empty", nil, 0, false},
+		{"simple", []int64{1 << 40, 1 << 40}, 1 << 41, false},
+		{"max", []int64{math.MaxInt64 - 1, 1}, math.MaxInt64, false},
+		{"max overflow", []int64{math.MaxInt64, 1}, 0, true},
+		{"min", []int64{math.MinInt64 + 1, -1}, math.MinInt64, false},
+		{"min overflow", []int64{math.MinInt64, -1}, 0, true},
+		{"cancel", []int64{math.MaxInt64, math.MinInt64}, -1, false},
+	}
+	for _, tc := range tests {
+		got, err := AddInt64Slice(tc.vals...)
+		if tc.wantErr {
+			if !errors.Is(err, ErrOverflow) {
+				t.Errorf("%s: expected ErrOverflow, got %d, %v", tc.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %d, %v, want %d", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestAddUint64(t *testing.T) {
+	if got, err := AddUint64(math.MaxUint64-1, 1); err != nil || got != math.MaxUint64 {
+		t.Errorf("max: got %d, %v", got, err)
+	}
+	if got, err := AddUint64(math.MaxUint64, 0); err != nil || got != math.MaxUint64 {
+		t.Errorf("max plus zero: got %d, %v", got, err)
+	}
+	if _, err := AddUint64(math.MaxUint64, 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected ErrOverflow, got %v", err)
+	}
+	if _, err := AddUint64(1<<63, 1<<63); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected ErrOverflow, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package zipcopy provides functions for streaming a zip archive over a channel.
+// Unlike tarcopy it is download only, there is no matching destination reader.
+package zipcopy
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/wavetermdev/waveterm/pkg/util/iochan"
+	"github.com/wavetermdev/waveterm/pkg/util/iochan/iochantypes"
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+const (
+	zipCopySrcName = "ZipCopySrc"
+	pipeReaderName = "pipe reader"
+	pipeWriterName = "pipe writer"
+	zipWriterName  = "zip writer"
+
+	// LevelStore stores every file without compressing it
+	LevelStore = -1
+)
+
+// already compressed formats, deflating these again costs cpu for no gain so they are always stored
+var storedExtensions = map[string]bool{
+	".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".zst": true, ".7z": true, ".rar": true,
+	".jar": true, ".whl": true, ".apk": true, ".docx": true, ".xlsx": true, ".pptx": true, ".odt": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".heic": true, ".avif": true,
+	".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true, ".flac": true,
+	".mp4": true, ".m4v": true, ".mov": true, ".mkv": true, ".webm": true, ".avi": true,
+	".woff": true, ".woff2": true,
+}
+
+func isCompressedFile(path string) bool {
+	return storedExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// ZipCopySrc creates a zip stream writer and returns a channel to send the zip stream to.
+// level is the deflate level (1-9), 0 uses the default level and LevelStore disables compression.
+// writeFile adds the file at path to the archive, named relative to pathPrefix. Regular files are copied, symlinks are stored
+// with their link target as content (the usual zip convention) and directories get an empty entry. Other file types are skipped.
+// Modes are preserved in the unix external attributes.
+// close writes the zip central directory and closes the internal pipe writer, it returns once the stream has been fully read. A non-nil error aborts the stream instead, it is
+// delivered after the data already written (see tarcopy.TarCopySrc).
+func ZipCopySrc(ctx context.Context, pathPrefix string, level int, readerOpts iochan.ReaderChanOpts) (outputChan chan wshrpc.RespOrErrorUnion[iochantypes.Packet], writeFile func(fi fs.FileInfo, path string) error, close func(err error), err error) {
+	if level < LevelStore || level > flate.BestCompression {
+		return nil, nil, nil, fmt.Errorf("invalid compression level %d", level)
+	}
+	// flate.DefaultCompression is also -1, so remember whether we store before mapping 0 to it
+	storeOnly := level == LevelStore
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+	pipeReader, pipeWriter := io.Pipe()
+	zipWriter := zip.NewWriter(pipeWriter)
+	if !storeOnly {
+		zipWriter.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(w, level)
+		})
+	}
+	var readerDone sync.WaitGroup
+	readerDone.Add(1)
+	rtnChan := iochan.ReaderChanWithOpts(ctx, pipeReader, readerOpts, func() {
+		log.Printf("Closing pipe reader\n")
+		utilfn.GracefulClose(pipeReader, zipCopySrcName, pipeReaderName)
+		readerDone.Done()
+	})
+
+	return rtnChan, func(fi fs.FileInfo, path string) error {
+			name, err := filepath.Rel(pathPrefix, path)
+			if err != nil {
+				return err
+			}
+			name = filepath.ToSlash(name)
+			// skip the root directory itself
+			if name == "." {
+				return nil
+			}
+			if name == ".." || strings.HasPrefix(name, "../") {
+				return fmt.Errorf("invalid zip path outside of %q: %s", pathPrefix, path)
+			}
+			isSymlink := fi.Mode()&fs.ModeSymlink != 0
+			// devices, sockets, etc. have no zip representation
+			if !fi.IsDir() && !isSymlink && !fi.Mode().IsRegular() {
+				return nil
+			}
+			header, err := zip.FileInfoHeader(fi)
+			if err != nil {
+				return err
+			}
+			header.Name = name
+			if fi.IsDir() {
+				header.Name += "/"
+				header.Method = zip.Store
+				_, err = zipWriter.CreateHeader(header)
+				return err
+			}
+			header.Method = zip.Deflate
+			if storeOnly || isSymlink || isCompressedFile(path) {
+				header.Method = zip.Store
+			}
+			entryWriter, err := zipWriter.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+			if isSymlink {
+				link, err := os.Readlink(path)
+				if err != nil {
+					return err
+				}
+				_, err = io.WriteString(entryWriter, link)
+				return err
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer utilfn.GracefulClose(file, zipCopySrcName, path)
+			_, err = io.Copy(entryWriter, file)
+			return err
+		}, func(err error) {
+			if err != nil {
+				log.Printf("Aborting zip stream: %v\n", err)
+				pipeWriter.CloseWithError(err)
+			} else {
+				log.Printf("Closing zip writer\n")
+				utilfn.GracefulClose(zipWriter, zipCopySrcName, zipWriterName)
+				utilfn.GracefulClose(pipeWriter, zipCopySrcName, pipeWriterName)
+			}
+			readerDone.Wait()
+		}, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package zipcopy_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/util/iochan"
+	"github.com/wavetermdev/waveterm/pkg/util/zipcopy"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func TestZipCopySrc_LevelStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+	if err := os.WriteFile(path, []byte(strings.Repeat("a", 10000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	finfo, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputChan, writeFile, zipClose, err := zipcopy.ZipCopySrc(context.Background(), dir, zipcopy.LevelStore, iochan.ReaderChanOpts{ChunkSize: wshrpc.FileChunkSize})
+	if err != nil {
+		t.Fatalf("ZipCopySrc: %v", err)
+	}
+	go func() {
+		zipClose(writeFile(finfo, path))
+	}()
+	var buf bytes.Buffer
+	for resp := range outputChan {
+		if resp.Error != nil {
+			t.Fatalf("zip stream: %v", resp.Error)
+		}
+		buf.Write(resp.Response.Data)
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("cannot read zip: %v", err)
+	}
+	if len(zipReader.File) != 1 || zipReader.File[0].Name != "data.txt" {
+		t.Fatalf("expected a single data.txt entry, got %d entries", len(zipReader.File))
+	}
+	if file := zipReader.File[0]; file.Method != zip.Store || file.CompressedSize64 != 10000 {
+		t.Errorf("expected stored entry, method %d compressed size %d", file.Method, file.CompressedSize64)
+	}
+
+	if _, _, _, err := zipcopy.ZipCopySrc(context.Background(), dir, 10, iochan.ReaderChanOpts{}); err == nil {
+		t.Errorf("expected error for compression level 10")
+	}
+}
@@ -19,10 +19,11 @@ var instance *Watcher
 var once sync.Once
 
 type Watcher struct {
-	initialized bool
-	watcher     *fsnotify.Watcher
-	mutex       sync.Mutex
-	fullConfig  FullConfigType
+	initialized    bool
+	watcher        *fsnotify.Watcher
+	mutex          sync.Mutex
+	fullConfig     FullConfigType
+	updateHandlers []func(FullConfigType)
 }
 
 type WatcherUpdate struct {
@@ -105,7 +106,20 @@ func (w *Watcher) Close() {
 	}
 }
 
+// AddUpdateHandler registers a backend callback that runs (under the watcher lock) with the initial config and after every change
+func (w *Watcher) AddUpdateHandler(handler func(FullConfigType)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.updateHandlers = append(w.updateHandlers, handler)
+	if w.initialized {
+		handler(w.fullConfig)
+	}
+}
+
 func (w *Watcher) broadcast(message WatcherUpdate) {
+	for _, handler := range w.updateHandlers {
+		handler(message.FullConfig)
+	}
 	// send to frontend
 	wps.Broker.Publish(wps.WaveEvent{
 		Event: wps.Event_Config,
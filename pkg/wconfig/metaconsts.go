@@ -60,6 +60,10 @@ const (
 	ConfigKey_MarkdownFixedFontSize          = "markdown:fixedfontsize"
 
 	ConfigKey_PreviewShowHiddenFiles         = "preview:showhiddenfiles"
+	ConfigKey_PreviewMimeTypes               = "preview:mimetypes"
+	ConfigKey_PreviewDirSizeTimeoutMs        = "preview:dirsizetimeoutms"
+	ConfigKey_PreviewMaxFileSize             = "preview:maxfilesize"
+	ConfigKey_PreviewFileTimeoutMs           = "preview:filetimeoutms"
 
 	ConfigKey_TabPreset                      = "tab:preset"
 
@@ -92,5 +96,6 @@ const (
 	ConfigKey_ConnClear                      = "conn:*"
 	ConfigKey_ConnAskBeforeWshInstall        = "conn:askbeforewshinstall"
 	ConfigKey_ConnWshEnabled                 = "conn:wshenabled"
+	ConfigKey_ConnMaxFileOps                 = "conn:maxfileops"
 )
 
@@ -104,7 +104,11 @@ type SettingsType struct {
 	MarkdownFontSize      float64 `json:"markdown:fontsize,omitempty"`
 	MarkdownFixedFontSize float64 `json:"markdown:fixedfontsize,omitempty"`
 
-	PreviewShowHiddenFiles *bool `json:"preview:showhiddenfiles,omitempty"`
+	PreviewShowHiddenFiles  *bool                           `json:"preview:showhiddenfiles,omitempty"`
+	PreviewMimeTypes        map[string]MimeTypeOverrideType `json:"preview:mimetypes,omitempty"`
+	PreviewDirSizeTimeoutMs int                             `json:"preview:dirsizetimeoutms,omitempty"`
+	PreviewMaxFileSize      int64                           `json:"preview:maxfilesize,omitempty"`
+	PreviewFileTimeoutMs    *int64                          `json:"preview:filetimeoutms,omitempty"`
 
 	TabPreset string `json:"tab:preset,omitempty"`
 
@@ -137,6 +141,7 @@ type SettingsType struct {
 	ConnClear               bool  `json:"conn:*,omitempty"`
 	ConnAskBeforeWshInstall *bool `json:"conn:askbeforewshinstall,omitempty"`
 	ConnWshEnabled          bool  `json:"conn:wshenabled,omitempty"`
+	ConnMaxFileOps          int   `json:"conn:maxfileops,omitempty"`
 }
 
 type ConfigError struct {
@@ -170,6 +175,7 @@ type ConnKeywords struct {
 	ConnWshPath             string `json:"conn:wshpath,omitempty"`
 	ConnShellPath           string `json:"conn:shellpath,omitempty"`
 	ConnIgnoreSshConfig     *bool  `json:"conn:ignoresshconfig,omitempty"`
+	ConnJailRoot            string `json:"conn:jailroot,omitempty"`
 
 	DisplayHidden *bool   `json:"display:hidden,omitempty"`
 	DisplayOrder  float32 `json:"display:order,omitempty"`
@@ -621,6 +627,12 @@ type WidgetConfigType struct {
 	BlockDef      waveobj.BlockDef `json:"blockdef"`
 }
 
+// keyed by file extension in preview:mimetypes
+type MimeTypeOverrideType struct {
+	MimeType string `json:"mimetype"`
+	Force    bool   `json:"force,omitempty"`
+}
+
 type MimeTypeConfigType struct {
 	Icon  string `json:"icon"`
 	Color string `json:"color"`
@@ -355,18 +355,75 @@ func RecordTEventCommand(w *wshutil.WshRpc, data telemetrydata.TEvent, opts *wsh
 	return err
 }
 
+// command "remoteclosefile", wshserver.RemoteCloseFileCommand
+func RemoteCloseFileCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) error {
+	_, err := sendRpcRequestCallHelper[any](w, "remoteclosefile", data, opts)
+	return err
+}
+
+// command "remotediskusage", wshserver.RemoteDiskUsageCommand
+func RemoteDiskUsageCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteDiskUsageData, opts *wshrpc.RpcOpts) (*wshrpc.CommandRemoteDiskUsageRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.CommandRemoteDiskUsageRtnData](w, "remotediskusage", data, opts)
+	return resp, err
+}
+
+// command "remotedownload", wshserver.RemoteDownloadCommand
+func RemoteDownloadCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteDownloadData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteDownloadProgress] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.CommandRemoteDownloadProgress](w, "remotedownload", data, opts)
+}
+
+// command "remoteensuredir", wshserver.RemoteEnsureDirCommand
+func RemoteEnsureDirCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteMkdirData, opts *wshrpc.RpcOpts) error {
+	_, err := sendRpcRequestCallHelper[any](w, "remoteensuredir", data, opts)
+	return err
+}
+
+// command "remotefileappend", wshserver.RemoteFileAppendCommand
+func RemoteFileAppendCommand(w *wshutil.WshRpc, data wshrpc.FileData, opts *wshrpc.RpcOpts) (*wshrpc.FileInfo, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.FileInfo](w, "remotefileappend", data, opts)
+	return resp, err
+}
+
+// command "remotefilechown", wshserver.RemoteFileChownCommand
+func RemoteFileChownCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteFileChownData, opts *wshrpc.RpcOpts) error {
+	_, err := sendRpcRequestCallHelper[any](w, "remotefilechown", data, opts)
+	return err
+}
+
+// command "remotefilecompare", wshserver.RemoteFileCompareCommand
+func RemoteFileCompareCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteFileCompareData, opts *wshrpc.RpcOpts) (*wshrpc.CommandRemoteFileCompareRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.CommandRemoteFileCompareRtnData](w, "remotefilecompare", data, opts)
+	return resp, err
+}
+
 // command "remotefilecopy", wshserver.RemoteFileCopyCommand
 func RemoteFileCopyCommand(w *wshutil.WshRpc, data wshrpc.CommandFileCopyData, opts *wshrpc.RpcOpts) (bool, error) {
 	resp, err := sendRpcRequestCallHelper[bool](w, "remotefilecopy", data, opts)
 	return resp, err
 }
 
+// command "remotefilecopyplan", wshserver.RemoteFileCopyPlanCommand
+func RemoteFileCopyPlanCommand(w *wshutil.WshRpc, data wshrpc.CommandFileCopyData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.FileCopyPlanEntry] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.FileCopyPlanEntry](w, "remotefilecopyplan", data, opts)
+}
+
+// command "remotefilecopystream", wshserver.RemoteFileCopyStreamCommand
+func RemoteFileCopyStreamCommand(w *wshutil.WshRpc, data wshrpc.CommandFileCopyData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileCopyProgress] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.CommandRemoteFileCopyProgress](w, "remotefilecopystream", data, opts)
+}
+
 // command "remotefiledelete", wshserver.RemoteFileDeleteCommand
 func RemoteFileDeleteCommand(w *wshutil.WshRpc, data wshrpc.CommandDeleteFileData, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "remotefiledelete", data, opts)
 	return err
 }
 
+// command "remotefileexists", wshserver.RemoteFileExistsCommand
+func RemoteFileExistsCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) (string, error) {
+	resp, err := sendRpcRequestCallHelper[string](w, "remotefileexists", data, opts)
+	return resp, err
+}
+
 // command "remotefileinfo", wshserver.RemoteFileInfoCommand
 func RemoteFileInfoCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) (*wshrpc.FileInfo, error) {
 	resp, err := sendRpcRequestCallHelper[*wshrpc.FileInfo](w, "remotefileinfo", data, opts)
@@ -379,24 +436,93 @@ func RemoteFileJoinCommand(w *wshutil.WshRpc, data []string, opts *wshrpc.RpcOpt
 	return resp, err
 }
 
+// command "remotefilelock", wshserver.RemoteFileLockCommand
+func RemoteFileLockCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteFileLockData, opts *wshrpc.RpcOpts) (*wshrpc.CommandRemoteFileLockRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.CommandRemoteFileLockRtnData](w, "remotefilelock", data, opts)
+	return resp, err
+}
+
 // command "remotefilemove", wshserver.RemoteFileMoveCommand
 func RemoteFileMoveCommand(w *wshutil.WshRpc, data wshrpc.CommandFileCopyData, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "remotefilemove", data, opts)
 	return err
 }
 
+// command "remotefilemovestream", wshserver.RemoteFileMoveStreamCommand
+func RemoteFileMoveStreamCommand(w *wshutil.WshRpc, data wshrpc.CommandFileCopyData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileCopyProgress] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.CommandRemoteFileCopyProgress](w, "remotefilemovestream", data, opts)
+}
+
+// command "remotefilepreview", wshserver.RemoteFilePreviewCommand
+func RemoteFilePreviewCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteFilePreviewData, opts *wshrpc.RpcOpts) (*wshrpc.CommandRemoteFilePreviewRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.CommandRemoteFilePreviewRtnData](w, "remotefilepreview", data, opts)
+	return resp, err
+}
+
+// command "remotefilesystemstats", wshserver.RemoteFileSystemStatsCommand
+func RemoteFileSystemStatsCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) (*wshrpc.FileSystemStats, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.FileSystemStats](w, "remotefilesystemstats", data, opts)
+	return resp, err
+}
+
 // command "remotefiletouch", wshserver.RemoteFileTouchCommand
-func RemoteFileTouchCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) error {
+func RemoteFileTouchCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteFileTouchData, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "remotefiletouch", data, opts)
 	return err
 }
 
+// command "remotefiletruncate", wshserver.RemoteFileTruncateCommand
+func RemoteFileTruncateCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteFileTruncateData, opts *wshrpc.RpcOpts) (int64, error) {
+	resp, err := sendRpcRequestCallHelper[int64](w, "remotefiletruncate", data, opts)
+	return resp, err
+}
+
+// command "remotefileunlock", wshserver.RemoteFileUnlockCommand
+func RemoteFileUnlockCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) error {
+	_, err := sendRpcRequestCallHelper[any](w, "remotefileunlock", data, opts)
+	return err
+}
+
+// command "remotefileuploadclose", wshserver.RemoteFileUploadCloseCommand
+func RemoteFileUploadCloseCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteFileUploadCloseData, opts *wshrpc.RpcOpts) (*wshrpc.FileInfo, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.FileInfo](w, "remotefileuploadclose", data, opts)
+	return resp, err
+}
+
+// command "remotefileuploadopen", wshserver.RemoteFileUploadOpenCommand
+func RemoteFileUploadOpenCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteFileUploadOpenData, opts *wshrpc.RpcOpts) (string, error) {
+	resp, err := sendRpcRequestCallHelper[string](w, "remotefileuploadopen", data, opts)
+	return resp, err
+}
+
+// command "remotefileuploadwrite", wshserver.RemoteFileUploadWriteCommand
+func RemoteFileUploadWriteCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteFileUploadWriteData, opts *wshrpc.RpcOpts) error {
+	_, err := sendRpcRequestCallHelper[any](w, "remotefileuploadwrite", data, opts)
+	return err
+}
+
 // command "remotegetinfo", wshserver.RemoteGetInfoCommand
 func RemoteGetInfoCommand(w *wshutil.WshRpc, opts *wshrpc.RpcOpts) (wshrpc.RemoteInfo, error) {
 	resp, err := sendRpcRequestCallHelper[wshrpc.RemoteInfo](w, "remotegetinfo", nil, opts)
 	return resp, err
 }
 
+// command "remoteglob", wshserver.RemoteGlobCommand
+func RemoteGlobCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.CommandRemoteListEntriesRtnData](w, "remoteglob", data, opts)
+}
+
+// command "remotegrep", wshserver.RemoteGrepCommand
+func RemoteGrepCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteGrepData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.GrepMatch] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.GrepMatch](w, "remotegrep", data, opts)
+}
+
+// command "remotehardlink", wshserver.RemoteHardlinkCommand
+func RemoteHardlinkCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteLinkData, opts *wshrpc.RpcOpts) (*wshrpc.FileInfo, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.FileInfo](w, "remotehardlink", data, opts)
+	return resp, err
+}
+
 // command "remoteinstallrcfiles", wshserver.RemoteInstallRcFilesCommand
 func RemoteInstallRcFilesCommand(w *wshutil.WshRpc, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "remoteinstallrcfiles", nil, opts)
@@ -408,12 +534,75 @@ func RemoteListEntriesCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteListEn
 	return sendRpcRequestResponseStreamHelper[wshrpc.CommandRemoteListEntriesRtnData](w, "remotelistentries", data, opts)
 }
 
+// command "remotemanifest", wshserver.RemoteManifestCommand
+func RemoteManifestCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteManifestData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.ManifestEntry] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.ManifestEntry](w, "remotemanifest", data, opts)
+}
+
 // command "remotemkdir", wshserver.RemoteMkdirCommand
-func RemoteMkdirCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) error {
+func RemoteMkdirCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteMkdirData, opts *wshrpc.RpcOpts) error {
 	_, err := sendRpcRequestCallHelper[any](w, "remotemkdir", data, opts)
 	return err
 }
 
+// command "remotemkdirtemp", wshserver.RemoteMkdirTempCommand
+func RemoteMkdirTempCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteMkdirTempData, opts *wshrpc.RpcOpts) (*wshrpc.FileInfo, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.FileInfo](w, "remotemkdirtemp", data, opts)
+	return resp, err
+}
+
+// command "remoteopenfile", wshserver.RemoteOpenFileCommand
+func RemoteOpenFileCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) (*wshrpc.CommandRemoteOpenFileRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.CommandRemoteOpenFileRtnData](w, "remoteopenfile", data, opts)
+	return resp, err
+}
+
+// command "remotereadat", wshserver.RemoteReadAtCommand
+func RemoteReadAtCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteReadAtData, opts *wshrpc.RpcOpts) (*wshrpc.FileData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.FileData](w, "remotereadat", data, opts)
+	return resp, err
+}
+
+// command "remotereadfilerange", wshserver.RemoteReadFileRangeCommand
+func RemoteReadFileRangeCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteReadFileRangeData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
+	return sendRpcRequestResponseStreamHelper[iochantypes.Packet](w, "remotereadfilerange", data, opts)
+}
+
+// command "remotereadlines", wshserver.RemoteReadLinesCommand
+func RemoteReadLinesCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteReadLinesData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.FileData] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.FileData](w, "remotereadlines", data, opts)
+}
+
+// command "remotereadlink", wshserver.RemoteReadLinkCommand
+func RemoteReadLinkCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) (string, error) {
+	resp, err := sendRpcRequestCallHelper[string](w, "remotereadlink", data, opts)
+	return resp, err
+}
+
+// command "remotereadtext", wshserver.RemoteReadTextCommand
+func RemoteReadTextCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteReadTextData, opts *wshrpc.RpcOpts) (*wshrpc.CommandRemoteReadTextRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.CommandRemoteReadTextRtnData](w, "remotereadtext", data, opts)
+	return resp, err
+}
+
+// command "remoterealpath", wshserver.RemoteRealPathCommand
+func RemoteRealPathCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) (*wshrpc.CommandRemoteRealPathRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.CommandRemoteRealPathRtnData](w, "remoterealpath", data, opts)
+	return resp, err
+}
+
+// command "remotesed", wshserver.RemoteSedCommand
+func RemoteSedCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteSedData, opts *wshrpc.RpcOpts) (*wshrpc.CommandRemoteSedRtnData, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.CommandRemoteSedRtnData](w, "remotesed", data, opts)
+	return resp, err
+}
+
+// command "remotestatvfs", wshserver.RemoteStatVFSCommand
+func RemoteStatVFSCommand(w *wshutil.WshRpc, data string, opts *wshrpc.RpcOpts) (*wshrpc.StatVFSInfo, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.StatVFSInfo](w, "remotestatvfs", data, opts)
+	return resp, err
+}
+
 // command "remotestreamcpudata", wshserver.RemoteStreamCpuDataCommand
 func RemoteStreamCpuDataCommand(w *wshutil.WshRpc, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.TimeSeriesData] {
 	return sendRpcRequestResponseStreamHelper[wshrpc.TimeSeriesData](w, "remotestreamcpudata", nil, opts)
@@ -424,6 +613,22 @@ func RemoteStreamFileCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteStreamF
 	return sendRpcRequestResponseStreamHelper[wshrpc.FileData](w, "remotestreamfile", data, opts)
 }
 
+// command "remotesymlink", wshserver.RemoteSymlinkCommand
+func RemoteSymlinkCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteLinkData, opts *wshrpc.RpcOpts) (*wshrpc.FileInfo, error) {
+	resp, err := sendRpcRequestCallHelper[*wshrpc.FileInfo](w, "remotesymlink", data, opts)
+	return resp, err
+}
+
+// command "remotesync", wshserver.RemoteSyncCommand
+func RemoteSyncCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteSyncData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteSyncProgress] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.CommandRemoteSyncProgress](w, "remotesync", data, opts)
+}
+
+// command "remotetailfile", wshserver.RemoteTailFileCommand
+func RemoteTailFileCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteTailFileData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[wshrpc.FileData] {
+	return sendRpcRequestResponseStreamHelper[wshrpc.FileData](w, "remotetailfile", data, opts)
+}
+
 // command "remotetarstream", wshserver.RemoteTarStreamCommand
 func RemoteTarStreamCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteStreamTarData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
 	return sendRpcRequestResponseStreamHelper[iochantypes.Packet](w, "remotetarstream", data, opts)
@@ -435,6 +640,11 @@ func RemoteWriteFileCommand(w *wshutil.WshRpc, data wshrpc.FileData, opts *wshrp
 	return err
 }
 
+// command "remotezipstream", wshserver.RemoteZipStreamCommand
+func RemoteZipStreamCommand(w *wshutil.WshRpc, data wshrpc.CommandRemoteStreamZipData, opts *wshrpc.RpcOpts) chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
+	return sendRpcRequestResponseStreamHelper[iochantypes.Packet](w, "remotezipstream", data, opts)
+}
+
 // command "resolveids", wshserver.ResolveIdsCommand
 func ResolveIdsCommand(w *wshutil.WshRpc, data wshrpc.CommandResolveIdsData, opts *wshrpc.RpcOpts) (wshrpc.CommandResolveIdsRtnData, error) {
 	resp, err := sendRpcRequestCallHelper[wshrpc.CommandResolveIdsRtnData](w, "resolveids", data, opts)
@@ -0,0 +1,83 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+)
+
+// dedupeKey identifies a written file for FileCopyOpts.Dedupe.  hardlinks share their metadata, so files only
+// match if they would also end up with the same mode (and times/owner, when those are preserved).
+type dedupeKey struct {
+	sum   [32]byte
+	size  int64
+	mode  fs.FileMode
+	mtime int64
+	uid   int
+	gid   int
+}
+
+func makeDedupeKey(sum []byte, finfo fs.FileInfo, preserveTimes bool, preserveOwner bool) dedupeKey {
+	key := dedupeKey{size: finfo.Size(), mode: finfo.Mode().Perm(), uid: -1, gid: -1}
+	copy(key.sum[:], sum)
+	if preserveTimes {
+		key.mtime = finfo.ModTime().UnixNano()
+	}
+	if preserveOwner {
+		if uid, gid, ok := fileOwnerIds(finfo); ok {
+			key.uid, key.gid = uid, gid
+		}
+	}
+	return key
+}
+
+// dedupeTracker remembers the files written during one copy by content, so later duplicates can be hardlinked
+// to the first copy (the lock is for parallel writes)
+type dedupeTracker struct {
+	lock   sync.Mutex
+	files  map[dedupeKey]string
+	linked int
+}
+
+func newDedupeTracker() *dedupeTracker {
+	return &dedupeTracker{files: make(map[dedupeKey]string)}
+}
+
+// linkDuplicate replaces the just written path with a hardlink to an earlier file with the same key, or records
+// path as the file for key.  the link is made under a temp name and renamed over path, so if linking fails
+// (e.g. the destination tree spans filesystems) the written copy is simply kept.
+func (d *dedupeTracker) linkDuplicate(path string, key dedupeKey) {
+	d.lock.Lock()
+	existing, ok := d.files[key]
+	if !ok {
+		d.files[key] = path
+	}
+	d.lock.Unlock()
+	if !ok || existing == path {
+		return
+	}
+	randHexStr, err := utilfn.RandomHexString(12)
+	if err != nil {
+		return
+	}
+	tmpPath := filepath.Join(filepath.Dir(path), "wsh-tmp-"+randHexStr)
+	if err := os.Link(existing, tmpPath); err != nil {
+		log.Printf("RemoteFileCopyCommand: cannot hardlink duplicate %q, keeping the copy: %v\n", path, err)
+		return
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		log.Printf("RemoteFileCopyCommand: cannot hardlink duplicate %q, keeping the copy: %v\n", path, err)
+		return
+	}
+	d.lock.Lock()
+	d.linked++
+	d.lock.Unlock()
+}
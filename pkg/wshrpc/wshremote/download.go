@@ -0,0 +1,150 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/util/iochan"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+)
+
+// var so tests can swap in the httptest client
+var downloadHttpClient = http.DefaultClient
+
+// RemoteDownloadCommand fetches data.Url straight to data.Path on this host, streaming progress.  the body is written to
+// a temp file that only replaces data.Path once it has been fully received (and matched data.Sha256, if set).
+func (impl *ServerImpl) RemoteDownloadCommand(ctx context.Context, data wshrpc.CommandRemoteDownloadData) chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteDownloadProgress] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteDownloadProgress], 16)
+	go func() {
+		defer close(ch)
+		send := func(progress wshrpc.CommandRemoteDownloadProgress) {
+			select {
+			case ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteDownloadProgress]{Response: progress}:
+			case <-ctx.Done():
+			}
+		}
+		rtn, err := impl.remoteDownloadInternal(ctx, data, send)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteDownloadProgress](err)
+			return
+		}
+		send(*rtn)
+	}()
+	return ch
+}
+
+func (impl *ServerImpl) remoteDownloadInternal(ctx context.Context, data wshrpc.CommandRemoteDownloadData, progressCallback func(wshrpc.CommandRemoteDownloadProgress)) (*wshrpc.CommandRemoteDownloadProgress, error) {
+	parsedUrl, err := url.Parse(data.Url)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse url %q: %w", data.Url, err)
+	}
+	if parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https" {
+		return nil, fmt.Errorf("cannot download %q: unsupported scheme %q (only http and https)", data.Url, parsedUrl.Scheme)
+	}
+	var expectedSum []byte
+	if data.Sha256 != "" {
+		expectedSum, err = hex.DecodeString(data.Sha256)
+		if err != nil || len(expectedSum) != sha256.Size {
+			return nil, fmt.Errorf("invalid sha256 %q", data.Sha256)
+		}
+	}
+	destPath, err := impl.cleanPath(data.Path)
+	if err != nil {
+		return nil, err
+	}
+	createMode := os.FileMode(0644)
+	destInfo, err := os.Stat(destPath)
+	if err == nil {
+		if destInfo.IsDir() {
+			return nil, fmt.Errorf("cannot download to %q: is a directory", data.Path)
+		}
+		if !data.Overwrite {
+			return nil, fmt.Errorf("cannot download to %q: file exists", data.Path)
+		}
+		createMode = destInfo.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot stat file %q: %w", data.Path, err)
+	}
+	if data.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(data.Timeout)*time.Millisecond)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsedUrl.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request for %q: %w", data.Url, err)
+	}
+	resp, err := downloadHttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download %q: %w", data.Url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("cannot download %q: %s", data.Url, resp.Status)
+	}
+	log.Printf("RemoteDownloadCommand: url=%s dest=%s size=%d\n", data.Url, destPath, resp.ContentLength)
+	rtn := &wshrpc.CommandRemoteDownloadProgress{Done: true, ContentType: resp.Header.Get("Content-Type")}
+	if resp.ContentLength > 0 {
+		rtn.TotalBytes = resp.ContentLength
+	}
+	tracker := &copyProgressTracker{
+		progress: wshrpc.CommandRemoteFileCopyProgress{CurrentFile: data.Path, TotalBytes: rtn.TotalBytes},
+		callback: func(progress wshrpc.CommandRemoteFileCopyProgress) {
+			if progressCallback != nil {
+				progressCallback(wshrpc.CommandRemoteDownloadProgress{BytesDone: progress.BytesDone, TotalBytes: progress.TotalBytes})
+			}
+		},
+	}
+	hashFn := sha256.New()
+	err = writeFileAtomic(destPath, createMode, func(w io.Writer) error {
+		// the iochan checksum packet catches chunks lost between the reader and the writer, the sha256 is over the whole body
+		var streamErr error
+		var written int64
+		done := make(chan struct{})
+		ioch := iochan.ReaderChanWithOpts(ctx, resp.Body, iochan.ReaderChanOpts{ChunkSize: wshrpc.FileChunkSize}, func() {})
+		iochan.WriterChanWithOpts(ctx, io.MultiWriter(w, hashFn, tracker), ioch, iochan.WriterChanOpts{}, func(n int64) {
+			written = n
+			close(done)
+		}, func(err error) { streamErr = err })
+		<-done
+		if streamErr != nil {
+			return streamErr
+		}
+		// ReaderChan ends the stream without an error when the context is canceled
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		if resp.ContentLength >= 0 && written != resp.ContentLength {
+			return fmt.Errorf("body was %d bytes, expected %d", written, resp.ContentLength)
+		}
+		if expectedSum != nil {
+			if sum := hashFn.Sum(nil); !strings.EqualFold(hex.EncodeToString(sum), data.Sha256) {
+				return fmt.Errorf("%w: expected sha256 %s, got %x", iochan.ErrChecksumMismatch, data.Sha256, sum)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot download %q: %w", data.Url, err)
+	}
+	rtn.BytesDone = tracker.progress.BytesDone
+	rtn.Sha256 = hex.EncodeToString(hashFn.Sum(nil))
+	rtn.Info, err = impl.fileInfoInternal(destPath, false)
+	if err != nil {
+		return nil, err
+	}
+	return rtn, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// runDownload returns the progress responses (the last has Done set) and the stream error
+func runDownload(impl *ServerImpl, data wshrpc.CommandRemoteDownloadData) ([]wshrpc.CommandRemoteDownloadProgress, error) {
+	var progress []wshrpc.CommandRemoteDownloadProgress
+	for resp := range impl.RemoteDownloadCommand(context.Background(), data) {
+		if resp.Error != nil {
+			return progress, resp.Error
+		}
+		progress = append(progress, resp.Response)
+	}
+	return progress, nil
+}
+
+func newDownloadServer(t *testing.T, body []byte) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/file.bin", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-test")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write(body)
+	})
+	mux.HandleFunc("/truncated", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write(body[:len(body)/2])
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestDownload(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789abcdef"), (copyProgressIntervalBytes*3+1000)/16)
+	sum := sha256.Sum256(body)
+	ts := newDownloadServer(t, body)
+	dest := filepath.Join(t.TempDir(), "file.bin")
+	impl := &ServerImpl{}
+	progress, err := runDownload(impl, wshrpc.CommandRemoteDownloadData{Url: ts.URL + "/file.bin", Path: dest, Sha256: hex.EncodeToString(sum[:])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) < 3 {
+		t.Fatalf("expected periodic progress before the result, got %d responses", len(progress))
+	}
+	for _, p := range progress[:len(progress)-1] {
+		if p.Done || p.TotalBytes != int64(len(body)) || p.BytesDone > int64(len(body)) {
+			t.Errorf("bad progress %+v", p)
+		}
+	}
+	rtn := progress[len(progress)-1]
+	if !rtn.Done || rtn.BytesDone != int64(len(body)) || rtn.ContentType != "application/x-test" || rtn.Sha256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("bad result %+v", rtn)
+	}
+	if rtn.Info == nil || rtn.Info.Size != int64(len(body)) {
+		t.Errorf("bad result info %+v", rtn.Info)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Error("downloaded content does not match")
+	}
+
+	// the destination now exists
+	if _, err := runDownload(impl, wshrpc.CommandRemoteDownloadData{Url: ts.URL + "/file.bin", Path: dest}); err == nil {
+		t.Error("expected an error downloading over an existing file")
+	}
+	if _, err := runDownload(impl, wshrpc.CommandRemoteDownloadData{Url: ts.URL + "/file.bin", Path: dest, Overwrite: true}); err != nil {
+		t.Errorf("overwrite: %v", err)
+	}
+}
+
+func TestDownload_HTTPS(t *testing.T) {
+	body := []byte("secure content\n")
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer ts.Close()
+	oldClient := downloadHttpClient
+	downloadHttpClient = ts.Client()
+	defer func() { downloadHttpClient = oldClient }()
+	dest := filepath.Join(t.TempDir(), "secure.txt")
+	progress, err := runDownload(&ServerImpl{}, wshrpc.CommandRemoteDownloadData{Url: ts.URL, Path: dest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rtn := progress[len(progress)-1]; !rtn.Done || rtn.BytesDone != int64(len(body)) {
+		t.Errorf("bad result %+v", rtn)
+	}
+}
+
+func TestDownload_Errors(t *testing.T) {
+	body := []byte("some file content\n")
+	ts := newDownloadServer(t, body)
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "file.bin")
+	badSum := sha256.Sum256([]byte("other content"))
+	tests := []struct {
+		name string
+		data wshrpc.CommandRemoteDownloadData
+	}{
+		{"file scheme", wshrpc.CommandRemoteDownloadData{Url: "file:///etc/passwd", Path: dest}},
+		{"ftp scheme", wshrpc.CommandRemoteDownloadData{Url: "ftp://example.com/file", Path: dest}},
+		{"not found", wshrpc.CommandRemoteDownloadData{Url: ts.URL + "/missing", Path: dest}},
+		{"sha256 mismatch", wshrpc.CommandRemoteDownloadData{Url: ts.URL + "/file.bin", Path: dest, Sha256: hex.EncodeToString(badSum[:])}},
+		{"invalid sha256", wshrpc.CommandRemoteDownloadData{Url: ts.URL + "/file.bin", Path: dest, Sha256: "abc"}},
+		{"truncated body", wshrpc.CommandRemoteDownloadData{Url: ts.URL + "/truncated", Path: dest}},
+		{"timeout", wshrpc.CommandRemoteDownloadData{Url: ts.URL + "/slow", Path: dest, Timeout: 50}},
+		{"dest is a dir", wshrpc.CommandRemoteDownloadData{Url: ts.URL + "/file.bin", Path: dir, Overwrite: true}},
+	}
+	impl := &ServerImpl{}
+	for _, tc := range tests {
+		if _, err := runDownload(impl, tc.data); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+	// failed downloads leave nothing behind, not even the temp file
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected an empty destination dir, got %d entries", len(entries))
+	}
+}
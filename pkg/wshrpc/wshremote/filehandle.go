@@ -0,0 +1,132 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// each connection runs its own wsh server, so this caps the open handles per connection
+const maxOpenFileHandles = 64
+
+// handles that aren't read from or closed for this long are closed (var so tests can shorten it)
+var fileHandleIdleTimeout = 2 * time.Minute
+
+// openFileHandle is a file kept open between RemoteReadAtCommand calls, so players that seek a lot don't reopen it for every range.
+// lock handles (from RemoteFileLockCommand) share the table, they hold the lock until unlocked and never go idle.
+type openFileHandle struct {
+	path      string
+	file      *os.File
+	idleTimer *time.Timer // nil for lock handles
+	isLock    bool
+	owner     string // rpc source that took the lock, its locks are released when it disconnects
+}
+
+var fileHandlesLock sync.Mutex
+var fileHandles = make(map[string]*openFileHandle)
+
+// getFileHandle only finds handles of the given kind, so a read handle can't be unlocked (or a lock handle read from)
+func getFileHandle(handleId string, isLock bool) (*openFileHandle, error) {
+	fileHandlesLock.Lock()
+	defer fileHandlesLock.Unlock()
+	handle := fileHandles[handleId]
+	if handle == nil || handle.isLock != isLock {
+		return nil, fmt.Errorf("file handle %q not found", handleId)
+	}
+	return handle, nil
+}
+
+func removeFileHandle(handleId string, isLock bool) *openFileHandle {
+	fileHandlesLock.Lock()
+	defer fileHandlesLock.Unlock()
+	handle := fileHandles[handleId]
+	if handle == nil || handle.isLock != isLock {
+		return nil
+	}
+	delete(fileHandles, handleId)
+	return handle
+}
+
+// RemoteOpenFileCommand opens path for RemoteReadAtCommand, returning the handle id and the file info
+func (impl *ServerImpl) RemoteOpenFileCommand(ctx context.Context, path string) (*wshrpc.CommandRemoteOpenFileRtnData, error) {
+	cleanedPath, err := impl.cleanPath(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(cleanedPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file %q: %w", path, err)
+	}
+	finfo, err := file.Stat()
+	if err != nil {
+		utilfn.GracefulClose(file, "RemoteOpenFileCommand", cleanedPath)
+		return nil, fmt.Errorf("cannot stat file %q: %w", path, err)
+	}
+	if finfo.IsDir() {
+		utilfn.GracefulClose(file, "RemoteOpenFileCommand", cleanedPath)
+		return nil, fmt.Errorf("cannot open %q: is a directory", path)
+	}
+	handleId := uuid.New().String()
+	handle := &openFileHandle{path: cleanedPath, file: file}
+	fileHandlesLock.Lock()
+	if len(fileHandles) >= maxOpenFileHandles {
+		fileHandlesLock.Unlock()
+		utilfn.GracefulClose(file, "RemoteOpenFileCommand", cleanedPath)
+		return nil, fmt.Errorf("cannot open %q: too many open file handles (max %d)", path, maxOpenFileHandles)
+	}
+	fileHandles[handleId] = handle
+	handle.idleTimer = time.AfterFunc(fileHandleIdleTimeout, func() {
+		if removeFileHandle(handleId, false) != nil {
+			utilfn.GracefulClose(file, "RemoteOpenFileCommand idle", cleanedPath)
+		}
+	})
+	fileHandlesLock.Unlock()
+	return &wshrpc.CommandRemoteOpenFileRtnData{HandleId: handleId, Info: statToFileInfo(cleanedPath, finfo, false)}, nil
+}
+
+// RemoteReadAtCommand reads data.Size bytes at data.Offset from an open handle, a short read means EOF
+func (impl *ServerImpl) RemoteReadAtCommand(ctx context.Context, data wshrpc.CommandRemoteReadAtData) (*wshrpc.FileData, error) {
+	if data.Offset < 0 || data.Size < 0 || data.Size > wshrpc.MaxReadAtSize {
+		return nil, fmt.Errorf("cannot read file handle %q: invalid range offset %d size %d (max %d)", data.HandleId, data.Offset, data.Size, wshrpc.MaxReadAtSize)
+	}
+	handle, err := getFileHandle(data.HandleId, false)
+	if err != nil {
+		return nil, err
+	}
+	handle.idleTimer.Reset(fileHandleIdleTimeout)
+	// ReadAt doesn't move a shared offset, so concurrent reads on one handle are fine
+	buf := make([]byte, data.Size)
+	n, err := handle.file.ReadAt(buf, data.Offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("cannot read file %q: %w", handle.path, err)
+	}
+	return &wshrpc.FileData{
+		Data64: base64.StdEncoding.EncodeToString(buf[:n]),
+		At:     &wshrpc.FileDataAt{Offset: data.Offset, Size: n},
+	}, nil
+}
+
+// RemoteCloseFileCommand releases a handle from RemoteOpenFileCommand
+func (impl *ServerImpl) RemoteCloseFileCommand(ctx context.Context, handleId string) error {
+	handle := removeFileHandle(handleId, false)
+	if handle == nil {
+		return fmt.Errorf("file handle %q not found", handleId)
+	}
+	handle.idleTimer.Stop()
+	if err := handle.file.Close(); err != nil {
+		return fmt.Errorf("cannot close file %q: %w", handle.path, err)
+	}
+	return nil
+}
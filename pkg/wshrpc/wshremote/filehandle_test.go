@@ -0,0 +1,150 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func readAtString(t *testing.T, impl *ServerImpl, handleId string, offset int64, size int) string {
+	t.Helper()
+	rtn, err := impl.RemoteReadAtCommand(context.Background(), wshrpc.CommandRemoteReadAtData{HandleId: handleId, Offset: offset, Size: size})
+	if err != nil {
+		t.Fatalf("RemoteReadAtCommand offset %d size %d: %v", offset, size, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(rtn.Data64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rtn.At == nil || rtn.At.Offset != offset || rtn.At.Size != len(data) {
+		t.Errorf("got At %+v for offset %d with %d bytes", rtn.At, offset, len(data))
+	}
+	return string(data)
+}
+
+func TestFileHandle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "media.bin")
+	writeTestFile(t, path, "0123456789abcdefghij")
+	impl := &ServerImpl{}
+	rtn, err := impl.RemoteOpenFileCommand(context.Background(), path)
+	if err != nil {
+		t.Fatalf("RemoteOpenFileCommand: %v", err)
+	}
+	if rtn.Info == nil || rtn.Info.Size != 20 {
+		t.Errorf("got info %+v, want size 20", rtn.Info)
+	}
+	// seek around the file out of order, the way a player scrubs
+	reads := []struct {
+		offset int64
+		size   int
+		want   string
+	}{
+		{10, 5, "abcde"},
+		{0, 4, "0123"},
+		{18, 10, "ij"},
+		{5, 0, ""},
+		{25, 4, ""},
+	}
+	for _, r := range reads {
+		if got := readAtString(t, impl, rtn.HandleId, r.offset, r.size); got != r.want {
+			t.Errorf("offset %d size %d: got %q, want %q", r.offset, r.size, got, r.want)
+		}
+	}
+	for _, bad := range []wshrpc.CommandRemoteReadAtData{
+		{HandleId: rtn.HandleId, Offset: -1, Size: 4},
+		{HandleId: rtn.HandleId, Offset: 0, Size: wshrpc.MaxReadAtSize + 1},
+	} {
+		if _, err := impl.RemoteReadAtCommand(context.Background(), bad); err == nil {
+			t.Errorf("expected an error for offset %d size %d", bad.Offset, bad.Size)
+		}
+	}
+	if err := impl.RemoteCloseFileCommand(context.Background(), rtn.HandleId); err != nil {
+		t.Fatalf("RemoteCloseFileCommand: %v", err)
+	}
+	if _, err := impl.RemoteReadAtCommand(context.Background(), wshrpc.CommandRemoteReadAtData{HandleId: rtn.HandleId, Size: 4}); err == nil {
+		t.Error("expected an error reading a closed handle")
+	}
+	if err := impl.RemoteCloseFileCommand(context.Background(), rtn.HandleId); err == nil {
+		t.Error("expected an error closing a handle twice")
+	}
+}
+
+func TestFileHandle_Errors(t *testing.T) {
+	dir := t.TempDir()
+	impl := &ServerImpl{}
+	if _, err := impl.RemoteOpenFileCommand(context.Background(), dir); err == nil {
+		t.Error("expected an error opening a directory")
+	}
+	if _, err := impl.RemoteOpenFileCommand(context.Background(), filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error opening a missing file")
+	}
+}
+
+func TestFileHandle_MaxOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "media.bin")
+	writeTestFile(t, path, "data")
+	impl := &ServerImpl{}
+	var handleIds []string
+	defer func() {
+		for _, handleId := range handleIds {
+			impl.RemoteCloseFileCommand(context.Background(), handleId)
+		}
+	}()
+	for i := 0; i < maxOpenFileHandles; i++ {
+		rtn, err := impl.RemoteOpenFileCommand(context.Background(), path)
+		if err != nil {
+			t.Fatalf("open %d: %v", i, err)
+		}
+		handleIds = append(handleIds, rtn.HandleId)
+	}
+	if _, err := impl.RemoteOpenFileCommand(context.Background(), path); err == nil {
+		t.Fatal("expected an error opening more than the max handles")
+	}
+	// closing one frees a slot
+	if err := impl.RemoteCloseFileCommand(context.Background(), handleIds[0]); err != nil {
+		t.Fatal(err)
+	}
+	rtn, err := impl.RemoteOpenFileCommand(context.Background(), path)
+	if err != nil {
+		t.Fatalf("open after close: %v", err)
+	}
+	handleIds[0] = rtn.HandleId
+}
+
+func TestFileHandle_IdleExpiry(t *testing.T) {
+	oldTimeout := fileHandleIdleTimeout
+	fileHandleIdleTimeout = 50 * time.Millisecond
+	defer func() { fileHandleIdleTimeout = oldTimeout }()
+	path := filepath.Join(t.TempDir(), "media.bin")
+	writeTestFile(t, path, "data")
+	impl := &ServerImpl{}
+	rtn, err := impl.RemoteOpenFileCommand(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// reads keep the handle alive past the timeout
+	for i := 0; i < 4; i++ {
+		time.Sleep(20 * time.Millisecond)
+		readAtString(t, impl, rtn.HandleId, 0, 4)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := getFileHandle(rtn.HandleId, false); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("idle handle was not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := impl.RemoteReadAtCommand(context.Background(), wshrpc.CommandRemoteReadAtData{HandleId: rtn.HandleId, Size: 4}); err == nil {
+		t.Error("expected an error reading an expired handle")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+)
+
+// how often a blocking lock retries.  a blocking flock can't be canceled, it could take the lock after the request
+// timed out with nobody left to release it, so waiting is done with try-locks instead (var so tests can shorten it)
+var fileLockPollInterval = 100 * time.Millisecond
+
+// RemoteFileLockCommand takes an advisory lock on data.Path, held by the returned handle until RemoteFileUnlockCommand.
+// the lock is only seen by other lockers, reads and writes aren't blocked.  locks taken by a client that disconnects
+// are released (and all of them are when the connection to the server drops, the server exits with it).
+func (impl *ServerImpl) RemoteFileLockCommand(ctx context.Context, data wshrpc.CommandRemoteFileLockData) (*wshrpc.CommandRemoteFileLockRtnData, error) {
+	return impl.remoteFileLockInternal(ctx, data, wshutil.GetRpcSourceFromContext(ctx))
+}
+
+func (impl *ServerImpl) remoteFileLockInternal(ctx context.Context, data wshrpc.CommandRemoteFileLockData, owner string) (*wshrpc.CommandRemoteFileLockRtnData, error) {
+	cleanedPath, err := impl.cleanPath(data.Path)
+	if err != nil {
+		return nil, err
+	}
+	// every lock gets its own open file, flock and LockFileEx locks belong to the open file so two handles contend
+	file, err := os.Open(cleanedPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file %q: %w", data.Path, err)
+	}
+	finfo, err := file.Stat()
+	if err != nil {
+		utilfn.GracefulClose(file, "RemoteFileLockCommand", cleanedPath)
+		return nil, fmt.Errorf("cannot stat file %q: %w", data.Path, err)
+	}
+	if finfo.IsDir() {
+		utilfn.GracefulClose(file, "RemoteFileLockCommand", cleanedPath)
+		return nil, fmt.Errorf("cannot lock %q: is a directory", data.Path)
+	}
+	for {
+		locked, err := lockFile(file, data.Shared)
+		if err != nil {
+			utilfn.GracefulClose(file, "RemoteFileLockCommand", cleanedPath)
+			return nil, fmt.Errorf("cannot lock file %q: %w", data.Path, err)
+		}
+		if locked {
+			break
+		}
+		if data.TryLock {
+			utilfn.GracefulClose(file, "RemoteFileLockCommand", cleanedPath)
+			return &wshrpc.CommandRemoteFileLockRtnData{Locked: false}, nil
+		}
+		select {
+		case <-ctx.Done():
+			utilfn.GracefulClose(file, "RemoteFileLockCommand", cleanedPath)
+			return nil, fmt.Errorf("cannot lock file %q: %w", data.Path, context.Cause(ctx))
+		case <-time.After(fileLockPollInterval):
+		}
+	}
+	handleId := uuid.New().String()
+	fileHandlesLock.Lock()
+	if len(fileHandles) >= maxOpenFileHandles {
+		fileHandlesLock.Unlock()
+		// closing the file releases the lock
+		utilfn.GracefulClose(file, "RemoteFileLockCommand", cleanedPath)
+		return nil, fmt.Errorf("cannot lock %q: too many open file handles (max %d)", data.Path, maxOpenFileHandles)
+	}
+	fileHandles[handleId] = &openFileHandle{path: cleanedPath, file: file, isLock: true, owner: owner}
+	fileHandlesLock.Unlock()
+	return &wshrpc.CommandRemoteFileLockRtnData{Locked: true, HandleId: handleId}, nil
+}
+
+// RemoteFileUnlockCommand releases a lock from RemoteFileLockCommand
+func (impl *ServerImpl) RemoteFileUnlockCommand(ctx context.Context, handleId string) error {
+	handle := removeFileHandle(handleId, true)
+	if handle == nil {
+		return fmt.Errorf("file lock %q not found", handleId)
+	}
+	return closeLockHandle(handle)
+}
+
+func closeLockHandle(handle *openFileHandle) error {
+	unlockErr := unlockFile(handle.file)
+	if err := handle.file.Close(); err != nil {
+		return fmt.Errorf("cannot close file %q: %w", handle.path, err)
+	}
+	if unlockErr != nil {
+		return fmt.Errorf("cannot unlock file %q: %w", handle.path, unlockErr)
+	}
+	return nil
+}
+
+// ReleaseFileLocks releases the locks held by owner, called when a client's route goes away
+func ReleaseFileLocks(owner string) {
+	if owner == "" {
+		return
+	}
+	var released []*openFileHandle
+	fileHandlesLock.Lock()
+	for handleId, handle := range fileHandles {
+		if handle.isLock && handle.owner == owner {
+			released = append(released, handle)
+			delete(fileHandles, handleId)
+		}
+	}
+	fileHandlesLock.Unlock()
+	for _, handle := range released {
+		if err := closeLockHandle(handle); err != nil {
+			log.Printf("ReleaseFileLocks: %v\n", err)
+		}
+	}
+}
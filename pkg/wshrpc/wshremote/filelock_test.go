@@ -0,0 +1,137 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func lockTestFile(t *testing.T, path string, shared bool, tryLock bool) *wshrpc.CommandRemoteFileLockRtnData {
+	t.Helper()
+	rtn, err := (&ServerImpl{}).remoteFileLockInternal(context.Background(), wshrpc.CommandRemoteFileLockData{Path: path, Shared: shared, TryLock: tryLock}, "owner")
+	if err != nil {
+		t.Fatalf("lock %q: %v", path, err)
+	}
+	if rtn.Locked != (rtn.HandleId != "") {
+		t.Errorf("got %+v, a handle is returned only when locked", rtn)
+	}
+	return rtn
+}
+
+func unlockTestFile(t *testing.T, handleId string) {
+	t.Helper()
+	if err := (&ServerImpl{}).RemoteFileUnlockCommand(context.Background(), handleId); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+}
+
+func TestFileLock_Exclusive(t *testing.T) {
+	oldInterval := fileLockPollInterval
+	fileLockPollInterval = 5 * time.Millisecond
+	t.Cleanup(func() { fileLockPollInterval = oldInterval })
+	path := filepath.Join(t.TempDir(), "edit.txt")
+	writeTestFile(t, path, "data\n")
+
+	held := lockTestFile(t, path, false, true)
+	if !held.Locked {
+		t.Fatal("expected the first exclusive lock to succeed")
+	}
+	if rtn := lockTestFile(t, path, false, true); rtn.Locked {
+		t.Error("a second exclusive try-lock succeeded")
+	}
+	if rtn := lockTestFile(t, path, true, true); rtn.Locked {
+		t.Error("a shared try-lock succeeded while an exclusive lock is held")
+	}
+	// a blocking lock waits until its request is canceled
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := (&ServerImpl{}).remoteFileLockInternal(ctx, wshrpc.CommandRemoteFileLockData{Path: path}, "owner"); err == nil {
+		t.Error("a blocking lock succeeded while an exclusive lock is held")
+	}
+
+	// a blocking lock gets the lock once it is released
+	waitCh := make(chan *wshrpc.CommandRemoteFileLockRtnData)
+	go func() {
+		rtn, err := (&ServerImpl{}).remoteFileLockInternal(context.Background(), wshrpc.CommandRemoteFileLockData{Path: path}, "owner")
+		if err != nil {
+			t.Errorf("blocking lock: %v", err)
+		}
+		waitCh <- rtn
+	}()
+	time.Sleep(20 * time.Millisecond)
+	unlockTestFile(t, held.HandleId)
+	waited := <-waitCh
+	if waited == nil || !waited.Locked {
+		t.Fatalf("blocking lock: got %+v", waited)
+	}
+	if err := (&ServerImpl{}).RemoteFileUnlockCommand(context.Background(), held.HandleId); err == nil {
+		t.Error("expected an error unlocking a released lock")
+	}
+	unlockTestFile(t, waited.HandleId)
+	if rtn := lockTestFile(t, path, false, true); !rtn.Locked {
+		t.Error("exclusive try-lock failed after unlocking")
+	} else {
+		unlockTestFile(t, rtn.HandleId)
+	}
+}
+
+func TestFileLock_Shared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "read.txt")
+	writeTestFile(t, path, "data\n")
+	first := lockTestFile(t, path, true, true)
+	second := lockTestFile(t, path, true, true)
+	if !first.Locked || !second.Locked {
+		t.Fatalf("expected shared locks to coexist, got %+v and %+v", first, second)
+	}
+	if rtn := lockTestFile(t, path, false, true); rtn.Locked {
+		t.Error("an exclusive try-lock succeeded while shared locks are held")
+	}
+	unlockTestFile(t, first.HandleId)
+	if rtn := lockTestFile(t, path, false, true); rtn.Locked {
+		t.Error("an exclusive try-lock succeeded while a shared lock is held")
+	}
+	unlockTestFile(t, second.HandleId)
+	if rtn := lockTestFile(t, path, false, true); !rtn.Locked {
+		t.Error("exclusive try-lock failed after the shared locks were released")
+	} else {
+		unlockTestFile(t, rtn.HandleId)
+	}
+}
+
+func TestFileLock_ReleaseOwner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "owned.txt")
+	writeTestFile(t, path, "data\n")
+	impl := &ServerImpl{}
+	gone, err := impl.remoteFileLockInternal(context.Background(), wshrpc.CommandRemoteFileLockData{Path: path, Shared: true}, "gone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := impl.remoteFileLockInternal(context.Background(), wshrpc.CommandRemoteFileLockData{Path: path, Shared: true}, "kept")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// lock handles aren't read handles
+	if _, err := impl.RemoteReadAtCommand(context.Background(), wshrpc.CommandRemoteReadAtData{HandleId: kept.HandleId, Size: 4}); err == nil {
+		t.Error("expected an error reading from a lock handle")
+	}
+
+	ReleaseFileLocks("gone")
+	if err := impl.RemoteFileUnlockCommand(context.Background(), gone.HandleId); err == nil {
+		t.Error("the disconnected owner's lock is still in the handle table")
+	}
+	if rtn := lockTestFile(t, path, false, true); rtn.Locked {
+		t.Error("an exclusive try-lock succeeded while another owner holds a shared lock")
+	}
+	ReleaseFileLocks("kept")
+	if rtn := lockTestFile(t, path, false, true); !rtn.Locked {
+		t.Error("exclusive try-lock failed after every owner disconnected")
+	} else {
+		unlockTestFile(t, rtn.HandleId)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package wshremote
+
+import (
+	"archive/tar"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// user/group lookups can hit /etc/passwd (or nss) for every entry, cache them for the life of the process
+var userNameCache sync.Map
+var groupNameCache sync.Map
+
+func lookupCached(cache *sync.Map, id uint32, lookup func(string) (string, error)) string {
+	if name, ok := cache.Load(id); ok {
+		return name.(string)
+	}
+	name, err := lookup(strconv.FormatUint(uint64(id), 10))
+	if err != nil {
+		name = ""
+	}
+	cache.Store(id, name)
+	return name
+}
+
+func lookupUserName(uid string) (string, error) {
+	u, err := user.LookupId(uid)
+	if err != nil {
+		return "", err
+	}
+	return u.Username, nil
+}
+
+func lookupGroupName(gid string) (string, error) {
+	g, err := user.LookupGroupId(gid)
+	if err != nil {
+		return "", err
+	}
+	return g.Name, nil
+}
+
+func fillFileOwner(rtn *wshrpc.FileInfo, finfo fs.FileInfo) {
+	stat, ok := finfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	rtn.Uid = int(stat.Uid)
+	rtn.Gid = int(stat.Gid)
+	rtn.Owner = lookupCached(&userNameCache, stat.Uid, lookupUserName)
+	rtn.Group = lookupCached(&groupNameCache, stat.Gid, lookupGroupName)
+}
+
+// fileOwnerIds returns the uid/gid of a local file or of a tar entry (whose FileInfo carries the header)
+func fileOwnerIds(finfo fs.FileInfo) (uid int, gid int, ok bool) {
+	switch sys := finfo.Sys().(type) {
+	case *syscall.Stat_t:
+		return int(sys.Uid), int(sys.Gid), true
+	case *tar.Header:
+		return sys.Uid, sys.Gid, true
+	}
+	return 0, 0, false
+}
+
+// chownLikeSource gives path (not following symlinks) the owner and group of finfo, the error wraps
+// fs.ErrPermission when we lack the privilege
+func chownLikeSource(path string, finfo fs.FileInfo) error {
+	uid, gid, ok := fileOwnerIds(finfo)
+	if !ok {
+		return nil
+	}
+	if err := os.Lchown(path, uid, gid); err != nil {
+		return fmt.Errorf("cannot set owner on %q: %w", path, err)
+	}
+	return nil
+}
+
+// resolveOwnerId resolves a user or group name (or a numeric id) for chown, "" resolves to -1 which leaves the id unchanged
+func resolveOwnerId(name string, kind string, lookup func(string) (string, error)) (int, error) {
+	if name == "" {
+		return -1, nil
+	}
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+	idStr, err := lookup(name)
+	if err != nil {
+		return 0, fmt.Errorf("cannot resolve %s %q: %w", kind, name, err)
+	}
+	return strconv.Atoi(idStr)
+}
+
+func resolveChownIds(owner string, group string) (uid int, gid int, err error) {
+	uid, err = resolveOwnerId(owner, "user", func(name string) (string, error) {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		return u.Uid, nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	gid, err = resolveOwnerId(group, "group", func(name string) (string, error) {
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			return "", err
+		}
+		return g.Gid, nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return uid, gid, nil
+}
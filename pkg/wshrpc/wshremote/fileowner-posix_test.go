@@ -0,0 +1,312 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package wshremote
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"golang.org/x/sys/unix"
+)
+
+func TestFileInfo_Owner(t *testing.T) {
+	impl := &ServerImpl{}
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := impl.RemoteFileInfoCommand(context.Background(), path)
+	if err != nil {
+		t.Fatalf("RemoteFileInfoCommand: %v", err)
+	}
+	if info.Uid != os.Getuid() {
+		t.Errorf("expected uid %d, got %d", os.Getuid(), info.Uid)
+	}
+	if info.Gid != os.Getegid() {
+		t.Errorf("expected gid %d, got %d", os.Getegid(), info.Gid)
+	}
+	if u, err := user.Current(); err == nil && info.Owner != u.Username {
+		t.Errorf("expected owner %q, got %q", u.Username, info.Owner)
+	}
+}
+
+func TestFileInfo_Xattrs(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("xattrs are only reported on linux and macos")
+	}
+	impl := &ServerImpl{}
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := impl.RemoteFileInfoCommand(context.Background(), path)
+	if err != nil {
+		t.Fatalf("RemoteFileInfoCommand: %v", err)
+	}
+	if info.Xattrs != nil {
+		t.Errorf("expected no xattrs, got %v", info.Xattrs)
+	}
+	if err := unix.Setxattr(path, "user.wave.test", []byte("hello"), 0); err != nil {
+		if errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EPERM) {
+			t.Skipf("filesystem does not support user xattrs: %v", err)
+		}
+		t.Fatalf("setxattr: %v", err)
+	}
+	info, err = impl.RemoteFileInfoCommand(context.Background(), path)
+	if err != nil {
+		t.Fatalf("RemoteFileInfoCommand: %v", err)
+	}
+	if info.Xattrs["user.wave.test"] != "hello" {
+		t.Errorf("expected xattr user.wave.test=hello, got %v", info.Xattrs)
+	}
+
+	// listings use the fast path and skip xattrs
+	for resp := range impl.RemoteListEntriesCommand(context.Background(), wshrpc.CommandRemoteListEntriesData{Path: filepath.Dir(path)}) {
+		for _, finfo := range resp.Response.FileInfo {
+			if finfo.Xattrs != nil {
+				t.Errorf("expected no xattrs in listing, got %v", finfo.Xattrs)
+			}
+		}
+	}
+}
+
+func fileUid(t *testing.T, path string) int {
+	t.Helper()
+	finfo, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(finfo.Sys().(*syscall.Stat_t).Uid)
+}
+
+func TestFileChown(t *testing.T) {
+	impl := &ServerImpl{}
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "sub", "file.txt"), "x")
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+	writeTestFile(t, outside, "x")
+	link := filepath.Join(root, "sub", "link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := impl.RemoteFileChownCommand(context.Background(), wshrpc.CommandRemoteFileChownData{Path: root}); err == nil {
+		t.Errorf("expected error without an owner or group")
+	}
+	if err := impl.RemoteFileChownCommand(context.Background(), wshrpc.CommandRemoteFileChownData{Path: root, Owner: "no-such-wave-user"}); err == nil {
+		t.Errorf("expected error for an unknown user")
+	}
+	// the current user by name is always allowed
+	if u, err := user.Current(); err == nil {
+		if err := impl.RemoteFileChownCommand(context.Background(), wshrpc.CommandRemoteFileChownData{Path: root, Owner: u.Username, Recursive: true}); err != nil {
+			t.Fatalf("chown to current user: %v", err)
+		}
+	}
+
+	if os.Geteuid() != 0 {
+		// not root, giving files away fails for every path but doesn't stop the walk
+		err := impl.RemoteFileChownCommand(context.Background(), wshrpc.CommandRemoteFileChownData{Path: root, Owner: "0", Recursive: true})
+		if err == nil || !strings.Contains(err.Error(), "cannot chown 4 of 4 paths") {
+			t.Fatalf("expected all 4 paths to fail, got %v", err)
+		}
+		err = impl.RemoteFileChownCommand(context.Background(), wshrpc.CommandRemoteFileChownData{Path: root, Owner: "0", Recursive: true, Strict: true})
+		if !errors.Is(err, os.ErrPermission) {
+			t.Fatalf("expected strict chown to stop with a permission error, got %v", err)
+		}
+		return
+	}
+
+	const nobody = 65534
+	err := impl.RemoteFileChownCommand(context.Background(), wshrpc.CommandRemoteFileChownData{Path: filepath.Join(root, "sub", "file.txt"), Owner: strconv.Itoa(nobody)})
+	if err != nil {
+		t.Fatalf("chown file: %v", err)
+	}
+	if uid := fileUid(t, filepath.Join(root, "sub", "file.txt")); uid != nobody {
+		t.Errorf("expected uid %d, got %d", nobody, uid)
+	}
+	if uid := fileUid(t, root); uid != os.Geteuid() {
+		t.Errorf("non-recursive chown changed the parent, uid %d", uid)
+	}
+
+	err = impl.RemoteFileChownCommand(context.Background(), wshrpc.CommandRemoteFileChownData{Path: root, Owner: strconv.Itoa(nobody), Recursive: true})
+	if err != nil {
+		t.Fatalf("recursive chown: %v", err)
+	}
+	for _, path := range []string{root, filepath.Join(root, "sub"), filepath.Join(root, "sub", "file.txt"), link} {
+		if uid := fileUid(t, path); uid != nobody {
+			t.Errorf("expected %q to have uid %d, got %d", path, nobody, uid)
+		}
+	}
+	if uid := fileUid(t, outside); uid != os.Geteuid() {
+		t.Errorf("symlink was followed, target has uid %d", uid)
+	}
+}
+
+func TestFileCopy_PreserveFlags(t *testing.T) {
+	oldUmask := syscall.Umask(0077)
+	defer syscall.Umask(oldUmask)
+
+	srcDir := filepath.Join(t.TempDir(), "src")
+	writeTestFile(t, filepath.Join(srcDir, "run.sh"), "#!/bin/sh\n")
+	writeTestFile(t, filepath.Join(srcDir, "ro", "data.txt"), "data")
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for path, mode := range map[string]os.FileMode{"run.sh": 0750, "ro/data.txt": 0604, "ro": 0555} {
+		path = filepath.Join(srcDir, path)
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { os.Chmod(filepath.Join(srcDir, "ro"), 0755) })
+
+	copyTree := func(t *testing.T, opts *wshrpc.FileCopyOpts) string {
+		t.Helper()
+		destDir := filepath.Join(t.TempDir(), "dest")
+		impl := &ServerImpl{}
+		_, err := impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+			SrcUri:  "wsh://local/" + srcDir,
+			DestUri: "wsh://local/" + destDir,
+			Opts:    opts,
+		})
+		if err != nil {
+			t.Fatalf("RemoteFileCopyCommand: %v", err)
+		}
+		t.Cleanup(func() { os.Chmod(filepath.Join(destDir, "ro"), 0755) })
+		return destDir
+	}
+	checkModes := func(t *testing.T, destDir string, want map[string]os.FileMode) {
+		t.Helper()
+		for path, mode := range want {
+			finfo, err := os.Stat(filepath.Join(destDir, path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if finfo.Mode().Perm() != mode {
+				t.Errorf("%s: mode %v, want %v", path, finfo.Mode().Perm(), mode)
+			}
+		}
+	}
+
+	t.Run("mode", func(t *testing.T) {
+		// the read-only directory only gets its mode once its children are written
+		destDir := copyTree(t, &wshrpc.FileCopyOpts{PreserveMode: true})
+		checkModes(t, destDir, map[string]os.FileMode{"run.sh": 0750, "ro/data.txt": 0604, "ro": 0555})
+	})
+
+	t.Run("umask file", func(t *testing.T) {
+		// same-host copies follow the flag too, a single file doesn't need a writable directory
+		for _, preserve := range []bool{false, true} {
+			destPath := filepath.Join(t.TempDir(), "run.sh")
+			impl := &ServerImpl{}
+			_, err := impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+				SrcUri:  "wsh://local/" + filepath.Join(srcDir, "run.sh"),
+				DestUri: "wsh://local/" + destPath,
+				Opts:    &wshrpc.FileCopyOpts{PreserveMode: preserve},
+			})
+			if err != nil {
+				t.Fatalf("RemoteFileCopyCommand: %v", err)
+			}
+			want := os.FileMode(0700)
+			if preserve {
+				want = 0750
+			}
+			finfo, err := os.Stat(destPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if finfo.Mode().Perm() != want {
+				t.Errorf("preserve %v: mode %v, want %v", preserve, finfo.Mode().Perm(), want)
+			}
+		}
+	})
+
+	t.Run("umask", func(t *testing.T) {
+		if os.Geteuid() != 0 {
+			// without PreserveMode the read-only directory is created without write permission
+			t.Skip("needs root to write into the read-only directory")
+		}
+		destDir := copyTree(t, nil)
+		checkModes(t, destDir, map[string]os.FileMode{"run.sh": 0700, "ro/data.txt": 0600, "ro": 0500})
+	})
+
+	t.Run("timestamps", func(t *testing.T) {
+		for _, preserve := range []bool{false, true} {
+			destDir := copyTree(t, &wshrpc.FileCopyOpts{PreserveMode: true, PreserveTimestamps: preserve})
+			for _, path := range []string{"run.sh", "ro", "ro/data.txt"} {
+				finfo, err := os.Stat(filepath.Join(destDir, path))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if finfo.ModTime().Equal(mtime) != preserve {
+					t.Errorf("%s (preserve %v): mtime %v", path, preserve, finfo.ModTime())
+				}
+			}
+		}
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		if os.Geteuid() != 0 {
+			// lacking the privilege keeps the current user instead of failing the copy
+			destDir := copyTree(t, &wshrpc.FileCopyOpts{PreserveMode: true, PreserveOwner: true})
+			if uid := fileUid(t, filepath.Join(destDir, "run.sh")); uid != os.Geteuid() {
+				t.Errorf("expected uid %d, got %d", os.Geteuid(), uid)
+			}
+			return
+		}
+		const nobody = 65534
+		if err := os.Lchown(filepath.Join(srcDir, "run.sh"), nobody, nobody); err != nil {
+			t.Fatal(err)
+		}
+		for _, preserve := range []bool{false, true} {
+			destDir := copyTree(t, &wshrpc.FileCopyOpts{PreserveOwner: preserve})
+			wantUid := os.Geteuid()
+			if preserve {
+				wantUid = nobody
+			}
+			if uid := fileUid(t, filepath.Join(destDir, "run.sh")); uid != wantUid {
+				t.Errorf("preserve %v: expected uid %d, got %d", preserve, wantUid, uid)
+			}
+		}
+	})
+}
+
+func TestChownLikeSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	writeTestFile(t, path, "x")
+	// give it to someone else, root when we aren't root
+	owner := 0
+	if os.Geteuid() == 0 {
+		owner = 65534
+	}
+	hdr := &tar.Header{Name: "file.txt", Mode: 0644, Uid: owner, Gid: owner}
+	err := chownLikeSource(path, hdr.FileInfo())
+	if os.Geteuid() != 0 {
+		if !errors.Is(err, fs.ErrPermission) {
+			t.Fatalf("expected a permission error, got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid := fileUid(t, path); uid != owner {
+		t.Errorf("expected uid %d, got %d", owner, uid)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows
+
+package wshremote
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// windows ownership is sid based, we don't report it
+func fillFileOwner(rtn *wshrpc.FileInfo, finfo fs.FileInfo) {}
+
+// ownership isn't carried over on windows, so there are no ids to compare
+func fileOwnerIds(finfo fs.FileInfo) (uid int, gid int, ok bool) {
+	return 0, 0, false
+}
+
+// ownership isn't carried over on windows, files belong to the user running the copy
+func chownLikeSource(path string, finfo fs.FileInfo) error {
+	return nil
+}
+
+func resolveChownIds(owner string, group string) (uid int, gid int, err error) {
+	return 0, 0, errors.New("changing file ownership is not supported on windows")
+}
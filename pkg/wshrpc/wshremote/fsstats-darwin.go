@@ -0,0 +1,14 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build darwin
+
+package wshremote
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func fsTypeName(stat *unix.Statfs_t) string {
+	return unix.ByteSliceToString(stat.Fstypename[:])
+}
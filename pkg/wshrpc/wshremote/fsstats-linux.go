@@ -0,0 +1,39 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package wshremote
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// linux only reports a magic number for the filesystem type, these are the common ones (see statfs(2))
+var linuxFsTypes = map[int64]string{
+	0xef53:     "ext4",
+	0x9123683e: "btrfs",
+	0x58465342: "xfs",
+	0x01021994: "tmpfs",
+	0x794c7630: "overlay",
+	0x2fc12fc1: "zfs",
+	0xf2f52010: "f2fs",
+	0x6969:     "nfs",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x65735546: "fuse",
+	0x4d44:     "vfat",
+	0x2011bab0: "exfat",
+	0x5346544e: "ntfs",
+	0x73717368: "squashfs",
+	0x01021997: "9p",
+}
+
+func fsTypeName(stat *unix.Statfs_t) string {
+	if name, ok := linuxFsTypes[int64(stat.Type)]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", stat.Type)
+}
@@ -0,0 +1,25 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package wshremote
+
+import (
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"golang.org/x/sys/unix"
+)
+
+func getFileSystemStats(path string) (*wshrpc.FileSystemStats, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return nil, err
+	}
+	blockSize := int64(stat.Bsize)
+	return &wshrpc.FileSystemStats{
+		Total:     int64(stat.Blocks) * blockSize,
+		Used:      int64(stat.Blocks-stat.Bfree) * blockSize,
+		Available: int64(stat.Bavail) * blockSize,
+		FsType:    fsTypeName(&stat),
+	}, nil
+}
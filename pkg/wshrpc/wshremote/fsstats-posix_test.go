@@ -0,0 +1,35 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package wshremote
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestFileSystemStats(t *testing.T) {
+	impl := &ServerImpl{}
+	stats, err := impl.RemoteFileSystemStatsCommand(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatalf("RemoteFileSystemStatsCommand: %v", err)
+	}
+	if stats.Total <= 0 {
+		t.Errorf("expected positive total, got %d", stats.Total)
+	}
+	if stats.Used < 0 || stats.Used > stats.Total {
+		t.Errorf("used %d out of range (total %d)", stats.Used, stats.Total)
+	}
+	if stats.Available < 0 || stats.Available > stats.Total {
+		t.Errorf("available %d out of range (total %d)", stats.Available, stats.Total)
+	}
+	if (runtime.GOOS == "linux" || runtime.GOOS == "darwin") && stats.FsType == "" {
+		t.Errorf("expected a filesystem type")
+	}
+	if _, err := impl.RemoteFileSystemStatsCommand(context.Background(), "/does/not/exist"); err == nil {
+		t.Errorf("expected error for missing path")
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows
+
+package wshremote
+
+import (
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"golang.org/x/sys/windows"
+)
+
+func getFileSystemStats(path string) (*wshrpc.FileSystemStats, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(pathPtr, &available, &total, &free); err != nil {
+		return nil, err
+	}
+	return &wshrpc.FileSystemStats{
+		Total:     int64(total),
+		Used:      int64(total - free),
+		Available: int64(available),
+		FsType:    getVolumeFsType(pathPtr),
+	}, nil
+}
+
+// getVolumeFsType returns the filesystem name (e.g. "NTFS") of the volume containing path, or "" if it can't be determined
+func getVolumeFsType(pathPtr *uint16) string {
+	volumePath := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(pathPtr, &volumePath[0], uint32(len(volumePath))); err != nil {
+		return ""
+	}
+	fsName := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumeInformation(&volumePath[0], nil, 0, nil, nil, nil, &fsName[0], uint32(len(fsName))); err != nil {
+		return ""
+	}
+	return windows.UTF16ToString(fsName)
+}
@@ -0,0 +1,190 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+)
+
+type gitignoreRule struct {
+	re      *regexp.Regexp // matched against the slash separated path relative to the directory of the .gitignore
+	negate  bool
+	dirOnly bool
+}
+
+// gitignoreMatcher applies .gitignore files the way git does: rules are scoped to the directory containing
+// the .gitignore, deeper files take precedence over their parents, and the last matching rule wins.
+// Files under an ignored directory can't be re-included, callers are expected to prune ignored directories.
+type gitignoreMatcher struct {
+	root  string
+	rules map[string][]gitignoreRule // keyed by absolute directory, loaded lazily
+}
+
+// newGitignoreMatcher finds the enclosing git repository (so .gitignore files above path still apply), falling back to path itself
+func newGitignoreMatcher(path string) *gitignoreMatcher {
+	root := filepath.Clean(path)
+	for dir := root; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			root = dir
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	return &gitignoreMatcher{root: root, rules: make(map[string][]gitignoreRule)}
+}
+
+func (m *gitignoreMatcher) dirRules(dir string) []gitignoreRule {
+	if rules, ok := m.rules[dir]; ok {
+		return rules
+	}
+	rules, err := parseGitignoreFile(filepath.Join(dir, ".gitignore"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("cannot read .gitignore in %q: %v\n", dir, err)
+	}
+	m.rules[dir] = rules
+	return rules
+}
+
+// isIgnored reports whether path (absolute) is ignored by the .gitignore files between the root and path
+func (m *gitignoreMatcher) isIgnored(path string, isDir bool) bool {
+	relPath, err := filepath.Rel(m.root, path)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return false
+	}
+	segments := strings.Split(filepath.ToSlash(relPath), "/")
+	ignored := false
+	dir := m.root
+	for i := range segments {
+		ruleRelPath := strings.Join(segments[i:], "/")
+		for _, rule := range m.dirRules(dir) {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.re.MatchString(ruleRelPath) {
+				ignored = !rule.negate
+			}
+		}
+		dir = filepath.Join(dir, segments[i])
+	}
+	return ignored
+}
+
+func parseGitignoreFile(path string) ([]gitignoreRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer utilfn.GracefulClose(file, "parseGitignoreFile", path)
+	var rules []gitignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseGitignoreLine(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+func parseGitignoreLine(line string) (gitignoreRule, bool) {
+	var rule gitignoreRule
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasSuffix(line, "\\ ") {
+		line = strings.TrimRight(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+	// a slash at the start or in the middle anchors the pattern to the .gitignore's directory
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	reStr := gitignoreGlobToRegexp(line)
+	if !anchored && !strings.HasPrefix(reStr, "(?:.*/)?") {
+		reStr = "(?:.*/)?" + reStr
+	}
+	re, err := regexp.Compile("^" + reStr + "$")
+	if err != nil {
+		return rule, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// gitignoreGlobToRegexp converts a gitignore glob ("*", "?", "[...]", and "**" path segments) to a regexp
+func gitignoreGlobToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		ch := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**") && i+2 == len(glob) && (i == 0 || glob[i-1] == '/'):
+			sb.WriteString(".*")
+			i++
+		case ch == '*':
+			sb.WriteString("[^/]*")
+		case ch == '?':
+			sb.WriteString("[^/]")
+		case ch == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case ch == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	return sb.String()
+}
+
+// gitignoreWalkFunc wraps walkFn, skipping paths ignored by .gitignore files (ignored directories are not descended into)
+func gitignoreWalkFunc(root string, walkFn filepath.WalkFunc) filepath.WalkFunc {
+	matcher := newGitignoreMatcher(root)
+	return func(path string, info fs.FileInfo, err error) error {
+		if err != nil || path == root {
+			return walkFn(path, info, err)
+		}
+		if matcher.isIgnored(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return walkFn(path, info, nil)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/util/tarcopy"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func makeGitRepo(t *testing.T) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "repo")
+	files := map[string]string{
+		".gitignore":          "# comment\n*.log\nbuild/\n!important.log\n/secret.txt\ndocs/**/draft.md\n",
+		"a.log":               "",
+		"important.log":       "",
+		"build/out.bin":       "",
+		"secret.txt":          "",
+		"docs/draft.md":       "",
+		"docs/a/b/draft.md":   "",
+		"docs/final.md":       "",
+		"other/f.tmp":         "",
+		"src/.gitignore":      "!debug.log\ngen/\n*.tmp\n",
+		"src/secret.txt":      "",
+		"src/debug.log":       "",
+		"src/x.log":           "",
+		"src/main.go":         "",
+		"src/gen/gen.go":      "",
+		"src/deep/z.tmp":      "",
+		"src/deep/build/x.go": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+const gitRepoKept = ".gitignore,docs/final.md,important.log,other/f.tmp,src/.gitignore,src/debug.log,src/main.go,src/secret.txt"
+
+func TestGitignoreLine(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.log", "a.log", true},
+		{"*.log", "x/y/a.log", true},
+		{"/a.log", "x/a.log", false},
+		{"a/b", "a/b", true},
+		{"a/b", "x/a/b", false},
+		{"**/foo", "x/y/foo", true},
+		{"**/foo", "foo", true},
+		{"a/**", "a/x/y", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"file[0-9].txt", "file7.txt", true},
+		{"file[!0-9].txt", "file7.txt", false},
+		{`\#hash`, "#hash", true},
+		{"?.c", "ab.c", false},
+	}
+	for _, tc := range tests {
+		rule, ok := parseGitignoreLine(tc.pattern)
+		if !ok {
+			t.Fatalf("cannot parse %q", tc.pattern)
+		}
+		if got := rule.re.MatchString(tc.path); got != tc.want {
+			t.Errorf("%q matching %q: got %v, want %v", tc.pattern, tc.path, got, tc.want)
+		}
+	}
+	for _, line := range []string{"", "# comment", "   "} {
+		if _, ok := parseGitignoreLine(line); ok {
+			t.Errorf("expected %q to be skipped", line)
+		}
+	}
+}
+
+func TestTarStream_Gitignore(t *testing.T) {
+	root := makeGitRepo(t)
+	impl := &ServerImpl{}
+	ch := impl.RemoteTarStreamCommand(context.Background(), wshrpc.CommandRemoteStreamTarData{Path: root + "/", Opts: &wshrpc.FileCopyOpts{RespectGitignore: true}})
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	var names []string
+	err := tarcopy.TarCopyDest(ctx, cancel, ch, func(next *tar.Header, reader *tar.Reader, singleFile bool) error {
+		if next.Typeflag == tar.TypeReg {
+			names = append(names, next.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TarCopyDest: %v", err)
+	}
+	slices.Sort(names)
+	if got := strings.Join(names, ","); got != gitRepoKept {
+		t.Errorf("got %s, want %s", got, gitRepoKept)
+	}
+}
+
+func TestListEntries_Gitignore(t *testing.T) {
+	root := makeGitRepo(t)
+	sorted, _, err := listEntriesSorted(context.Background(), root, &wshrpc.FileListOpts{All: true, SortBy: wshrpc.FileListSortBy_Name, RespectGitignore: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, finfo := range sorted {
+		relPath, _ := filepath.Rel(root, finfo.Path)
+		names = append(names, filepath.ToSlash(relPath))
+	}
+	slices.Sort(names)
+	if got := strings.Join(names, ","); got != gitRepoKept {
+		t.Errorf("got %s, want %s", got, gitRepoKept)
+	}
+
+	// listing a subdirectory still applies the .gitignore files above it in the repo
+	got := listNames(t, filepath.Join(root, "src"), &wshrpc.FileListOpts{RespectGitignore: true})
+	slices.Sort(got)
+	if strings.Join(got, ",") != ".gitignore,debug.log,deep,main.go,secret.txt" {
+		t.Errorf("src listing: got %v", got)
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package wshremote
+
+import (
+	"io/fs"
+)
+
+// only the leading dot marks a file as hidden
+func hasHiddenAttr(entry fs.DirEntry) bool {
+	return false
+}
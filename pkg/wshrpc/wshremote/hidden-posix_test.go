@@ -0,0 +1,66 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package wshremote
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func TestListEntries_ShowHidden(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.txt"), "x")
+	writeTestFile(t, filepath.Join(dir, ".hidden.txt"), "x")
+	writeTestFile(t, filepath.Join(dir, ".hdir", "inner.txt"), "x")
+	writeTestFile(t, filepath.Join(dir, "sub", "b.txt"), "x")
+	writeTestFile(t, filepath.Join(dir, "sub", ".c.txt"), "x")
+	show, hide := true, false
+
+	tests := []struct {
+		name string
+		opts wshrpc.FileListOpts
+		want []string
+	}{
+		{"plain default", wshrpc.FileListOpts{}, []string{".hdir", ".hidden.txt", "a.txt", "sub"}},
+		{"plain shown", wshrpc.FileListOpts{ShowHidden: &show}, []string{".hdir", ".hidden.txt", "a.txt", "sub"}},
+		{"plain hidden", wshrpc.FileListOpts{ShowHidden: &hide}, []string{"a.txt", "sub"}},
+		{"sorted hidden", wshrpc.FileListOpts{ShowHidden: &hide, SortBy: wshrpc.FileListSortBy_Name}, []string{"a.txt", "sub"}},
+		{"all default", wshrpc.FileListOpts{All: true}, []string{".c.txt", ".hidden.txt", "a.txt", "b.txt", "inner.txt"}},
+		{"all hidden", wshrpc.FileListOpts{All: true, ShowHidden: &hide}, []string{"a.txt", "b.txt"}},
+		{"all sorted hidden", wshrpc.FileListOpts{All: true, ShowHidden: &hide, SortBy: wshrpc.FileListSortBy_Name}, []string{"a.txt", "b.txt"}},
+	}
+	for _, tc := range tests {
+		opts := tc.opts
+		names := listNames(t, dir, &opts)
+		slices.Sort(names)
+		if !slices.Equal(names, tc.want) {
+			t.Errorf("%s: got %v, expected %v", tc.name, names, tc.want)
+		}
+	}
+
+	// hidden entries don't use up the limit, the walk visits "." .hdir .hdir/inner.txt .hidden.txt a.txt ... in order
+	names := listNames(t, dir, &wshrpc.FileListOpts{All: true, Limit: 4})
+	if want := []string{"inner.txt", ".hidden.txt"}; !slices.Equal(names, want) {
+		t.Errorf("limit with hidden: got %v, expected %v", names, want)
+	}
+	names = listNames(t, dir, &wshrpc.FileListOpts{All: true, Limit: 4, ShowHidden: &hide})
+	if want := []string{"a.txt", "b.txt"}; !slices.Equal(names, want) {
+		t.Errorf("limit without hidden: got %v, expected %v", names, want)
+	}
+
+	// listing a hidden directory itself still shows what's in it
+	names = listNames(t, filepath.Join(dir, ".hdir"), &wshrpc.FileListOpts{ShowHidden: &hide})
+	if want := []string{"inner.txt"}; !slices.Equal(names, want) {
+		t.Errorf("hidden root: got %v, expected %v", names, want)
+	}
+	names = listNames(t, filepath.Join(dir, ".hdir"), &wshrpc.FileListOpts{All: true, ShowHidden: &hide, SortBy: wshrpc.FileListSortBy_Name})
+	if want := []string{"inner.txt"}; !slices.Equal(names, want) {
+		t.Errorf("hidden root walk: got %v, expected %v", names, want)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows
+
+package wshremote
+
+import (
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func setHiddenAttr(t *testing.T, path string) {
+	t.Helper()
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs, err := syscall.GetFileAttributes(pathPtr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.SetFileAttributes(pathPtr, attrs|syscall.FILE_ATTRIBUTE_HIDDEN); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListEntries_ShowHidden(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.txt"), "x")
+	writeTestFile(t, filepath.Join(dir, ".dotfile.txt"), "x")
+	writeTestFile(t, filepath.Join(dir, "attr.txt"), "x")
+	writeTestFile(t, filepath.Join(dir, "attrdir", "inner.txt"), "x")
+	writeTestFile(t, filepath.Join(dir, "sub", "b.txt"), "x")
+	setHiddenAttr(t, filepath.Join(dir, "attr.txt"))
+	setHiddenAttr(t, filepath.Join(dir, "attrdir"))
+	show, hide := true, false
+
+	tests := []struct {
+		name string
+		opts wshrpc.FileListOpts
+		want []string
+	}{
+		{"plain shown", wshrpc.FileListOpts{ShowHidden: &show}, []string{".dotfile.txt", "a.txt", "attr.txt", "attrdir", "sub"}},
+		{"plain hidden", wshrpc.FileListOpts{ShowHidden: &hide}, []string{"a.txt", "sub"}},
+		{"sorted hidden", wshrpc.FileListOpts{ShowHidden: &hide, SortBy: wshrpc.FileListSortBy_Name}, []string{"a.txt", "sub"}},
+		{"all hidden", wshrpc.FileListOpts{All: true, ShowHidden: &hide}, []string{"a.txt", "b.txt"}},
+		{"all sorted hidden", wshrpc.FileListOpts{All: true, ShowHidden: &hide, SortBy: wshrpc.FileListSortBy_Name}, []string{"a.txt", "b.txt"}},
+	}
+	for _, tc := range tests {
+		opts := tc.opts
+		names := listNames(t, dir, &opts)
+		slices.Sort(names)
+		if !slices.Equal(names, tc.want) {
+			t.Errorf("%s: got %v, expected %v", tc.name, names, tc.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+)
+
+// ErrOutsideJail is returned for paths that resolve outside of ServerImpl.JailRoot
+var ErrOutsideJail = errors.New("path is outside of the jail root")
+
+// cleanPath expands ~ and cleans a path passed to a remote file command.  every command that takes a path goes
+// through here, so when the server has a JailRoot this is also where paths escaping it are rejected.
+func (impl *ServerImpl) cleanPath(path string) (string, error) {
+	expandedPath, err := wavebase.ExpandHomeDir(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot expand path %q: %w", path, err)
+	}
+	cleanedPath := filepath.Clean(expandedPath)
+	if err := impl.checkJail(cleanedPath); err != nil {
+		return "", err
+	}
+	return cleanedPath, nil
+}
+
+// checkJail fails unless path is within JailRoot once made absolute and resolved as far as it exists, so ".."
+// components and symlinks pointing out of the root are both caught
+func (impl *ServerImpl) checkJail(path string) error {
+	if impl.JailRoot == "" {
+		return nil
+	}
+	jailRoot, err := filepath.Abs(filepath.Clean(wavebase.ExpandHomeDirSafe(impl.JailRoot)))
+	if err != nil {
+		return fmt.Errorf("cannot resolve jail root %q: %w", impl.JailRoot, err)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("cannot resolve path %q: %w", path, err)
+	}
+	// both sides are resolved, so a jail root under a symlinked dir (e.g. /tmp on macos) still works
+	realRoot, _ := realPath(jailRoot)
+	resolvedPath, _ := realPath(absPath)
+	if !isWithinDir(resolvedPath, realRoot) {
+		return fmt.Errorf("cannot access %q: %w", path, ErrOutsideJail)
+	}
+	return nil
+}
+
+func isWithinDir(path string, dir string) bool {
+	if path == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
+
+// jailWalkFunc guards a walk that follows symlinks, entries reached through a link out of the jail are passed
+// to walkFn with an ErrOutsideJail error instead of being read (and linked directories are not descended into)
+func (impl *ServerImpl) jailWalkFunc(followSymlinks bool, walkFn filepath.WalkFunc) filepath.WalkFunc {
+	if impl.JailRoot == "" || !followSymlinks {
+		return walkFn
+	}
+	return func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return walkFn(path, info, err)
+		}
+		if jailErr := impl.checkJail(path); jailErr != nil {
+			if err := walkFn(path, info, jailErr); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return walkFn(path, info, nil)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func TestJail(t *testing.T) {
+	root := t.TempDir()
+	jail := filepath.Join(root, "jail")
+	outside := filepath.Join(root, "outside")
+	writeTestFile(t, filepath.Join(jail, "a.txt"), "inside\n")
+	writeTestFile(t, filepath.Join(jail, "sub", "b.txt"), "inside too\n")
+	writeTestFile(t, filepath.Join(outside, "secret.txt"), "secret\n")
+	if err := os.Symlink(outside, filepath.Join(jail, "outlink")); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(jail, "sub"), filepath.Join(jail, "inlink")); err != nil {
+		t.Fatal(err)
+	}
+	impl := &ServerImpl{JailRoot: jail}
+	ctx := context.Background()
+
+	allowed := []string{
+		jail,
+		filepath.Join(jail, "a.txt"),
+		filepath.Join(jail, "sub", "..", "a.txt"),
+		filepath.Join(jail, "inlink", "b.txt"),
+		filepath.Join(jail, "missing", "new.txt"),
+	}
+	for _, path := range allowed {
+		if _, err := impl.cleanPath(path); err != nil {
+			t.Errorf("%q: unexpected error %v", path, err)
+		}
+	}
+	escapes := []string{
+		root,
+		filepath.Join(outside, "secret.txt"),
+		filepath.Join(jail, "..", "outside", "secret.txt"),
+		filepath.Join(jail, "outlink", "secret.txt"),
+		filepath.Join(jail, "outlink", "new.txt"),
+		jail + "-sibling",
+	}
+	for _, path := range escapes {
+		if _, err := impl.cleanPath(path); !errors.Is(err, ErrOutsideJail) {
+			t.Errorf("%q: expected ErrOutsideJail, got %v", path, err)
+		}
+	}
+
+	// the commands go through the same check
+	if _, err := impl.RemoteFileInfoCommand(ctx, filepath.Join(jail, "a.txt")); err != nil {
+		t.Errorf("info inside the jail: %v", err)
+	}
+	if _, err := impl.RemoteFileInfoCommand(ctx, filepath.Join(jail, "outlink", "secret.txt")); !errors.Is(err, ErrOutsideJail) {
+		t.Errorf("info through a symlink: expected ErrOutsideJail, got %v", err)
+	}
+	err := impl.RemoteWriteFileCommand(ctx, wshrpc.FileData{Info: &wshrpc.FileInfo{Path: filepath.Join(outside, "new.txt")}})
+	if !errors.Is(err, ErrOutsideJail) {
+		t.Errorf("write outside: expected ErrOutsideJail, got %v", err)
+	}
+	err = impl.RemoteFileDeleteCommand(ctx, wshrpc.CommandDeleteFileData{Path: filepath.Join(jail, "..", "outside", "secret.txt")})
+	if !errors.Is(err, ErrOutsideJail) {
+		t.Errorf("delete outside: expected ErrOutsideJail, got %v", err)
+	}
+	if _, err := impl.RemoteSymlinkCommand(ctx, wshrpc.CommandRemoteLinkData{Path: filepath.Join(jail, "link2"), Target: "../outside"}); !errors.Is(err, ErrOutsideJail) {
+		t.Errorf("symlink out: expected ErrOutsideJail, got %v", err)
+	}
+	if _, err := impl.RemoteHardlinkCommand(ctx, wshrpc.CommandRemoteLinkData{Path: filepath.Join(jail, "hard"), Target: filepath.Join(outside, "secret.txt")}); !errors.Is(err, ErrOutsideJail) {
+		t.Errorf("hardlink out: expected ErrOutsideJail, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "secret.txt")); err != nil {
+		t.Errorf("file outside the jail was touched: %v", err)
+	}
+
+	// a copy that follows symlinks must not pull in the linked directory outside the jail
+	destDir := filepath.Join(jail, "dest")
+	_, err = impl.RemoteFileCopyCommand(ctx, wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + jail + "/",
+		DestUri: "wsh://local/" + destDir,
+		Opts:    &wshrpc.FileCopyOpts{Recursive: true, FollowSymlinks: true},
+	})
+	if !errors.Is(err, ErrOutsideJail) {
+		t.Errorf("copy following a link out: expected ErrOutsideJail, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "outlink", "secret.txt")); err == nil {
+		t.Error("copy followed a symlink out of the jail")
+	}
+
+	// no jail root, no restriction
+	if _, err := (&ServerImpl{}).cleanPath(filepath.Join(outside, "secret.txt")); err != nil {
+		t.Errorf("unjailed: unexpected error %v", err)
+	}
+}
+
+func TestJail_HardlinkCopy(t *testing.T) {
+	root := t.TempDir()
+	jail := filepath.Join(root, "jail")
+	outside := filepath.Join(root, "outside")
+	writeTestFile(t, filepath.Join(jail, "src", "a.txt"), "inside\n")
+	if err := os.MkdirAll(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// the copy lands in dest/src, where the entry for a.txt is a symlink to a directory outside the jail
+	if err := os.MkdirAll(filepath.Join(jail, "dest", "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(jail, "dest", "src", "a.txt")); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+	impl := &ServerImpl{JailRoot: jail}
+	_, err := impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + filepath.Join(jail, "src") + "/",
+		DestUri: "wsh://local/" + filepath.Join(jail, "dest"),
+		Opts:    &wshrpc.FileCopyOpts{Recursive: true, Merge: true, Hardlink: true},
+	})
+	if !errors.Is(err, ErrOutsideJail) {
+		t.Errorf("hardlinking through a symlinked directory: expected ErrOutsideJail, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(outside, "a.txt")); err == nil {
+		t.Error("hardlink copy created a file outside the jail")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+)
+
+// manifestDir is a directory of the manifest walk that is held back until all of its entries have been sent
+type manifestDir struct {
+	entry  wshrpc.ManifestEntry
+	hasher hash.Hash // nil unless hashing
+}
+
+// addEntry folds a child into the directory hash, the kind is included so a file and a link with the same
+// content hash still differ
+func (d *manifestDir) addEntry(entry wshrpc.ManifestEntry) {
+	if d.hasher == nil {
+		return
+	}
+	kind := "f"
+	if entry.IsDir {
+		kind = "d"
+	} else if entry.LinkTarget != "" {
+		kind = "l"
+	}
+	fmt.Fprintf(d.hasher, "%s\x00%s\x00%s\n", path.Base(entry.Path), kind, entry.Sha256)
+}
+
+// RemoteManifestCommand streams the files, directories and symlinks under data.Path with their size, mtime and
+// (optionally) content hash, so a client can diff it against an earlier manifest and only copy what changed.
+func (impl *ServerImpl) RemoteManifestCommand(ctx context.Context, data wshrpc.CommandRemoteManifestData) chan wshrpc.RespOrErrorUnion[wshrpc.ManifestEntry] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.ManifestEntry], 16)
+	go func() {
+		defer close(ch)
+		err := impl.remoteManifestInternal(ctx, data, func(entry wshrpc.ManifestEntry) error {
+			select {
+			case ch <- wshrpc.RespOrErrorUnion[wshrpc.ManifestEntry]{Response: entry}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.ManifestEntry](err)
+		}
+	}()
+	return ch
+}
+
+func (impl *ServerImpl) remoteManifestInternal(ctx context.Context, data wshrpc.CommandRemoteManifestData, send func(wshrpc.ManifestEntry) error) error {
+	opts := data.Opts
+	if opts == nil {
+		opts = &wshrpc.ManifestOpts{}
+	}
+	rootPath, err := impl.cleanPath(data.Path)
+	if err != nil {
+		return err
+	}
+	finfo, err := os.Stat(rootPath)
+	if err != nil {
+		return fmt.Errorf("cannot stat %q: %w", data.Path, err)
+	}
+	if !finfo.IsDir() {
+		return fmt.Errorf("cannot make manifest of %q: not a directory", data.Path)
+	}
+	var dirStack []*manifestDir
+	closeDir := func() error {
+		dir := dirStack[len(dirStack)-1]
+		dirStack = dirStack[:len(dirStack)-1]
+		if dir.hasher != nil {
+			dir.entry.Sha256 = hex.EncodeToString(dir.hasher.Sum(nil))
+		}
+		if len(dirStack) > 0 {
+			dirStack[len(dirStack)-1].addEntry(dir.entry)
+		}
+		return send(dir.entry)
+	}
+	walkFn := func(innerPath string, info fs.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// a sync would take an entry missing from the manifest as deleted, so unreadable entries fail the walk
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(rootPath, innerPath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		// the walk is lexical, so once we are out of a directory it is complete
+		for len(dirStack) > 0 && dirStack[len(dirStack)-1].entry.Path != path.Dir(relPath) {
+			if err := closeDir(); err != nil {
+				return err
+			}
+		}
+		entry := wshrpc.ManifestEntry{Path: relPath, ModTime: info.ModTime().UnixMilli(), Mode: info.Mode()}
+		switch {
+		case info.IsDir():
+			entry.IsDir = true
+			dir := &manifestDir{entry: entry}
+			if opts.Hash {
+				dir.hasher = sha256.New()
+			}
+			dirStack = append(dirStack, dir)
+			return nil
+		case info.Mode()&fs.ModeSymlink != 0:
+			entry.LinkTarget, err = os.Readlink(innerPath)
+			if err != nil {
+				return fmt.Errorf("cannot read link %q: %w", innerPath, err)
+			}
+			if opts.Hash {
+				sum := sha256.Sum256([]byte(entry.LinkTarget))
+				entry.Sha256 = hex.EncodeToString(sum[:])
+			}
+		case info.Mode().IsRegular():
+			entry.Size = info.Size()
+			if opts.Hash {
+				entry.Sha256, err = hashFilePrefix(innerPath, info.Size())
+				if err != nil {
+					return err
+				}
+			}
+		default:
+			// sockets, pipes and devices can't be copied, they are left out
+			return nil
+		}
+		if len(dirStack) > 0 {
+			dirStack[len(dirStack)-1].addEntry(entry)
+		}
+		return send(entry)
+	}
+	excludeFn, err := excludeWalkFunc(rootPath, opts.ExcludePatterns, walkFn)
+	if err != nil {
+		return err
+	}
+	if err := walkWithSymlinks(rootPath, false, excludeFn); err != nil {
+		return fmt.Errorf("cannot make manifest of %q: %w", data.Path, err)
+	}
+	for len(dirStack) > 0 {
+		if err := closeDir(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
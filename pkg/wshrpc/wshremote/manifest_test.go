@@ -0,0 +1,145 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func runManifest(t *testing.T, ctx context.Context, root string, opts *wshrpc.ManifestOpts) (map[string]wshrpc.ManifestEntry, error) {
+	t.Helper()
+	entries := make(map[string]wshrpc.ManifestEntry)
+	var last string
+	for resp := range (&ServerImpl{}).RemoteManifestCommand(ctx, wshrpc.CommandRemoteManifestData{Path: root, Opts: opts}) {
+		if resp.Error != nil {
+			return entries, resp.Error
+		}
+		if _, ok := entries[resp.Response.Path]; ok {
+			t.Errorf("duplicate manifest entry %q", resp.Response.Path)
+		}
+		entries[resp.Response.Path] = resp.Response
+		last = resp.Response.Path
+	}
+	if last != "." {
+		t.Errorf("expected the root to be the last entry, got %q", last)
+	}
+	return entries, nil
+}
+
+// diffManifests returns the paths that were added, removed or changed (by size, mtime or hash) between old and new
+func diffManifests(oldEntries map[string]wshrpc.ManifestEntry, newEntries map[string]wshrpc.ManifestEntry) []string {
+	var changed []string
+	for path, newEntry := range newEntries {
+		oldEntry, ok := oldEntries[path]
+		if !ok || oldEntry.Size != newEntry.Size || oldEntry.Sha256 != newEntry.Sha256 || (!newEntry.IsDir && oldEntry.ModTime != newEntry.ModTime) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range oldEntries {
+		if _, ok := newEntries[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+func TestManifest(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "a.txt"), "aaa\n")
+	writeTestFile(t, filepath.Join(root, "same", "b.txt"), "bbb\n")
+	writeTestFile(t, filepath.Join(root, "same", "deep", "c.txt"), "ccc\n")
+	writeTestFile(t, filepath.Join(root, "edit", "d.txt"), "ddd\n")
+	writeTestFile(t, filepath.Join(root, "edit", "gone.txt"), "gone\n")
+	writeTestFile(t, filepath.Join(root, "node_modules", "x.js"), "x\n")
+	if err := os.Symlink("a.txt", filepath.Join(root, "link")); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+	opts := &wshrpc.ManifestOpts{Hash: true, ExcludePatterns: []string{"node_modules"}}
+	before, err := runManifest(t, context.Background(), root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".", "a.txt", "edit", "edit/d.txt", "edit/gone.txt", "link", "same", "same/b.txt", "same/deep", "same/deep/c.txt"}
+	var got []string
+	for path := range before {
+		got = append(got, path)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Fatalf("got entries %v, want %v", got, want)
+	}
+	if entry := before["a.txt"]; entry.Size != 4 || entry.Sha256 != "17e682f060b5f8e47ea04c5c4855908b0a5ad612022260fe50e11ecb0cc0ab76" {
+		t.Errorf("bad file entry %+v", entry)
+	}
+	if entry := before["link"]; entry.LinkTarget != "a.txt" || entry.Sha256 == "" {
+		t.Errorf("bad link entry %+v", entry)
+	}
+	if !before["same"].IsDir || before["same"].Sha256 == "" {
+		t.Errorf("bad dir entry %+v", before["same"])
+	}
+
+	// new content with the same size and mtime: only the hash catches the rewrite
+	writeTestFile(t, filepath.Join(root, "edit", "d.txt"), "DDD\n")
+	oldTime := time.UnixMilli(before["edit/d.txt"].ModTime)
+	if err := os.Chtimes(filepath.Join(root, "edit", "d.txt"), oldTime, oldTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "edit", "gone.txt")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(root, "edit", "new.txt"), "new\n")
+	writeTestFile(t, filepath.Join(root, "node_modules", "y.js"), "y\n")
+	after, err := runManifest(t, context.Background(), root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChanged := []string{".", "edit", "edit/d.txt", "edit/gone.txt", "edit/new.txt"}
+	if changed := diffManifests(before, after); !slices.Equal(changed, wantChanged) {
+		t.Errorf("got changed %v, want %v", changed, wantChanged)
+	}
+
+	// without hashes the rewrite is invisible, the client falls back to size and mtime
+	noHash, err := runManifest(t, context.Background(), root, &wshrpc.ManifestOpts{ExcludePatterns: []string{"node_modules"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, entry := range noHash {
+		if entry.Sha256 != "" {
+			t.Errorf("%q: unexpected hash without Hash", path)
+		}
+	}
+	if noHash["edit/d.txt"].ModTime != before["edit/d.txt"].ModTime || noHash["edit/d.txt"].Size != before["edit/d.txt"].Size {
+		t.Errorf("bad entry without hash %+v", noHash["edit/d.txt"])
+	}
+}
+
+func TestManifest_Errors(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "file.txt"), "data\n")
+	if _, err := runManifest(t, context.Background(), filepath.Join(root, "file.txt"), nil); err == nil {
+		t.Error("expected an error for a file root")
+	}
+	if _, err := runManifest(t, context.Background(), root, &wshrpc.ManifestOpts{ExcludePatterns: []string{"["}}); err == nil {
+		t.Error("expected an error for a bad exclude pattern")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var gotErr bool
+	for resp := range (&ServerImpl{}).RemoteManifestCommand(ctx, wshrpc.CommandRemoteManifestData{Path: root}) {
+		if resp.Error != nil {
+			gotErr = true
+		}
+	}
+	if !gotErr {
+		t.Error("expected a canceled manifest to end with an error")
+	}
+}
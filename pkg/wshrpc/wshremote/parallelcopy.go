@@ -0,0 +1,99 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+
+	"github.com/wavetermdev/waveterm/pkg/panichandler"
+)
+
+const (
+	// upper bound for FileCopyOpts.ParallelWrites
+	MaxParallelWrites = 32
+
+	// larger files are written inline, so at most ~2*workers*parallelWriteMaxFileSize bytes are buffered
+	parallelWriteMaxFileSize = 1024 * 1024
+)
+
+type parallelWriteJob struct {
+	path  string
+	finfo fs.FileInfo
+	data  []byte
+}
+
+// parallelFileWriter fans small files read from a (sequential) tar stream out to a pool of workers.
+// the first write error cancels the pool, later submits return it and queued jobs are dropped.
+type parallelFileWriter struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	jobs    chan parallelWriteJob
+	wg      sync.WaitGroup
+	lock    sync.Mutex
+	err     error
+	written int64
+}
+
+func newParallelFileWriter(ctx context.Context, workers int, writeFn func(path string, finfo fs.FileInfo, data []byte) (int64, error)) *parallelFileWriter {
+	workers = min(workers, MaxParallelWrites)
+	w := &parallelFileWriter{jobs: make(chan parallelWriteJob, workers)}
+	w.ctx, w.cancel = context.WithCancelCause(ctx)
+	for i := 0; i < workers; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer func() {
+				panichandler.PanicHandler("parallelFileWriter", recover())
+			}()
+			defer w.wg.Done()
+			for job := range w.jobs {
+				if w.ctx.Err() != nil {
+					continue
+				}
+				n, err := writeFn(job.path, job.finfo, job.data)
+				w.lock.Lock()
+				if err != nil && w.err == nil {
+					w.err = err
+					w.cancel(err)
+				}
+				w.written += n
+				w.lock.Unlock()
+			}
+		}()
+	}
+	return w
+}
+
+func (w *parallelFileWriter) firstErr() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	return context.Cause(w.ctx)
+}
+
+// submit queues a file, blocking while all workers are busy.  returns the first write error (if any).
+func (w *parallelFileWriter) submit(path string, finfo fs.FileInfo, data []byte) error {
+	if w.ctx.Err() != nil {
+		return w.firstErr()
+	}
+	select {
+	case w.jobs <- parallelWriteJob{path: path, finfo: finfo, data: data}:
+		return nil
+	case <-w.ctx.Done():
+		return w.firstErr()
+	}
+}
+
+// wait finishes the queued writes and returns the total bytes written and the first write error
+func (w *parallelFileWriter) wait() (int64, error) {
+	close(w.jobs)
+	w.wg.Wait()
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.cancel(nil)
+	return w.written, w.err
+}
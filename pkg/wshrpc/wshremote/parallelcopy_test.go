@@ -0,0 +1,101 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writes the file the way copyFileFunc does (create, write, chmod, close), plus an fsync to make the syscall latency visible
+func writeSmallFile(path string, finfo fs.FileInfo, data []byte) (int64, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	n, err := file.Write(data)
+	if err != nil {
+		return int64(n), err
+	}
+	return int64(n), file.Sync()
+}
+
+func TestParallelFileWriter(t *testing.T) {
+	dir := t.TempDir()
+	writer := newParallelFileWriter(context.Background(), 4, writeSmallFile)
+	for i := 0; i < 50; i++ {
+		if err := writer.submit(filepath.Join(dir, fmt.Sprintf("f%02d.txt", i)), nil, []byte("hello")); err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+	written, err := writer.wait()
+	if err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if written != 50*5 {
+		t.Errorf("expected %d bytes written, got %d", 50*5, written)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 50 {
+		t.Errorf("expected 50 files, got %d", len(entries))
+	}
+}
+
+func TestParallelFileWriter_FirstErrorCancels(t *testing.T) {
+	writeErr := errors.New("disk full")
+	var calls atomic.Int32
+	writer := newParallelFileWriter(context.Background(), 2, func(path string, finfo fs.FileInfo, data []byte) (int64, error) {
+		calls.Add(1)
+		if path == "bad" {
+			return 0, writeErr
+		}
+		time.Sleep(time.Millisecond)
+		return int64(len(data)), nil
+	})
+	var submitErr error
+	for i := 0; i < 1000 && submitErr == nil; i++ {
+		path := fmt.Sprintf("f%d", i)
+		if i == 3 {
+			path = "bad"
+		}
+		submitErr = writer.submit(path, nil, []byte("x"))
+	}
+	if !errors.Is(submitErr, writeErr) {
+		t.Errorf("expected submit to return the write error, got %v", submitErr)
+	}
+	if _, err := writer.wait(); !errors.Is(err, writeErr) {
+		t.Errorf("expected wait to return the write error, got %v", err)
+	}
+	if n := calls.Load(); n >= 1000 {
+		t.Errorf("expected writes to stop after the first error, got %d", n)
+	}
+}
+
+func BenchmarkParallelFileWriter(b *testing.B) {
+	const numFiles = 500
+	data := make([]byte, 512)
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				dir := b.TempDir()
+				writer := newParallelFileWriter(context.Background(), workers, writeSmallFile)
+				for j := 0; j < numFiles; j++ {
+					if err := writer.submit(filepath.Join(dir, fmt.Sprintf("f%04d", j)), nil, data); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if _, err := writer.wait(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+
+	"github.com/wavetermdev/waveterm/pkg/util/fileutil"
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+const (
+	previewDefaultSize  = 256
+	previewMaxSize      = 2048
+	previewMaxFileSize  = 32 * 1024 * 1024
+	previewJpegQuality  = 80
+	previewCtxCheckRows = 64
+)
+
+// images are checked against this before decoding so a small compressed file
+// can't expand into gigabytes of pixels (var so tests can lower it)
+var previewMaxPixels int64 = 50 * 1000 * 1000
+
+func (impl *ServerImpl) RemoteFilePreviewCommand(ctx context.Context, data wshrpc.CommandRemoteFilePreviewData) (*wshrpc.CommandRemoteFilePreviewRtnData, error) {
+	cleanedPath, err := impl.cleanPath(data.Path)
+	if err != nil {
+		return nil, err
+	}
+	maxSize := data.MaxSize
+	if maxSize <= 0 {
+		maxSize = previewDefaultSize
+	}
+	maxSize = min(maxSize, previewMaxSize)
+	if data.Format != "" && data.Format != wshrpc.PreviewFormat_Jpeg && data.Format != wshrpc.PreviewFormat_Png {
+		return nil, fmt.Errorf("cannot preview %q: invalid format %q", data.Path, data.Format)
+	}
+	file, err := os.Open(cleanedPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file %q: %w", data.Path, err)
+	}
+	defer utilfn.GracefulClose(file, "RemoteFilePreviewCommand", cleanedPath)
+	finfo, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("cannot stat file %q: %w", data.Path, err)
+	}
+	if finfo.IsDir() {
+		return nil, fmt.Errorf("cannot preview %q: is a directory", data.Path)
+	}
+	config, _, err := image.DecodeConfig(file)
+	if errors.Is(err, image.ErrFormat) {
+		return &wshrpc.CommandRemoteFilePreviewRtnData{
+			MimeType:  fileutil.DetectMimeType(cleanedPath, finfo, true),
+			NoPreview: true,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode image %q: %w", data.Path, err)
+	}
+	if finfo.Size() > previewMaxFileSize {
+		return nil, fmt.Errorf("cannot preview %q: file is larger than %d bytes", data.Path, previewMaxFileSize)
+	}
+	if int64(config.Width)*int64(config.Height) > previewMaxPixels {
+		return nil, fmt.Errorf("cannot preview %q: image is %dx%d, larger than %d pixels", data.Path, config.Width, config.Height, previewMaxPixels)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("cannot seek file %q: %w", data.Path, err)
+	}
+	img, _, err := image.Decode(io.LimitReader(file, previewMaxFileSize))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode image %q: %w", data.Path, err)
+	}
+	thumb, err := scaleImage(ctx, img, maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot preview %q: %w", data.Path, err)
+	}
+	format := data.Format
+	if format == "" {
+		format = wshrpc.PreviewFormat_Png
+		if thumb.Opaque() {
+			format = wshrpc.PreviewFormat_Jpeg
+		}
+	}
+	var buf bytes.Buffer
+	var mimeType string
+	if format == wshrpc.PreviewFormat_Jpeg {
+		mimeType = "image/jpeg"
+		err = jpeg.Encode(&buf, flattenImage(thumb), &jpeg.Options{Quality: previewJpegQuality})
+	} else {
+		mimeType = "image/png"
+		err = png.Encode(&buf, thumb)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode preview for %q: %w", data.Path, err)
+	}
+	bounds := img.Bounds()
+	return &wshrpc.CommandRemoteFilePreviewRtnData{
+		Data64:     base64.StdEncoding.EncodeToString(buf.Bytes()),
+		MimeType:   mimeType,
+		Width:      thumb.Bounds().Dx(),
+		Height:     thumb.Bounds().Dy(),
+		OrigWidth:  bounds.Dx(),
+		OrigHeight: bounds.Dy(),
+	}, nil
+}
+
+// previewDims fits width x height into a maxSize square keeping the aspect ratio, never scaling up
+func previewDims(width, height, maxSize int) (int, int) {
+	if width <= maxSize && height <= maxSize {
+		return width, height
+	}
+	if width >= height {
+		return maxSize, max(1, (height*maxSize+width/2)/width)
+	}
+	return max(1, (width*maxSize+height/2)/height), maxSize
+}
+
+// scaleImage box-filters img down to fit in maxSize. source rows are converted to RGBA one at a
+// time with draw.Draw so memory stays proportional to the image width rather than its area.
+func scaleImage(ctx context.Context, img image.Image, maxSize int) (*image.RGBA, error) {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := previewDims(srcW, srcH, maxSize)
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	if srcW == 0 || srcH == 0 {
+		return dst, nil
+	}
+	row := image.NewRGBA(image.Rect(0, 0, srcW, 1))
+	colMap := make([]int, srcW)
+	for x := range colMap {
+		colMap[x] = x * dstW / srcW
+	}
+	sums := make([]uint64, dstW*4)
+	counts := make([]uint64, dstW)
+	flushRow := func(dy int) {
+		off := dy * dst.Stride
+		for dx := 0; dx < dstW; dx++ {
+			n := counts[dx]
+			if n > 0 {
+				for c := 0; c < 4; c++ {
+					dst.Pix[off+dx*4+c] = uint8((sums[dx*4+c] + n/2) / n)
+				}
+			}
+			counts[dx] = 0
+		}
+		clear(sums)
+	}
+	curDy := 0
+	for y := 0; y < srcH; y++ {
+		if y%previewCtxCheckRows == 0 && ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+		dy := y * dstH / srcH
+		if dy != curDy {
+			flushRow(curDy)
+			curDy = dy
+		}
+		draw.Draw(row, row.Bounds(), img, image.Pt(bounds.Min.X, bounds.Min.Y+y), draw.Src)
+		for x := 0; x < srcW; x++ {
+			dx := colMap[x]
+			for c := 0; c < 4; c++ {
+				sums[dx*4+c] += uint64(row.Pix[x*4+c])
+			}
+			counts[dx]++
+		}
+	}
+	flushRow(curDy)
+	return dst, nil
+}
+
+// flattenImage composites a translucent image over white, jpeg has no alpha channel
+func flattenImage(img *image.RGBA) image.Image {
+	if img.Opaque() {
+		return img
+	}
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flat
+}
@@ -0,0 +1,127 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func writeTestPng(t *testing.T, path string, width, height int, alpha uint8) {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: alpha})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFilePreview(t *testing.T) {
+	dir := t.TempDir()
+	wide := filepath.Join(dir, "wide.png")
+	writeTestPng(t, wide, 400, 200, 255)
+	tall := filepath.Join(dir, "tall.png")
+	writeTestPng(t, tall, 100, 300, 128)
+	small := filepath.Join(dir, "small.png")
+	writeTestPng(t, small, 40, 30, 255)
+
+	tests := []struct {
+		name       string
+		data       wshrpc.CommandRemoteFilePreviewData
+		wantMime   string
+		wantWidth  int
+		wantHeight int
+	}{
+		{"default size", wshrpc.CommandRemoteFilePreviewData{Path: wide}, "image/jpeg", 256, 128},
+		{"max size", wshrpc.CommandRemoteFilePreviewData{Path: tall, MaxSize: 60}, "image/png", 20, 60},
+		{"explicit format", wshrpc.CommandRemoteFilePreviewData{Path: wide, MaxSize: 100, Format: wshrpc.PreviewFormat_Png}, "image/png", 100, 50},
+		{"transparent to jpeg", wshrpc.CommandRemoteFilePreviewData{Path: tall, MaxSize: 30, Format: wshrpc.PreviewFormat_Jpeg}, "image/jpeg", 10, 30},
+		{"no upscale", wshrpc.CommandRemoteFilePreviewData{Path: small}, "image/jpeg", 40, 30},
+	}
+	impl := &ServerImpl{}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rtn, err := impl.RemoteFilePreviewCommand(context.Background(), tc.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rtn.NoPreview || rtn.MimeType != tc.wantMime {
+				t.Fatalf("got mimetype %q (nopreview %v), want %q", rtn.MimeType, rtn.NoPreview, tc.wantMime)
+			}
+			if rtn.Width != tc.wantWidth || rtn.Height != tc.wantHeight {
+				t.Errorf("got %dx%d, want %dx%d", rtn.Width, rtn.Height, tc.wantWidth, tc.wantHeight)
+			}
+			raw, err := base64.StdEncoding.DecodeString(rtn.Data64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, format, err := image.DecodeConfig(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if "image/"+format != tc.wantMime || config.Width != tc.wantWidth || config.Height != tc.wantHeight {
+				t.Errorf("encoded thumbnail is %s %dx%d, want %s %dx%d", format, config.Width, config.Height, tc.wantMime, tc.wantWidth, tc.wantHeight)
+			}
+		})
+	}
+
+	t.Run("not an image", func(t *testing.T) {
+		path := filepath.Join(dir, "notes.txt")
+		writeTestFile(t, path, "hello\n")
+		rtn, err := impl.RemoteFilePreviewCommand(context.Background(), wshrpc.CommandRemoteFilePreviewData{Path: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rtn.NoPreview || rtn.Data64 != "" || rtn.MimeType != "text/plain" {
+			t.Errorf("got %+v, want no preview with text/plain", rtn)
+		}
+	})
+
+	t.Run("too many pixels", func(t *testing.T) {
+		oldMax := previewMaxPixels
+		previewMaxPixels = 400*200 - 1
+		defer func() { previewMaxPixels = oldMax }()
+		if _, err := impl.RemoteFilePreviewCommand(context.Background(), wshrpc.CommandRemoteFilePreviewData{Path: wide}); err == nil {
+			t.Error("expected an error for an image over the pixel cap")
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		if _, err := impl.RemoteFilePreviewCommand(context.Background(), wshrpc.CommandRemoteFilePreviewData{Path: wide, Format: "webp"}); err == nil {
+			t.Error("expected an error for an unsupported format")
+		}
+	})
+}
+
+func TestScaleImage_Average(t *testing.T) {
+	// a 2x2 checkerboard of black and white averages to a single mid gray pixel
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	img.SetGray(0, 0, color.Gray{Y: 255})
+	img.SetGray(1, 1, color.Gray{Y: 255})
+	thumb, err := scaleImage(context.Background(), img, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := thumb.RGBAAt(0, 0)
+	if got.R != 128 || got.G != 128 || got.B != 128 || got.A != 255 {
+		t.Errorf("got %v, want mid gray", got)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package wshremote
+
+import (
+	"errors"
+	"io/fs"
+
+	"golang.org/x/sys/unix"
+)
+
+// accessReadOnly checks write permission with access(2), which also reports read-only mounts (EROFS).
+// known is false for other errors (e.g. a filesystem that doesn't support the check), the caller can fall back to probing.
+func accessReadOnly(path string, finfo fs.FileInfo) (readOnly bool, known bool) {
+	err := unix.Access(path, unix.W_OK)
+	if err == nil {
+		return false, true
+	}
+	if errors.Is(err, unix.EACCES) || errors.Is(err, unix.EROFS) || errors.Is(err, unix.EPERM) {
+		return true, true
+	}
+	return false, false
+}
@@ -0,0 +1,69 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package wshremote
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckIsReadOnly_MatchesProbe(t *testing.T) {
+	dir := t.TempDir()
+	writableFile := filepath.Join(dir, "writable.txt")
+	readOnlyFile := filepath.Join(dir, "readonly.txt")
+	readOnlyDir := filepath.Join(dir, "readonlydir")
+	writeTestFile(t, writableFile, "w")
+	writeTestFile(t, readOnlyFile, "r")
+	writeTestFile(t, filepath.Join(readOnlyDir, "inner.txt"), "r")
+	if err := os.Chmod(readOnlyFile, 0444); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(readOnlyDir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(readOnlyDir, 0755) })
+	// root can write regardless of the mode bits, both paths should agree on that too
+	isRoot := os.Geteuid() == 0
+
+	tests := []struct {
+		path     string
+		readOnly bool
+	}{
+		{writableFile, false},
+		{readOnlyFile, !isRoot},
+		{dir, false},
+		{filepath.Join(dir, "missing.txt"), false},
+		{filepath.Join(readOnlyDir, "missing.txt"), !isRoot},
+		{filepath.Join(readOnlyDir, "inner.txt"), false},
+	}
+	for _, tc := range tests {
+		finfo, err := os.Stat(tc.path)
+		exists := err == nil
+		fast := checkIsReadOnly(tc.path, finfo, exists, false)
+		probe := probeIsReadOnly(tc.path, finfo, exists)
+		if fast != probe {
+			t.Errorf("%s: fast path says read-only=%v, probe says %v", tc.path, fast, probe)
+		}
+		if fast != tc.readOnly {
+			t.Errorf("%s: got read-only=%v, want %v", tc.path, fast, tc.readOnly)
+		}
+	}
+
+	// the probe isn't needed when the permission check can tell, so the directory isn't touched
+	before, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIsReadOnly(filepath.Join(dir, "missing.txt"), nil, false, true)
+	after, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("checking a missing path changed the directory mtime")
+	}
+}
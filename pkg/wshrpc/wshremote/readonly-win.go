@@ -0,0 +1,19 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows
+
+package wshremote
+
+import (
+	"io/fs"
+)
+
+// windows has no access(2), a file's read-only attribute shows up as a missing write bit.  directories ignore
+// the attribute, so for them (finfo is nil) only the probe can tell.
+func accessReadOnly(path string, finfo fs.FileInfo) (readOnly bool, known bool) {
+	if finfo == nil || finfo.IsDir() {
+		return false, false
+	}
+	return finfo.Mode().Perm()&0200 == 0, true
+}
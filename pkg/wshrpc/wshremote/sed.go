@@ -0,0 +1,133 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/wavetermdev/waveterm/pkg/util/fileutil"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// files are edited in memory, larger files are skipped
+const sedMaxFileSize = 16 * 1024 * 1024
+
+// sedFile returns the number of matches in path, and unless dryRun rewrites it atomically with the replacements
+func sedFile(path string, finfo fs.FileInfo, re *regexp.Regexp, replace func([]byte) []byte, dryRun bool) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	count := len(re.FindAllIndex(content, -1))
+	if count == 0 || dryRun {
+		return count, nil
+	}
+	newContent := replace(content)
+	if bytes.Equal(newContent, content) {
+		return count, nil
+	}
+	err = writeFileAtomic(path, finfo.Mode().Perm(), func(w io.Writer) error {
+		_, err := w.Write(newContent)
+		return err
+	})
+	if err != nil {
+		return count, err
+	}
+	// the temp file belongs to us, give the edited file back to its owner where we can
+	if err := chownLikeSource(path, finfo); err != nil {
+		log.Printf("RemoteSedCommand: %v\n", err)
+	}
+	return count, nil
+}
+
+// RemoteSedCommand replaces data.Pattern in the text files under data.Path, each file is replaced
+// atomically so a failed or canceled run never leaves a partially edited file
+func (impl *ServerImpl) RemoteSedCommand(ctx context.Context, data wshrpc.CommandRemoteSedData) (*wshrpc.CommandRemoteSedRtnData, error) {
+	opts := data.Opts
+	if opts == nil {
+		opts = &wshrpc.SedOpts{}
+	}
+	if data.Pattern == "" {
+		return nil, fmt.Errorf("sed pattern is required")
+	}
+	pattern := data.Pattern
+	if opts.Regex {
+		// the whole file is matched at once, ^ and $ still anchor to lines like they do for grep
+		pattern = "(?m)" + pattern
+	}
+	re, err := makeGrepRegexp(pattern, &wshrpc.GrepOpts{Regex: opts.Regex, IgnoreCase: opts.IgnoreCase, WholeWord: opts.WholeWord})
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", data.Pattern, err)
+	}
+	replacement := []byte(data.Replacement)
+	replace := func(content []byte) []byte { return re.ReplaceAllLiteral(content, replacement) }
+	if opts.Regex {
+		replace = func(content []byte) []byte { return re.ReplaceAll(content, replacement) }
+	}
+	if opts.Glob != "" {
+		if _, err := filepath.Match(opts.Glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", opts.Glob, err)
+		}
+	}
+	rootPath, err := impl.cleanPath(data.Path)
+	if err != nil {
+		return nil, err
+	}
+	rtn := &wshrpc.CommandRemoteSedRtnData{DryRun: opts.DryRun}
+	err = filepath.WalkDir(rootPath, newWalkGuard(DefaultMaxWalkDepth).walkDirFunc(func(innerPath string, d fs.DirEntry, err error) error {
+		// checked before each file, the file being edited is always finished
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil && innerPath == rootPath {
+			return err
+		}
+		if err != nil {
+			log.Printf("RemoteSedCommand: cannot read %q: %v\n", innerPath, err)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if opts.Glob != "" {
+			if matched, _ := filepath.Match(opts.Glob, d.Name()); !matched {
+				return nil
+			}
+		}
+		finfo, err := d.Info()
+		if err != nil || finfo.Size() > sedMaxFileSize || !isTextMimeType(fileutil.DetectMimeType(innerPath, finfo, true)) {
+			rtn.SkippedCount++
+			return nil
+		}
+		count, err := sedFile(innerPath, finfo, re, replace, opts.DryRun)
+		if count == 0 {
+			if err != nil {
+				log.Printf("RemoteSedCommand: cannot read %q: %v\n", innerPath, err)
+				rtn.SkippedCount++
+			}
+			return nil
+		}
+		result := wshrpc.SedFileResult{Path: wavebase.ReplaceHomeDir(innerPath), Count: count}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			rtn.TotalCount += count
+		}
+		rtn.Files = append(rtn.Files, result)
+		return nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("cannot edit files in %q: %w", data.Path, err)
+	}
+	return rtn, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func makeSedTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "main.go"), "package main\n\nfunc Foo() {}\nfunc foobar() { Foo() }\n")
+	writeTestFile(t, filepath.Join(dir, "sub", "notes.txt"), "call foo.Bar(1+2)\nnothing here\n")
+	writeTestFile(t, filepath.Join(dir, "sub", "other.txt"), "no matches\n")
+	// binary content that happens to contain the pattern
+	if err := os.WriteFile(filepath.Join(dir, "data.bin"), append([]byte{0x00, 0x01, 0x02, 0xff}, []byte("\nfoo Foo\n")...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func sedCounts(rtn *wshrpc.CommandRemoteSedRtnData) map[string]int {
+	counts := make(map[string]int)
+	for _, file := range rtn.Files {
+		counts[filepath.Base(file.Path)] = file.Count
+	}
+	return counts
+}
+
+func TestSed(t *testing.T) {
+	dir := makeSedTree(t)
+	binContent := readTestFile(t, filepath.Join(dir, "data.bin"))
+	if err := os.Chmod(filepath.Join(dir, "main.go"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	impl := &ServerImpl{}
+	rtn, err := impl.RemoteSedCommand(context.Background(), wshrpc.CommandRemoteSedData{
+		Path:        dir,
+		Pattern:     "foo",
+		Replacement: "baz",
+		Opts:        &wshrpc.SedOpts{IgnoreCase: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := sedCounts(rtn)
+	if len(counts) != 2 || counts["main.go"] != 3 || counts["notes.txt"] != 1 || rtn.TotalCount != 4 {
+		t.Errorf("got counts %v (total %d), want main.go:3 notes.txt:1", counts, rtn.TotalCount)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "main.go")); got != "package main\n\nfunc baz() {}\nfunc bazbar() { baz() }\n" {
+		t.Errorf("main.go: got %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "sub", "notes.txt")); got != "call baz.Bar(1+2)\nnothing here\n" {
+		t.Errorf("notes.txt: got %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "data.bin")); got != binContent {
+		t.Errorf("binary file was edited: got %q", got)
+	}
+	if rtn.SkippedCount != 1 {
+		t.Errorf("got %d skipped files, want 1 (the binary file)", rtn.SkippedCount)
+	}
+	finfo, err := os.Stat(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finfo.Mode().Perm() != 0600 {
+		t.Errorf("mode: got %v, want 0600", finfo.Mode().Perm())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("expected no temp files left behind, got %d entries", len(entries))
+	}
+}
+
+func TestSed_Regex(t *testing.T) {
+	dir := makeSedTree(t)
+	impl := &ServerImpl{}
+	rtn, err := impl.RemoteSedCommand(context.Background(), wshrpc.CommandRemoteSedData{
+		Path:        dir,
+		Pattern:     `^func (\w+)\(\)`,
+		Replacement: "func ${1}Renamed()",
+		Opts:        &wshrpc.SedOpts{Regex: true, Glob: "*.go"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts := sedCounts(rtn); len(counts) != 1 || counts["main.go"] != 2 {
+		t.Errorf("got counts %v, want main.go:2", counts)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "main.go")); got != "package main\n\nfunc FooRenamed() {}\nfunc foobarRenamed() { Foo() }\n" {
+		t.Errorf("main.go: got %q", got)
+	}
+
+	// literal patterns don't expand $ in the replacement
+	rtn, err = impl.RemoteSedCommand(context.Background(), wshrpc.CommandRemoteSedData{Path: dir, Pattern: "(1+2)", Replacement: "($1)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "sub", "notes.txt")); got != "call foo.Bar($1)\nnothing here\n" || rtn.TotalCount != 1 {
+		t.Errorf("notes.txt: got %q (total %d)", got, rtn.TotalCount)
+	}
+}
+
+func TestSed_DryRun(t *testing.T) {
+	dir := makeSedTree(t)
+	before := readTestFile(t, filepath.Join(dir, "main.go"))
+	impl := &ServerImpl{}
+	rtn, err := impl.RemoteSedCommand(context.Background(), wshrpc.CommandRemoteSedData{
+		Path:        dir,
+		Pattern:     "Foo",
+		Replacement: "Baz",
+		Opts:        &wshrpc.SedOpts{DryRun: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts := sedCounts(rtn); !rtn.DryRun || len(counts) != 1 || counts["main.go"] != 2 || rtn.TotalCount != 2 {
+		t.Errorf("got counts %v (total %d, dryrun %v), want main.go:2", counts, rtn.TotalCount, rtn.DryRun)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "main.go")); got != before {
+		t.Errorf("dry run modified main.go: got %q", got)
+	}
+}
+
+func TestSed_Errors(t *testing.T) {
+	dir := makeSedTree(t)
+	impl := &ServerImpl{}
+	bad := []wshrpc.CommandRemoteSedData{
+		{Path: dir, Pattern: ""},
+		{Path: dir, Pattern: "(", Opts: &wshrpc.SedOpts{Regex: true}},
+		{Path: dir, Pattern: "foo", Opts: &wshrpc.SedOpts{Glob: "["}},
+		{Path: filepath.Join(dir, "missing"), Pattern: "foo"},
+	}
+	for _, data := range bad {
+		if _, err := impl.RemoteSedCommand(context.Background(), data); err == nil {
+			t.Errorf("expected an error for %+v", data)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	before := readTestFile(t, filepath.Join(dir, "main.go"))
+	if _, err := impl.RemoteSedCommand(ctx, wshrpc.CommandRemoteSedData{Path: dir, Pattern: "foo", Replacement: "baz"}); err == nil {
+		t.Error("expected an error for a canceled context")
+	}
+	if got := readTestFile(t, filepath.Join(dir, "main.go")); got != before {
+		t.Errorf("canceled run modified main.go: got %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build darwin
+
+package wshremote
+
+import (
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"golang.org/x/sys/unix"
+)
+
+// darwin's statfs carries the mount point, type name and mount flags, no need for the mount table
+func getStatVFS(path string) (*wshrpc.StatVFSInfo, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return nil, err
+	}
+	fsType := fsTypeName(&stat)
+	return &wshrpc.StatVFSInfo{
+		MountPoint: unix.ByteSliceToString(stat.Mntonname[:]),
+		FsType:     fsType,
+		ReadOnly:   stat.Flags&unix.MNT_RDONLY != 0,
+		Network:    stat.Flags&unix.MNT_LOCAL == 0 || networkFsTypes[fsType],
+	}, nil
+}
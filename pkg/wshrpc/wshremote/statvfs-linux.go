@@ -0,0 +1,100 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package wshremote
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"golang.org/x/sys/unix"
+)
+
+type mountInfoEntry struct {
+	MountPoint string
+	FsType     string
+	ReadOnly   bool
+}
+
+// unescapeMountField undoes the octal escapes (e.g. "\040" for a space) in mountinfo paths
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var sb strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+4 <= len(field) {
+			if code, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(field[i])
+	}
+	return sb.String()
+}
+
+// findMountInfo returns the mount holding path from a /proc/self/mountinfo table (see proc(5)), the last of the
+// longest matching mount points wins since later mounts are stacked on top of earlier ones
+func findMountInfo(r io.Reader, path string) (*mountInfoEntry, error) {
+	var rtn *mountInfoEntry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		sepIdx := slices.Index(fields, "-")
+		if sepIdx < 6 || sepIdx+1 >= len(fields) {
+			continue
+		}
+		mountPoint := unescapeMountField(fields[4])
+		if !isWithinDir(path, mountPoint) {
+			continue
+		}
+		if rtn != nil && len(mountPoint) < len(rtn.MountPoint) {
+			continue
+		}
+		rtn = &mountInfoEntry{
+			MountPoint: mountPoint,
+			FsType:     fields[sepIdx+1],
+			ReadOnly:   slices.Contains(strings.Split(fields[5], ","), "ro"),
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rtn, nil
+}
+
+func getStatVFS(path string) (*wshrpc.StatVFSInfo, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return nil, err
+	}
+	rtn := &wshrpc.StatVFSInfo{
+		FsType:   fsTypeName(&stat),
+		ReadOnly: stat.Flags&unix.ST_RDONLY != 0,
+	}
+	// statfs only has a magic number for the type, the mount table has the name (e.g. "nfs4" or "fuse.sshfs")
+	// and the mount point.  it is matched against the resolved path, mounts are listed without symlinks.
+	if resolvedPath, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolvedPath
+	}
+	if mountInfoFile, err := os.Open("/proc/self/mountinfo"); err == nil {
+		defer mountInfoFile.Close()
+		if entry, err := findMountInfo(mountInfoFile, path); err == nil && entry != nil {
+			rtn.MountPoint = entry.MountPoint
+			rtn.FsType = entry.FsType
+			rtn.ReadOnly = rtn.ReadOnly || entry.ReadOnly
+		}
+	}
+	rtn.Network = networkFsTypes[rtn.FsType]
+	return rtn, nil
+}
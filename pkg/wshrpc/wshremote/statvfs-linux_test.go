@@ -0,0 +1,53 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package wshremote
+
+import (
+	"strings"
+	"testing"
+)
+
+const testMountInfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw,errors=remount-ro
+23 22 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+40 22 0:35 / /home/user/remote rw,nosuid,nodev,relatime shared:20 - fuse.sshfs user@host:/srv rw,user_id=1000
+41 22 0:36 / /mnt/nfs\040share rw,relatime shared:21 - nfs4 server:/export rw,vers=4.2
+42 22 8:2 / /mnt/cdrom ro,relatime shared:22 - iso9660 /dev/sr0 ro
+43 42 0:37 / /mnt/cdrom rw,relatime shared:23 - tmpfs tmpfs rw
+bad line
+`
+
+func TestFindMountInfo(t *testing.T) {
+	tests := []struct {
+		path     string
+		want     string
+		fsType   string
+		readOnly bool
+	}{
+		{"/home/user/file.txt", "/", "ext4", false},
+		{"/", "/", "ext4", false},
+		{"/home/user/remote/docs/a.txt", "/home/user/remote", "fuse.sshfs", false},
+		{"/home/user/remote-not", "/", "ext4", false},
+		{"/mnt/nfs share/x", "/mnt/nfs share", "nfs4", false},
+		// the later mount is stacked over the read-only one
+		{"/mnt/cdrom/y", "/mnt/cdrom", "tmpfs", false},
+	}
+	for _, tc := range tests {
+		entry, err := findMountInfo(strings.NewReader(testMountInfo), tc.path)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.path, err)
+		}
+		if entry == nil || entry.MountPoint != tc.want || entry.FsType != tc.fsType || entry.ReadOnly != tc.readOnly {
+			t.Errorf("%q: got %+v, want mount %q type %q readonly %v", tc.path, entry, tc.want, tc.fsType, tc.readOnly)
+		}
+	}
+	readOnlyTable := "42 22 8:2 / /mnt/cdrom ro,relatime shared:22 - iso9660 /dev/sr0 ro\n"
+	if entry, _ := findMountInfo(strings.NewReader(readOnlyTable), "/mnt/cdrom/z"); entry == nil || !entry.ReadOnly {
+		t.Errorf("expected a read-only mount, got %+v", entry)
+	}
+	if entry, _ := findMountInfo(strings.NewReader(readOnlyTable), "/home"); entry != nil {
+		t.Errorf("expected no mount, got %+v", entry)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows
+
+package wshremote
+
+import (
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"golang.org/x/sys/windows"
+)
+
+func getStatVFS(path string) (*wshrpc.StatVFSInfo, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	volumePath := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(pathPtr, &volumePath[0], uint32(len(volumePath))); err != nil {
+		return nil, err
+	}
+	var fsFlags uint32
+	fsName := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumeInformation(&volumePath[0], nil, 0, nil, nil, &fsFlags, &fsName[0], uint32(len(fsName))); err != nil {
+		return nil, err
+	}
+	return &wshrpc.StatVFSInfo{
+		MountPoint: windows.UTF16ToString(volumePath),
+		FsType:     windows.UTF16ToString(fsName),
+		ReadOnly:   fsFlags&windows.FILE_READ_ONLY_VOLUME != 0,
+		// mapped drives and unc shares both report as remote
+		Network: windows.GetDriveType(&volumePath[0]) == windows.DRIVE_REMOTE,
+	}, nil
+}
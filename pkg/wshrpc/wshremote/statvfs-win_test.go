@@ -0,0 +1,34 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows
+
+package wshremote
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatVFS(t *testing.T) {
+	impl := &ServerImpl{}
+	dir := t.TempDir()
+	info, err := impl.RemoteStatVFSCommand(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("RemoteStatVFSCommand: %v", err)
+	}
+	if info.FsType == "" || info.MountPoint == "" {
+		t.Errorf("expected a filesystem type and volume, got %+v", info)
+	}
+	if info.ReadOnly || info.Network {
+		t.Errorf("temp dir reported as read-only or remote: %+v", info)
+	}
+	missing, err := impl.RemoteStatVFSCommand(context.Background(), filepath.Join(dir, "new", "file.txt"))
+	if err != nil {
+		t.Fatalf("RemoteStatVFSCommand for a missing path: %v", err)
+	}
+	if missing.Path != info.Path || missing.FsType != info.FsType {
+		t.Errorf("missing path: got %+v, want the temp dir's %+v", missing, info)
+	}
+}
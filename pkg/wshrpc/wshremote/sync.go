@@ -0,0 +1,283 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
+	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/wshfs"
+	"github.com/wavetermdev/waveterm/pkg/util/iterfn"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
+	"github.com/wavetermdev/waveterm/pkg/wshutil"
+)
+
+// collectManifest reads a whole manifest stream, failing unless it completed (the root is always the last entry)
+func collectManifest(ch chan wshrpc.RespOrErrorUnion[wshrpc.ManifestEntry]) (map[string]wshrpc.ManifestEntry, error) {
+	entries := make(map[string]wshrpc.ManifestEntry)
+	var err error
+	for resp := range ch {
+		// keep draining after an error so the sender is never left blocked
+		if resp.Error != nil && err == nil {
+			err = resp.Error
+		}
+		if err == nil {
+			entries[resp.Response.Path] = resp.Response
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := entries["."]; !ok {
+		return nil, fmt.Errorf("manifest ended early")
+	}
+	return entries, nil
+}
+
+func manifestKind(entry wshrpc.ManifestEntry) string {
+	if entry.IsDir {
+		return "dir"
+	}
+	if entry.LinkTarget != "" {
+		return "link"
+	}
+	return "file"
+}
+
+// syncFileChanged compares two file entries the way rsync does, by size and mtime unless checksum is set
+func syncFileChanged(src wshrpc.ManifestEntry, dest wshrpc.ManifestEntry, checksum bool) bool {
+	if src.Size != dest.Size || src.Mode.Perm() != dest.Mode.Perm() {
+		return true
+	}
+	if checksum {
+		return src.Sha256 != dest.Sha256
+	}
+	return src.ModTime != dest.ModTime
+}
+
+// childConn returns conn with relPath (slash separated) appended to its path
+func childConn(conn *connparse.Connection, relPath string) *connparse.Connection {
+	return &connparse.Connection{Scheme: conn.Scheme, Host: conn.Host, Path: path.Join(conn.Path, relPath)}
+}
+
+// RemoteSyncCommand makes the destination directory match the source directory, only copying the files that differ
+// between their manifests.  identical files are left untouched, with opts.Delete extra destination entries are removed.
+func (impl *ServerImpl) RemoteSyncCommand(ctx context.Context, data wshrpc.CommandRemoteSyncData) chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteSyncProgress] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteSyncProgress], 16)
+	go func() {
+		defer close(ch)
+		send := func(progress wshrpc.CommandRemoteSyncProgress) {
+			select {
+			case ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteSyncProgress]{Response: progress}:
+			case <-ctx.Done():
+			}
+		}
+		rtn, err := impl.remoteSyncInternal(ctx, data, send)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteSyncProgress](err)
+			return
+		}
+		send(*rtn)
+	}()
+	return ch
+}
+
+func (impl *ServerImpl) remoteSyncInternal(ctx context.Context, data wshrpc.CommandRemoteSyncData, progressCallback func(wshrpc.CommandRemoteSyncProgress)) (*wshrpc.CommandRemoteSyncProgress, error) {
+	log.Printf("RemoteSyncCommand: src=%s, dest=%s\n", data.SrcUri, data.DestUri)
+	opts := data.Opts
+	if opts == nil {
+		opts = &wshrpc.SyncOpts{}
+	}
+	srcConn, err := connparse.ParseURIAndReplaceCurrentHost(ctx, data.SrcUri)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse source URI %q: %w", data.SrcUri, err)
+	}
+	destConn, err := connparse.ParseURIAndReplaceCurrentHost(ctx, data.DestUri)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse destination URI %q: %w", data.DestUri, err)
+	}
+	destPath, err := impl.cleanPath(destConn.Path)
+	if err != nil {
+		return nil, err
+	}
+	manifestOpts := &wshrpc.ManifestOpts{Hash: opts.Checksum, ExcludePatterns: opts.ExcludePatterns}
+	srcManifestData := wshrpc.CommandRemoteManifestData{Path: srcConn.Path, Opts: manifestOpts}
+	var srcCh chan wshrpc.RespOrErrorUnion[wshrpc.ManifestEntry]
+	if srcConn.Host == destConn.Host {
+		srcCh = impl.RemoteManifestCommand(ctx, srcManifestData)
+	} else {
+		srcCh = wshclient.RemoteManifestCommand(wshfs.RpcClient, srcManifestData, &wshrpc.RpcOpts{Route: wshutil.MakeConnectionRouteId(srcConn.Host)})
+	}
+	srcEntries, err := collectManifest(srcCh)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read source %q: %w", data.SrcUri, err)
+	}
+	destEntries := make(map[string]wshrpc.ManifestEntry)
+	destInfo, err := os.Lstat(destPath)
+	if err == nil {
+		if !destInfo.IsDir() {
+			return nil, fmt.Errorf("cannot sync to %q: not a directory", data.DestUri)
+		}
+		destEntries, err = collectManifest(impl.RemoteManifestCommand(ctx, wshrpc.CommandRemoteManifestData{Path: destPath, Opts: manifestOpts}))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read destination %q: %w", data.DestUri, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot stat destination %q: %w", data.DestUri, err)
+	} else if !opts.DryRun {
+		if err := os.MkdirAll(destPath, srcEntries["."].Mode.Perm()); err != nil {
+			return nil, fmt.Errorf("cannot create directory %q: %w", data.DestUri, err)
+		}
+	}
+
+	rtn := &wshrpc.CommandRemoteSyncProgress{Done: true, DryRun: opts.DryRun}
+	report := func(relPath string, action string) {
+		if action == wshrpc.SyncAction_Copy || action == wshrpc.SyncAction_Link {
+			rtn.FilesDone++
+		}
+		if progressCallback != nil {
+			progressCallback(wshrpc.CommandRemoteSyncProgress{Path: relPath, Action: action, FilesDone: rtn.FilesDone, BytesDone: rtn.BytesDone})
+		}
+	}
+	destFullPath := func(relPath string) string {
+		return filepath.Join(destPath, filepath.FromSlash(relPath))
+	}
+	removeDest := func(relPath string) error {
+		if !opts.DryRun {
+			if err := os.RemoveAll(destFullPath(relPath)); err != nil {
+				return fmt.Errorf("cannot delete %q: %w", destFullPath(relPath), err)
+			}
+		}
+		isDir := destEntries[relPath].IsDir
+		delete(destEntries, relPath)
+		if isDir {
+			for destRelPath := range destEntries {
+				if strings.HasPrefix(destRelPath, relPath+"/") {
+					delete(destEntries, destRelPath)
+				}
+			}
+		}
+		rtn.Deleted++
+		report(relPath, wshrpc.SyncAction_Delete)
+		return nil
+	}
+
+	if opts.Delete {
+		for _, relPath := range iterfn.MapKeysToSorted(destEntries) {
+			if ctx.Err() != nil {
+				return nil, context.Cause(ctx)
+			}
+			if _, ok := destEntries[relPath]; !ok || relPath == "." {
+				continue
+			}
+			if _, ok := srcEntries[relPath]; !ok {
+				if err := removeDest(relPath); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// directory modes are set once their entries are written, a read-only directory could not be filled otherwise
+	// (and new directories would get the mode filtered through the umask)
+	var dirModePaths []string
+	// sorted, so every directory is handled before its entries
+	for _, relPath := range iterfn.MapKeysToSorted(srcEntries) {
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+		if relPath == "." {
+			continue
+		}
+		srcEntry := srcEntries[relPath]
+		destEntry, exists := destEntries[relPath]
+		if exists && manifestKind(destEntry) != manifestKind(srcEntry) {
+			// a file that became a directory (or the other way around), the old entry has to go first
+			if err := removeDest(relPath); err != nil {
+				return nil, err
+			}
+			exists = false
+		}
+		fullPath := destFullPath(relPath)
+		switch manifestKind(srcEntry) {
+		case "dir":
+			if !exists {
+				if !opts.DryRun {
+					if err := os.Mkdir(fullPath, 0700); err != nil {
+						return nil, fmt.Errorf("cannot create directory %q: %w", fullPath, err)
+					}
+				}
+				dirModePaths = append(dirModePaths, relPath)
+				report(relPath, wshrpc.SyncAction_Mkdir)
+			} else if destEntry.Mode.Perm() != srcEntry.Mode.Perm() {
+				dirModePaths = append(dirModePaths, relPath)
+				report(relPath, wshrpc.SyncAction_Chmod)
+			}
+		case "link":
+			if exists && destEntry.LinkTarget == srcEntry.LinkTarget {
+				rtn.Unchanged++
+				continue
+			}
+			if !opts.DryRun {
+				// the target is taken from the manifest as is, so the link works on any host
+				resolvedTarget := srcEntry.LinkTarget
+				if !filepath.IsAbs(resolvedTarget) {
+					resolvedTarget = filepath.Join(filepath.Dir(fullPath), resolvedTarget)
+				}
+				if err := impl.checkJail(resolvedTarget); err != nil {
+					return nil, err
+				}
+				if exists {
+					if err := os.Remove(fullPath); err != nil {
+						return nil, fmt.Errorf("cannot replace symlink %q: %w", fullPath, err)
+					}
+				}
+				if err := os.Symlink(srcEntry.LinkTarget, fullPath); err != nil {
+					return nil, fmt.Errorf("cannot create symlink %q: %w", fullPath, err)
+				}
+			}
+			report(relPath, wshrpc.SyncAction_Link)
+		default:
+			if exists && !syncFileChanged(srcEntry, destEntry, opts.Checksum) {
+				rtn.Unchanged++
+				continue
+			}
+			if !opts.DryRun {
+				bytesBefore := rtn.BytesDone
+				copyData := wshrpc.CommandFileCopyData{
+					SrcUri:  childConn(srcConn, relPath).GetFullURI(),
+					DestUri: childConn(destConn, relPath).GetFullURI(),
+					// the times are kept so an unchanged file compares equal by mtime on the next sync
+					Opts: &wshrpc.FileCopyOpts{Overwrite: true, PreserveTimestamps: true, PreserveMode: true, Timeout: opts.Timeout},
+				}
+				_, err := impl.remoteFileCopyInternal(ctx, copyData, func(progress wshrpc.CommandRemoteFileCopyProgress) {
+					if progressCallback != nil {
+						progressCallback(wshrpc.CommandRemoteSyncProgress{Path: relPath, FilesDone: rtn.FilesDone, BytesDone: bytesBefore + progress.BytesDone})
+					}
+				}, nil)
+				if err != nil {
+					return nil, err
+				}
+			}
+			rtn.BytesDone += srcEntry.Size
+			report(relPath, wshrpc.SyncAction_Copy)
+		}
+	}
+	if !opts.DryRun {
+		for _, relPath := range slices.Backward(dirModePaths) {
+			if err := os.Chmod(destFullPath(relPath), srcEntries[relPath].Mode.Perm()); err != nil {
+				return nil, fmt.Errorf("cannot chmod directory %q: %w", destFullPath(relPath), err)
+			}
+		}
+	}
+	return rtn, nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func runSync(t *testing.T, src string, dest string, opts *wshrpc.SyncOpts) (*wshrpc.CommandRemoteSyncProgress, []wshrpc.CommandRemoteSyncProgress) {
+	t.Helper()
+	var progress []wshrpc.CommandRemoteSyncProgress
+	for resp := range (&ServerImpl{}).RemoteSyncCommand(context.Background(), wshrpc.CommandRemoteSyncData{SrcUri: "wsh://local/" + src, DestUri: "wsh://local/" + dest, Opts: opts}) {
+		if resp.Error != nil {
+			t.Fatalf("sync: %v", resp.Error)
+		}
+		progress = append(progress, resp.Response)
+	}
+	if len(progress) == 0 || !progress[len(progress)-1].Done {
+		t.Fatal("sync did not finish")
+	}
+	return &progress[len(progress)-1], progress[:len(progress)-1]
+}
+
+// assertConverged checks that dest has the same entries as src, with the same content, permissions and file mtimes
+func assertConverged(t *testing.T, src string, dest string, opts *wshrpc.ManifestOpts) {
+	t.Helper()
+	srcEntries, err := runManifest(t, context.Background(), src, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	destEntries, err := runManifest(t, context.Background(), dest, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, srcEntry := range srcEntries {
+		destEntry, ok := destEntries[path]
+		if !ok {
+			t.Errorf("%q: missing from the destination", path)
+			continue
+		}
+		if manifestKind(srcEntry) != manifestKind(destEntry) || srcEntry.Sha256 != destEntry.Sha256 || srcEntry.LinkTarget != destEntry.LinkTarget {
+			t.Errorf("%q: got %+v, want %+v", path, destEntry, srcEntry)
+		}
+		if path != "." && srcEntry.Mode.Perm() != destEntry.Mode.Perm() {
+			t.Errorf("%q: got mode %v, want %v", path, destEntry.Mode, srcEntry.Mode)
+		}
+		if manifestKind(srcEntry) == "file" && (srcEntry.ModTime != destEntry.ModTime || srcEntry.Size != destEntry.Size) {
+			t.Errorf("%q: got size %d mtime %d, want size %d mtime %d", path, destEntry.Size, destEntry.ModTime, srcEntry.Size, srcEntry.ModTime)
+		}
+	}
+	for path := range destEntries {
+		if _, ok := srcEntries[path]; !ok {
+			t.Errorf("%q: extra entry in the destination", path)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	dest := filepath.Join(root, "dest")
+	writeTestFile(t, filepath.Join(src, "a.txt"), "aaa\n")
+	writeTestFile(t, filepath.Join(src, "dir", "b.txt"), "bbb\n")
+	writeTestFile(t, filepath.Join(src, "dir", "sub", "c.txt"), "ccc\n")
+	writeTestFile(t, filepath.Join(src, "becomes-dir"), "file for now\n")
+	writeTestFile(t, filepath.Join(src, "becomes-file", "x.txt"), "dir for now\n")
+	writeTestFile(t, filepath.Join(src, "readonly", "r.txt"), "rrr\n")
+	if err := os.Symlink("a.txt", filepath.Join(src, "link")); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(src, "readonly"), 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chmod(filepath.Join(src, "readonly"), 0755)
+		os.Chmod(filepath.Join(dest, "readonly"), 0755)
+	})
+	hashOpts := &wshrpc.ManifestOpts{Hash: true}
+
+	// the destination doesn't exist yet
+	rtn, progress := runSync(t, src, dest, &wshrpc.SyncOpts{Delete: true})
+	assertConverged(t, src, dest, hashOpts)
+	if rtn.FilesDone != 7 || rtn.Unchanged != 0 || rtn.Deleted != 0 {
+		t.Errorf("first sync: got %+v", rtn)
+	}
+	if len(progress) < rtn.FilesDone {
+		t.Errorf("expected progress for every copied file, got %d responses", len(progress))
+	}
+
+	// nothing changed, nothing is copied
+	rtn, _ = runSync(t, src, dest, &wshrpc.SyncOpts{Delete: true})
+	if rtn.FilesDone != 0 || rtn.Unchanged != 7 || rtn.Deleted != 0 {
+		t.Errorf("second sync: got %+v", rtn)
+	}
+
+	// change, add and remove entries, and swap a file and a directory
+	writeTestFile(t, filepath.Join(src, "a.txt"), "a changed\n")
+	writeTestFile(t, filepath.Join(src, "dir", "new.txt"), "new\n")
+	if err := os.RemoveAll(filepath.Join(src, "dir", "sub")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(src, "becomes-dir")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(src, "becomes-dir", "y.txt"), "now a dir\n")
+	if err := os.RemoveAll(filepath.Join(src, "becomes-file")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(src, "becomes-file"), "now a file\n")
+	if err := os.Remove(filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("dir/b.txt", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dest, "extra.txt"), "only in dest\n")
+	writeTestFile(t, filepath.Join(dest, "keep.log"), "excluded\n")
+
+	// a dry run reports the changes without making them
+	rtn, _ = runSync(t, src, dest, &wshrpc.SyncOpts{Delete: true, DryRun: true, ExcludePatterns: []string{"*.log"}})
+	if !rtn.DryRun || rtn.FilesDone == 0 || rtn.Deleted == 0 {
+		t.Errorf("dry run: got %+v", rtn)
+	}
+	if content, err := os.ReadFile(filepath.Join(dest, "a.txt")); err != nil || string(content) != "aaa\n" {
+		t.Errorf("dry run changed a.txt: %q %v", content, err)
+	}
+
+	// without Delete the extra file stays
+	runSync(t, src, dest, &wshrpc.SyncOpts{ExcludePatterns: []string{"*.log"}})
+	if _, err := os.Stat(filepath.Join(dest, "extra.txt")); err != nil {
+		t.Errorf("extra file was deleted without Delete: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "dir", "sub", "c.txt")); err != nil {
+		t.Errorf("removed file was deleted without Delete: %v", err)
+	}
+	rtn, _ = runSync(t, src, dest, &wshrpc.SyncOpts{Delete: true, ExcludePatterns: []string{"*.log"}})
+	if rtn.Deleted != 2 {
+		t.Errorf("expected extra.txt and dir/sub to be deleted, got %+v", rtn)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "keep.log")); err != nil {
+		t.Errorf("excluded file was deleted: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dest, "keep.log")); err != nil {
+		t.Fatal(err)
+	}
+	assertConverged(t, src, dest, hashOpts)
+
+	// a rewrite that keeps the size and mtime is only caught with Checksum
+	aInfo, err := os.Stat(filepath.Join(src, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(src, "a.txt"), "A CHANGED\n")
+	if err := os.Chtimes(filepath.Join(src, "a.txt"), aInfo.ModTime(), aInfo.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if rtn, _ = runSync(t, src, dest, nil); rtn.FilesDone != 0 {
+		t.Errorf("expected no copies comparing by size and mtime, got %+v", rtn)
+	}
+	if rtn, _ = runSync(t, src, dest, &wshrpc.SyncOpts{Checksum: true}); rtn.FilesDone != 1 {
+		t.Errorf("expected one copy comparing by checksum, got %+v", rtn)
+	}
+	assertConverged(t, src, dest, hashOpts)
+}
+
+func TestSync_Errors(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "src", "a.txt"), "aaa\n")
+	writeTestFile(t, filepath.Join(root, "file"), "not a dir\n")
+	tests := []struct {
+		name string
+		data wshrpc.CommandRemoteSyncData
+	}{
+		{"missing source", wshrpc.CommandRemoteSyncData{SrcUri: "wsh://local/" + filepath.Join(root, "missing"), DestUri: "wsh://local/" + filepath.Join(root, "dest")}},
+		{"file source", wshrpc.CommandRemoteSyncData{SrcUri: "wsh://local/" + filepath.Join(root, "file"), DestUri: "wsh://local/" + filepath.Join(root, "dest")}},
+		{"file destination", wshrpc.CommandRemoteSyncData{SrcUri: "wsh://local/" + filepath.Join(root, "src"), DestUri: "wsh://local/" + filepath.Join(root, "file")}},
+	}
+	for _, tc := range tests {
+		var gotErr bool
+		for resp := range (&ServerImpl{}).RemoteSyncCommand(context.Background(), tc.data) {
+			if resp.Error != nil {
+				gotErr = true
+			}
+		}
+		if !gotErr {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
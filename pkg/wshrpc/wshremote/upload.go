@@ -0,0 +1,155 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wavetermdev/waveterm/pkg/util/iochan"
+	"github.com/wavetermdev/waveterm/pkg/util/iochan/iochantypes"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+// uploads that don't receive a write or close for this long are aborted
+const uploadIdleTimeout = 5 * time.Minute
+
+type fileUpload struct {
+	path      string
+	ch        chan wshrpc.RespOrErrorUnion[iochantypes.Packet]
+	done      chan error
+	cancel    context.CancelCauseFunc
+	idleTimer *time.Timer
+}
+
+var uploadsLock sync.Mutex
+var uploads = make(map[string]*fileUpload)
+
+// writeFileFromChan writes the packets from ch to path, verifying the final checksum packet.
+// The data is written to a temp file which only replaces path once the whole stream was received and verified.
+func writeFileFromChan(ctx context.Context, path string, createMode os.FileMode, ch <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet]) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("cannot create directory %q: %w", filepath.Dir(path), err)
+	}
+	return writeFileAtomic(path, createMode, func(w io.Writer) error {
+		writeCtx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		done := make(chan struct{})
+		iochan.WriterChan(writeCtx, w, ch, func(int64) {
+			close(done)
+		}, cancel)
+		<-done
+		return context.Cause(writeCtx)
+	})
+}
+
+func getUpload(uploadId string) (*fileUpload, error) {
+	uploadsLock.Lock()
+	defer uploadsLock.Unlock()
+	upload := uploads[uploadId]
+	if upload == nil {
+		return nil, fmt.Errorf("upload %q not found", uploadId)
+	}
+	return upload, nil
+}
+
+func removeUpload(uploadId string) *fileUpload {
+	uploadsLock.Lock()
+	defer uploadsLock.Unlock()
+	upload := uploads[uploadId]
+	delete(uploads, uploadId)
+	return upload
+}
+
+// RemoteFileUploadOpenCommand starts a chunked upload to data.Path, returning the upload id used for writes and the final close
+func (impl *ServerImpl) RemoteFileUploadOpenCommand(ctx context.Context, data wshrpc.CommandRemoteFileUploadOpenData) (string, error) {
+	path, err := impl.cleanPath(data.Path)
+	if err != nil {
+		return "", err
+	}
+	createMode := os.FileMode(0644)
+	if data.Mode > 0 {
+		createMode = data.Mode
+	}
+	uploadId := uuid.New().String()
+	// the upload outlives this rpc call, so it gets its own context
+	uploadCtx, cancel := context.WithCancelCause(context.Background())
+	upload := &fileUpload{
+		path:   path,
+		ch:     make(chan wshrpc.RespOrErrorUnion[iochantypes.Packet], 32),
+		done:   make(chan error, 1),
+		cancel: cancel,
+	}
+	upload.idleTimer = time.AfterFunc(uploadIdleTimeout, func() {
+		if removeUpload(uploadId) != nil {
+			cancel(fmt.Errorf("upload %q timed out", uploadId))
+		}
+	})
+	uploadsLock.Lock()
+	uploads[uploadId] = upload
+	uploadsLock.Unlock()
+	go func() {
+		upload.done <- writeFileFromChan(uploadCtx, upload.path, createMode, upload.ch)
+	}()
+	return uploadId, nil
+}
+
+// RemoteFileUploadWriteCommand appends a chunk of data to an open upload
+func (impl *ServerImpl) RemoteFileUploadWriteCommand(ctx context.Context, data wshrpc.CommandRemoteFileUploadWriteData) error {
+	if data.Packet.Checksum != nil {
+		return fmt.Errorf("upload checksum must be sent on close")
+	}
+	upload, err := getUpload(data.UploadId)
+	if err != nil {
+		return err
+	}
+	upload.idleTimer.Reset(uploadIdleTimeout)
+	select {
+	case upload.ch <- wshrpc.RespOrErrorUnion[iochantypes.Packet]{Response: data.Packet}:
+		return nil
+	case err := <-upload.done:
+		// the writer failed (and won't read any more packets), keep the error for close
+		upload.done <- err
+		return fmt.Errorf("cannot write upload %q: %w", data.UploadId, err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RemoteFileUploadCloseCommand finishes (or cancels) an upload, the destination is only replaced if the checksum matches
+func (impl *ServerImpl) RemoteFileUploadCloseCommand(ctx context.Context, data wshrpc.CommandRemoteFileUploadCloseData) (*wshrpc.FileInfo, error) {
+	upload := removeUpload(data.UploadId)
+	if upload == nil {
+		return nil, fmt.Errorf("upload %q not found", data.UploadId)
+	}
+	upload.idleTimer.Stop()
+	defer upload.cancel(nil)
+	if data.Cancel || len(data.Checksum) == 0 {
+		upload.cancel(errors.New("upload canceled"))
+		close(upload.ch)
+		<-upload.done
+		if data.Cancel {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot close upload %q: checksum is required", data.UploadId)
+	}
+	select {
+	case upload.ch <- wshrpc.RespOrErrorUnion[iochantypes.Packet]{Response: iochantypes.Packet{Checksum: data.Checksum}}:
+	case err := <-upload.done:
+		close(upload.ch)
+		return nil, fmt.Errorf("cannot write upload %q: %w", data.UploadId, err)
+	}
+	close(upload.ch)
+	if err := <-upload.done; err != nil {
+		return nil, fmt.Errorf("cannot write upload %q: %w", data.UploadId, err)
+	}
+	return impl.fileInfoInternal(upload.path, false)
+}
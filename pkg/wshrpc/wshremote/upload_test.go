@@ -0,0 +1,107 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/util/iochan"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func makeUploadData() []byte {
+	data := make([]byte, 3*wshrpc.FileChunkSize+1234)
+	for i := range data {
+		data[i] = byte(i % 253)
+	}
+	return data
+}
+
+// uploadChunks opens an upload and writes data in FileChunkSize packets
+func uploadChunks(t *testing.T, impl *ServerImpl, path string, data []byte) string {
+	t.Helper()
+	uploadId, err := impl.RemoteFileUploadOpenCommand(context.Background(), wshrpc.CommandRemoteFileUploadOpenData{Path: path})
+	if err != nil {
+		t.Fatalf("RemoteFileUploadOpenCommand: %v", err)
+	}
+	for resp := range iochan.ReaderChanWithOpts(context.Background(), bytes.NewReader(data), iochan.ReaderChanOpts{ChunkSize: wshrpc.FileChunkSize, NoStreamChecksum: true}, func() {}) {
+		if resp.Error != nil {
+			t.Fatal(resp.Error)
+		}
+		err := impl.RemoteFileUploadWriteCommand(context.Background(), wshrpc.CommandRemoteFileUploadWriteData{UploadId: uploadId, Packet: resp.Response})
+		if err != nil {
+			t.Fatalf("RemoteFileUploadWriteCommand: %v", err)
+		}
+	}
+	return uploadId
+}
+
+func TestFileUpload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "upload.bin")
+	data := makeUploadData()
+	impl := &ServerImpl{}
+	uploadId := uploadChunks(t, impl, path, data)
+	checksum := sha256.Sum256(data)
+	finfo, err := impl.RemoteFileUploadCloseCommand(context.Background(), wshrpc.CommandRemoteFileUploadCloseData{UploadId: uploadId, Checksum: checksum[:]})
+	if err != nil {
+		t.Fatalf("RemoteFileUploadCloseCommand: %v", err)
+	}
+	if finfo.Size != int64(len(data)) {
+		t.Errorf("size: got %d, want %d", finfo.Size, len(data))
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("uploaded content does not match")
+	}
+	if _, err := impl.RemoteFileUploadCloseCommand(context.Background(), wshrpc.CommandRemoteFileUploadCloseData{UploadId: uploadId, Checksum: checksum[:]}); err == nil {
+		t.Errorf("expected error closing an upload twice")
+	}
+}
+
+func TestFileUpload_BadChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upload.bin")
+	if err := os.WriteFile(path, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	impl := &ServerImpl{}
+	uploadId := uploadChunks(t, impl, path, makeUploadData())
+	badChecksum := sha256.Sum256([]byte("something else"))
+	_, err := impl.RemoteFileUploadCloseCommand(context.Background(), wshrpc.CommandRemoteFileUploadCloseData{UploadId: uploadId, Checksum: badChecksum[:]})
+	if !errors.Is(err, iochan.ErrChecksumMismatch) {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "original" {
+		t.Errorf("destination modified by failed upload: got %d bytes", len(got))
+	}
+}
+
+func TestFileUpload_Cancel(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "upload.bin")
+	impl := &ServerImpl{}
+	uploadId := uploadChunks(t, impl, path, makeUploadData())
+	if _, err := impl.RemoteFileUploadCloseCommand(context.Background(), wshrpc.CommandRemoteFileUploadCloseData{UploadId: uploadId, Cancel: true}); err != nil {
+		t.Fatalf("RemoteFileUploadCloseCommand: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no files after canceling, got %d", len(entries))
+	}
+}
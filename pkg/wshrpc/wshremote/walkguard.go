@@ -0,0 +1,102 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// directories nested deeper than this (below the walk root) are not descended into
+const DefaultMaxWalkDepth = 256
+
+var errWalkCycle = errors.New("directory cycle detected")
+var errWalkMaxDepth = errors.New("max walk depth exceeded")
+
+type walkAncestor struct {
+	path string
+	info fs.FileInfo
+}
+
+// walkGuard protects recursive walks against directory cycles (symlinks followed back to an ancestor, bind mounts) and runaway depth.
+// directories are compared with os.SameFile (dev+ino on unix) against the ancestors of the current path, so the same
+// directory reached twice through unrelated paths is not a cycle.
+type walkGuard struct {
+	maxDepth  int
+	ancestors []walkAncestor
+}
+
+func newWalkGuard(maxDepth int) *walkGuard {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxWalkDepth
+	}
+	return &walkGuard{maxDepth: maxDepth}
+}
+
+func isWalkAncestor(dir string, path string) bool {
+	if dir == "." {
+		return path != "."
+	}
+	prefix := dir
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, prefix)
+}
+
+// enterDir must be called for every directory in walk order (parents before children).
+// it returns an error wrapping errWalkCycle or errWalkMaxDepth if the directory must not be descended into.
+func (g *walkGuard) enterDir(path string, info fs.FileInfo) error {
+	for len(g.ancestors) > 0 && !isWalkAncestor(g.ancestors[len(g.ancestors)-1].path, path) {
+		g.ancestors = g.ancestors[:len(g.ancestors)-1]
+	}
+	if len(g.ancestors) > g.maxDepth {
+		return fmt.Errorf("%w: %q is more than %d levels deep", errWalkMaxDepth, path, g.maxDepth)
+	}
+	for _, ancestor := range g.ancestors {
+		if os.SameFile(ancestor.info, info) {
+			return fmt.Errorf("%w: %q is the same directory as %q", errWalkCycle, path, ancestor.path)
+		}
+	}
+	g.ancestors = append(g.ancestors, walkAncestor{path: path, info: info})
+	return nil
+}
+
+// walkDirFunc guards a WalkDir walk (listing, disk usage, grep), offending directories are logged and skipped
+func (g *walkGuard) walkDirFunc(fn fs.WalkDirFunc) fs.WalkDirFunc {
+	return func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d == nil || !d.IsDir() {
+			return fn(path, d, err)
+		}
+		info, infoErr := d.Info()
+		if infoErr != nil {
+			return fn(path, d, infoErr)
+		}
+		// fs.WalkDir paths are slash separated
+		if guardErr := g.enterDir(filepath.FromSlash(path), info); guardErr != nil {
+			log.Printf("skipping directory: %v\n", guardErr)
+			return fs.SkipDir
+		}
+		return fn(path, d, nil)
+	}
+}
+
+// walkFunc guards a filepath.Walk walk (copies, tar streams).  offending directories fail the walk, since a
+// silently incomplete copy is worse than an error.
+func (g *walkGuard) walkFunc(fn filepath.WalkFunc) filepath.WalkFunc {
+	return func(path string, info fs.FileInfo, err error) error {
+		if err != nil || info == nil || !info.IsDir() {
+			return fn(path, info, err)
+		}
+		if guardErr := g.enterDir(path, info); guardErr != nil {
+			return guardErr
+		}
+		return fn(path, info, nil)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWalkGuard_SymlinkToAncestor(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(root, "a", "b", "file.txt"), "x")
+	if err := os.Symlink(root, filepath.Join(root, "a", "b", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := walkWithSymlinks(root, true, func(path string, info fs.FileInfo, err error) error {
+		return err
+	})
+	if !errors.Is(err, errWalkCycle) {
+		t.Fatalf("expected cycle error following a link to the root, got %v", err)
+	}
+
+	// without following, the link is just an entry
+	err = walkWithSymlinks(root, false, func(path string, info fs.FileInfo, err error) error {
+		return err
+	})
+	if err != nil {
+		t.Fatalf("walk without following symlinks: %v", err)
+	}
+}
+
+func TestWalkGuard_IndirectCycle(t *testing.T) {
+	// x/to-y -> y and y/to-x -> x, neither link points at one of its own ancestors
+	root := t.TempDir()
+	for _, dir := range []string{"x", "y"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("../y", filepath.Join(root, "x", "to-y")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../x", filepath.Join(root, "y", "to-x")); err != nil {
+		t.Fatal(err)
+	}
+	err := walkWithSymlinks(filepath.Join(root, "x"), true, func(path string, info fs.FileInfo, err error) error {
+		return err
+	})
+	if !errors.Is(err, errWalkCycle) {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+}
+
+func TestWalkGuard_MaxDepth(t *testing.T) {
+	root := t.TempDir()
+	deep := filepath.Join(root, "1", "2", "3", "4")
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(deep, "file.txt"), "x")
+
+	var seen []string
+	err := filepath.WalkDir(root, newWalkGuard(2).walkDirFunc(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		seen = append(seen, filepath.ToSlash(rel))
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("WalkDir: %v", err)
+	}
+	if got := strings.Join(seen, ","); got != ".,1,1/2" {
+		t.Errorf("expected walk to stop at depth 2, got %s", got)
+	}
+
+	err = filepath.Walk(root, newWalkGuard(2).walkFunc(func(path string, info fs.FileInfo, err error) error {
+		return err
+	}))
+	if !errors.Is(err, errWalkMaxDepth) {
+		t.Errorf("expected max depth error, got %v", err)
+	}
+}
+
+func TestWalkGuard_SiblingsAreNotCycles(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a/x", "a/y", "b"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the same directory reached twice through unrelated links is fine
+	if err := os.Symlink("../../b", filepath.Join(root, "a", "x", "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../b", filepath.Join(root, "a", "y", "link")); err != nil {
+		t.Fatal(err)
+	}
+	err := walkWithSymlinks(filepath.Join(root, "a"), true, func(path string, info fs.FileInfo, err error) error {
+		return err
+	})
+	if err != nil {
+		t.Fatalf("expected no cycle, got %v", err)
+	}
+}
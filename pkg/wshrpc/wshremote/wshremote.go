@@ -5,16 +5,28 @@ package wshremote
 
 import (
 	"archive/tar"
+	"bufio"
+	"bytes"
+	"cmp"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/wavetermdev/waveterm/pkg/remote/connparse"
@@ -22,9 +34,11 @@ import (
 	"github.com/wavetermdev/waveterm/pkg/remote/fileshare/wshfs"
 	"github.com/wavetermdev/waveterm/pkg/suggestion"
 	"github.com/wavetermdev/waveterm/pkg/util/fileutil"
+	"github.com/wavetermdev/waveterm/pkg/util/iochan"
 	"github.com/wavetermdev/waveterm/pkg/util/iochan/iochantypes"
 	"github.com/wavetermdev/waveterm/pkg/util/tarcopy"
 	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/util/zipcopy"
 	"github.com/wavetermdev/waveterm/pkg/wavebase"
 	"github.com/wavetermdev/waveterm/pkg/wshrpc"
 	"github.com/wavetermdev/waveterm/pkg/wshrpc/wshclient"
@@ -33,6 +47,12 @@ import (
 
 type ServerImpl struct {
 	LogWriter io.Writer
+
+	// when the permission check can't tell whether a path is read-only, find out by trying to write to it (creates a temp file in directories)
+	ProbeReadOnly bool
+
+	// when set, the file commands refuse paths that resolve (through ".." or symlinks) outside of this directory
+	JailRoot string
 }
 
 func (*ServerImpl) WshServerImpl() {}
@@ -67,27 +87,96 @@ func (impl *ServerImpl) StreamTestCommand(ctx context.Context) chan wshrpc.RespO
 }
 
 type ByteRangeType struct {
-	All   bool
-	Start int64
-	End   int64
+	All       bool
+	Start     int64
+	End       int64 // exclusive, ByteRangeEOF means read until the end
+	SuffixLen int64 // set for "-N" ranges, Start/End are filled in by Resolve once the size is known
 }
 
+// ByteRangeEOF is used as the End of an open-ended byte range ("start-" or "-N")
+const ByteRangeEOF = -1
+
+// parseByteRange parses "start-end", "start-" (start to EOF), or "-N" (the last N bytes)
 func parseByteRange(rangeStr string) (ByteRangeType, error) {
 	if rangeStr == "" {
 		return ByteRangeType{All: true}, nil
 	}
-	var start, end int64
-	_, err := fmt.Sscanf(rangeStr, "%d-%d", &start, &end)
-	if err != nil {
+	startStr, endStr, found := strings.Cut(rangeStr, "-")
+	if !found || (startStr == "" && endStr == "") {
+		return ByteRangeType{}, errors.New("invalid byte range")
+	}
+	if startStr == "" {
+		suffixLen, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || suffixLen <= 0 {
+			return ByteRangeType{}, errors.New("invalid byte range")
+		}
+		return ByteRangeType{SuffixLen: suffixLen, End: ByteRangeEOF}, nil
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
 		return ByteRangeType{}, errors.New("invalid byte range")
 	}
-	if start < 0 || end < 0 || start > end {
+	if endStr == "" {
+		return ByteRangeType{Start: start, End: ByteRangeEOF}, nil
+	}
+	end, err := strconv.ParseInt(endStr, 10, 64)
+	if err != nil || end < 0 || start > end {
 		return ByteRangeType{}, errors.New("invalid byte range")
 	}
 	return ByteRangeType{Start: start, End: end}, nil
 }
 
-func (impl *ServerImpl) remoteStreamFileDir(ctx context.Context, path string, byteRange ByteRangeType, dataCallback func(fileInfo []*wshrpc.FileInfo, data []byte, byteRange ByteRangeType)) error {
+// parseByteRanges parses a comma-separated list of ranges ("0-99,500-599,1000-").
+// ranges are served in the order given and are not merged, so overlapping ranges send the shared bytes once per range.
+func parseByteRanges(rangeStr string) ([]ByteRangeType, error) {
+	if rangeStr == "" {
+		return []ByteRangeType{{All: true}}, nil
+	}
+	var rtn []ByteRangeType
+	for _, part := range strings.Split(rangeStr, ",") {
+		byteRange, err := parseByteRange(strings.TrimSpace(part))
+		if err != nil || byteRange.All {
+			return nil, fmt.Errorf("invalid byte range %q", part)
+		}
+		rtn = append(rtn, byteRange)
+	}
+	return rtn, nil
+}
+
+// IsOpenEnded returns true if the range has no explicit end (reads until EOF)
+func (r ByteRangeType) IsOpenEnded() bool {
+	return !r.All && r.End == ByteRangeEOF
+}
+
+// Resolve converts a suffix range ("-N") into an absolute range given the total size (bytes or dir entries)
+func (r ByteRangeType) Resolve(size int64) ByteRangeType {
+	if r.All || r.SuffixLen == 0 {
+		return r
+	}
+	start := size - r.SuffixLen
+	if start < 0 {
+		start = 0
+	}
+	return ByteRangeType{Start: start, End: ByteRangeEOF}
+}
+
+// fileChunkSize applies a per-request file chunk size, 0 (or less) is the default
+func fileChunkSize(override int) int {
+	if override <= 0 {
+		return wshrpc.FileChunkSize
+	}
+	return min(max(override, wshrpc.MinFileChunkSize), wshrpc.MaxFileChunkSize)
+}
+
+// dirChunkSize applies a per-request directory chunk size, 0 (or less) is the default
+func dirChunkSize(override int) int {
+	if override <= 0 {
+		return wshrpc.DirChunkSize
+	}
+	return min(max(override, wshrpc.MinDirChunkSize), wshrpc.MaxDirChunkSize)
+}
+
+func (impl *ServerImpl) remoteStreamFileDir(ctx context.Context, path string, byteRange ByteRangeType, chunkSize int, dataCallback func(fileInfo []*wshrpc.FileInfo, data []byte, byteRange ByteRangeType)) error {
 	innerFilesEntries, err := os.ReadDir(path)
 	if err != nil {
 		return fmt.Errorf("cannot open dir %q: %w", path, err)
@@ -97,9 +186,10 @@ func (impl *ServerImpl) remoteStreamFileDir(ctx context.Context, path string, by
 			innerFilesEntries = innerFilesEntries[:wshrpc.MaxDirSize]
 		}
 	} else {
+		byteRange = byteRange.Resolve(int64(len(innerFilesEntries)))
 		if byteRange.Start < int64(len(innerFilesEntries)) {
 			realEnd := byteRange.End
-			if realEnd > int64(len(innerFilesEntries)) {
+			if realEnd == ByteRangeEOF || realEnd > int64(len(innerFilesEntries)) {
 				realEnd = int64(len(innerFilesEntries))
 			}
 			innerFilesEntries = innerFilesEntries[byteRange.Start:realEnd]
@@ -118,7 +208,7 @@ func (impl *ServerImpl) remoteStreamFileDir(ctx context.Context, path string, by
 		}
 		innerFileInfo := statToFileInfo(filepath.Join(path, innerFileInfoInt.Name()), innerFileInfoInt, false)
 		fileInfoArr = append(fileInfoArr, innerFileInfo)
-		if len(fileInfoArr) >= wshrpc.DirChunkSize {
+		if len(fileInfoArr) >= chunkSize {
 			dataCallback(fileInfoArr, nil, byteRange)
 			fileInfoArr = nil
 		}
@@ -129,12 +219,29 @@ func (impl *ServerImpl) remoteStreamFileDir(ctx context.Context, path string, by
 	return nil
 }
 
-func (impl *ServerImpl) remoteStreamFileRegular(ctx context.Context, path string, byteRange ByteRangeType, dataCallback func(fileInfo []*wshrpc.FileInfo, data []byte, byteRange ByteRangeType)) error {
+// remoteStreamFileRegular streams a snapshot of the file as of when it was opened: data appended while streaming is not sent,
+// and a file that shrinks below the requested range fails the stream instead of silently coming up short.
+// files that report a size of 0 (e.g. /proc files) are read until EOF.
+func (impl *ServerImpl) remoteStreamFileRegular(ctx context.Context, path string, byteRange ByteRangeType, chunkSize int, dataCallback func(fileInfo []*wshrpc.FileInfo, data []byte, byteRange ByteRangeType)) error {
 	fd, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("cannot open file %q: %w", path, err)
 	}
 	defer utilfn.GracefulClose(fd, "remoteStreamFileRegular", path)
+	finfo, err := fd.Stat()
+	if err != nil {
+		return fmt.Errorf("cannot stat file %q: %w", path, err)
+	}
+	size := finfo.Size()
+	byteRange = byteRange.Resolve(size)
+	// end is exclusive, -1 reads until EOF
+	end := int64(-1)
+	if size > 0 {
+		end = size
+	}
+	if !byteRange.All && !byteRange.IsOpenEnded() && (end < 0 || byteRange.End < end) {
+		end = byteRange.End
+	}
 	var filePos int64
 	if !byteRange.All && byteRange.Start > 0 {
 		_, err := fd.Seek(byteRange.Start, io.SeekStart)
@@ -143,23 +250,33 @@ func (impl *ServerImpl) remoteStreamFileRegular(ctx context.Context, path string
 		}
 		filePos = byteRange.Start
 	}
-	buf := make([]byte, wshrpc.FileChunkSize)
+	// a range that starts past the end of the file is empty, not a shrink
+	if end >= 0 && filePos >= end {
+		return nil
+	}
+	buf := make([]byte, chunkSize)
 	for {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
 		n, err := fd.Read(buf)
+		if end >= 0 {
+			n = int(min(int64(n), end-filePos))
+		}
 		if n > 0 {
-			if !byteRange.All && filePos+int64(n) > byteRange.End {
-				n = int(byteRange.End - filePos)
-			}
+			// each chunk reports its own position in the file
+			chunkRange := ByteRangeType{Start: filePos, End: filePos + int64(n)}
 			filePos += int64(n)
-			dataCallback(nil, buf[:n], byteRange)
+			dataCallback(nil, buf[:n], chunkRange)
 		}
-		if !byteRange.All && filePos >= byteRange.End {
+		if end >= 0 && filePos >= end {
 			break
 		}
 		if errors.Is(err, io.EOF) {
+			// size > 0 means end was capped to it
+			if size > 0 && filePos < end {
+				return fmt.Errorf("file %q shrank while streaming (read up to offset %d, expected %d)", path, filePos, end)
+			}
 			break
 		}
 		if err != nil {
@@ -170,11 +287,11 @@ func (impl *ServerImpl) remoteStreamFileRegular(ctx context.Context, path string
 }
 
 func (impl *ServerImpl) remoteStreamFileInternal(ctx context.Context, data wshrpc.CommandRemoteStreamFileData, dataCallback func(fileInfo []*wshrpc.FileInfo, data []byte, byteRange ByteRangeType)) error {
-	byteRange, err := parseByteRange(data.ByteRange)
+	byteRanges, err := parseByteRanges(data.ByteRange)
 	if err != nil {
 		return err
 	}
-	path, err := wavebase.ExpandHomeDir(data.Path)
+	path, err := impl.cleanPath(data.Path)
 	if err != nil {
 		return err
 	}
@@ -182,17 +299,285 @@ func (impl *ServerImpl) remoteStreamFileInternal(ctx context.Context, data wshrp
 	if err != nil {
 		return fmt.Errorf("cannot stat file %q: %w", path, err)
 	}
-	dataCallback([]*wshrpc.FileInfo{finfo}, nil, byteRange)
-	if finfo.NotFound {
+	dataCallback([]*wshrpc.FileInfo{finfo}, nil, byteRanges[0])
+	if finfo.NotFound || data.HeadOnly {
 		return nil
 	}
 	if finfo.IsDir {
-		return impl.remoteStreamFileDir(ctx, path, byteRange, dataCallback)
-	} else {
-		return impl.remoteStreamFileRegular(ctx, path, byteRange, dataCallback)
+		if len(byteRanges) > 1 {
+			return fmt.Errorf("multiple byte ranges are not supported for directory %q", path)
+		}
+		return impl.remoteStreamFileDir(ctx, path, byteRanges[0], dirChunkSize(data.DirChunkSize), dataCallback)
+	}
+	chunkSize := fileChunkSize(data.FileChunkSize)
+	for _, byteRange := range byteRanges {
+		if err := impl.remoteStreamFileRegular(ctx, path, byteRange, chunkSize, dataCallback); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	tailDefaultLines  = 10
+	tailDefaultPollMs = 250
+)
+
+// tailStartOffset returns the offset of the start of the last n lines of fd (a trailing newline does not start a new line)
+func tailStartOffset(fd *os.File, size int64, n int) (int64, error) {
+	buf := make([]byte, wshrpc.FileChunkSize)
+	pos := size
+	newlines := 0
+	for pos > 0 {
+		readLen := min(int64(len(buf)), pos)
+		pos -= readLen
+		if _, err := fd.ReadAt(buf[:readLen], pos); err != nil {
+			return 0, err
+		}
+		for i := readLen - 1; i >= 0; i-- {
+			if buf[i] != '\n' || pos+i == size-1 {
+				continue
+			}
+			newlines++
+			if newlines == n {
+				return pos + i + 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// tailFile sends the last lines of path and then follows it as it grows, until ctx is done.
+// infoCallback is called when the file is (re)opened, after rotation (the file at path is replaced) or truncation, following data starts at the offset passed to dataCallback.
+func tailFile(ctx context.Context, path string, lines int, pollInterval time.Duration, infoCallback func(*wshrpc.FileInfo), dataCallback func(data []byte, offset int64)) error {
+	var fd *os.File
+	defer func() {
+		if fd != nil {
+			utilfn.GracefulClose(fd, "RemoteTailFileCommand", path)
+		}
+	}()
+	var fdInfo fs.FileInfo
+	var pos int64
+	openFile := func(startOffset func(*os.File, int64) (int64, error)) error {
+		if fd != nil {
+			utilfn.GracefulClose(fd, "RemoteTailFileCommand", path)
+		}
+		var err error
+		fd, err = os.Open(path)
+		if err != nil {
+			return fmt.Errorf("cannot open file %q: %w", path, err)
+		}
+		fdInfo, err = fd.Stat()
+		if err != nil {
+			return fmt.Errorf("cannot stat file %q: %w", path, err)
+		}
+		pos, err = startOffset(fd, fdInfo.Size())
+		if err != nil {
+			return fmt.Errorf("cannot read file %q: %w", path, err)
+		}
+		infoCallback(statToFileInfo(path, fdInfo, false))
+		return nil
+	}
+	fromStart := func(*os.File, int64) (int64, error) { return 0, nil }
+	err := openFile(func(fd *os.File, size int64) (int64, error) { return tailStartOffset(fd, size, lines) })
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, wshrpc.FileChunkSize)
+	readToEOF := func() error {
+		for {
+			n, err := fd.ReadAt(buf, pos)
+			if n > 0 {
+				dataCallback(buf[:n], pos)
+				pos += int64(n)
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("reading file %q: %w", path, err)
+			}
+		}
+	}
+	for {
+		if err := readToEOF(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+		pathInfo, err := os.Stat(path)
+		if err != nil {
+			// the file may be briefly missing while it is being rotated
+			continue
+		}
+		if !os.SameFile(pathInfo, fdInfo) {
+			// rotated, send whatever was written to the old file and then follow the new one from its start
+			if err := readToEOF(); err != nil {
+				return err
+			}
+			if err := openFile(fromStart); err != nil {
+				return err
+			}
+			continue
+		}
+		if pathInfo.Size() < pos {
+			// truncated
+			pos = 0
+			infoCallback(statToFileInfo(path, pathInfo, false))
+		}
+	}
+}
+
+// RemoteTailFileCommand is like "tail -F", it never returns on EOF and stops when the context is done.
+// A packet with Info (and no data) is sent when the file is opened and whenever it is truncated or rotated, following data restarts at offset 0.
+func (impl *ServerImpl) RemoteTailFileCommand(ctx context.Context, data wshrpc.CommandRemoteTailFileData) chan wshrpc.RespOrErrorUnion[wshrpc.FileData] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.FileData], 16)
+	go func() {
+		defer close(ch)
+		lines := data.Lines
+		if lines <= 0 {
+			lines = tailDefaultLines
+		}
+		pollMs := data.PollMs
+		if pollMs <= 0 {
+			pollMs = tailDefaultPollMs
+		}
+		path, err := impl.cleanPath(data.Path)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.FileData](err)
+			return
+		}
+		send := func(resp wshrpc.FileData) {
+			select {
+			case ch <- wshrpc.RespOrErrorUnion[wshrpc.FileData]{Response: resp}:
+			case <-ctx.Done():
+			}
+		}
+		err = tailFile(ctx, filepath.Clean(path), lines, time.Duration(pollMs)*time.Millisecond, func(info *wshrpc.FileInfo) {
+			send(wshrpc.FileData{Info: info})
+		}, func(data []byte, offset int64) {
+			send(wshrpc.FileData{Data64: base64.StdEncoding.EncodeToString(data), At: &wshrpc.FileDataAt{Offset: offset, Size: len(data)}})
+		})
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.FileData](err)
+		}
+	}()
+	return ch
+}
+
+// readLines scans r from the start of the file and calls dataCallback with the bytes of lines startLine through endLine
+// (1-based, inclusive, an endLine of 0 reads to EOF), newlines included.  lines are never buffered whole, a chunk is
+// sent for each read that overlaps the range, so very long lines are fine.
+// the total line count (a final line without a newline counts) is only returned if the scan reached EOF, known is false
+// when it stopped after endLine.
+func readLines(ctx context.Context, r io.Reader, startLine int, endLine int, dataCallback func(data []byte, offset int64)) (totalLines int, known bool, err error) {
+	buf := make([]byte, wshrpc.FileChunkSize)
+	line := 1
+	var pos int64
+	lastByte := byte('\n')
+	for {
+		if ctx.Err() != nil {
+			return 0, false, ctx.Err()
+		}
+		n, readErr := r.Read(buf)
+		// the part of this chunk inside the range
+		sendStart, sendEnd := -1, -1
+		for i := 0; i < n; {
+			if endLine > 0 && line > endLine {
+				break
+			}
+			lineEnd := n
+			if idx := bytes.IndexByte(buf[i:n], '\n'); idx >= 0 {
+				lineEnd = i + idx + 1
+			}
+			if line >= startLine {
+				if sendStart < 0 {
+					sendStart = i
+				}
+				sendEnd = lineEnd
+			}
+			if buf[lineEnd-1] == '\n' {
+				line++
+			}
+			i = lineEnd
+		}
+		if sendStart >= 0 {
+			dataCallback(buf[sendStart:sendEnd], pos+int64(sendStart))
+		}
+		if n > 0 {
+			pos += int64(n)
+			lastByte = buf[n-1]
+		}
+		if endLine > 0 && line > endLine {
+			return 0, false, nil
+		}
+		if errors.Is(readErr, io.EOF) {
+			if lastByte == '\n' {
+				return line - 1, true, nil
+			}
+			return line, true, nil
+		}
+		if readErr != nil {
+			return 0, false, readErr
+		}
 	}
 }
 
+// RemoteReadLinesCommand streams a range of lines from a file.  The first packet has the FileInfo, data packets have their
+// byte offset in At, and once the scan reaches EOF (the range runs past the end of the file) a final packet has TotalLines.
+func (impl *ServerImpl) RemoteReadLinesCommand(ctx context.Context, data wshrpc.CommandRemoteReadLinesData) chan wshrpc.RespOrErrorUnion[wshrpc.FileData] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.FileData], 16)
+	go func() {
+		defer close(ch)
+		startLine := data.StartLine
+		if startLine == 0 {
+			startLine = 1
+		}
+		if startLine < 0 || data.EndLine < 0 || (data.EndLine > 0 && data.EndLine < startLine) {
+			ch <- wshutil.RespErr[wshrpc.FileData](fmt.Errorf("invalid line range %d-%d", data.StartLine, data.EndLine))
+			return
+		}
+		path, err := impl.cleanPath(data.Path)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.FileData](err)
+			return
+		}
+		finfo, err := impl.fileInfoInternal(path, true)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.FileData](fmt.Errorf("cannot stat file %q: %w", path, err))
+			return
+		}
+		ch <- wshrpc.RespOrErrorUnion[wshrpc.FileData]{Response: wshrpc.FileData{Info: finfo}}
+		if finfo.NotFound {
+			return
+		}
+		if finfo.IsDir {
+			ch <- wshutil.RespErr[wshrpc.FileData](fmt.Errorf("cannot read lines of directory %q", path))
+			return
+		}
+		fd, err := os.Open(path)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.FileData](fmt.Errorf("cannot open file %q: %w", path, err))
+			return
+		}
+		defer utilfn.GracefulClose(fd, "RemoteReadLinesCommand", path)
+		totalLines, known, err := readLines(ctx, fd, startLine, data.EndLine, func(data []byte, offset int64) {
+			ch <- wshrpc.RespOrErrorUnion[wshrpc.FileData]{Response: wshrpc.FileData{Data64: base64.StdEncoding.EncodeToString(data), At: &wshrpc.FileDataAt{Offset: offset, Size: len(data)}}}
+		})
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.FileData](fmt.Errorf("reading file %q: %w", path, err))
+			return
+		}
+		if known {
+			ch <- wshrpc.RespOrErrorUnion[wshrpc.FileData]{Response: wshrpc.FileData{TotalLines: totalLines}}
+		}
+	}()
+	return ch
+}
+
 func (impl *ServerImpl) RemoteStreamFileCommand(ctx context.Context, data wshrpc.CommandRemoteStreamFileData) chan wshrpc.RespOrErrorUnion[wshrpc.FileData] {
 	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.FileData], 16)
 	go func() {
@@ -222,6 +607,127 @@ func (impl *ServerImpl) RemoteStreamFileCommand(ctx context.Context, data wshrpc
 	return ch
 }
 
+// RemoteReadFileRangeCommand streams a byte range of a file as iochan packets for binary viewers.  unlike
+// RemoteStreamFileCommand there is no FileData or base64 string built per chunk, the []byte is handed to the
+// transport as is.  packet offsets are relative to data.Offset, and the stream ends with a sha256 checksum
+// packet so iochan.WriterChan can verify it.
+func (impl *ServerImpl) RemoteReadFileRangeCommand(ctx context.Context, data wshrpc.CommandRemoteReadFileRangeData) <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
+	if data.Offset < 0 || data.Size <= 0 {
+		return wshutil.SendErrCh[iochantypes.Packet](fmt.Errorf("cannot read %q: invalid range offset %d size %d", data.Path, data.Offset, data.Size))
+	}
+	cleanedPath, err := impl.cleanPath(data.Path)
+	if err != nil {
+		return wshutil.SendErrCh[iochantypes.Packet](err)
+	}
+	file, err := os.Open(cleanedPath)
+	if err != nil {
+		return wshutil.SendErrCh[iochantypes.Packet](fmt.Errorf("cannot open file %q: %w", data.Path, err))
+	}
+	finfo, err := file.Stat()
+	if err != nil {
+		utilfn.GracefulClose(file, "RemoteReadFileRangeCommand", cleanedPath)
+		return wshutil.SendErrCh[iochantypes.Packet](fmt.Errorf("cannot stat file %q: %w", data.Path, err))
+	}
+	if finfo.IsDir() {
+		utilfn.GracefulClose(file, "RemoteReadFileRangeCommand", cleanedPath)
+		return wshutil.SendErrCh[iochantypes.Packet](fmt.Errorf("cannot read %q: is a directory", data.Path))
+	}
+	// clamp to the file size, a range past EOF is cut short
+	size := max(0, min(data.Size, finfo.Size()-data.Offset))
+	reader := io.NewSectionReader(file, data.Offset, size)
+	return iochan.ReaderChanWithOpts(ctx, reader, iochan.ReaderChanOpts{ChunkSize: wshrpc.FileChunkSize}, func() {
+		utilfn.GracefulClose(file, "RemoteReadFileRangeCommand", cleanedPath)
+	})
+}
+
+// walkWithSymlinks walks root like filepath.Walk. Symlinks are passed to walkFn as-is unless followSymlinks is set,
+// in which case they are resolved and symlinked directories are walked as if they were regular directories.
+// directory cycles and trees deeper than DefaultMaxWalkDepth fail the walk.
+func walkWithSymlinks(root string, followSymlinks bool, walkFn filepath.WalkFunc) error {
+	return walkWithSymlinksInternal(root, followSymlinks, newWalkGuard(DefaultMaxWalkDepth).walkFunc(walkFn))
+}
+
+func walkWithSymlinksInternal(root string, followSymlinks bool, walkFn filepath.WalkFunc) error {
+	return filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
+		if err != nil || !followSymlinks || info.Mode()&fs.ModeSymlink == 0 {
+			return walkFn(path, info, err)
+		}
+		targetInfo, err := os.Stat(path)
+		if err != nil {
+			return walkFn(path, info, fmt.Errorf("cannot follow symlink %q: %w", path, err))
+		}
+		if !targetInfo.IsDir() {
+			return walkFn(path, targetInfo, nil)
+		}
+		realTarget, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return walkFn(path, info, fmt.Errorf("cannot follow symlink %q: %w", path, err))
+		}
+		realParent, err := filepath.EvalSymlinks(filepath.Dir(path))
+		if err != nil {
+			return walkFn(path, info, fmt.Errorf("cannot resolve directory %q: %w", filepath.Dir(path), err))
+		}
+		// a link to an ancestor is caught here before anything under it is walked, the guard in walkFn catches the rest
+		if realParent == realTarget || strings.HasPrefix(realParent, realTarget+string(filepath.Separator)) {
+			return fmt.Errorf("%w: symlink %q points to an ancestor directory", errWalkCycle, path)
+		}
+		return walkWithSymlinksInternal(realTarget, followSymlinks, func(innerPath string, innerInfo fs.FileInfo, err error) error {
+			relPath, relErr := filepath.Rel(realTarget, innerPath)
+			if relErr != nil {
+				return relErr
+			}
+			return walkFn(filepath.Join(path, relPath), innerInfo, err)
+		})
+	})
+}
+
+// excludeWalkFunc wraps walkFn, skipping paths that match any of the exclude patterns (excluded directories are not descended into).
+// Patterns without a "/" are matched (with filepath.Match) against the name of each entry at any depth, e.g. "node_modules" or "*.o".
+// Patterns containing a "/" are matched against the slash separated path relative to root, e.g. "build/*.tmp".
+// A trailing "/" only matches directories.
+func excludeWalkFunc(root string, patterns []string, walkFn filepath.WalkFunc) (filepath.WalkFunc, error) {
+	if len(patterns) == 0 {
+		return walkFn, nil
+	}
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(strings.TrimSuffix(pattern, "/"), ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+	return func(path string, info fs.FileInfo, err error) error {
+		if err != nil || path == root {
+			return walkFn(path, info, err)
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		for _, pattern := range patterns {
+			if strings.HasSuffix(pattern, "/") {
+				if !info.IsDir() {
+					continue
+				}
+				pattern = strings.TrimSuffix(pattern, "/")
+			}
+			var matched bool
+			if strings.Contains(pattern, "/") {
+				matched, _ = filepath.Match(pattern, relPath)
+			} else {
+				matched, _ = filepath.Match(pattern, info.Name())
+			}
+			if !matched {
+				continue
+			}
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return walkFn(path, info, nil)
+	}, nil
+}
+
 func (impl *ServerImpl) RemoteTarStreamCommand(ctx context.Context, data wshrpc.CommandRemoteStreamTarData) <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
 	path := data.Path
 	opts := data.Opts
@@ -230,11 +736,10 @@ func (impl *ServerImpl) RemoteTarStreamCommand(ctx context.Context, data wshrpc.
 	}
 	log.Printf("RemoteTarStreamCommand: path=%s\n", path)
 	srcHasSlash := strings.HasSuffix(path, "/")
-	path, err := wavebase.ExpandHomeDir(path)
+	cleanedPath, err := impl.cleanPath(path)
 	if err != nil {
-		return wshutil.SendErrCh[iochantypes.Packet](fmt.Errorf("cannot expand path %q: %w", path, err))
+		return wshutil.SendErrCh[iochantypes.Packet](err)
 	}
-	cleanedPath := filepath.Clean(wavebase.ExpandHomeDirSafe(path))
 	finfo, err := os.Stat(cleanedPath)
 	if err != nil {
 		return wshutil.SendErrCh[iochantypes.Packet](fmt.Errorf("cannot stat file %q: %w", path, err))
@@ -253,11 +758,14 @@ func (impl *ServerImpl) RemoteTarStreamCommand(ctx context.Context, data wshrpc.
 		timeout = time.Duration(opts.Timeout) * time.Millisecond
 	}
 	readerCtx, cancel := context.WithTimeout(ctx, timeout)
-	rtn, writeHeader, fileWriter, tarClose := tarcopy.TarCopySrc(readerCtx, pathPrefix)
+	rtn, writeHeader, fileWriter, tarClose := tarcopy.TarCopySrcWithOpts(readerCtx, pathPrefix, iochan.ReaderChanOpts{ChunkSize: int64(fileChunkSize(opts.FileChunkSize)), BufferDepth: opts.BufferDepth})
 
 	go func() {
+		// every return path must close the pipe writer, otherwise the ReaderChan goroutine blocks forever.
+		// errors go through the pipe (not rtn) so they arrive after the data already written.
+		var streamErr error
 		defer func() {
-			tarClose()
+			tarClose(streamErr)
 			cancel()
 		}()
 		walkFunc := func(path string, info fs.FileInfo, err error) error {
@@ -270,8 +778,8 @@ func (impl *ServerImpl) RemoteTarStreamCommand(ctx context.Context, data wshrpc.
 			if err = writeHeader(info, path, singleFile); err != nil {
 				return err
 			}
-			// if not a dir, write file content
-			if !info.IsDir() {
+			// only regular files have content, symlinks are written as a header with the link target
+			if info.Mode().IsRegular() {
 				data, err := os.Open(path)
 				if err != nil {
 					return err
@@ -285,71 +793,446 @@ func (impl *ServerImpl) RemoteTarStreamCommand(ctx context.Context, data wshrpc.
 		}
 		log.Printf("RemoteTarStreamCommand: starting\n")
 		err = nil
-		if singleFile {
+		if singleFile && opts.ResumeOffset > 0 {
+			err = writeResumedFile(cleanedPath, finfo, opts, writeHeader, fileWriter)
+		} else if singleFile {
 			err = walkFunc(cleanedPath, finfo, nil)
 		} else {
-			err = filepath.Walk(cleanedPath, walkFunc)
-		}
-		if err != nil {
-			rtn <- wshutil.RespErr[iochantypes.Packet](err)
+			err = impl.walkStreamSource(cleanedPath, opts, walkFunc)
 		}
+		streamErr = err
 		log.Printf("RemoteTarStreamCommand: done\n")
 	}()
 	log.Printf("RemoteTarStreamCommand: returning channel\n")
 	return rtn
 }
 
-func (impl *ServerImpl) RemoteFileCopyCommand(ctx context.Context, data wshrpc.CommandFileCopyData) (bool, error) {
-	log.Printf("RemoteFileCopyCommand: src=%s, dest=%s\n", data.SrcUri, data.DestUri)
+// walkStreamSource walks a directory that is being streamed as an archive, applying the exclude, gitignore and follow symlink opts
+func (impl *ServerImpl) walkStreamSource(root string, opts *wshrpc.FileCopyOpts, walkFn filepath.WalkFunc) error {
+	excludeFn, err := excludeWalkFunc(root, opts.ExcludePatterns, walkFn)
+	if err != nil {
+		return err
+	}
+	if opts.RespectGitignore {
+		excludeFn = gitignoreWalkFunc(root, excludeFn)
+	}
+	return walkWithSymlinks(root, opts.FollowSymlinks, impl.jailWalkFunc(opts.FollowSymlinks, excludeFn))
+}
+
+// RemoteZipStreamCommand streams a file or directory as a zip archive (download only, copies between connections use tar).
+// a trailing slash on a directory path puts its contents at the root of the archive, like RemoteTarStreamCommand.
+func (impl *ServerImpl) RemoteZipStreamCommand(ctx context.Context, data wshrpc.CommandRemoteStreamZipData) <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
 	opts := data.Opts
 	if opts == nil {
 		opts = &wshrpc.FileCopyOpts{}
 	}
-	destUri := data.DestUri
-	srcUri := data.SrcUri
-	merge := opts.Merge
-	overwrite := opts.Overwrite
-	if overwrite && merge {
-		return false, fmt.Errorf("cannot specify both overwrite and merge")
-	}
-
-	destConn, err := connparse.ParseURIAndReplaceCurrentHost(ctx, destUri)
+	log.Printf("RemoteZipStreamCommand: path=%s\n", data.Path)
+	srcHasSlash := strings.HasSuffix(data.Path, "/")
+	path, err := impl.cleanPath(data.Path)
 	if err != nil {
-		return false, fmt.Errorf("cannot parse destination URI %q: %w", destUri, err)
+		return wshutil.SendErrCh[iochantypes.Packet](err)
 	}
-	destPathCleaned := filepath.Clean(wavebase.ExpandHomeDirSafe(destConn.Path))
-	destinfo, err := os.Stat(destPathCleaned)
+	cleanedPath := filepath.Clean(path)
+	finfo, err := os.Stat(cleanedPath)
 	if err != nil {
-		if !errors.Is(err, fs.ErrNotExist) {
-			return false, fmt.Errorf("cannot stat destination %q: %w", destPathCleaned, err)
-		}
+		return wshutil.SendErrCh[iochantypes.Packet](fmt.Errorf("cannot stat file %q: %w", path, err))
+	}
+	pathPrefix := filepath.Dir(cleanedPath)
+	if finfo.IsDir() && srcHasSlash {
+		pathPrefix = cleanedPath
 	}
 
-	destExists := destinfo != nil
-	destIsDir := destExists && destinfo.IsDir()
-	destHasSlash := strings.HasSuffix(destUri, "/")
-
-	if destExists && !destIsDir {
-		if !overwrite {
-			return false, fmt.Errorf(fstype.OverwriteRequiredError, destPathCleaned)
-		} else {
-			err := os.Remove(destPathCleaned)
-			if err != nil {
-				return false, fmt.Errorf("cannot remove file %q: %w", destPathCleaned, err)
-			}
-		}
+	timeout := fstype.DefaultTimeout
+	if opts.Timeout > 0 {
+		timeout = time.Duration(opts.Timeout) * time.Millisecond
 	}
-	srcConn, err := connparse.ParseURIAndReplaceCurrentHost(ctx, srcUri)
+	readerCtx, cancel := context.WithTimeout(ctx, timeout)
+	rtn, writeFile, zipClose, err := zipcopy.ZipCopySrc(readerCtx, pathPrefix, data.CompressionLevel, iochan.ReaderChanOpts{ChunkSize: int64(fileChunkSize(opts.FileChunkSize)), BufferDepth: opts.BufferDepth})
 	if err != nil {
-		return false, fmt.Errorf("cannot parse source URI %q: %w", srcUri, err)
+		cancel()
+		return wshutil.SendErrCh[iochantypes.Packet](err)
 	}
 
-	copyFileFunc := func(path string, finfo fs.FileInfo, srcFile io.Reader) (int64, error) {
-		nextinfo, err := os.Stat(path)
-		if err != nil && !errors.Is(err, fs.ErrNotExist) {
-			return 0, fmt.Errorf("cannot stat file %q: %w", path, err)
+	go func() {
+		var streamErr error
+		defer func() {
+			zipClose(streamErr)
+			cancel()
+		}()
+		walkFunc := func(path string, info fs.FileInfo, err error) error {
+			if readerCtx.Err() != nil {
+				return readerCtx.Err()
+			}
+			if err != nil {
+				return err
+			}
+			return writeFile(info, path)
+		}
+		if finfo.IsDir() {
+			err = impl.walkStreamSource(cleanedPath, opts, walkFunc)
+		} else {
+			err = walkFunc(cleanedPath, finfo, nil)
+		}
+		streamErr = err
+	}()
+	return rtn
+}
+
+// hashFilePrefix returns the hex encoded sha256 of the first n bytes of the file at path
+func hashFilePrefix(path string, n int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot open file %q: %w", path, err)
+	}
+	defer utilfn.GracefulClose(file, "hashFilePrefix", path)
+	hasher := sha256.New()
+	if _, err := io.CopyN(hasher, file, n); err != nil {
+		return "", fmt.Errorf("cannot read file %q: %w", path, err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// verifyResume checks that the bytes the destination already has are a prefix of the source file
+func verifyResume(path string, finfo fs.FileInfo, opts *wshrpc.FileCopyOpts) error {
+	if !finfo.Mode().IsRegular() {
+		return fmt.Errorf("cannot resume copy of %q: not a regular file", path)
+	}
+	if opts.ResumeOffset > finfo.Size() {
+		return fmt.Errorf("cannot resume copy of %q: destination is larger than source", path)
+	}
+	checksum, err := hashFilePrefix(path, opts.ResumeOffset)
+	if err != nil {
+		return err
+	}
+	if checksum != opts.ResumeChecksum {
+		return fmt.Errorf("cannot resume copy of %q: destination does not match source", path)
+	}
+	return nil
+}
+
+// resumedFileInfo describes the remainder of a file after the resume offset.
+// Sys returns a tar header so the offset is carried to the destination as a PAX record.
+type resumedFileInfo struct {
+	fs.FileInfo
+	header *tar.Header
+	offset int64
+}
+
+func (fi resumedFileInfo) Size() int64 {
+	return fi.FileInfo.Size() - fi.offset
+}
+
+func (fi resumedFileInfo) Sys() any {
+	return fi.header
+}
+
+// writeResumedFile writes a single file tar entry containing only the data after opts.ResumeOffset
+func writeResumedFile(path string, finfo fs.FileInfo, opts *wshrpc.FileCopyOpts, writeHeader func(fs.FileInfo, string, bool) error, fileWriter io.Writer) error {
+	if err := verifyResume(path, finfo, opts); err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(finfo, "")
+	if err != nil {
+		return err
+	}
+	header.PAXRecords = map[string]string{tarcopy.ResumeOffset: strconv.FormatInt(opts.ResumeOffset, 10)}
+	if err := writeHeader(resumedFileInfo{FileInfo: finfo, header: header, offset: opts.ResumeOffset}, path, true); err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer utilfn.GracefulClose(file, "RemoteTarStreamCommand", path)
+	if _, err := file.Seek(opts.ResumeOffset, io.SeekStart); err != nil {
+		return fmt.Errorf("cannot seek file %q: %w", path, err)
+	}
+	_, err = io.Copy(fileWriter, file)
+	return err
+}
+
+// getFileTimes returns the access and modification times to restore for a copied file.
+// tar headers only carry an access time for PAX/GNU archives, so fall back to the mod time.
+func getFileTimes(finfo fs.FileInfo) (time.Time, time.Time) {
+	mtime := finfo.ModTime()
+	atime := mtime
+	if hdr, ok := finfo.Sys().(*tar.Header); ok && !hdr.AccessTime.IsZero() {
+		atime = hdr.AccessTime
+	}
+	return atime, mtime
+}
+
+// isUnderDir returns true if path is strictly inside one of dirs
+func isUnderDir(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// dirMetaEntry is directory metadata that is restored once the copy completes, writing the children
+// would bump the times and a read-only mode would block them
+type dirMetaEntry struct {
+	path     string
+	setMode  bool
+	mode     fs.FileMode
+	setTimes bool
+	atime    time.Time
+	mtime    time.Time
+}
+
+// restoreDirMeta sets directory modes and times deepest-first, after all children have been written
+func restoreDirMeta(dirMeta []dirMetaEntry) error {
+	for i := len(dirMeta) - 1; i >= 0; i-- {
+		entry := dirMeta[i]
+		if entry.setMode {
+			if err := os.Chmod(entry.path, entry.mode); err != nil {
+				return fmt.Errorf("cannot set mode on directory %q: %w", entry.path, err)
+			}
+		}
+		if entry.setTimes {
+			if err := os.Chtimes(entry.path, entry.atime, entry.mtime); err != nil {
+				return fmt.Errorf("cannot set times on directory %q: %w", entry.path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// copyProgressIntervalBytes is how often (in bytes) progress is reported while copying a single large file
+const copyProgressIntervalBytes = 4 * 1024 * 1024
+
+// copyProgressTracker counts the bytes written during a copy, reporting progress after each file and every copyProgressIntervalBytes
+// the lock is only needed for parallel writes (FileCopyOpts.ParallelWrites), where files are written from several goroutines
+type copyProgressTracker struct {
+	lock      sync.Mutex
+	progress  wshrpc.CommandRemoteFileCopyProgress
+	lastBytes int64
+	callback  func(wshrpc.CommandRemoteFileCopyProgress)
+}
+
+func (t *copyProgressTracker) Write(p []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.progress.BytesDone += int64(len(p))
+	if t.progress.BytesDone-t.lastBytes >= copyProgressIntervalBytes {
+		t.sendLocked()
+	}
+	return len(p), nil
+}
+
+// addBytes counts bytes that were transferred without going through Write (e.g. a hardlinked file)
+func (t *copyProgressTracker) addBytes(n int64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.progress.BytesDone += n
+}
+
+func (t *copyProgressTracker) setCurrentFile(path string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.progress.CurrentFile = path
+}
+
+func (t *copyProgressTracker) fileDone() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.progress.FilesDone++
+	t.sendLocked()
+}
+
+func (t *copyProgressTracker) send() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.sendLocked()
+}
+
+func (t *copyProgressTracker) sendLocked() {
+	t.lastBytes = t.progress.BytesDone
+	if t.callback != nil {
+		t.callback(t.progress)
+	}
+}
+
+func (impl *ServerImpl) RemoteFileCopyCommand(ctx context.Context, data wshrpc.CommandFileCopyData) (bool, error) {
+	return impl.remoteFileCopyInternal(ctx, data, nil, nil)
+}
+
+func (impl *ServerImpl) RemoteFileCopyStreamCommand(ctx context.Context, data wshrpc.CommandFileCopyData) chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileCopyProgress] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileCopyProgress], 16)
+	go func() {
+		defer close(ch)
+		_, err := impl.remoteFileCopyInternal(ctx, data, func(progress wshrpc.CommandRemoteFileCopyProgress) {
+			select {
+			case ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileCopyProgress]{Response: progress}:
+			case <-ctx.Done():
+			}
+		}, nil)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteFileCopyProgress](err)
+		}
+	}()
+	return ch
+}
+
+// RemoteFileCopyPlanCommand runs the copy in dry-run mode, streaming what would be done to each destination path
+// without touching the destination.  conflicts are reported instead of failing the copy.
+func (impl *ServerImpl) RemoteFileCopyPlanCommand(ctx context.Context, data wshrpc.CommandFileCopyData) chan wshrpc.RespOrErrorUnion[wshrpc.FileCopyPlanEntry] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.FileCopyPlanEntry], 16)
+	opts := wshrpc.FileCopyOpts{}
+	if data.Opts != nil {
+		opts = *data.Opts
+	}
+	opts.DryRun = true
+	data.Opts = &opts
+	go func() {
+		defer close(ch)
+		_, err := impl.remoteFileCopyInternal(ctx, data, nil, func(entry wshrpc.FileCopyPlanEntry) {
+			select {
+			case ch <- wshrpc.RespOrErrorUnion[wshrpc.FileCopyPlanEntry]{Response: entry}:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.FileCopyPlanEntry](err)
+		}
+	}()
+	return ch
+}
+
+// progressCallback may be nil, otherwise it is called after each file and periodically during large files.
+// with opts.DryRun, nothing is written and planCallback (may be nil) receives an entry per destination path instead.
+func (impl *ServerImpl) remoteFileCopyInternal(ctx context.Context, data wshrpc.CommandFileCopyData, progressCallback func(wshrpc.CommandRemoteFileCopyProgress), planCallback func(wshrpc.FileCopyPlanEntry)) (bool, error) {
+	log.Printf("RemoteFileCopyCommand: src=%s, dest=%s\n", data.SrcUri, data.DestUri)
+	opts := data.Opts
+	if opts == nil {
+		opts = &wshrpc.FileCopyOpts{}
+	}
+	dryRun := opts.DryRun
+	addPlanEntry := func(path string, action string, finfo fs.FileInfo, reason string) {
+		if planCallback == nil {
+			return
+		}
+		entry := wshrpc.FileCopyPlanEntry{Path: path, Action: action, IsDir: finfo.IsDir(), Reason: reason}
+		if finfo.Mode().IsRegular() {
+			entry.Size = finfo.Size()
+		}
+		planCallback(entry)
+	}
+	destUri := data.DestUri
+	srcUri := data.SrcUri
+	merge := opts.Merge
+	overwrite := opts.Overwrite
+	if overwrite && merge {
+		return false, fmt.Errorf("cannot specify both overwrite and merge")
+	}
+	var dirMeta []dirMetaEntry
+	tracker := &copyProgressTracker{callback: progressCallback}
+	var dedupe *dedupeTracker
+	if opts.Dedupe && !dryRun {
+		dedupe = newDedupeTracker()
+	}
+
+	destConn, err := connparse.ParseURIAndReplaceCurrentHost(ctx, destUri)
+	if err != nil {
+		return false, fmt.Errorf("cannot parse destination URI %q: %w", destUri, err)
+	}
+	destPathCleaned, err := impl.cleanPath(destConn.Path)
+	if err != nil {
+		return false, err
+	}
+	destinfo, err := os.Stat(destPathCleaned)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return false, fmt.Errorf("cannot stat destination %q: %w", destPathCleaned, err)
+		}
+	}
+
+	destExists := destinfo != nil
+	destIsDir := destExists && destinfo.IsDir()
+	destHasSlash := strings.HasSuffix(destUri, "/")
+
+	srcConn, err := connparse.ParseURIAndReplaceCurrentHost(ctx, srcUri)
+	if err != nil {
+		return false, fmt.Errorf("cannot parse source URI %q: %w", srcUri, err)
+	}
+
+	// when resuming, tell the source how much of the destination file we already have so it can skip ahead
+	var resumePath string
+	if opts.Resume && !dryRun {
+		resumePath = destPathCleaned
+		if destIsDir || destHasSlash {
+			resumePath = filepath.Join(destPathCleaned, filepath.Base(srcConn.Path))
+		}
+		resumeInfo, err := os.Stat(resumePath)
+		if err == nil && resumeInfo.Mode().IsRegular() && resumeInfo.Size() > 0 {
+			checksum, err := hashFilePrefix(resumePath, resumeInfo.Size())
+			if err != nil {
+				return false, err
+			}
+			resumeOpts := *opts
+			resumeOpts.ResumeOffset = resumeInfo.Size()
+			resumeOpts.ResumeChecksum = checksum
+			opts = &resumeOpts
+		}
+	}
+
+	// in a dry run, copyFileFunc reports the existing file as an overwrite or conflict
+	if destExists && !destIsDir && opts.ResumeOffset == 0 && !dryRun {
+		if !overwrite {
+			return false, fmt.Errorf(fstype.OverwriteRequiredError, destPathCleaned)
+		} else {
+			err := os.Remove(destPathCleaned)
+			if err != nil {
+				return false, fmt.Errorf("cannot remove file %q: %w", destPathCleaned, err)
+			}
+		}
+	}
+
+	// lacking the privilege to chown is expected when not running as root, keep the current owner and warn once
+	var ownerWarnOnce sync.Once
+	preserveOwnerFunc := func(path string, finfo fs.FileInfo) error {
+		if !opts.PreserveOwner {
+			return nil
+		}
+		err := chownLikeSource(path, finfo)
+		if errors.Is(err, fs.ErrPermission) {
+			ownerWarnOnce.Do(func() {
+				log.Printf("RemoteFileCopyCommand: cannot preserve file owners, keeping the current user: %v\n", err)
+			})
+			return nil
+		}
+		return err
+	}
+
+	var dryRunRemovedDirs []string
+	// linkTarget is only used when finfo is a symlink
+	copyFileFunc := func(path string, finfo fs.FileInfo, srcFile io.Reader, linkTarget string) (int64, error) {
+		tracker.setCurrentFile(path)
+		// the destination root is checked up front, this catches symlinked directories inside it
+		if err := impl.checkJail(path); err != nil {
+			return 0, err
+		}
+		nextinfo, err := os.Stat(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return 0, fmt.Errorf("cannot stat file %q: %w", path, err)
+		}
+		if dryRun && isUnderDir(path, dryRunRemovedDirs) {
+			// the real copy would have removed this already (overwriting a parent directory)
+			nextinfo = nil
+		}
+		// a dry run records conflicts and keeps going, so the plan shows all of them
+		conflict := func(path string, err error) (int64, error) {
+			if !dryRun {
+				return 0, err
+			}
+			addPlanEntry(path, wshrpc.FileCopyPlanAction_Conflict, finfo, err.Error())
+			return 0, nil
 		}
 
+		action := wshrpc.FileCopyPlanAction_Create
 		if nextinfo != nil {
 			if nextinfo.IsDir() {
 				if !finfo.IsDir() {
@@ -359,21 +1242,33 @@ func (impl *ServerImpl) RemoteFileCopyCommand(ctx context.Context, data wshrpc.C
 					if err != nil && !errors.Is(err, fs.ErrNotExist) {
 						return 0, fmt.Errorf("cannot stat file %q: %w", path, err)
 					}
-					if newdestinfo != nil && !overwrite {
-						return 0, fmt.Errorf(fstype.OverwriteRequiredError, path)
+					if newdestinfo != nil {
+						if !overwrite {
+							return conflict(path, fmt.Errorf(fstype.OverwriteRequiredError, path))
+						}
+						action = wshrpc.FileCopyPlanAction_Overwrite
 					}
 				} else if overwrite {
-					err := os.RemoveAll(path)
-					if err != nil {
-						return 0, fmt.Errorf("cannot remove directory %q: %w", path, err)
+					action = wshrpc.FileCopyPlanAction_Overwrite
+					if dryRun {
+						dryRunRemovedDirs = append(dryRunRemovedDirs, path)
+					} else {
+						err := os.RemoveAll(path)
+						if err != nil {
+							return 0, fmt.Errorf("cannot remove directory %q: %w", path, err)
+						}
 					}
 				} else if !merge {
-					return 0, fmt.Errorf(fstype.MergeRequiredError, path)
+					return conflict(path, fmt.Errorf(fstype.MergeRequiredError, path))
+				} else {
+					action = wshrpc.FileCopyPlanAction_Skip
 				}
 			} else {
 				if !overwrite {
-					return 0, fmt.Errorf(fstype.OverwriteRequiredError, path)
-				} else if finfo.IsDir() {
+					return conflict(path, fmt.Errorf(fstype.OverwriteRequiredError, path))
+				}
+				action = wshrpc.FileCopyPlanAction_Overwrite
+				if finfo.IsDir() && !dryRun {
 					err := os.RemoveAll(path)
 					if err != nil {
 						return 0, fmt.Errorf("cannot remove directory %q: %w", path, err)
@@ -382,11 +1277,63 @@ func (impl *ServerImpl) RemoteFileCopyCommand(ctx context.Context, data wshrpc.C
 			}
 		}
 
+		if dryRun {
+			// os.Stat follows symlinks, an existing (possibly dangling) link at the destination is replaced too
+			if action == wshrpc.FileCopyPlanAction_Create && finfo.Mode()&fs.ModeSymlink != 0 {
+				if _, err := os.Lstat(path); err == nil {
+					if !overwrite {
+						return conflict(path, fmt.Errorf(fstype.OverwriteRequiredError, path))
+					}
+					action = wshrpc.FileCopyPlanAction_Overwrite
+				}
+			}
+			addPlanEntry(path, action, finfo, "")
+			return 0, nil
+		}
+
+		if finfo.Mode()&fs.ModeSymlink != 0 {
+			err := os.MkdirAll(filepath.Dir(path), 0755)
+			if err != nil {
+				return 0, fmt.Errorf("cannot create parent directory %q: %w", filepath.Dir(path), err)
+			}
+			// os.Stat above follows symlinks, so check for an existing (possibly dangling) link
+			if _, err := os.Lstat(path); err == nil {
+				if !overwrite {
+					return 0, fmt.Errorf(fstype.OverwriteRequiredError, path)
+				}
+				if err := os.Remove(path); err != nil {
+					return 0, fmt.Errorf("cannot remove file %q: %w", path, err)
+				}
+			}
+			if err := os.Symlink(linkTarget, path); err != nil {
+				return 0, fmt.Errorf("cannot create symlink %q: %w", path, err)
+			}
+			if err := preserveOwnerFunc(path, finfo); err != nil {
+				return 0, err
+			}
+			tracker.fileDone()
+			return 0, nil
+		}
+
 		if finfo.IsDir() {
-			err := os.MkdirAll(path, finfo.Mode())
+			dirMode := finfo.Mode().Perm()
+			if opts.PreserveMode {
+				// keep it writable for the children, the exact mode is restored at the end
+				dirMode |= 0700
+			}
+			err := os.MkdirAll(path, dirMode)
 			if err != nil {
 				return 0, fmt.Errorf("cannot create directory %q: %w", path, err)
 			}
+			if err := preserveOwnerFunc(path, finfo); err != nil {
+				return 0, err
+			}
+			// directory modes and times are restored after the copy completes
+			if opts.PreserveMode || opts.PreserveTimestamps {
+				entry := dirMetaEntry{path: path, setMode: opts.PreserveMode, mode: finfo.Mode().Perm(), setTimes: opts.PreserveTimestamps}
+				entry.atime, entry.mtime = getFileTimes(finfo)
+				dirMeta = append(dirMeta, entry)
+			}
 			return 0, nil
 		} else {
 			err := os.MkdirAll(filepath.Dir(path), 0755)
@@ -400,17 +1347,103 @@ func (impl *ServerImpl) RemoteFileCopyCommand(ctx context.Context, data wshrpc.C
 			return 0, fmt.Errorf("cannot create new file %q: %w", path, err)
 		}
 		defer utilfn.GracefulClose(file, "RemoteFileCopyCommand", path)
-		_, err = io.Copy(file, srcFile)
+		var hashFn hash.Hash
+		writer := io.MultiWriter(file, tracker)
+		if dedupe != nil && finfo.Size() > 0 {
+			hashFn = sha256.New()
+			writer = io.MultiWriter(file, tracker, hashFn)
+		}
+		_, err = io.Copy(writer, srcFile)
 		if err != nil {
 			return 0, fmt.Errorf("cannot write file %q: %w", path, err)
 		}
+		// OpenFile applies the umask and leaves the mode of an existing file alone, like cp without -p
+		if opts.PreserveMode {
+			if err := file.Chmod(finfo.Mode().Perm()); err != nil {
+				return 0, fmt.Errorf("cannot set mode on file %q: %w", path, err)
+			}
+		}
+		if err := preserveOwnerFunc(path, finfo); err != nil {
+			return 0, err
+		}
+		if opts.PreserveTimestamps {
+			atime, mtime := getFileTimes(finfo)
+			if err := os.Chtimes(path, atime, mtime); err != nil {
+				return 0, fmt.Errorf("cannot set times on file %q: %w", path, err)
+			}
+		}
+		if hashFn != nil {
+			// the first copy has its final metadata by now, so a duplicate can share it
+			dedupe.linkDuplicate(path, makeDedupeKey(hashFn.Sum(nil), finfo, opts.PreserveTimestamps, opts.PreserveOwner))
+		}
+		tracker.fileDone()
 
 		return finfo.Size(), nil
 	}
 
+	// linkFileFunc hardlinks srcPath to path instead of copying, returns false if the caller should fall back to copying
+	linkFileFunc := func(path string, srcPath string, finfo fs.FileInfo) (bool, error) {
+		if !finfo.Mode().IsRegular() {
+			return false, nil
+		}
+		if nextinfo, err := os.Stat(path); err == nil && nextinfo.IsDir() {
+			path = filepath.Join(path, filepath.Base(finfo.Name()))
+		}
+		// same as copyFileFunc, a symlinked directory inside the destination can point out of the jail
+		if err := impl.checkJail(path); err != nil {
+			return false, err
+		}
+		if _, err := os.Lstat(path); err == nil {
+			if !overwrite {
+				return false, fmt.Errorf(fstype.OverwriteRequiredError, path)
+			}
+			if err := os.Remove(path); err != nil {
+				return false, fmt.Errorf("cannot remove file %q: %w", path, err)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return false, fmt.Errorf("cannot create parent directory %q: %w", filepath.Dir(path), err)
+		}
+		if err := os.Link(srcPath, path); err != nil {
+			// most likely a different filesystem (or one without hardlinks), copy instead
+			log.Printf("RemoteFileCopyCommand: cannot hardlink %q, copying instead: %v\n", srcPath, err)
+			return false, nil
+		}
+		tracker.setCurrentFile(path)
+		tracker.addBytes(finfo.Size())
+		tracker.fileDone()
+		return true, nil
+	}
+
+	// appendFileFunc writes the rest of a partially copied file when resuming
+	appendFileFunc := func(path string, finfo fs.FileInfo, srcFile io.Reader) (int64, error) {
+		tracker.setCurrentFile(path)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return 0, fmt.Errorf("cannot open file %q: %w", path, err)
+		}
+		defer utilfn.GracefulClose(file, "RemoteFileCopyCommand", path)
+		n, err := io.Copy(io.MultiWriter(file, tracker), srcFile)
+		if err != nil {
+			return 0, fmt.Errorf("cannot write file %q: %w", path, err)
+		}
+		if opts.PreserveTimestamps {
+			atime, mtime := getFileTimes(finfo)
+			if err := os.Chtimes(path, atime, mtime); err != nil {
+				return 0, fmt.Errorf("cannot set times on file %q: %w", path, err)
+			}
+		}
+		tracker.fileDone()
+		return n, nil
+	}
+
 	srcIsDir := false
+	// same machine, copy (or hardlink) directly instead of going through a tar stream
 	if srcConn.Host == destConn.Host {
-		srcPathCleaned := filepath.Clean(wavebase.ExpandHomeDirSafe(srcConn.Path))
+		srcPathCleaned, err := impl.cleanPath(srcConn.Path)
+		if err != nil {
+			return false, err
+		}
 
 		srcFileStat, err := os.Stat(srcPathCleaned)
 		if err != nil {
@@ -425,43 +1458,92 @@ func (impl *ServerImpl) RemoteFileCopyCommand(ctx context.Context, data wshrpc.C
 			} else {
 				srcPathPrefix = srcPathCleaned
 			}
-			err = filepath.Walk(srcPathCleaned, func(path string, info fs.FileInfo, err error) error {
+			copyWalkFn, err := excludeWalkFunc(srcPathCleaned, opts.ExcludePatterns, func(path string, info fs.FileInfo, err error) error {
 				if err != nil {
 					return err
 				}
 				srcFilePath := path
-				destFilePath := filepath.Join(destPathCleaned, strings.TrimPrefix(path, srcPathPrefix))
+				relPath := strings.TrimPrefix(path, srcPathPrefix)
+				if opts.StripComponents > 0 {
+					var ok bool
+					relPath, ok = stripPathComponents(filepath.ToSlash(relPath), opts.StripComponents)
+					if !ok {
+						// keep walking, the children of a stripped directory can still have enough segments
+						return nil
+					}
+				}
+				destFilePath := filepath.Join(destPathCleaned, relPath)
+				if opts.Hardlink && !dryRun {
+					linked, err := linkFileFunc(destFilePath, srcFilePath, info)
+					if err != nil || linked {
+						return err
+					}
+				}
 				var file *os.File
-				if !info.IsDir() {
+				var linkTarget string
+				if info.Mode()&fs.ModeSymlink != 0 {
+					linkTarget, err = os.Readlink(srcFilePath)
+					if err != nil {
+						return fmt.Errorf("cannot read symlink %q: %w", srcFilePath, err)
+					}
+				} else if !info.IsDir() && !dryRun {
 					file, err = os.Open(srcFilePath)
 					if err != nil {
 						return fmt.Errorf("cannot open file %q: %w", srcFilePath, err)
 					}
 					defer utilfn.GracefulClose(file, "RemoteFileCopyCommand", srcFilePath)
 				}
-				_, err = copyFileFunc(destFilePath, info, file)
+				_, err = copyFileFunc(destFilePath, info, file, linkTarget)
 				return err
 			})
 			if err != nil {
-				return false, fmt.Errorf("cannot copy %q to %q: %w", srcUri, destUri, err)
+				return false, err
 			}
-		} else {
-			file, err := os.Open(srcPathCleaned)
+			if opts.RespectGitignore {
+				copyWalkFn = gitignoreWalkFunc(srcPathCleaned, copyWalkFn)
+			}
+			err = walkWithSymlinks(srcPathCleaned, opts.FollowSymlinks, impl.jailWalkFunc(opts.FollowSymlinks, copyWalkFn))
 			if err != nil {
-				return false, fmt.Errorf("cannot open file %q: %w", srcPathCleaned, err)
+				return false, fmt.Errorf("cannot copy %q to %q: %w", srcUri, destUri, err)
 			}
-			defer utilfn.GracefulClose(file, "RemoteFileCopyCommand", srcPathCleaned)
+		} else {
 			var destFilePath string
 			if destHasSlash {
 				destFilePath = filepath.Join(destPathCleaned, filepath.Base(srcPathCleaned))
 			} else {
 				destFilePath = destPathCleaned
 			}
-			_, err = copyFileFunc(destFilePath, srcFileStat, file)
-			if err != nil {
-				return false, fmt.Errorf("cannot copy %q to %q: %w", srcUri, destUri, err)
+			linked := false
+			if opts.Hardlink && opts.ResumeOffset == 0 && !dryRun {
+				linked, err = linkFileFunc(destFilePath, srcPathCleaned, srcFileStat)
+				if err != nil {
+					return false, fmt.Errorf("cannot copy %q to %q: %w", srcUri, destUri, err)
+				}
 			}
-		}
+			if !linked {
+				file, err := os.Open(srcPathCleaned)
+				if err != nil {
+					return false, fmt.Errorf("cannot open file %q: %w", srcPathCleaned, err)
+				}
+				defer utilfn.GracefulClose(file, "RemoteFileCopyCommand", srcPathCleaned)
+				tracker.progress.TotalBytes = srcFileStat.Size()
+				if opts.ResumeOffset > 0 {
+					if err := verifyResume(srcPathCleaned, srcFileStat, opts); err != nil {
+						return false, err
+					}
+					if _, err := file.Seek(opts.ResumeOffset, io.SeekStart); err != nil {
+						return false, fmt.Errorf("cannot seek file %q: %w", srcPathCleaned, err)
+					}
+					tracker.progress.TotalBytes -= opts.ResumeOffset
+					_, err = appendFileFunc(resumePath, srcFileStat, file)
+				} else {
+					_, err = copyFileFunc(destFilePath, srcFileStat, file, "")
+				}
+				if err != nil {
+					return false, fmt.Errorf("cannot copy %q to %q: %w", srcUri, destUri, err)
+				}
+			}
+		}
 	} else {
 		timeout := fstype.DefaultTimeout
 		if opts.Timeout > 0 {
@@ -475,23 +1557,77 @@ func (impl *ServerImpl) RemoteFileCopyCommand(ctx context.Context, data wshrpc.C
 		numFiles := 0
 		numSkipped := 0
 		totalBytes := int64(0)
+		// small files are buffered and written by a worker pool so the write syscalls overlap, everything else is written in stream order
+		var parallelWriter *parallelFileWriter
+		if opts.ParallelWrites > 1 && !dryRun && opts.ResumeOffset == 0 {
+			parallelWriter = newParallelFileWriter(readCtx, opts.ParallelWrites, func(path string, finfo fs.FileInfo, data []byte) (int64, error) {
+				n, err := copyFileFunc(path, finfo, bytes.NewReader(data), "")
+				if err != nil {
+					return n, fmt.Errorf("cannot copy file %q: %w", path, err)
+				}
+				return n, nil
+			})
+		}
 
 		err := tarcopy.TarCopyDest(readCtx, cancel, ioch, func(next *tar.Header, reader *tar.Reader, singleFile bool) error {
 			numFiles++
-			nextpath := filepath.Join(destPathCleaned, next.Name)
 			srcIsDir = !singleFile
+			var nextpath string
 			if singleFile && !destHasSlash {
 				// custom flag to indicate that the source is a single file, not a directory the contents of a directory
 				nextpath = destPathCleaned
+			} else {
+				name := next.Name
+				if opts.StripComponents > 0 {
+					// absolute names are rejected before stripping could turn them into relative ones
+					if isAbsTarName(name) {
+						return fmt.Errorf("invalid tar entry %q: absolute path", name)
+					}
+					var ok bool
+					name, ok = stripPathComponents(name, opts.StripComponents)
+					if !ok {
+						numSkipped++
+						return nil
+					}
+				}
+				var err error
+				nextpath, err = tarEntryDestPath(destPathCleaned, name)
+				if err != nil {
+					return err
+				}
 			}
 			finfo := next.FileInfo()
-			n, err := copyFileFunc(nextpath, finfo, reader)
+			if singleFile {
+				tracker.progress.TotalBytes = next.Size
+			}
+			if parallelWriter != nil && !singleFile && finfo.Mode().IsRegular() && next.Size <= parallelWriteMaxFileSize {
+				data, err := io.ReadAll(reader)
+				if err != nil {
+					return fmt.Errorf("cannot read file %q: %w", next.Name, err)
+				}
+				return parallelWriter.submit(nextpath, finfo, data)
+			}
+			var n int64
+			var err error
+			// only append if the source actually skipped ahead, otherwise the entry holds the whole file
+			if singleFile && opts.ResumeOffset > 0 && next.PAXRecords[tarcopy.ResumeOffset] == strconv.FormatInt(opts.ResumeOffset, 10) {
+				n, err = appendFileFunc(resumePath, finfo, reader)
+			} else {
+				n, err = copyFileFunc(nextpath, finfo, reader, next.Linkname)
+			}
 			if err != nil {
 				return fmt.Errorf("cannot copy file %q: %w", next.Name, err)
 			}
 			totalBytes += n
 			return nil
 		})
+		if parallelWriter != nil {
+			n, writeErr := parallelWriter.wait()
+			totalBytes += n
+			if err == nil {
+				err = writeErr
+			}
+		}
 		if err != nil {
 			return false, fmt.Errorf("cannot copy %q to %q: %w", srcUri, destUri, err)
 		}
@@ -503,28 +1639,307 @@ func (impl *ServerImpl) RemoteFileCopyCommand(ctx context.Context, data wshrpc.C
 		}
 		log.Printf("RemoteFileCopyCommand: done; %d files copied in %.3fs, total of %.4f MB, %.2f MB/s, %d files skipped\n", numFiles, totalTime, totalMegaBytes, rate, numSkipped)
 	}
+	if dryRun {
+		return srcIsDir, nil
+	}
+	if err := restoreDirMeta(dirMeta); err != nil {
+		return false, fmt.Errorf("cannot copy %q to %q: %w", srcUri, destUri, err)
+	}
+	if dedupe != nil {
+		log.Printf("RemoteFileCopyCommand: %d duplicate files hardlinked\n", dedupe.linked)
+	}
+	tracker.progress.CurrentFile = ""
+	tracker.send()
 	return srcIsDir, nil
 }
 
+func isAbsTarName(name string) bool {
+	return filepath.IsAbs(name) || filepath.VolumeName(name) != "" || strings.HasPrefix(name, "/") || strings.HasPrefix(name, "\\")
+}
+
+// stripPathComponents drops the first n segments of a slash separated path, like tar --strip-components.
+// empty and "." segments don't count. returns false if nothing is left. ".." segments are kept
+// (and counted) so the result still has to pass the traversal checks.
+func stripPathComponents(name string, n int) (string, bool) {
+	var parts []string
+	for _, part := range strings.Split(name, "/") {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) <= n {
+		return "", false
+	}
+	return strings.Join(parts[n:], "/"), true
+}
+
+// tarEntryDestPath returns where a tar entry should be written under destDir.  entries with absolute
+// names, or whose path (after resolving symlinks already on disk) would land outside destDir, are rejected.
+func tarEntryDestPath(destDir string, name string) (string, error) {
+	if name == "" || isAbsTarName(name) {
+		return "", fmt.Errorf("invalid tar entry %q: absolute path", name)
+	}
+	destDir = filepath.Clean(destDir)
+	nextPath := filepath.Join(destDir, name)
+	if nextPath == destDir {
+		return nextPath, nil
+	}
+	if !isUnderDir(nextPath, []string{destDir}) {
+		return "", fmt.Errorf("invalid tar entry %q: path escapes destination %q", name, destDir)
+	}
+	// a symlink written by an earlier entry must not redirect later entries outside the destination.
+	// check the nearest ancestor that already exists, parents that don't exist yet get created under it.
+	resolvedDest, err := filepath.EvalSymlinks(destDir)
+	if err != nil {
+		// destination doesn't exist yet, so nothing under it can be a symlink
+		return nextPath, nil
+	}
+	parent := filepath.Dir(nextPath)
+	for parent != destDir {
+		if _, err := os.Lstat(parent); err == nil {
+			break
+		}
+		parent = filepath.Dir(parent)
+	}
+	resolvedParent, err := filepath.EvalSymlinks(parent)
+	if err != nil {
+		return "", fmt.Errorf("invalid tar entry %q: cannot resolve %q: %w", name, parent, err)
+	}
+	if resolvedParent != resolvedDest && !isUnderDir(resolvedParent, []string{resolvedDest}) {
+		return "", fmt.Errorf("invalid tar entry %q: path escapes destination %q through a symlink", name, destDir)
+	}
+	return nextPath, nil
+}
+
+// listEntriesSorted gathers the full listing (up to MaxSortedDirSize entries), sorts it, and then applies Offset/Limit
+// listEntriesSorted also returns the number of entries before paging, and whether that count was cut off at MaxSortedDirSize
+func listEntriesSorted(ctx context.Context, path string, opts *wshrpc.FileListOpts) ([]*wshrpc.FileInfo, listCounts, error) {
+	switch opts.SortBy {
+	case "", wshrpc.FileListSortBy_Name, wshrpc.FileListSortBy_Size, wshrpc.FileListSortBy_ModTime:
+	default:
+		return nil, listCounts{}, fmt.Errorf("invalid sort key %q", opts.SortBy)
+	}
+	var gitignore *gitignoreMatcher
+	if opts.RespectGitignore {
+		gitignore = newGitignoreMatcher(path)
+	}
+	hideHidden := opts.ShowHidden != nil && !*opts.ShowHidden
+	var fileInfoArr []*wshrpc.FileInfo
+	var counts listCounts
+	if opts.All {
+		err := filepath.WalkDir(path, newWalkGuard(DefaultMaxWalkDepth).walkDirFunc(func(innerPath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if innerPath == path {
+					return err
+				}
+				// removed or unreadable while walking, leave it out but keep going
+				log.Printf("cannot list %q: %v\n", innerPath, err)
+				counts.skipped++
+				return skipEntry(d)
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if (gitignore != nil && gitignore.isIgnored(innerPath, d.IsDir())) || (hideHidden && innerPath != path && isHiddenEntry(d)) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if len(fileInfoArr) >= wshrpc.MaxSortedDirSize {
+				counts.totalIsMin = true
+				return fs.SkipAll
+			}
+			finfo, err := d.Info()
+			if err != nil {
+				log.Printf("cannot stat file %q: %v\n", innerPath, err)
+				counts.skipped++
+				return nil
+			}
+			fileInfoArr = append(fileInfoArr, statToFileInfo(innerPath, finfo, false))
+			return nil
+		}))
+		if err != nil {
+			return nil, listCounts{}, fmt.Errorf("cannot walk dir %q: %w", path, err)
+		}
+	} else {
+		entries, err := osReadDir(path)
+		if err != nil {
+			return nil, listCounts{}, fmt.Errorf("cannot open dir %q: %w", path, err)
+		}
+		if len(entries) > wshrpc.MaxSortedDirSize {
+			entries = entries[:wshrpc.MaxSortedDirSize]
+			counts.totalIsMin = true
+		}
+		for _, entry := range entries {
+			if ctx.Err() != nil {
+				return nil, listCounts{}, ctx.Err()
+			}
+			if gitignore != nil && gitignore.isIgnored(filepath.Join(path, entry.Name()), entry.IsDir()) {
+				continue
+			}
+			if hideHidden && isHiddenEntry(entry) {
+				continue
+			}
+			finfo, err := entry.Info()
+			if err != nil {
+				log.Printf("cannot stat file %q: %v\n", entry.Name(), err)
+				counts.skipped++
+				continue
+			}
+			fileInfoArr = append(fileInfoArr, statToFileInfo(filepath.Join(path, entry.Name()), finfo, false))
+		}
+	}
+	sortFileInfos(fileInfoArr, opts)
+	counts.total = len(fileInfoArr)
+	if opts.Offset >= len(fileInfoArr) {
+		return nil, counts, nil
+	}
+	fileInfoArr = fileInfoArr[opts.Offset:]
+	if opts.Limit > 0 && len(fileInfoArr) > opts.Limit {
+		fileInfoArr = fileInfoArr[:opts.Limit]
+	}
+	return fileInfoArr, counts, nil
+}
+
+// isHiddenEntry reports whether a listing entry is hidden, a dotfile or (on windows) one with the hidden attribute
+func isHiddenEntry(entry fs.DirEntry) bool {
+	return strings.HasPrefix(entry.Name(), ".") || hasHiddenAttr(entry)
+}
+
+// skipEntry is returned for an entry that failed in a WalkDir listing.  a directory is skipped right away, so
+// one that is gone isn't read (and counted) a second time.
+func skipEntry(d fs.DirEntry) error {
+	if d != nil && d.IsDir() {
+		return fs.SkipDir
+	}
+	return nil
+}
+
+// listCounts are reported on the done response of a listing
+type listCounts struct {
+	total      int
+	totalIsMin bool
+	skipped    int
+}
+
+// sortFileInfos sorts by opts.SortBy (falling back to name, then path), directories first if opts.DirsFirst is set
+func sortFileInfos(fileInfoArr []*wshrpc.FileInfo, opts *wshrpc.FileListOpts) {
+	slices.SortStableFunc(fileInfoArr, func(a, b *wshrpc.FileInfo) int {
+		if opts.DirsFirst && a.IsDir != b.IsDir {
+			if a.IsDir {
+				return -1
+			}
+			return 1
+		}
+		var rtn int
+		switch opts.SortBy {
+		case wshrpc.FileListSortBy_Size:
+			rtn = cmp.Compare(a.Size, b.Size)
+		case wshrpc.FileListSortBy_ModTime:
+			rtn = cmp.Compare(a.ModTime, b.ModTime)
+		}
+		if rtn == 0 {
+			rtn = strings.Compare(a.Name, b.Name)
+		}
+		if rtn == 0 {
+			rtn = strings.Compare(a.Path, b.Path)
+		}
+		if opts.SortDesc {
+			rtn = -rtn
+		}
+		return rtn
+	})
+}
+
 func (impl *ServerImpl) RemoteListEntriesCommand(ctx context.Context, data wshrpc.CommandRemoteListEntriesData) chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData] {
 	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData], 16)
 	go func() {
 		defer close(ch)
-		path, err := wavebase.ExpandHomeDir(data.Path)
+		path, err := impl.cleanPath(data.Path)
 		if err != nil {
 			ch <- wshutil.RespErr[wshrpc.CommandRemoteListEntriesRtnData](err)
 			return
 		}
-		innerFilesEntries := []os.DirEntry{}
+		if data.Opts == nil {
+			data.Opts = &wshrpc.FileListOpts{}
+		}
+		// not sent on the error paths, so consumers can tell a complete listing from a failed one
+		sendDone := func(counts listCounts) {
+			resp := wshrpc.CommandRemoteListEntriesRtnData{Done: true, TotalCount: counts.total, TotalCountIsMin: counts.totalIsMin, SkippedCount: counts.skipped}
+			ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData]{Response: resp}
+		}
+		chunkSize := dirChunkSize(data.Opts.DirChunkSize)
 		seen := 0
 		if data.Opts.Limit == 0 {
 			data.Opts.Limit = wshrpc.MaxDirSize
 		}
+		if data.Opts.SortBy != "" || data.Opts.DirsFirst {
+			fileInfoArr, counts, err := listEntriesSorted(ctx, path, data.Opts)
+			if err != nil {
+				ch <- wshutil.RespErr[wshrpc.CommandRemoteListEntriesRtnData](err)
+				return
+			}
+			for len(fileInfoArr) > 0 {
+				chunk := fileInfoArr[:min(chunkSize, len(fileInfoArr))]
+				ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData]{Response: wshrpc.CommandRemoteListEntriesRtnData{FileInfo: chunk}}
+				fileInfoArr = fileInfoArr[len(chunk):]
+			}
+			sendDone(counts)
+			return
+		}
+		var gitignore *gitignoreMatcher
+		if data.Opts.RespectGitignore {
+			gitignore = newGitignoreMatcher(path)
+		}
+		hideHidden := data.Opts.ShowHidden != nil && !*data.Opts.ShowHidden
+		var fileInfoArr []*wshrpc.FileInfo
+		skipped := 0
+		flush := func() {
+			if len(fileInfoArr) == 0 {
+				return
+			}
+			resp := wshrpc.CommandRemoteListEntriesRtnData{FileInfo: fileInfoArr}
+			ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData]{Response: resp}
+			fileInfoArr = nil
+		}
+		addEntry := func(fullPath string, entry fs.DirEntry) {
+			innerFileInfoInt, err := entry.Info()
+			if err != nil {
+				// most likely removed since it was read from the directory
+				log.Printf("cannot stat file %q: %v\n", fullPath, err)
+				skipped++
+				return
+			}
+			fileInfoArr = append(fileInfoArr, statToFileInfo(fullPath, innerFileInfoInt, false))
+			if len(fileInfoArr) >= chunkSize {
+				flush()
+			}
+		}
 		if data.Opts.All {
-			fs.WalkDir(os.DirFS(path), ".", func(path string, d fs.DirEntry, err error) error {
+			// entries are sent as the walk finds them (in chunkSize chunks) so large trees stream progressively
+			rootPath := path
+			numFiles := 0
+			walkErr := fs.WalkDir(dirFS(path), ".", newWalkGuard(DefaultMaxWalkDepth).walkDirFunc(func(path string, d fs.DirEntry, err error) error {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				// hidden entries are dropped before they count toward Offset/Limit
+				if err == nil && ((gitignore != nil && gitignore.isIgnored(filepath.Join(rootPath, path), d.IsDir())) || (hideHidden && path != "." && isHiddenEntry(d))) {
+					if d.IsDir() {
+						return fs.SkipDir
+					}
+					return nil
+				}
 				defer func() {
 					seen++
 				}()
+				if err == nil && !d.IsDir() {
+					numFiles++
+				}
 				if seen < data.Opts.Offset {
 					return nil
 				}
@@ -532,235 +1947,1453 @@ func (impl *ServerImpl) RemoteListEntriesCommand(ctx context.Context, data wshrp
 					return io.EOF
 				}
 				if err != nil {
-					return err
+					if path == "." {
+						return err
+					}
+					// a single entry that was removed or can't be read doesn't end the walk
+					log.Printf("cannot list %q: %v\n", filepath.Join(rootPath, path), err)
+					skipped++
+					return skipEntry(d)
 				}
 				if d.IsDir() {
 					return nil
 				}
-				innerFilesEntries = append(innerFilesEntries, d)
+				addEntry(filepath.Join(rootPath, path), d)
 				return nil
-			})
-		} else {
-			innerFilesEntries, err = os.ReadDir(path)
-			if err != nil {
-				ch <- wshutil.RespErr[wshrpc.CommandRemoteListEntriesRtnData](fmt.Errorf("cannot open dir %q: %w", path, err))
+			}))
+			if ctx.Err() != nil {
+				ch <- wshutil.RespErr[wshrpc.CommandRemoteListEntriesRtnData](ctx.Err())
 				return
 			}
+			if walkErr != nil && walkErr != io.EOF {
+				log.Printf("error walking dir %q: %v\n", rootPath, walkErr)
+			}
+			flush()
+			sendDone(listCounts{total: numFiles, totalIsMin: walkErr != nil, skipped: skipped})
+			return
+		}
+		innerFilesEntries, err := osReadDir(path)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteListEntriesRtnData](fmt.Errorf("cannot open dir %q: %w", path, err))
+			return
+		}
+		if gitignore != nil {
+			innerFilesEntries = slices.DeleteFunc(innerFilesEntries, func(entry os.DirEntry) bool {
+				return gitignore.isIgnored(filepath.Join(path, entry.Name()), entry.IsDir())
+			})
+		}
+		if hideHidden {
+			innerFilesEntries = slices.DeleteFunc(innerFilesEntries, isHiddenEntry)
 		}
-		var fileInfoArr []*wshrpc.FileInfo
 		for _, innerFileEntry := range innerFilesEntries {
 			if ctx.Err() != nil {
 				ch <- wshutil.RespErr[wshrpc.CommandRemoteListEntriesRtnData](ctx.Err())
 				return
 			}
-			innerFileInfoInt, err := innerFileEntry.Info()
-			if err != nil {
-				log.Printf("cannot stat file %q: %v\n", innerFileEntry.Name(), err)
-				continue
+			addEntry(filepath.Join(path, innerFileEntry.Name()), innerFileEntry)
+		}
+		flush()
+		sendDone(listCounts{total: len(innerFilesEntries), skipped: skipped})
+	}()
+	return ch
+}
+
+func statToFileInfo(fullPath string, finfo fs.FileInfo, extended bool) *wshrpc.FileInfo {
+	mimeType := fileutil.DetectMimeType(fullPath, finfo, extended)
+	rtn := &wshrpc.FileInfo{
+		Path:          wavebase.ReplaceHomeDir(fullPath),
+		Dir:           computeDirPart(fullPath),
+		Name:          finfo.Name(),
+		Size:          finfo.Size(),
+		Mode:          finfo.Mode(),
+		ModeStr:       finfo.Mode().String(),
+		ModTime:       finfo.ModTime().UnixMilli(),
+		IsDir:         finfo.IsDir(),
+		MimeType:      mimeType,
+		SupportsMkdir: true,
+	}
+	if finfo.IsDir() {
+		rtn.Size = -1
+		rtn.NumEntries = -1
+	}
+	isLink := finfo.Mode()&fs.ModeSymlink != 0
+	if isLink {
+		if target, err := os.Readlink(fullPath); err == nil {
+			rtn.LinkTarget = target
+		}
+	}
+	fillFileOwner(rtn, finfo)
+	if extended {
+		rtn.Xattrs = getXattrs(fullPath, isLink)
+	}
+	return rtn
+}
+
+// checkIsReadOnly reports whether path can't be written to.  for a directory or a missing path that means its parent
+// directory (entries can't be created there), otherwise the file itself.  fileInfo might be nil when the path doesn't
+// exist.  the permission check is cheap and has no side effects, probeIsReadOnly is only used when the check can't
+// tell and probe is set.
+func checkIsReadOnly(path string, fileInfo fs.FileInfo, exists bool, probe bool) bool {
+	target, targetInfo := path, fileInfo
+	if !exists || fileInfo.Mode().IsDir() {
+		target, targetInfo = filepath.Dir(path), nil
+	}
+	if readOnly, known := accessReadOnly(target, targetInfo); known {
+		return readOnly
+	}
+	if !probe {
+		// unknown, assume writable
+		return false
+	}
+	return probeIsReadOnly(path, fileInfo, exists)
+}
+
+// probeIsReadOnly actually tries writing: creating (and removing) a temp file in the directory, or opening the file for append
+func probeIsReadOnly(path string, fileInfo fs.FileInfo, exists bool) bool {
+	if !exists || fileInfo.Mode().IsDir() {
+		dirName := filepath.Dir(path)
+		randHexStr, err := utilfn.RandomHexString(12)
+		if err != nil {
+			// we're not sure, just return false
+			return false
+		}
+		tmpFileName := filepath.Join(dirName, "wsh-tmp-"+randHexStr)
+		fd, err := os.Create(tmpFileName)
+		if err != nil {
+			return true
+		}
+		utilfn.GracefulClose(fd, "probeIsReadOnly", tmpFileName)
+		os.Remove(tmpFileName)
+		return false
+	}
+	// try to open for writing, if this fails then it is read-only
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return true
+	}
+	utilfn.GracefulClose(file, "probeIsReadOnly", path)
+	return false
+}
+
+func computeDirPart(path string) string {
+	path = filepath.Clean(wavebase.ExpandHomeDirSafe(path))
+	path = filepath.ToSlash(path)
+	if path == "/" {
+		return "/"
+	}
+	return filepath.Dir(path)
+}
+
+func (impl *ServerImpl) fileInfoInternal(path string, extended bool) (*wshrpc.FileInfo, error) {
+	cleanedPath := filepath.Clean(wavebase.ExpandHomeDirSafe(path))
+	finfo, err := os.Stat(cleanedPath)
+	if os.IsNotExist(err) {
+		// a dangling symlink still exists (and can be deleted), report the link itself
+		if linfo, lerr := os.Lstat(cleanedPath); lerr == nil && linfo.Mode()&fs.ModeSymlink != 0 {
+			rtn := statToFileInfo(cleanedPath, linfo, false)
+			if extended {
+				rtn.ReadOnly = checkIsReadOnly(cleanedPath, linfo, false, impl.ProbeReadOnly)
+			}
+			return rtn, nil
+		}
+		return &wshrpc.FileInfo{
+			Path:          wavebase.ReplaceHomeDir(path),
+			Dir:           computeDirPart(path),
+			NotFound:      true,
+			ReadOnly:      checkIsReadOnly(cleanedPath, finfo, false, impl.ProbeReadOnly),
+			SupportsMkdir: true,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot stat file %q: %w", path, err)
+	}
+	rtn := statToFileInfo(cleanedPath, finfo, extended)
+	// os.Stat follows symlinks, lstat the path itself so we can report the link target
+	if linfo, err := os.Lstat(cleanedPath); err == nil && linfo.Mode()&fs.ModeSymlink != 0 {
+		if target, err := os.Readlink(cleanedPath); err == nil {
+			rtn.LinkTarget = target
+		}
+	}
+	if extended {
+		rtn.ReadOnly = checkIsReadOnly(cleanedPath, finfo, true, impl.ProbeReadOnly)
+		if finfo.IsDir() {
+			rtn.NumEntries = countDirEntries(cleanedPath)
+		}
+	}
+	return rtn, nil
+}
+
+// countDirEntries returns the number of entries in dir (not recursive), -1 if it can't be read
+func countDirEntries(dir string) int {
+	fd, err := os.Open(dir)
+	if err != nil {
+		return -1
+	}
+	defer utilfn.GracefulClose(fd, "countDirEntries", dir)
+	names, err := fd.Readdirnames(-1)
+	if err != nil {
+		return -1
+	}
+	return len(names)
+}
+
+func resolvePaths(paths []string) string {
+	if len(paths) == 0 {
+		return wavebase.ExpandHomeDirSafe("~")
+	}
+	rtnPath := wavebase.ExpandHomeDirSafe(paths[0])
+	for _, path := range paths[1:] {
+		path = wavebase.ExpandHomeDirSafe(path)
+		if filepath.IsAbs(path) {
+			rtnPath = path
+			continue
+		}
+		rtnPath = filepath.Join(rtnPath, path)
+	}
+	return rtnPath
+}
+
+func (impl *ServerImpl) RemoteFileJoinCommand(ctx context.Context, paths []string) (*wshrpc.FileInfo, error) {
+	rtnPath, err := impl.cleanPath(resolvePaths(paths))
+	if err != nil {
+		return nil, err
+	}
+	return impl.fileInfoInternal(rtnPath, true)
+}
+
+func (impl *ServerImpl) RemoteFileInfoCommand(ctx context.Context, path string) (*wshrpc.FileInfo, error) {
+	cleanedPath, err := impl.cleanPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return impl.fileInfoInternal(cleanedPath, true)
+}
+
+// RemoteRealPathCommand returns the canonical absolute form of path for the UI: "~" expanded, cleaned (".." is
+// resolved lexically, like the other file commands) and symlinks resolved.  when the path doesn't exist, its
+// longest existing prefix is resolved and the rest is appended as is.
+func (impl *ServerImpl) RemoteRealPathCommand(ctx context.Context, path string) (*wshrpc.CommandRemoteRealPathRtnData, error) {
+	expandedPath, err := impl.cleanPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %q: %w", path, err)
+	}
+	absPath, err := filepath.Abs(expandedPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %q: %w", path, err)
+	}
+	resolved, exists := realPath(absPath)
+	return &wshrpc.CommandRemoteRealPathRtnData{Path: resolved, Exists: exists}, nil
+}
+
+// realPath resolves the symlinks in the clean absolute path absPath, walking up to the nearest part that can be
+// resolved when it doesn't exist (or can't be read)
+func realPath(absPath string) (string, bool) {
+	var tail []string
+	dir := absPath
+	for {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			if len(tail) == 0 {
+				return resolved, true
+			}
+			slices.Reverse(tail)
+			return filepath.Join(append([]string{resolved}, tail...)...), false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			// not even the root resolves, the cleaned path is the best we have
+			return absPath, false
+		}
+		tail = append(tail, filepath.Base(dir))
+		dir = parent
+	}
+}
+
+// RemoteFileExistsCommand returns the kind of entry at path (one of the FileKind_ constants) with a single lstat.  it
+// skips the mimetype detection and writability probe of RemoteFileInfoCommand.
+func (impl *ServerImpl) RemoteFileExistsCommand(ctx context.Context, path string) (string, error) {
+	cleanedPath, err := impl.cleanPath(path)
+	if err != nil {
+		return "", err
+	}
+	finfo, err := os.Lstat(cleanedPath)
+	// a parent that is a file (ENOTDIR) also means nothing is there
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return wshrpc.FileKind_Missing, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot stat %q: %w", path, err)
+	}
+	mode := finfo.Mode()
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		return wshrpc.FileKind_Symlink, nil
+	case mode.IsDir():
+		return wshrpc.FileKind_Dir, nil
+	case mode.IsRegular():
+		return wshrpc.FileKind_File, nil
+	}
+	return wshrpc.FileKind_Other, nil
+}
+
+func (impl *ServerImpl) RemoteReadLinkCommand(ctx context.Context, path string) (string, error) {
+	cleanedPath, err := impl.cleanPath(path)
+	if err != nil {
+		return "", err
+	}
+	target, err := os.Readlink(cleanedPath)
+	if err != nil {
+		return "", fmt.Errorf("cannot read link %q: %w", path, err)
+	}
+	return target, nil
+}
+
+// RemoteFileTouchCommand works like touch: existing files get their access and modification times set to now, missing files are created
+func (impl *ServerImpl) RemoteFileTouchCommand(ctx context.Context, data wshrpc.CommandRemoteFileTouchData) error {
+	path := data.Path
+	cleanedPath, err := impl.cleanPath(path)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stat(cleanedPath)
+	if err == nil {
+		now := time.Now()
+		if err := os.Chtimes(cleanedPath, now, now); err != nil {
+			return fmt.Errorf("cannot set times on file %q: %w", path, err)
+		}
+		return nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("cannot stat file %q: %w", path, err)
+	}
+	if data.NoCreate {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(cleanedPath), 0755); err != nil {
+		return fmt.Errorf("cannot create directory %q: %w", filepath.Dir(cleanedPath), err)
+	}
+	if err := os.WriteFile(cleanedPath, []byte{}, 0644); err != nil {
+		return fmt.Errorf("cannot create file %q: %w", cleanedPath, err)
+	}
+	return nil
+}
+
+// RemoteDiskUsageCommand walks the tree under data.Path summing regular file sizes, symlinks are not followed.
+// with TimeoutMs set a walk that runs too long returns the partial totals (marked Truncated) instead of an error.
+func (impl *ServerImpl) RemoteDiskUsageCommand(ctx context.Context, data wshrpc.CommandRemoteDiskUsageData) (*wshrpc.CommandRemoteDiskUsageRtnData, error) {
+	path, err := impl.cleanPath(data.Path)
+	if err != nil {
+		return nil, err
+	}
+	cleanedPath := filepath.Clean(path)
+	rtn := &wshrpc.CommandRemoteDiskUsageRtnData{}
+	var deadline time.Time
+	if data.TimeoutMs > 0 {
+		deadline = time.Now().Add(time.Duration(data.TimeoutMs) * time.Millisecond)
+	}
+	err = filepath.WalkDir(cleanedPath, newWalkGuard(DefaultMaxWalkDepth).walkDirFunc(func(innerPath string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			rtn.Truncated = true
+			return fs.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if innerPath != cleanedPath {
+				rtn.DirCount++
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		finfo, err := d.Info()
+		if err != nil {
+			log.Printf("cannot stat file %q: %v\n", innerPath, err)
+			return nil
+		}
+		rtn.TotalBytes += finfo.Size()
+		rtn.FileCount++
+		if data.TopN > 0 {
+			rtn.LargestFiles = insertLargestFile(rtn.LargestFiles, statToFileInfo(innerPath, finfo, false), data.TopN)
+		}
+		return nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("cannot compute disk usage of %q: %w", data.Path, err)
+	}
+	return rtn, nil
+}
+
+// RemoteFileCompareCommand reports whether two files have the same content.  a size mismatch is reported without reading
+// either file, otherwise both files are read chunk by chunk until the first difference.
+func (impl *ServerImpl) RemoteFileCompareCommand(ctx context.Context, data wshrpc.CommandRemoteFileCompareData) (*wshrpc.CommandRemoteFileCompareRtnData, error) {
+	var paths [2]string
+	var finfos [2]fs.FileInfo
+	for i, rawPath := range []string{data.Path1, data.Path2} {
+		path, err := impl.cleanPath(rawPath)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = filepath.Clean(path)
+		finfo, err := os.Stat(paths[i])
+		if errors.Is(err, fs.ErrNotExist) {
+			return &wshrpc.CommandRemoteFileCompareRtnData{Result: wshrpc.FileCompareResult_Missing, DiffOffset: -1, MissingPath: rawPath}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot stat file %q: %w", paths[i], err)
+		}
+		if !finfo.Mode().IsRegular() {
+			return nil, fmt.Errorf("cannot compare %q: not a regular file", paths[i])
+		}
+		finfos[i] = finfo
+	}
+	identical := &wshrpc.CommandRemoteFileCompareRtnData{Result: wshrpc.FileCompareResult_Identical, DiffOffset: -1}
+	if os.SameFile(finfos[0], finfos[1]) {
+		return identical, nil
+	}
+	if finfos[0].Size() != finfos[1].Size() {
+		return &wshrpc.CommandRemoteFileCompareRtnData{Result: wshrpc.FileCompareResult_Differ, DiffOffset: -1}, nil
+	}
+	if data.QuickCheck && finfos[0].ModTime().Equal(finfos[1].ModTime()) {
+		return identical, nil
+	}
+	diffOffset, err := compareFileContents(ctx, paths[0], paths[1])
+	if err != nil {
+		return nil, err
+	}
+	if diffOffset < 0 {
+		return identical, nil
+	}
+	return &wshrpc.CommandRemoteFileCompareRtnData{Result: wshrpc.FileCompareResult_Differ, DiffOffset: diffOffset}, nil
+}
+
+// compareFileContents returns the offset of the first differing byte, or -1 if the contents are the same
+func compareFileContents(ctx context.Context, path1 string, path2 string) (int64, error) {
+	fd1, err := os.Open(path1)
+	if err != nil {
+		return 0, fmt.Errorf("cannot open file %q: %w", path1, err)
+	}
+	defer utilfn.GracefulClose(fd1, "RemoteFileCompareCommand", path1)
+	fd2, err := os.Open(path2)
+	if err != nil {
+		return 0, fmt.Errorf("cannot open file %q: %w", path2, err)
+	}
+	defer utilfn.GracefulClose(fd2, "RemoteFileCompareCommand", path2)
+	buf1 := make([]byte, wshrpc.FileChunkSize)
+	buf2 := make([]byte, wshrpc.FileChunkSize)
+	var offset int64
+	for {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		n1, err1 := io.ReadFull(fd1, buf1)
+		if err1 != nil && err1 != io.EOF && err1 != io.ErrUnexpectedEOF {
+			return 0, fmt.Errorf("cannot read file %q: %w", path1, err1)
+		}
+		n2, err2 := io.ReadFull(fd2, buf2)
+		if err2 != nil && err2 != io.EOF && err2 != io.ErrUnexpectedEOF {
+			return 0, fmt.Errorf("cannot read file %q: %w", path2, err2)
+		}
+		// the sizes were checked up front, but the files can change while we read them
+		minLen := min(n1, n2)
+		if !bytes.Equal(buf1[:minLen], buf2[:minLen]) {
+			for i := 0; i < minLen; i++ {
+				if buf1[i] != buf2[i] {
+					return offset + int64(i), nil
+				}
+			}
+		}
+		if n1 != n2 {
+			return offset + int64(minLen), nil
+		}
+		if n1 < len(buf1) {
+			return -1, nil
+		}
+		offset += int64(n1)
+	}
+}
+
+const (
+	// open-ended and whole-file reads of RemoteReadTextCommand return a preview of this size
+	readTextDefaultSize = 64 * 1024
+	readTextMaxSize     = 1024 * 1024
+)
+
+// RemoteReadTextCommand reads a byte range of a text file for previews, transcoding it to UTF-8.  the encoding is detected
+// from the chunk itself unless data.Encoding is set.  binary content is flagged and no text is returned.
+func (impl *ServerImpl) RemoteReadTextCommand(ctx context.Context, data wshrpc.CommandRemoteReadTextData) (*wshrpc.CommandRemoteReadTextRtnData, error) {
+	cleanedPath, err := impl.cleanPath(data.Path)
+	if err != nil {
+		return nil, err
+	}
+	byteRange, err := parseByteRange(data.ByteRange)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read text from %q: %w", data.Path, err)
+	}
+	file, err := os.Open(cleanedPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file %q: %w", data.Path, err)
+	}
+	defer utilfn.GracefulClose(file, "RemoteReadTextCommand", cleanedPath)
+	finfo, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("cannot stat file %q: %w", data.Path, err)
+	}
+	if finfo.IsDir() {
+		return nil, fmt.Errorf("cannot read text from %q: is a directory", data.Path)
+	}
+	byteRange = byteRange.Resolve(finfo.Size())
+	start, end := byteRange.Start, byteRange.End
+	if byteRange.All || byteRange.IsOpenEnded() {
+		end = start + readTextDefaultSize
+	}
+	end = min(end, start+readTextMaxSize)
+	buf := make([]byte, end-start)
+	n, err := file.ReadAt(buf, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("cannot read file %q: %w", data.Path, err)
+	}
+	buf = buf[:n]
+	atEOF := start+int64(n) >= finfo.Size()
+	encoding := data.Encoding
+	if encoding == "" {
+		encoding = fileutil.DetectTextEncoding(buf, start == 0, atEOF)
+	}
+	if encoding == "" {
+		return &wshrpc.CommandRemoteReadTextRtnData{IsBinary: true, BytesRead: int64(n), EOF: atEOF}, nil
+	}
+	text, consumed, err := fileutil.DecodeText(buf, encoding, start == 0, atEOF)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read text from %q: %w", data.Path, err)
+	}
+	return &wshrpc.CommandRemoteReadTextRtnData{Text: text, Encoding: encoding, BytesRead: int64(consumed), EOF: atEOF}, nil
+}
+
+// RemoteFileSystemStatsCommand returns the size and free space of the filesystem containing path
+func (impl *ServerImpl) RemoteFileSystemStatsCommand(ctx context.Context, path string) (*wshrpc.FileSystemStats, error) {
+	expandedPath, err := impl.cleanPath(path)
+	if err != nil {
+		return nil, err
+	}
+	cleanedPath := filepath.Clean(expandedPath)
+	rtn, err := getFileSystemStats(cleanedPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get filesystem stats for %q: %w", path, err)
+	}
+	rtn.Path = wavebase.ReplaceHomeDir(cleanedPath)
+	return rtn, nil
+}
+
+// network filesystem types, as reported by the linux mount table (and darwin's statfs, which also flags non-local mounts)
+var networkFsTypes = map[string]bool{
+	"nfs": true, "nfs4": true, "cifs": true, "smb": true, "smb2": true, "smb3": true, "smbfs": true,
+	"9p": true, "afs": true, "afpfs": true, "ceph": true, "glusterfs": true, "lustre": true,
+	"davfs": true, "webdav": true, "fuse.sshfs": true, "fuse.rclone": true, "fuse.s3fs": true,
+}
+
+// RemoteStatVFSCommand reports the type of the filesystem holding path and whether it is read-only or a network
+// mount.  a path that doesn't exist yet (a file about to be saved) reports its nearest existing parent.
+func (impl *ServerImpl) RemoteStatVFSCommand(ctx context.Context, path string) (*wshrpc.StatVFSInfo, error) {
+	cleanedPath, err := impl.cleanPath(path)
+	if err != nil {
+		return nil, err
+	}
+	statPath := cleanedPath
+	for {
+		_, err := os.Stat(statPath)
+		if err == nil {
+			break
+		}
+		parentPath := filepath.FromSlash(computeDirPart(statPath))
+		if !os.IsNotExist(err) || parentPath == statPath {
+			return nil, fmt.Errorf("cannot stat %q: %w", path, err)
+		}
+		statPath = parentPath
+	}
+	rtn, err := getStatVFS(statPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get filesystem info for %q: %w", path, err)
+	}
+	rtn.Path = wavebase.ReplaceHomeDir(statPath)
+	return rtn, nil
+}
+
+// grepMaxLineLen truncates long matched lines (e.g. minified files)
+const grepMaxLineLen = 1024
+
+// grep scans token-sized lines, so a line longer than this stops the search in that file
+const grepMaxScanLen = 1024 * 1024
+
+func makeGrepRegexp(pattern string, opts *wshrpc.GrepOpts) (*regexp.Regexp, error) {
+	if !opts.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.WholeWord {
+		pattern = `\b(?:` + pattern + `)\b`
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+func isTextMimeType(mimeType string) bool {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	if strings.HasPrefix(mimeType, "text/") || strings.HasSuffix(mimeType, "+json") || strings.HasSuffix(mimeType, "+xml") {
+		return true
+	}
+	switch mimeType {
+	case "application/json", "application/javascript", "application/xml", "application/x-sh", "application/toml", "application/yaml", "application/x-yaml":
+		return true
+	}
+	return false
+}
+
+// grepFile sends matches from a single file, returning false once the match limit is reached
+func grepFile(ctx context.Context, path string, re *regexp.Regexp, numMatches *int, maxMatches int, ch chan wshrpc.RespOrErrorUnion[wshrpc.GrepMatch]) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return true, err
+	}
+	defer utilfn.GracefulClose(file, "RemoteGrepCommand", path)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), grepMaxScanLen)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		if !re.Match(line) {
+			continue
+		}
+		if len(line) > grepMaxLineLen {
+			line = line[:grepMaxLineLen]
+		}
+		match := wshrpc.GrepMatch{Path: wavebase.ReplaceHomeDir(path), LineNum: lineNum, Line: string(line)}
+		select {
+		case ch <- wshrpc.RespOrErrorUnion[wshrpc.GrepMatch]{Response: match}:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+		*numMatches++
+		if *numMatches >= maxMatches {
+			return false, nil
+		}
+	}
+	return true, scanner.Err()
+}
+
+// RemoteGrepCommand searches the text files under data.Path, streaming each matching line
+func (impl *ServerImpl) RemoteGrepCommand(ctx context.Context, data wshrpc.CommandRemoteGrepData) chan wshrpc.RespOrErrorUnion[wshrpc.GrepMatch] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.GrepMatch], 16)
+	go func() {
+		defer close(ch)
+		opts := data.Opts
+		if opts == nil {
+			opts = &wshrpc.GrepOpts{}
+		}
+		maxMatches := opts.MaxMatches
+		if maxMatches <= 0 || maxMatches > wshrpc.MaxGrepMatches {
+			maxMatches = wshrpc.MaxGrepMatches
+		}
+		if data.Pattern == "" {
+			ch <- wshutil.RespErr[wshrpc.GrepMatch](fmt.Errorf("grep pattern is required"))
+			return
+		}
+		re, err := makeGrepRegexp(data.Pattern, opts)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.GrepMatch](fmt.Errorf("invalid pattern %q: %w", data.Pattern, err))
+			return
+		}
+		if opts.Glob != "" {
+			if _, err := filepath.Match(opts.Glob, ""); err != nil {
+				ch <- wshutil.RespErr[wshrpc.GrepMatch](fmt.Errorf("invalid glob %q: %w", opts.Glob, err))
+				return
+			}
+		}
+		path, err := impl.cleanPath(data.Path)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.GrepMatch](err)
+			return
+		}
+		rootPath := filepath.Clean(path)
+		numMatches := 0
+		err = filepath.WalkDir(rootPath, newWalkGuard(DefaultMaxWalkDepth).walkDirFunc(func(innerPath string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil && innerPath == rootPath {
+				return err
+			}
+			if err != nil {
+				// keep searching past unreadable directories
+				log.Printf("RemoteGrepCommand: cannot read %q: %v\n", innerPath, err)
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if opts.Glob != "" {
+				if matched, _ := filepath.Match(opts.Glob, d.Name()); !matched {
+					return nil
+				}
+			}
+			finfo, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if !isTextMimeType(fileutil.DetectMimeType(innerPath, finfo, true)) {
+				return nil
+			}
+			more, err := grepFile(ctx, innerPath, re, &numMatches, maxMatches, ch)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Printf("RemoteGrepCommand: cannot search %q: %v\n", innerPath, err)
+			}
+			if !more {
+				return fs.SkipAll
+			}
+			return nil
+		}))
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.GrepMatch](fmt.Errorf("cannot search %q: %w", data.Path, err))
+		}
+	}()
+	return ch
+}
+
+// splitGlob splits a glob into the leading directory that has no wildcards (the walk root) and the remaining pattern segments
+func splitGlob(pattern string) (string, []string) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	idx := slices.IndexFunc(segments, func(segment string) bool {
+		return strings.ContainsAny(segment, "*?[")
+	})
+	if idx < 0 {
+		return filepath.Clean(pattern), nil
+	}
+	base := strings.Join(segments[:idx], "/")
+	if base == "" && idx > 0 {
+		// the pattern is absolute
+		base = "/"
+	}
+	if base == "" {
+		base = "."
+	}
+	return filepath.FromSlash(base), slices.DeleteFunc(segments[idx:], func(segment string) bool {
+		return segment == "" || segment == "."
+	})
+}
+
+// matchGlobSegment matches a single path segment, like the shell a wildcard doesn't match a leading "."
+func matchGlobSegment(pattern string, name string) bool {
+	if strings.HasPrefix(name, ".") && !strings.HasPrefix(pattern, ".") {
+		return false
+	}
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
+
+// matchGlobSegments matches path segments against glob segments, "**" matches any number of segments (including none) but
+// doesn't descend into hidden directories.  with partial set it reports whether segments could be the start of a match,
+// which is used to prune directories from the walk.
+func matchGlobSegments(pattern []string, segments []string, partial bool) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if i > 0 && strings.HasPrefix(segments[i-1], ".") {
+					return false
+				}
+				if matchGlobSegments(pattern[1:], segments[i:], partial) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return partial
+		}
+		if !matchGlobSegment(pattern[0], segments[0]) {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// RemoteGlobCommand expands a shell-style glob ("~" is expanded, "**" matches any number of directories) and streams the
+// matching paths in lexical order, up to MaxGlobMatches.  symlinks are matched but not followed.
+func (impl *ServerImpl) RemoteGlobCommand(ctx context.Context, pattern string) chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData], 16)
+	go func() {
+		defer close(ch)
+		if pattern == "" {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteListEntriesRtnData](fmt.Errorf("glob pattern is required"))
+			return
+		}
+		expandedPattern, err := wavebase.ExpandHomeDir(pattern)
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteListEntriesRtnData](err)
+			return
+		}
+		basePath, patternSegments := splitGlob(expandedPattern)
+		// ".." in the wildcard segments never matches a walked entry, so only the base can escape the jail
+		if err := impl.checkJail(basePath); err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteListEntriesRtnData](err)
+			return
+		}
+		for i, segment := range patternSegments {
+			// path.Match negates with "[^...]", the shell with "[!...]"
+			segment = strings.ReplaceAll(segment, "[!", "[^")
+			patternSegments[i] = segment
+			if _, err := path.Match(segment, ""); err != nil {
+				ch <- wshutil.RespErr[wshrpc.CommandRemoteListEntriesRtnData](fmt.Errorf("invalid glob %q: %w", pattern, err))
+				return
+			}
+		}
+		if len(patternSegments) == 0 {
+			// no wildcards, the pattern only matches itself
+			finfo, err := os.Lstat(basePath)
+			if errors.Is(err, fs.ErrNotExist) {
+				ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData]{Response: wshrpc.CommandRemoteListEntriesRtnData{Done: true}}
+				return
+			}
+			if err != nil {
+				ch <- wshutil.RespErr[wshrpc.CommandRemoteListEntriesRtnData](fmt.Errorf("cannot stat %q: %w", pattern, err))
+				return
+			}
+			ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData]{Response: wshrpc.CommandRemoteListEntriesRtnData{FileInfo: []*wshrpc.FileInfo{statToFileInfo(basePath, finfo, false)}}}
+			ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData]{Response: wshrpc.CommandRemoteListEntriesRtnData{Done: true, TotalCount: 1}}
+			return
+		}
+		var fileInfoArr []*wshrpc.FileInfo
+		numMatches := 0
+		capped := false
+		// walked through dirFS so a symlinked base directory is still followed
+		walkErr := fs.WalkDir(dirFS(basePath), ".", newWalkGuard(DefaultMaxWalkDepth).walkDirFunc(func(relPath string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil && relPath == "." {
+				return err
+			}
+			if err != nil {
+				// keep matching past unreadable directories
+				log.Printf("RemoteGlobCommand: cannot read %q: %v\n", relPath, err)
+				return nil
+			}
+			// the base itself can only be matched by a pattern of "**"
+			var segments []string
+			if relPath != "." {
+				segments = strings.Split(relPath, "/")
+			}
+			if matchGlobSegments(patternSegments, segments, false) {
+				if numMatches >= wshrpc.MaxGlobMatches {
+					capped = true
+					return fs.SkipAll
+				}
+				innerPath := filepath.Join(basePath, filepath.FromSlash(relPath))
+				finfo, err := d.Info()
+				if err != nil {
+					// removed since it was listed
+					return nil
+				}
+				numMatches++
+				fileInfoArr = append(fileInfoArr, statToFileInfo(innerPath, finfo, false))
+				if len(fileInfoArr) >= wshrpc.DirChunkSize {
+					ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData]{Response: wshrpc.CommandRemoteListEntriesRtnData{FileInfo: fileInfoArr}}
+					fileInfoArr = nil
+				}
+			}
+			if d.IsDir() && !matchGlobSegments(patternSegments, segments, true) {
+				return fs.SkipDir
+			}
+			return nil
+		}))
+		if len(fileInfoArr) > 0 {
+			ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData]{Response: wshrpc.CommandRemoteListEntriesRtnData{FileInfo: fileInfoArr}}
+		}
+		if errors.Is(walkErr, fs.ErrNotExist) {
+			// like the shell, a missing base directory just matches nothing
+			walkErr = nil
+		}
+		if walkErr != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteListEntriesRtnData](fmt.Errorf("cannot expand glob %q: %w", pattern, walkErr))
+			return
+		}
+		ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData]{Response: wshrpc.CommandRemoteListEntriesRtnData{Done: true, TotalCount: numMatches, TotalCountIsMin: capped}}
+	}()
+	return ch
+}
+
+// insertLargestFile keeps the n largest files in largest, sorted by descending size
+func insertLargestFile(largest []*wshrpc.FileInfo, finfo *wshrpc.FileInfo, n int) []*wshrpc.FileInfo {
+	if len(largest) >= n && finfo.Size <= largest[len(largest)-1].Size {
+		return largest
+	}
+	idx, _ := slices.BinarySearchFunc(largest, finfo.Size, func(elem *wshrpc.FileInfo, size int64) int {
+		return cmp.Compare(size, elem.Size)
+	})
+	largest = slices.Insert(largest, idx, finfo)
+	if len(largest) > n {
+		largest = largest[:n]
+	}
+	return largest
+}
+
+// osRename is overridden in tests to simulate moves across filesystems
+var osRename = os.Rename
+
+// dirFS is overridden in tests to simulate slow filesystems
+var dirFS = os.DirFS
+
+// osReadDir is overridden in tests to simulate entries removed during a listing
+var osReadDir = os.ReadDir
+
+// copyFileWithMode copies the regular file src to dest, preserving its mode and timestamps.  the bytes copied are counted by tracker
+func copyFileWithMode(src string, dest string, finfo fs.FileInfo, tracker *copyProgressTracker) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer utilfn.GracefulClose(srcFile, "copyFileWithMode", src)
+	destFile, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, finfo.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	tracker.setCurrentFile(src)
+	if _, err := io.Copy(io.MultiWriter(destFile, tracker), srcFile); err != nil {
+		destFile.Close()
+		return err
+	}
+	if err := destFile.Chmod(finfo.Mode().Perm()); err != nil {
+		destFile.Close()
+		return err
+	}
+	if err := destFile.Close(); err != nil {
+		return err
+	}
+	atime, mtime := getFileTimes(finfo)
+	if err := os.Chtimes(dest, atime, mtime); err != nil {
+		return err
+	}
+	tracker.fileDone()
+	return nil
+}
+
+// moveAcrossDevices copies src (recursively) to dest, preserving modes and timestamps, and removes src only after the copy succeeds
+func moveAcrossDevices(src string, dest string, tracker *copyProgressTracker) error {
+	_, statErr := os.Lstat(dest)
+	destExisted := statErr == nil
+	var dirMeta []dirMetaEntry
+	err := filepath.Walk(src, newWalkGuard(DefaultMaxWalkDepth).walkFunc(func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, relPath)
+		switch {
+		case info.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("cannot create directory %q: %w", target, err)
+			}
+			atime, mtime := getFileTimes(info)
+			dirMeta = append(dirMeta, dirMetaEntry{path: target, setTimes: true, atime: atime, mtime: mtime})
+		case info.Mode()&fs.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("cannot read symlink %q: %w", path, err)
+			}
+			if err := os.Symlink(linkTarget, target); err != nil {
+				return fmt.Errorf("cannot create symlink %q: %w", target, err)
+			}
+		case info.Mode().IsRegular():
+			if err := copyFileWithMode(path, target, info, tracker); err != nil {
+				return fmt.Errorf("cannot copy file %q: %w", path, err)
+			}
+		default:
+			return fmt.Errorf("cannot move %q: unsupported file type", path)
+		}
+		return nil
+	}))
+	if err == nil {
+		err = restoreDirMeta(dirMeta)
+	}
+	if err != nil {
+		// don't leave a partial copy behind, the source is still intact
+		if !destExisted {
+			os.RemoveAll(dest)
+		}
+		return err
+	}
+	if err := os.RemoveAll(src); err != nil {
+		return fmt.Errorf("copied to %q but cannot remove source: %w", dest, err)
+	}
+	return nil
+}
+
+// moveEntry renames src to dest, falling back to copy+delete when they are on different filesystems (only the copy reports progress)
+func moveEntry(src string, dest string, tracker *copyProgressTracker) error {
+	err := osRename(src, dest)
+	if errors.Is(err, syscall.EXDEV) {
+		// rename can't cross filesystems, copy then delete the source
+		err = moveAcrossDevices(src, dest, tracker)
+	}
+	return err
+}
+
+// mergeMoveDir moves the contents of the directory src into the existing directory dest and then removes src.
+// directories that exist on both sides are merged recursively, any other existing entry is a conflict (overwrite
+// replaces the whole destination directory instead, same as RemoteFileCopyCommand).
+// with check set nothing is changed and only the first conflict is returned, so a failed merge doesn't leave a half-moved tree.
+func mergeMoveDir(src string, dest string, check bool, tracker *copyProgressTracker) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("cannot read directory %q: %w", src, err)
+	}
+	for _, entry := range entries {
+		srcPath := filepath.Join(src, entry.Name())
+		destPath := filepath.Join(dest, entry.Name())
+		destInfo, err := os.Lstat(destPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			if !check {
+				if err := moveEntry(srcPath, destPath, tracker); err != nil {
+					return fmt.Errorf("cannot move %q to %q: %w", srcPath, destPath, err)
+				}
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot stat %q: %w", destPath, err)
+		}
+		if !entry.IsDir() || !destInfo.IsDir() {
+			return fmt.Errorf(fstype.OverwriteRequiredError, destPath)
+		}
+		if err := mergeMoveDir(srcPath, destPath, check, tracker); err != nil {
+			return err
+		}
+	}
+	if check {
+		return nil
+	}
+	if err := os.Remove(src); err != nil {
+		return fmt.Errorf("cannot remove directory %q: %w", src, err)
+	}
+	return nil
+}
+
+// RemoteFileMoveCommand moves src to dest, using rename when possible (atomic on the same filesystem) and copy+delete otherwise.
+// if dest exists it is only replaced with the overwrite flag.  a directory moved onto an existing directory can instead
+// be merged into it with the merge flag.  a file moved onto an existing directory goes inside it.
+func (impl *ServerImpl) RemoteFileMoveCommand(ctx context.Context, data wshrpc.CommandFileCopyData) error {
+	return impl.remoteFileMoveInternal(ctx, data, nil)
+}
+
+// RemoteFileMoveStreamCommand is RemoteFileMoveCommand with progress.  a rename is instant so only the copy+delete
+// fallback (moves across filesystems) reports progress as it goes, a final progress packet is sent either way.
+func (impl *ServerImpl) RemoteFileMoveStreamCommand(ctx context.Context, data wshrpc.CommandFileCopyData) chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileCopyProgress] {
+	ch := make(chan wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileCopyProgress], 16)
+	go func() {
+		defer close(ch)
+		err := impl.remoteFileMoveInternal(ctx, data, func(progress wshrpc.CommandRemoteFileCopyProgress) {
+			select {
+			case ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteFileCopyProgress]{Response: progress}:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil {
+			ch <- wshutil.RespErr[wshrpc.CommandRemoteFileCopyProgress](err)
+		}
+	}()
+	return ch
+}
+
+// progressCallback may be nil, otherwise it is called after each copied file, periodically during large files and once the move is done
+func (impl *ServerImpl) remoteFileMoveInternal(ctx context.Context, data wshrpc.CommandFileCopyData, progressCallback func(wshrpc.CommandRemoteFileCopyProgress)) error {
+	opts := data.Opts
+	destUri := data.DestUri
+	srcUri := data.SrcUri
+	overwrite := opts != nil && opts.Overwrite
+	merge := opts != nil && opts.Merge
+	recursive := opts != nil && opts.Recursive
+	makeParents := opts != nil && opts.MakeParents
+
+	destConn, err := connparse.ParseURIAndReplaceCurrentHost(ctx, destUri)
+	if err != nil {
+		return fmt.Errorf("cannot parse destination URI %q: %w", srcUri, err)
+	}
+	srcConn, err := connparse.ParseURIAndReplaceCurrentHost(ctx, srcUri)
+	if err != nil {
+		return fmt.Errorf("cannot parse source URI %q: %w", srcUri, err)
+	}
+	if srcConn.Host != destConn.Host {
+		return fmt.Errorf("cannot move file %q to %q: different hosts", srcUri, destUri)
+	}
+	srcPathCleaned, err := impl.cleanPath(srcConn.Path)
+	if err != nil {
+		return err
+	}
+	destPathCleaned, err := impl.cleanPath(destConn.Path)
+	if err != nil {
+		return err
+	}
+	srcinfo, err := os.Stat(srcPathCleaned)
+	if err != nil {
+		return fmt.Errorf("cannot stat file %q: %w", srcPathCleaned, err)
+	}
+	tracker := &copyProgressTracker{callback: progressCallback}
+	if srcinfo.Mode().IsRegular() {
+		tracker.progress.TotalBytes = srcinfo.Size()
+	}
+	if srcinfo.IsDir() && !recursive {
+		return fmt.Errorf(fstype.RecursiveRequiredError)
+	}
+	destinfo, err := os.Stat(destPathCleaned)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("cannot stat destination %q: %w", destUri, err)
+	}
+	if destinfo != nil && destinfo.IsDir() && !srcinfo.IsDir() {
+		destPathCleaned = filepath.Join(destPathCleaned, filepath.Base(srcPathCleaned))
+		destinfo, err = os.Stat(destPathCleaned)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("cannot stat destination %q: %w", destPathCleaned, err)
+		}
+	}
+	if destinfo != nil {
+		if os.SameFile(srcinfo, destinfo) {
+			tracker.send()
+			return nil
+		}
+		if srcinfo.IsDir() && destinfo.IsDir() && !overwrite {
+			if !merge {
+				return fmt.Errorf(fstype.MergeRequiredError, destPathCleaned)
+			}
+			if err := mergeMoveDir(srcPathCleaned, destPathCleaned, true, tracker); err != nil {
+				return err
 			}
-			innerFileInfo := statToFileInfo(filepath.Join(path, innerFileInfoInt.Name()), innerFileInfoInt, false)
-			fileInfoArr = append(fileInfoArr, innerFileInfo)
-			if len(fileInfoArr) >= wshrpc.DirChunkSize {
-				resp := wshrpc.CommandRemoteListEntriesRtnData{FileInfo: fileInfoArr}
-				ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData]{Response: resp}
-				fileInfoArr = nil
+			if err := mergeMoveDir(srcPathCleaned, destPathCleaned, false, tracker); err != nil {
+				return fmt.Errorf("cannot move file %q to %q: %w", srcPathCleaned, destPathCleaned, err)
 			}
+			tracker.send()
+			return nil
 		}
-		if len(fileInfoArr) > 0 {
-			resp := wshrpc.CommandRemoteListEntriesRtnData{FileInfo: fileInfoArr}
-			ch <- wshrpc.RespOrErrorUnion[wshrpc.CommandRemoteListEntriesRtnData]{Response: resp}
+		if !overwrite {
+			return fmt.Errorf(fstype.OverwriteRequiredError, destPathCleaned)
+		}
+		// rename replaces a file atomically, but can't replace across types (or replace a non-empty directory)
+		if srcinfo.IsDir() || destinfo.IsDir() {
+			if err := os.RemoveAll(destPathCleaned); err != nil {
+				return fmt.Errorf("cannot remove %q: %w", destPathCleaned, err)
+			}
 		}
-	}()
-	return ch
-}
-
-func statToFileInfo(fullPath string, finfo fs.FileInfo, extended bool) *wshrpc.FileInfo {
-	mimeType := fileutil.DetectMimeType(fullPath, finfo, extended)
-	rtn := &wshrpc.FileInfo{
-		Path:          wavebase.ReplaceHomeDir(fullPath),
-		Dir:           computeDirPart(fullPath),
-		Name:          finfo.Name(),
-		Size:          finfo.Size(),
-		Mode:          finfo.Mode(),
-		ModeStr:       finfo.Mode().String(),
-		ModTime:       finfo.ModTime().UnixMilli(),
-		IsDir:         finfo.IsDir(),
-		MimeType:      mimeType,
-		SupportsMkdir: true,
 	}
-	if finfo.IsDir() {
-		rtn.Size = -1
+	if makeParents {
+		if err := os.MkdirAll(filepath.Dir(destPathCleaned), 0755); err != nil {
+			return fmt.Errorf("cannot create directory %q: %w", filepath.Dir(destPathCleaned), err)
+		}
 	}
-	return rtn
+	if err := moveEntry(srcPathCleaned, destPathCleaned, tracker); err != nil {
+		return fmt.Errorf("cannot move file %q to %q: %w", srcPathCleaned, destPathCleaned, err)
+	}
+	tracker.send()
+	return nil
 }
 
-// fileInfo might be null
-func checkIsReadOnly(path string, fileInfo fs.FileInfo, exists bool) bool {
-	if !exists || fileInfo.Mode().IsDir() {
-		dirName := filepath.Dir(path)
-		randHexStr, err := utilfn.RandomHexString(12)
-		if err != nil {
-			// we're not sure, just return false
-			return false
-		}
-		tmpFileName := filepath.Join(dirName, "wsh-tmp-"+randHexStr)
-		fd, err := os.Create(tmpFileName)
-		if err != nil {
-			return true
+// mkdirWithMode creates path and any missing parents.  a zero mode means 0755, otherwise the new directory
+// gets exactly mode (parents still get it minus the umask).
+func mkdirWithMode(path string, mode os.FileMode) error {
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0755
+	}
+	if err := os.MkdirAll(path, perm); err != nil {
+		return fmt.Errorf("cannot create directory %q: %w", path, err)
+	}
+	// MkdirAll is subject to the umask, set an explicitly requested mode exactly
+	if mode.Perm() != 0 {
+		if err := os.Chmod(path, perm); err != nil {
+			return fmt.Errorf("cannot set mode on directory %q: %w", path, err)
 		}
-		utilfn.GracefulClose(fd, "checkIsReadOnly", tmpFileName)
-		os.Remove(tmpFileName)
-		return false
 	}
-	// try to open for writing, if this fails then it is read-only
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+	return nil
+}
+
+func (impl *ServerImpl) RemoteMkdirCommand(ctx context.Context, data wshrpc.CommandRemoteMkdirData) error {
+	path := data.Path
+	cleanedPath, err := impl.cleanPath(path)
 	if err != nil {
-		return true
+		return err
 	}
-	utilfn.GracefulClose(file, "checkIsReadOnly", path)
-	return false
+	if stat, err := os.Stat(cleanedPath); err == nil {
+		if stat.IsDir() {
+			return fmt.Errorf("directory %q already exists", path)
+		} else {
+			return fmt.Errorf("cannot create directory %q, file exists at path", path)
+		}
+	}
+	return mkdirWithMode(cleanedPath, data.Mode)
 }
 
-func computeDirPart(path string) string {
-	path = filepath.Clean(wavebase.ExpandHomeDirSafe(path))
-	path = filepath.ToSlash(path)
-	if path == "/" {
-		return "/"
+// RemoteEnsureDirCommand makes sure data.Path is a directory, creating it (and its parents) if needed.  unlike
+// RemoteMkdirCommand an existing directory is not an error, and its mode is left alone (data.Mode only applies to
+// a new directory).
+func (impl *ServerImpl) RemoteEnsureDirCommand(ctx context.Context, data wshrpc.CommandRemoteMkdirData) error {
+	cleanedPath, err := impl.cleanPath(data.Path)
+	if err != nil {
+		return err
 	}
-	return filepath.Dir(path)
+	if stat, err := os.Stat(cleanedPath); err == nil {
+		if !stat.IsDir() {
+			return fmt.Errorf("cannot create directory %q, file exists at path", data.Path)
+		}
+		return nil
+	}
+	return mkdirWithMode(cleanedPath, data.Mode)
 }
 
-func (*ServerImpl) fileInfoInternal(path string, extended bool) (*wshrpc.FileInfo, error) {
-	cleanedPath := filepath.Clean(wavebase.ExpandHomeDirSafe(path))
-	finfo, err := os.Stat(cleanedPath)
-	if os.IsNotExist(err) {
-		return &wshrpc.FileInfo{
-			Path:          wavebase.ReplaceHomeDir(path),
-			Dir:           computeDirPart(path),
-			NotFound:      true,
-			ReadOnly:      checkIsReadOnly(cleanedPath, finfo, false),
-			SupportsMkdir: true,
-		}, nil
+// RemoteMkdirTempCommand creates a new uniquely named directory (mode 0700) for scratch space, same as os.MkdirTemp
+func (impl *ServerImpl) RemoteMkdirTempCommand(ctx context.Context, data wshrpc.CommandRemoteMkdirTempData) (*wshrpc.FileInfo, error) {
+	dir := os.TempDir()
+	if data.Dir != "" {
+		dir = data.Dir
 	}
+	dir, err := impl.cleanPath(dir)
 	if err != nil {
-		return nil, fmt.Errorf("cannot stat file %q: %w", path, err)
+		return nil, err
 	}
-	rtn := statToFileInfo(cleanedPath, finfo, extended)
-	if extended {
-		rtn.ReadOnly = checkIsReadOnly(cleanedPath, finfo, true)
+	pattern := data.Pattern
+	if pattern == "" {
+		pattern = "wsh-tmp-*"
 	}
-	return rtn, nil
+	tempDir, err := os.MkdirTemp(dir, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create temp directory in %q: %w", dir, err)
+	}
+	return impl.fileInfoInternal(tempDir, false)
 }
 
-func resolvePaths(paths []string) string {
-	if len(paths) == 0 {
-		return wavebase.ExpandHomeDirSafe("~")
+// chownMaxReportedFailures caps the number of failed paths listed in the returned error
+const chownMaxReportedFailures = 20
+
+// RemoteFileChownCommand changes the owner and/or group of data.Path (and everything under it with Recursive).
+// symlinks are changed with lchown and never followed.  without Strict a path that can't be changed (e.g. EPERM) doesn't stop
+// the walk, the failures are returned together at the end.
+func (impl *ServerImpl) RemoteFileChownCommand(ctx context.Context, data wshrpc.CommandRemoteFileChownData) error {
+	cleanedPath, err := impl.cleanPath(data.Path)
+	if err != nil {
+		return err
 	}
-	rtnPath := wavebase.ExpandHomeDirSafe(paths[0])
-	for _, path := range paths[1:] {
-		path = wavebase.ExpandHomeDirSafe(path)
-		if filepath.IsAbs(path) {
-			rtnPath = path
-			continue
+	if data.Owner == "" && data.Group == "" {
+		return fmt.Errorf("cannot chown %q: no owner or group given", data.Path)
+	}
+	uid, gid, err := resolveChownIds(data.Owner, data.Group)
+	if err != nil {
+		return fmt.Errorf("cannot chown %q: %w", data.Path, err)
+	}
+	chownEntry := func(path string, isSymlink bool) error {
+		if isSymlink {
+			return os.Lchown(path, uid, gid)
 		}
-		rtnPath = filepath.Join(rtnPath, path)
+		return os.Chown(path, uid, gid)
 	}
-	return rtnPath
+	if !data.Recursive {
+		finfo, err := os.Lstat(cleanedPath)
+		if err != nil {
+			return fmt.Errorf("cannot stat %q: %w", data.Path, err)
+		}
+		if err := chownEntry(cleanedPath, finfo.Mode()&fs.ModeSymlink != 0); err != nil {
+			return fmt.Errorf("cannot chown %q: %w", data.Path, err)
+		}
+		return nil
+	}
+	var failures []string
+	numPaths := 0
+	err = filepath.WalkDir(cleanedPath, newWalkGuard(DefaultMaxWalkDepth).walkDirFunc(func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			// the root itself can't be stat'ed
+			if d == nil {
+				return err
+			}
+		} else {
+			numPaths++
+			err = chownEntry(path, d.Type()&fs.ModeSymlink != 0)
+		}
+		if err == nil {
+			return nil
+		}
+		if data.Strict {
+			return err
+		}
+		failures = append(failures, err.Error())
+		return nil
+	}))
+	if err != nil {
+		return fmt.Errorf("cannot chown %q: %w", data.Path, err)
+	}
+	if len(failures) > 0 {
+		numFailures := len(failures)
+		if numFailures > chownMaxReportedFailures {
+			failures = append(failures[:chownMaxReportedFailures], fmt.Sprintf("and %d more", numFailures-chownMaxReportedFailures))
+		}
+		return fmt.Errorf("cannot chown %d of %d paths under %q: %s", numFailures, numPaths, data.Path, strings.Join(failures, "; "))
+	}
+	return nil
 }
 
-func (impl *ServerImpl) RemoteFileJoinCommand(ctx context.Context, paths []string) (*wshrpc.FileInfo, error) {
-	rtnPath := resolvePaths(paths)
-	return impl.fileInfoInternal(rtnPath, true)
+// prepareLinkPath expands and cleans the link path, making sure nothing exists there yet
+func (impl *ServerImpl) prepareLinkPath(path string, makeParents bool) (string, error) {
+	cleanedPath, err := impl.cleanPath(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(cleanedPath); err == nil {
+		return "", fmt.Errorf("cannot create link %q: file exists", path)
+	}
+	if makeParents {
+		if err := os.MkdirAll(filepath.Dir(cleanedPath), 0755); err != nil {
+			return "", fmt.Errorf("cannot create directory %q: %w", filepath.Dir(cleanedPath), err)
+		}
+	}
+	return cleanedPath, nil
 }
 
-func (impl *ServerImpl) RemoteFileInfoCommand(ctx context.Context, path string) (*wshrpc.FileInfo, error) {
-	return impl.fileInfoInternal(path, true)
+func (impl *ServerImpl) RemoteSymlinkCommand(ctx context.Context, data wshrpc.CommandRemoteLinkData) (*wshrpc.FileInfo, error) {
+	if data.Target == "" {
+		return nil, fmt.Errorf("cannot create symlink %q: no target", data.Path)
+	}
+	linkPath, err := impl.prepareLinkPath(data.Path, data.MakeParents)
+	if err != nil {
+		return nil, err
+	}
+	target := wavebase.ExpandHomeDirSafe(data.Target)
+	resolvedTarget := target
+	if !filepath.IsAbs(resolvedTarget) {
+		resolvedTarget = filepath.Join(filepath.Dir(linkPath), resolvedTarget)
+	}
+	// a link out of the jail would let later commands follow it out
+	if err := impl.checkJail(resolvedTarget); err != nil {
+		return nil, err
+	}
+	if data.TargetMustExist {
+		if _, err := os.Stat(resolvedTarget); err != nil {
+			return nil, fmt.Errorf("cannot stat symlink target %q: %w", data.Target, err)
+		}
+	}
+	if err := os.Symlink(target, linkPath); err != nil {
+		return nil, fmt.Errorf("cannot create symlink %q: %w", data.Path, err)
+	}
+	finfo, err := os.Lstat(linkPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot stat symlink %q: %w", data.Path, err)
+	}
+	return statToFileInfo(linkPath, finfo, false), nil
 }
 
-func (impl *ServerImpl) RemoteFileTouchCommand(ctx context.Context, path string) error {
-	cleanedPath := filepath.Clean(wavebase.ExpandHomeDirSafe(path))
-	if _, err := os.Stat(cleanedPath); err == nil {
-		return fmt.Errorf("file %q already exists", path)
+func (impl *ServerImpl) RemoteHardlinkCommand(ctx context.Context, data wshrpc.CommandRemoteLinkData) (*wshrpc.FileInfo, error) {
+	target, err := impl.cleanPath(data.Target)
+	if err != nil {
+		return nil, err
 	}
-	if err := os.MkdirAll(filepath.Dir(cleanedPath), 0755); err != nil {
-		return fmt.Errorf("cannot create directory %q: %w", filepath.Dir(cleanedPath), err)
+	linkPath, err := impl.prepareLinkPath(data.Path, data.MakeParents)
+	if err != nil {
+		return nil, err
 	}
-	if err := os.WriteFile(cleanedPath, []byte{}, 0644); err != nil {
-		return fmt.Errorf("cannot create file %q: %w", cleanedPath, err)
+	if err := os.Link(target, linkPath); err != nil {
+		return nil, fmt.Errorf("cannot create hardlink %q to %q: %w", data.Path, data.Target, err)
 	}
-	return nil
+	return impl.fileInfoInternal(linkPath, false)
 }
 
-func (impl *ServerImpl) RemoteFileMoveCommand(ctx context.Context, data wshrpc.CommandFileCopyData) error {
-	opts := data.Opts
-	destUri := data.DestUri
-	srcUri := data.SrcUri
-	overwrite := opts != nil && opts.Overwrite
-	recursive := opts != nil && opts.Recursive
-
-	destConn, err := connparse.ParseURIAndReplaceCurrentHost(ctx, destUri)
+// writeFileAtomic writes to a temp file in the same directory as path and renames it over path,
+// so readers see either the old or the new contents. If write fails, path is left untouched.
+func writeFileAtomic(path string, createMode os.FileMode, write func(io.Writer) error) error {
+	dirName := filepath.Dir(path)
+	randHexStr, err := utilfn.RandomHexString(12)
 	if err != nil {
-		return fmt.Errorf("cannot parse destination URI %q: %w", srcUri, err)
+		return fmt.Errorf("cannot generate temp file name: %w", err)
 	}
-	destPathCleaned := filepath.Clean(wavebase.ExpandHomeDirSafe(destConn.Path))
-	destinfo, err := os.Stat(destPathCleaned)
-	if err == nil {
-		if !destinfo.IsDir() {
-			if !overwrite {
-				return fmt.Errorf("destination %q already exists, use overwrite option", destUri)
-			} else {
-				err := os.Remove(destPathCleaned)
-				if err != nil {
-					return fmt.Errorf("cannot remove file %q: %w", destUri, err)
-				}
-			}
+	tmpFileName := filepath.Join(dirName, "wsh-tmp-"+randHexStr)
+	tmpFile, err := os.OpenFile(tmpFileName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, createMode)
+	if err != nil {
+		return fmt.Errorf("cannot create temp file %q: %w", tmpFileName, err)
+	}
+	renamed := false
+	defer func() {
+		if !renamed {
+			os.Remove(tmpFileName)
 		}
-	} else if !errors.Is(err, fs.ErrNotExist) {
-		return fmt.Errorf("cannot stat destination %q: %w", destUri, err)
+	}()
+	err = write(tmpFile)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
 	}
-	srcConn, err := connparse.ParseURIAndReplaceCurrentHost(ctx, srcUri)
 	if err != nil {
-		return fmt.Errorf("cannot parse source URI %q: %w", srcUri, err)
+		return fmt.Errorf("cannot write temp file %q: %w", tmpFileName, err)
 	}
-	if srcConn.Host == destConn.Host {
-		srcPathCleaned := filepath.Clean(wavebase.ExpandHomeDirSafe(srcConn.Path))
-		finfo, err := os.Stat(srcPathCleaned)
-		if err != nil {
-			return fmt.Errorf("cannot stat file %q: %w", srcPathCleaned, err)
-		}
-		if finfo.IsDir() && !recursive {
-			return fmt.Errorf(fstype.RecursiveRequiredError)
-		}
-		err = os.Rename(srcPathCleaned, destPathCleaned)
-		if err != nil {
-			return fmt.Errorf("cannot move file %q to %q: %w", srcPathCleaned, destPathCleaned, err)
-		}
-	} else {
-		return fmt.Errorf("cannot move file %q to %q: different hosts", srcUri, destUri)
+	// the umask may have restricted the mode passed to OpenFile
+	if err := os.Chmod(tmpFileName, createMode); err != nil {
+		return fmt.Errorf("cannot chmod temp file %q: %w", tmpFileName, err)
+	}
+	err = os.Rename(tmpFileName, path)
+	if errors.Is(err, syscall.EXDEV) {
+		// path is on a different device than its directory (e.g. a bind-mounted file), so copy over it instead
+		err = copyFileContents(tmpFileName, path)
+	} else if err == nil {
+		renamed = true
+	}
+	if err != nil {
+		return fmt.Errorf("cannot replace file %q: %w", path, err)
 	}
 	return nil
 }
 
-func (impl *ServerImpl) RemoteMkdirCommand(ctx context.Context, path string) error {
-	cleanedPath := filepath.Clean(wavebase.ExpandHomeDirSafe(path))
-	if stat, err := os.Stat(cleanedPath); err == nil {
-		if stat.IsDir() {
-			return fmt.Errorf("directory %q already exists", path)
-		} else {
-			return fmt.Errorf("cannot create directory %q, file exists at path", path)
-		}
+// copyFileContents overwrites dest with the contents of src, this is not atomic
+func copyFileContents(src string, dest string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
 	}
-	if err := os.MkdirAll(cleanedPath, 0755); err != nil {
-		return fmt.Errorf("cannot create directory %q: %w", cleanedPath, err)
+	defer utilfn.GracefulClose(srcFile, "copyFileContents", src)
+	destFile, err := os.OpenFile(dest, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
 	}
-	return nil
+	if _, err := io.Copy(destFile, srcFile); err != nil {
+		destFile.Close()
+		return err
+	}
+	if err := destFile.Sync(); err != nil {
+		destFile.Close()
+		return err
+	}
+	return destFile.Close()
 }
-func (*ServerImpl) RemoteWriteFileCommand(ctx context.Context, data wshrpc.FileData) error {
-	var truncate, append bool
-	var atOffset int64
+
+func (impl *ServerImpl) RemoteWriteFileCommand(ctx context.Context, data wshrpc.FileData) error {
+	var truncate, append, atomic bool
+	var atOffset, expectedModTime, expectedSize int64
+	var expectedSha256 string
 	if data.Info != nil && data.Info.Opts != nil {
 		truncate = data.Info.Opts.Truncate
 		append = data.Info.Opts.Append
+		atomic = data.Info.Opts.Atomic
+		expectedModTime = data.Info.Opts.ExpectedModTime
+		expectedSize = data.Info.Opts.ExpectedSize
+		expectedSha256 = data.Info.Opts.Sha256
 	}
 	if data.At != nil {
 		atOffset = data.At.Offset
@@ -771,7 +3404,10 @@ func (*ServerImpl) RemoteWriteFileCommand(ctx context.Context, data wshrpc.FileD
 	if append && atOffset > 0 {
 		return fmt.Errorf("cannot specify non-zero offset with append option")
 	}
-	path, err := wavebase.ExpandHomeDir(data.Info.Path)
+	if atomic && (append || atOffset > 0) {
+		return fmt.Errorf("cannot specify append or non-zero offset with atomic option")
+	}
+	path, err := impl.cleanPath(data.Info.Path)
 	if err != nil {
 		return err
 	}
@@ -785,6 +3421,13 @@ func (*ServerImpl) RemoteWriteFileCommand(ctx context.Context, data wshrpc.FileD
 	if err != nil {
 		return fmt.Errorf("cannot decode base64 data: %w", err)
 	}
+	// verify before opening the file, so a corrupted upload leaves nothing behind
+	if expectedSha256 != "" {
+		checksum := sha256.Sum256(dataBytes[:n])
+		if localChecksum := hex.EncodeToString(checksum[:]); !strings.EqualFold(localChecksum, expectedSha256) {
+			return fmt.Errorf("cannot write to file %q: %w: expected sha256 %s, got %s", path, iochan.ErrChecksumMismatch, expectedSha256, localChecksum)
+		}
+	}
 	finfo, err := os.Stat(path)
 	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return fmt.Errorf("cannot stat file %q: %w", path, err)
@@ -793,9 +3436,26 @@ func (*ServerImpl) RemoteWriteFileCommand(ctx context.Context, data wshrpc.FileD
 	if finfo != nil {
 		fileSize = finfo.Size()
 	}
+	if expectedModTime != 0 || expectedSize != 0 {
+		if finfo == nil {
+			return fmt.Errorf(fstype.WriteConflictError+": file no longer exists", path)
+		}
+		if expectedModTime != 0 && finfo.ModTime().UnixMilli() != expectedModTime {
+			return fmt.Errorf(fstype.WriteConflictError+": expected modtime %d, got %d", path, expectedModTime, finfo.ModTime().UnixMilli())
+		}
+		if expectedSize != 0 && fileSize != expectedSize {
+			return fmt.Errorf(fstype.WriteConflictError+": expected size %d, got %d", path, expectedSize, fileSize)
+		}
+	}
 	if atOffset > fileSize {
 		return fmt.Errorf("cannot write at offset %d, file size is %d", atOffset, fileSize)
 	}
+	if atomic {
+		return writeFileAtomic(path, createMode, func(w io.Writer) error {
+			_, err := w.Write(dataBytes[:n])
+			return err
+		})
+	}
 	openFlags := os.O_CREATE | os.O_WRONLY
 	if truncate {
 		openFlags |= os.O_TRUNC
@@ -820,12 +3480,102 @@ func (*ServerImpl) RemoteWriteFileCommand(ctx context.Context, data wshrpc.FileD
 	return nil
 }
 
-func (*ServerImpl) RemoteFileDeleteCommand(ctx context.Context, data wshrpc.CommandDeleteFileData) error {
-	expandedPath, err := wavebase.ExpandHomeDir(data.Path)
+// RemoteFileAppendCommand appends data to a file (creating it and its parent directories if needed), returning the updated file info so callers can track the size
+func (impl *ServerImpl) RemoteFileAppendCommand(ctx context.Context, data wshrpc.FileData) (*wshrpc.FileInfo, error) {
+	if data.Info == nil {
+		return nil, fmt.Errorf("file info is required")
+	}
+	path, err := impl.cleanPath(data.Info.Path)
+	if err != nil {
+		return nil, err
+	}
+	cleanedPath := filepath.Clean(path)
+	createMode := os.FileMode(0644)
+	if data.Info.Mode > 0 {
+		createMode = data.Info.Mode
+	}
+	dataBytes, err := base64.StdEncoding.DecodeString(data.Data64)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode base64 data: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cleanedPath), 0755); err != nil {
+		return nil, fmt.Errorf("cannot create directory %q: %w", filepath.Dir(cleanedPath), err)
+	}
+	file, err := os.OpenFile(cleanedPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, createMode)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file %q: %w", cleanedPath, err)
+	}
+	defer utilfn.GracefulClose(file, "RemoteFileAppendCommand", cleanedPath)
+	if _, err := file.Write(dataBytes); err != nil {
+		return nil, fmt.Errorf("cannot append to file %q: %w", cleanedPath, err)
+	}
+	finfo, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("cannot stat file %q: %w", cleanedPath, err)
+	}
+	return statToFileInfo(cleanedPath, finfo, false), nil
+}
+
+// checkRecursiveDelete refuses to recursively delete the filesystem root or the user's home directory (including via symlinks)
+func (impl *ServerImpl) RemoteFileTruncateCommand(ctx context.Context, data wshrpc.CommandRemoteFileTruncateData) (int64, error) {
+	if data.Size < 0 {
+		return 0, fmt.Errorf("cannot truncate file %q: invalid size %d", data.Path, data.Size)
+	}
+	expandedPath, err := impl.cleanPath(data.Path)
+	if err != nil {
+		return 0, err
+	}
+	cleanedPath := filepath.Clean(expandedPath)
+	finfo, err := os.Stat(cleanedPath)
+	if errors.Is(err, fs.ErrNotExist) && data.Create {
+		file, err := os.OpenFile(cleanedPath, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return 0, fmt.Errorf("cannot create file %q: %w", data.Path, err)
+		}
+		utilfn.GracefulClose(file, "RemoteFileTruncateCommand", cleanedPath)
+	} else if err != nil {
+		return 0, fmt.Errorf("cannot stat file %q: %w", data.Path, err)
+	} else if finfo.IsDir() {
+		return 0, fmt.Errorf("cannot truncate %q: is a directory", data.Path)
+	}
+	if err := os.Truncate(cleanedPath, data.Size); err != nil {
+		return 0, fmt.Errorf("cannot truncate file %q: %w", data.Path, err)
+	}
+	finfo, err = os.Stat(cleanedPath)
+	if err != nil {
+		return 0, fmt.Errorf("cannot stat file %q: %w", data.Path, err)
+	}
+	return finfo.Size(), nil
+}
+
+func checkRecursiveDelete(path string) error {
+	paths := []string{filepath.Clean(path)}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		paths = append(paths, resolved)
+	}
+	homeDir := filepath.Clean(wavebase.GetHomeDir())
+	for _, p := range paths {
+		if filepath.Dir(p) == p {
+			return fmt.Errorf("refusing to recursively delete filesystem root %q", p)
+		}
+		if p == homeDir {
+			return fmt.Errorf("refusing to recursively delete home directory %q", p)
+		}
+	}
+	return nil
+}
+
+func (impl *ServerImpl) RemoteFileDeleteCommand(ctx context.Context, data wshrpc.CommandDeleteFileData) error {
+	expandedPath, err := impl.cleanPath(data.Path)
 	if err != nil {
 		return fmt.Errorf("cannot delete file %q: %w", data.Path, err)
 	}
 	cleanedPath := filepath.Clean(expandedPath)
+	if data.Recursive {
+		if err := checkRecursiveDelete(cleanedPath); err != nil {
+			return err
+		}
+	}
 
 	err = os.Remove(cleanedPath)
 	if err != nil {
@@ -0,0 +1,3347 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wshremote
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/wavetermdev/waveterm/pkg/util/fileutil"
+	"github.com/wavetermdev/waveterm/pkg/util/iochan"
+	"github.com/wavetermdev/waveterm/pkg/util/iochan/iochantypes"
+	"github.com/wavetermdev/waveterm/pkg/util/tarcopy"
+	"github.com/wavetermdev/waveterm/pkg/util/utilfn"
+	"github.com/wavetermdev/waveterm/pkg/wavebase"
+	"github.com/wavetermdev/waveterm/pkg/wshrpc"
+)
+
+func TestParseByteRange(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ByteRangeType
+		wantErr  bool
+	}{
+		{"", ByteRangeType{All: true}, false},
+		{"100-200", ByteRangeType{Start: 100, End: 200}, false},
+		{"100-", ByteRangeType{Start: 100, End: ByteRangeEOF}, false},
+		{"-50", ByteRangeType{SuffixLen: 50, End: ByteRangeEOF}, false},
+		{"200-100", ByteRangeType{}, true},
+		{"-", ByteRangeType{}, true},
+		{"-0", ByteRangeType{}, true},
+		{"abc", ByteRangeType{}, true},
+		{"10-x", ByteRangeType{}, true},
+	}
+	for _, tc := range tests {
+		br, err := parseByteRange(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseByteRange(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			continue
+		}
+		if br != tc.expected {
+			t.Errorf("parseByteRange(%q) = %+v, expected %+v", tc.input, br, tc.expected)
+		}
+	}
+}
+
+func readRange(t *testing.T, path string, rangeStr string) []byte {
+	t.Helper()
+	byteRange, err := parseByteRange(rangeStr)
+	if err != nil {
+		t.Fatalf("parseByteRange(%q): %v", rangeStr, err)
+	}
+	var rtn []byte
+	impl := &ServerImpl{}
+	err = impl.remoteStreamFileRegular(context.Background(), path, byteRange, wshrpc.FileChunkSize, func(fileInfo []*wshrpc.FileInfo, data []byte, byteRange ByteRangeType) {
+		rtn = append(rtn, data...)
+	})
+	if err != nil {
+		t.Fatalf("remoteStreamFileRegular(%q): %v", rangeStr, err)
+	}
+	return rtn
+}
+
+func TestStreamFileRegular_ByteRanges(t *testing.T) {
+	content := make([]byte, 1000)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("cannot write test file: %v", err)
+	}
+
+	if got := readRange(t, path, "100-200"); string(got) != string(content[100:200]) {
+		t.Errorf("100-200: got %d bytes, expected %d", len(got), 100)
+	}
+	if got := readRange(t, path, "100-"); string(got) != string(content[100:]) {
+		t.Errorf("100-: got %d bytes, expected %d", len(got), 900)
+	}
+	if got := readRange(t, path, "-50"); string(got) != string(content[950:]) {
+		t.Errorf("-50: got %d bytes, expected %d", len(got), 50)
+	}
+	if got := readRange(t, path, "-5000"); string(got) != string(content) {
+		t.Errorf("-5000: got %d bytes, expected %d", len(got), len(content))
+	}
+}
+
+func TestStreamFile_MultipleRanges(t *testing.T) {
+	content := make([]byte, 2000)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("cannot write test file: %v", err)
+	}
+
+	type chunk struct {
+		offset int64
+		data   []byte
+	}
+	streamRanges := func(rangeStr string) []chunk {
+		var rtn []chunk
+		impl := &ServerImpl{}
+		for resp := range impl.RemoteStreamFileCommand(context.Background(), wshrpc.CommandRemoteStreamFileData{Path: path, ByteRange: rangeStr}) {
+			if resp.Error != nil {
+				t.Fatalf("RemoteStreamFileCommand(%q): %v", rangeStr, resp.Error)
+			}
+			if resp.Response.At == nil {
+				continue
+			}
+			data, err := base64.StdEncoding.DecodeString(resp.Response.Data64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rtn = append(rtn, chunk{offset: resp.Response.At.Offset, data: data})
+		}
+		return rtn
+	}
+
+	chunks := streamRanges("0-100,500-600")
+	if len(chunks) != 2 {
+		t.Fatalf("0-100,500-600: expected 2 chunks, got %d", len(chunks))
+	}
+	if chunks[0].offset != 0 || !bytes.Equal(chunks[0].data, content[0:100]) {
+		t.Errorf("first range: offset %d, %d bytes", chunks[0].offset, len(chunks[0].data))
+	}
+	if chunks[1].offset != 500 || !bytes.Equal(chunks[1].data, content[500:600]) {
+		t.Errorf("second range: offset %d, %d bytes", chunks[1].offset, len(chunks[1].data))
+	}
+
+	chunks = streamRanges("1000-1010,1500-")
+	if len(chunks) != 2 {
+		t.Fatalf("1000-1010,1500-: expected 2 chunks, got %d", len(chunks))
+	}
+	if chunks[1].offset != 1500 || !bytes.Equal(chunks[1].data, content[1500:]) {
+		t.Errorf("open-ended range: offset %d, %d bytes", chunks[1].offset, len(chunks[1].data))
+	}
+
+	// ranges are served in request order and overlaps are not merged
+	chunks = streamRanges("50-60,40-55")
+	if len(chunks) != 2 || chunks[0].offset != 50 || chunks[1].offset != 40 || !bytes.Equal(chunks[1].data, content[40:55]) {
+		t.Errorf("out-of-order/overlapping ranges: got %+v", chunks)
+	}
+
+	if _, err := parseByteRanges("0-10,"); err == nil {
+		t.Errorf("expected error for empty range in list")
+	}
+}
+
+func TestStreamFileDir_OpenEndedRange(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("cannot write test file: %v", err)
+		}
+	}
+	listRange := func(rangeStr string) []string {
+		byteRange, err := parseByteRange(rangeStr)
+		if err != nil {
+			t.Fatalf("parseByteRange(%q): %v", rangeStr, err)
+		}
+		var names []string
+		impl := &ServerImpl{}
+		err = impl.remoteStreamFileDir(context.Background(), dir, byteRange, wshrpc.DirChunkSize, func(fileInfo []*wshrpc.FileInfo, data []byte, byteRange ByteRangeType) {
+			for _, fi := range fileInfo {
+				names = append(names, fi.Name)
+			}
+		})
+		if err != nil {
+			t.Fatalf("remoteStreamFileDir(%q): %v", rangeStr, err)
+		}
+		return names
+	}
+	if names := listRange("2-"); len(names) != 3 || names[0] != "c" {
+		t.Errorf("2-: got %v, expected [c d e]", names)
+	}
+	if names := listRange("-2"); len(names) != 2 || names[0] != "d" {
+		t.Errorf("-2: got %v, expected [d e]", names)
+	}
+	if names := listRange("1-3"); len(names) != 2 || names[0] != "b" {
+		t.Errorf("1-3: got %v, expected [b c]", names)
+	}
+}
+
+func TestFileCopy_PreserveTimestamps(t *testing.T) {
+	srcDir := filepath.Join(t.TempDir(), "src")
+	destDir := filepath.Join(t.TempDir(), "dest")
+	subDir := filepath.Join(srcDir, "sub")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("cannot create test dirs: %v", err)
+	}
+	filePath := filepath.Join(subDir, "file.txt")
+	if err := os.WriteFile(filePath, []byte("hello"), 0644); err != nil {
+		t.Fatalf("cannot write test file: %v", err)
+	}
+	fileTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	dirTime := time.Date(2019, 6, 7, 8, 9, 10, 0, time.UTC)
+	for _, p := range []string{filePath, subDir, srcDir} {
+		mtime := dirTime
+		if p == filePath {
+			mtime = fileTime
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatalf("cannot set times: %v", err)
+		}
+	}
+
+	impl := &ServerImpl{}
+	_, err := impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + srcDir,
+		DestUri: "wsh://local/" + destDir,
+		Opts:    &wshrpc.FileCopyOpts{PreserveTimestamps: true},
+	})
+	if err != nil {
+		t.Fatalf("RemoteFileCopyCommand: %v", err)
+	}
+	checkMtime := func(path string, expected time.Time) {
+		finfo, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("cannot stat %q: %v", path, err)
+		}
+		if !finfo.ModTime().Equal(expected) {
+			t.Errorf("%q: mtime %v, expected %v", path, finfo.ModTime(), expected)
+		}
+	}
+	checkMtime(filepath.Join(destDir, "sub", "file.txt"), fileTime)
+	checkMtime(filepath.Join(destDir, "sub"), dirTime)
+	checkMtime(destDir, dirTime)
+}
+
+func makeSymlinkTree(t *testing.T) string {
+	t.Helper()
+	srcDir := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(filepath.Join(srcDir, "dir"), 0755); err != nil {
+		t.Fatalf("cannot create test dirs: %v", err)
+	}
+	targetPath := filepath.Join(srcDir, "dir", "target.txt")
+	if err := os.WriteFile(targetPath, []byte("target"), 0644); err != nil {
+		t.Fatalf("cannot write test file: %v", err)
+	}
+	if err := os.Symlink("dir/target.txt", filepath.Join(srcDir, "rel-link")); err != nil {
+		t.Fatalf("cannot create symlink: %v", err)
+	}
+	if err := os.Symlink(targetPath, filepath.Join(srcDir, "abs-link")); err != nil {
+		t.Fatalf("cannot create symlink: %v", err)
+	}
+	if err := os.Symlink("dir", filepath.Join(srcDir, "dir-link")); err != nil {
+		t.Fatalf("cannot create symlink: %v", err)
+	}
+	return srcDir
+}
+
+func TestFileCopy_Symlinks(t *testing.T) {
+	srcDir := makeSymlinkTree(t)
+	impl := &ServerImpl{}
+
+	preserveDest := filepath.Join(t.TempDir(), "preserve")
+	_, err := impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + srcDir,
+		DestUri: "wsh://local/" + preserveDest,
+	})
+	if err != nil {
+		t.Fatalf("RemoteFileCopyCommand: %v", err)
+	}
+	for name, expected := range map[string]string{
+		"rel-link": "dir/target.txt",
+		"abs-link": filepath.Join(srcDir, "dir", "target.txt"),
+		"dir-link": "dir",
+	} {
+		target, err := os.Readlink(filepath.Join(preserveDest, name))
+		if err != nil {
+			t.Errorf("%s: expected symlink: %v", name, err)
+			continue
+		}
+		if target != expected {
+			t.Errorf("%s: link target %q, expected %q", name, target, expected)
+		}
+	}
+
+	followDest := filepath.Join(t.TempDir(), "follow")
+	_, err = impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + srcDir,
+		DestUri: "wsh://local/" + followDest,
+		Opts:    &wshrpc.FileCopyOpts{FollowSymlinks: true},
+	})
+	if err != nil {
+		t.Fatalf("RemoteFileCopyCommand: %v", err)
+	}
+	for _, name := range []string{"rel-link", "abs-link", "dir-link/target.txt"} {
+		path := filepath.Join(followDest, name)
+		finfo, err := os.Lstat(path)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !finfo.Mode().IsRegular() {
+			t.Errorf("%s: expected regular file, got mode %v", name, finfo.Mode())
+		}
+		if contents, _ := os.ReadFile(path); string(contents) != "target" {
+			t.Errorf("%s: contents %q, expected %q", name, contents, "target")
+		}
+	}
+}
+
+func TestFileCopy_SymlinkCycle(t *testing.T) {
+	srcDir := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		t.Fatalf("cannot create test dir: %v", err)
+	}
+	if err := os.Symlink("..", filepath.Join(srcDir, "parent-link")); err != nil {
+		t.Fatalf("cannot create symlink: %v", err)
+	}
+	impl := &ServerImpl{}
+	_, err := impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + srcDir,
+		DestUri: "wsh://local/" + filepath.Join(t.TempDir(), "dest"),
+		Opts:    &wshrpc.FileCopyOpts{FollowSymlinks: true},
+	})
+	if err == nil {
+		t.Fatalf("expected symlink cycle error")
+	}
+}
+
+func TestTarStream_Symlinks(t *testing.T) {
+	srcDir := makeSymlinkTree(t)
+	impl := &ServerImpl{}
+	ch := impl.RemoteTarStreamCommand(context.Background(), wshrpc.CommandRemoteStreamTarData{Path: srcDir})
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	links := make(map[string]string)
+	err := tarcopy.TarCopyDest(ctx, cancel, ch, func(next *tar.Header, reader *tar.Reader, singleFile bool) error {
+		if next.Typeflag == tar.TypeSymlink {
+			links[next.Name] = next.Linkname
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TarCopyDest: %v", err)
+	}
+	if links["src/rel-link"] != "dir/target.txt" {
+		t.Errorf("rel-link: got %q", links["src/rel-link"])
+	}
+	if links["src/abs-link"] != filepath.Join(srcDir, "dir", "target.txt") {
+		t.Errorf("abs-link: got %q", links["src/abs-link"])
+	}
+}
+
+func TestFileCopyStream_Progress(t *testing.T) {
+	srcDir := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		t.Fatalf("cannot create test dir: %v", err)
+	}
+	bigFile := make([]byte, copyProgressIntervalBytes*2+100)
+	if err := os.WriteFile(filepath.Join(srcDir, "big.bin"), bigFile, 0644); err != nil {
+		t.Fatalf("cannot write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "small.txt"), []byte("small"), 0644); err != nil {
+		t.Fatalf("cannot write test file: %v", err)
+	}
+	impl := &ServerImpl{}
+	ch := impl.RemoteFileCopyStreamCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + srcDir,
+		DestUri: "wsh://local/" + filepath.Join(t.TempDir(), "dest"),
+	})
+	var numPackets int
+	var last wshrpc.CommandRemoteFileCopyProgress
+	for resp := range ch {
+		if resp.Error != nil {
+			t.Fatalf("RemoteFileCopyStreamCommand: %v", resp.Error)
+		}
+		numPackets++
+		last = resp.Response
+	}
+	expectedBytes := int64(len(bigFile) + len("small"))
+	if last.FilesDone != 2 || last.BytesDone != expectedBytes {
+		t.Errorf("final progress: %+v, expected 2 files and %d bytes", last, expectedBytes)
+	}
+	// two interval updates for the big file, one per file, and the final update
+	if numPackets != 5 {
+		t.Errorf("got %d progress packets, expected 5", numPackets)
+	}
+}
+
+func TestFileCopy_Resume(t *testing.T) {
+	dir := t.TempDir()
+	content := make([]byte, 1000)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	srcPath := filepath.Join(dir, "src.bin")
+	destPath := filepath.Join(dir, "dest.bin")
+	if err := os.WriteFile(srcPath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(destPath, content[:400], 0644); err != nil {
+		t.Fatal(err)
+	}
+	impl := &ServerImpl{}
+	_, err := impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + srcPath,
+		DestUri: "wsh://local/" + destPath,
+		Opts:    &wshrpc.FileCopyOpts{Resume: true},
+	})
+	if err != nil {
+		t.Fatalf("RemoteFileCopyCommand: %v", err)
+	}
+	got, err := os.ReadFile(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("resumed file does not match source, got %d bytes", len(got))
+	}
+
+	// a destination that isn't a prefix of the source must not be appended to
+	if err := os.WriteFile(destPath, []byte("something else"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + srcPath,
+		DestUri: "wsh://local/" + destPath,
+		Opts:    &wshrpc.FileCopyOpts{Resume: true},
+	})
+	if err == nil {
+		t.Errorf("expected error resuming onto a mismatched destination")
+	}
+}
+
+func TestTarStream_Resume(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("0123456789abcdefghij")
+	srcPath := filepath.Join(dir, "src.txt")
+	if err := os.WriteFile(srcPath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	checksum, err := hashFilePrefix(srcPath, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	impl := &ServerImpl{}
+	ch := impl.RemoteTarStreamCommand(context.Background(), wshrpc.CommandRemoteStreamTarData{
+		Path: srcPath,
+		Opts: &wshrpc.FileCopyOpts{ResumeOffset: 10, ResumeChecksum: checksum},
+	})
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	var got []byte
+	var offset string
+	err = tarcopy.TarCopyDest(ctx, cancel, ch, func(next *tar.Header, reader *tar.Reader, singleFile bool) error {
+		offset = next.PAXRecords[tarcopy.ResumeOffset]
+		var readErr error
+		got, readErr = io.ReadAll(reader)
+		return readErr
+	})
+	if err != nil {
+		t.Fatalf("TarCopyDest: %v", err)
+	}
+	if offset != "10" {
+		t.Errorf("resume offset record: got %q", offset)
+	}
+	if string(got) != "abcdefghij" {
+		t.Errorf("resumed data: got %q", got)
+	}
+}
+
+func appendData(t *testing.T, impl *ServerImpl, path string, data string) *wshrpc.FileInfo {
+	t.Helper()
+	finfo, err := impl.RemoteFileAppendCommand(context.Background(), wshrpc.FileData{
+		Info:   &wshrpc.FileInfo{Path: path},
+		Data64: base64.StdEncoding.EncodeToString([]byte(data)),
+	})
+	if err != nil {
+		t.Fatalf("RemoteFileAppendCommand: %v", err)
+	}
+	return finfo
+}
+
+func TestFileAppend_NewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "new.log")
+	impl := &ServerImpl{}
+	finfo := appendData(t, impl, path, "hello\n")
+	if finfo.Size != 6 {
+		t.Errorf("size: got %d, want 6", finfo.Size)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello\n" {
+		t.Errorf("content: got %q", got)
+	}
+}
+
+func TestFileAppend_Existing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "existing.log")
+	if err := os.WriteFile(path, []byte("line1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	impl := &ServerImpl{}
+	appendData(t, impl, path, "line2\n")
+	finfo := appendData(t, impl, path, "line3\n")
+	if finfo.Size != 18 {
+		t.Errorf("size: got %d, want 18", finfo.Size)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "line1\nline2\nline3\n" {
+		t.Errorf("content: got %q", got)
+	}
+}
+
+func TestWriteFile_Atomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a write that fails partway through
+	err := writeFileAtomic(path, 0644, func(w io.Writer) error {
+		if _, err := w.Write([]byte("partial")); err != nil {
+			return err
+		}
+		return errors.New("interrupted")
+	})
+	if err == nil {
+		t.Fatalf("expected error from interrupted write")
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "original" {
+		t.Errorf("original file modified: got %q", got)
+	}
+
+	impl := &ServerImpl{}
+	err = impl.RemoteWriteFileCommand(context.Background(), wshrpc.FileData{
+		Info:   &wshrpc.FileInfo{Path: path, Mode: 0600, Opts: &wshrpc.FileOpts{Atomic: true}},
+		Data64: base64.StdEncoding.EncodeToString([]byte("updated")),
+	})
+	if err != nil {
+		t.Fatalf("RemoteWriteFileCommand: %v", err)
+	}
+	got, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "updated" {
+		t.Errorf("content: got %q", got)
+	}
+	finfo, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finfo.Mode().Perm() != 0600 {
+		t.Errorf("mode: got %v, want 0600", finfo.Mode().Perm())
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "wsh-tmp-") {
+			t.Errorf("temp file left behind: %s", entry.Name())
+		}
+	}
+}
+
+func TestWriteFile_Sha256(t *testing.T) {
+	dir := t.TempDir()
+	impl := &ServerImpl{}
+	write := func(name string, content string, sha string) error {
+		return impl.RemoteWriteFileCommand(context.Background(), wshrpc.FileData{
+			Info:   &wshrpc.FileInfo{Path: filepath.Join(dir, name), Opts: &wshrpc.FileOpts{Sha256: sha}},
+			Data64: base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+	}
+	checksum := sha256.Sum256([]byte("hello"))
+	digest := hex.EncodeToString(checksum[:])
+
+	if err := write("match.txt", "hello", strings.ToUpper(digest)); err != nil {
+		t.Fatalf("matching digest: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "match.txt")); string(data) != "hello" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	err := write("corrupt.txt", "hellO", digest)
+	if !errors.Is(err, iochan.ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "corrupt.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected no file after a failed write, stat err: %v", err)
+	}
+
+	if err := write("nodigest.txt", "hello", ""); err != nil {
+		t.Fatalf("write without digest: %v", err)
+	}
+}
+
+func TestWriteFile_Conditional(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	writeTestFile(t, path, "v1")
+	finfo, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modTime := finfo.ModTime().UnixMilli()
+
+	impl := &ServerImpl{}
+	write := func(content string, opts *wshrpc.FileOpts) error {
+		if opts == nil {
+			opts = &wshrpc.FileOpts{}
+		}
+		opts.Truncate = true
+		return impl.RemoteWriteFileCommand(context.Background(), wshrpc.FileData{
+			Info:   &wshrpc.FileInfo{Path: path, Opts: opts},
+			Data64: base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+	}
+	readContent := func() string {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(got)
+	}
+
+	if err := write("v2", &wshrpc.FileOpts{ExpectedModTime: modTime, ExpectedSize: 2}); err != nil {
+		t.Fatalf("matching write: %v", err)
+	}
+	if got := readContent(); got != "v2" {
+		t.Fatalf("content after matching write: got %q", got)
+	}
+
+	// someone else changes the file behind our back
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+	err = write("v3", &wshrpc.FileOpts{ExpectedModTime: modTime})
+	if err == nil || !strings.Contains(err.Error(), "was modified since it was read") {
+		t.Fatalf("expected conflict on modtime mismatch, got %v", err)
+	}
+	err = write("v3", &wshrpc.FileOpts{ExpectedSize: 10})
+	if err == nil || !strings.Contains(err.Error(), "was modified since it was read") {
+		t.Fatalf("expected conflict on size mismatch, got %v", err)
+	}
+	if got := readContent(); got != "v2" {
+		t.Fatalf("conflicting write modified the file: got %q", got)
+	}
+
+	if err := write("v4", nil); err != nil {
+		t.Fatalf("unchecked write: %v", err)
+	}
+	if got := readContent(); got != "v4" {
+		t.Fatalf("content after unchecked write: got %q", got)
+	}
+
+	os.Remove(path)
+	err = write("v5", &wshrpc.FileOpts{ExpectedSize: 2})
+	if err == nil || !strings.Contains(err.Error(), "was modified since it was read") {
+		t.Fatalf("expected conflict for deleted file, got %v", err)
+	}
+}
+
+func TestFileCopy_HardlinkAndMode(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "script.sh")
+	if err := os.WriteFile(srcPath, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(srcPath, 0751); err != nil {
+		t.Fatal(err)
+	}
+	impl := &ServerImpl{}
+
+	// the exact mode needs PreserveMode, without it the umask applies (see TestFileCopy_PreserveFlags)
+	copyPath := filepath.Join(dir, "copy.sh")
+	_, err := impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + srcPath,
+		DestUri: "wsh://local/" + copyPath,
+		Opts:    &wshrpc.FileCopyOpts{PreserveMode: true},
+	})
+	if err != nil {
+		t.Fatalf("RemoteFileCopyCommand: %v", err)
+	}
+	srcInfo, _ := os.Stat(srcPath)
+	copyInfo, err := os.Stat(copyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copyInfo.Mode().Perm() != 0751 {
+		t.Errorf("copy mode: got %v, want 0751", copyInfo.Mode().Perm())
+	}
+	if os.SameFile(srcInfo, copyInfo) {
+		t.Errorf("copy without hardlink option should not share the source inode")
+	}
+
+	linkPath := filepath.Join(dir, "link.sh")
+	_, err = impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + srcPath,
+		DestUri: "wsh://local/" + linkPath,
+		Opts:    &wshrpc.FileCopyOpts{Hardlink: true},
+	})
+	if err != nil {
+		t.Fatalf("RemoteFileCopyCommand: %v", err)
+	}
+	linkInfo, err := os.Stat(linkPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(srcInfo, linkInfo) {
+		t.Errorf("expected %q to be a hardlink to %q", linkPath, srcPath)
+	}
+}
+
+func makeBenchFile(b *testing.B) string {
+	b.Helper()
+	path := filepath.Join(b.TempDir(), "bench.bin")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("0123456789abcdef"), 512*1024), 0644); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+// BenchmarkFileCopy_Local measures the same-machine fast path
+func BenchmarkFileCopy_Local(b *testing.B) {
+	srcPath := makeBenchFile(b)
+	destPath := filepath.Join(b.TempDir(), "dest.bin")
+	impl := &ServerImpl{}
+	b.SetBytes(8 * 1024 * 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+			SrcUri:  "wsh://local/" + srcPath,
+			DestUri: "wsh://local/" + destPath,
+			Opts:    &wshrpc.FileCopyOpts{Overwrite: true},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFileCopy_TarStream measures the tar stream used between machines (without the rpc hop)
+func BenchmarkFileCopy_TarStream(b *testing.B) {
+	srcPath := makeBenchFile(b)
+	destPath := filepath.Join(b.TempDir(), "dest.bin")
+	impl := &ServerImpl{}
+	b.SetBytes(8 * 1024 * 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ch := impl.RemoteTarStreamCommand(context.Background(), wshrpc.CommandRemoteStreamTarData{Path: srcPath})
+		ctx, cancel := context.WithCancelCause(context.Background())
+		err := tarcopy.TarCopyDest(ctx, cancel, ch, func(next *tar.Header, reader *tar.Reader, singleFile bool) error {
+			file, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, next.FileInfo().Mode())
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = io.Copy(file, reader)
+			return err
+		})
+		cancel(nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReadFileRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.bin")
+	content := make([]byte, 3*wshrpc.FileChunkSize+777)
+	for i := range content {
+		content[i] = byte(i * 7 % 256)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	impl := &ServerImpl{}
+	readRange := func(data wshrpc.CommandRemoteReadFileRangeData) ([]byte, error) {
+		var buf bytes.Buffer
+		var streamErr error
+		done := make(chan struct{})
+		ch := impl.RemoteReadFileRangeCommand(context.Background(), data)
+		// WriterChan verifies the trailing checksum packet
+		iochan.WriterChan(context.Background(), &buf, ch, func(int64) { close(done) }, func(err error) { streamErr = err })
+		<-done
+		return buf.Bytes(), streamErr
+	}
+
+	tests := []struct {
+		name   string
+		offset int64
+		size   int64
+		want   []byte
+	}{
+		{"within a chunk", 10, 100, content[10:110]},
+		{"across chunks", wshrpc.FileChunkSize - 5, 2*wshrpc.FileChunkSize + 10, content[wshrpc.FileChunkSize-5 : 3*wshrpc.FileChunkSize+5]},
+		{"past eof", int64(len(content)) - 50, 1000, content[len(content)-50:]},
+		{"after eof", int64(len(content)) + 10, 10, nil},
+	}
+	for _, tc := range tests {
+		got, err := readRange(wshrpc.CommandRemoteReadFileRangeData{Path: path, Offset: tc.offset, Size: tc.size})
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("%s: got %d bytes, expected %d matching bytes", tc.name, len(got), len(tc.want))
+		}
+	}
+
+	for _, data := range []wshrpc.CommandRemoteReadFileRangeData{
+		{Path: path, Offset: -1, Size: 10},
+		{Path: path, Size: 0},
+		{Path: filepath.Dir(path), Size: 10},
+		{Path: path + ".missing", Size: 10},
+	} {
+		if _, err := readRange(data); err == nil {
+			t.Errorf("%+v: expected an error", data)
+		}
+	}
+}
+
+// benchReadRange drains a read of the whole bench file, marshaling each response the way the rpc layer does.
+// the json encoder turns []byte into base64 as well, so the wire size is about the same for both, the
+// difference is the extra base64 string (and FileData) built per chunk by RemoteStreamFileCommand.
+func benchReadRange[T any](b *testing.B, read func(path string) <-chan wshrpc.RespOrErrorUnion[T]) {
+	srcPath := makeBenchFile(b)
+	b.SetBytes(8 * 1024 * 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	var wireBytes int
+	for i := 0; i < b.N; i++ {
+		for resp := range read(srcPath) {
+			if resp.Error != nil {
+				b.Fatal(resp.Error)
+			}
+			msg, err := json.Marshal(resp.Response)
+			if err != nil {
+				b.Fatal(err)
+			}
+			wireBytes += len(msg)
+		}
+	}
+	b.ReportMetric(float64(wireBytes)/float64(b.N), "wirebytes/op")
+}
+
+func BenchmarkReadRange_StreamFile(b *testing.B) {
+	impl := &ServerImpl{}
+	benchReadRange(b, func(path string) <-chan wshrpc.RespOrErrorUnion[wshrpc.FileData] {
+		return impl.RemoteStreamFileCommand(context.Background(), wshrpc.CommandRemoteStreamFileData{Path: path, ByteRange: "0-8388607"})
+	})
+}
+
+func BenchmarkReadRange_Packets(b *testing.B) {
+	impl := &ServerImpl{}
+	benchReadRange(b, func(path string) <-chan wshrpc.RespOrErrorUnion[iochantypes.Packet] {
+		return impl.RemoteReadFileRangeCommand(context.Background(), wshrpc.CommandRemoteReadFileRangeData{Path: path, Size: 8 * 1024 * 1024})
+	})
+}
+
+func listNames(t *testing.T, path string, opts *wshrpc.FileListOpts) []string {
+	t.Helper()
+	impl := &ServerImpl{}
+	var names []string
+	for resp := range impl.RemoteListEntriesCommand(context.Background(), wshrpc.CommandRemoteListEntriesData{Path: path, Opts: opts}) {
+		if resp.Error != nil {
+			t.Fatalf("RemoteListEntriesCommand: %v", resp.Error)
+		}
+		for _, finfo := range resp.Response.FileInfo {
+			names = append(names, finfo.Name)
+		}
+	}
+	return names
+}
+
+func TestListEntries_Sort(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	files := []struct {
+		name  string
+		size  int
+		mtime time.Time
+	}{
+		{"a", 30, base.Add(time.Hour)},
+		{"b", 10, base.Add(3 * time.Hour)},
+		{"c", 20, base.Add(2 * time.Hour)},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, make([]byte, f.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, f.mtime, f.mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dir, "d"), base, base); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		opts wshrpc.FileListOpts
+		want string
+	}{
+		{wshrpc.FileListOpts{SortBy: wshrpc.FileListSortBy_Name}, "a,b,c,d"},
+		{wshrpc.FileListOpts{SortBy: wshrpc.FileListSortBy_Name, SortDesc: true}, "d,c,b,a"},
+		{wshrpc.FileListOpts{SortBy: wshrpc.FileListSortBy_Size}, "d,b,c,a"},
+		{wshrpc.FileListOpts{SortBy: wshrpc.FileListSortBy_Size, SortDesc: true}, "a,c,b,d"},
+		{wshrpc.FileListOpts{SortBy: wshrpc.FileListSortBy_ModTime}, "d,a,c,b"},
+		{wshrpc.FileListOpts{SortBy: wshrpc.FileListSortBy_ModTime, SortDesc: true}, "b,c,a,d"},
+		{wshrpc.FileListOpts{SortBy: wshrpc.FileListSortBy_Name, SortDesc: true, DirsFirst: true}, "d,c,b,a"},
+		{wshrpc.FileListOpts{SortBy: wshrpc.FileListSortBy_Size, SortDesc: true, DirsFirst: true}, "d,a,c,b"},
+		{wshrpc.FileListOpts{SortBy: wshrpc.FileListSortBy_Size, Offset: 1, Limit: 2}, "b,c"},
+	}
+	for _, tc := range tests {
+		opts := tc.opts
+		got := strings.Join(listNames(t, dir, &opts), ",")
+		if got != tc.want {
+			t.Errorf("%+v: got %s, want %s", tc.opts, got, tc.want)
+		}
+	}
+
+	opts := wshrpc.FileListOpts{SortBy: "bogus"}
+	impl := &ServerImpl{}
+	resp := <-impl.RemoteListEntriesCommand(context.Background(), wshrpc.CommandRemoteListEntriesData{Path: dir, Opts: &opts})
+	if resp.Error == nil {
+		t.Errorf("expected error for invalid sort key")
+	}
+}
+
+func TestFileDelete_Recursive(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tree")
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a", "b", "file.txt"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	impl := &ServerImpl{}
+	if err := impl.RemoteFileDeleteCommand(context.Background(), wshrpc.CommandDeleteFileData{Path: dir}); err == nil {
+		t.Errorf("expected error deleting a non-empty directory without recursive")
+	}
+	if err := impl.RemoteFileDeleteCommand(context.Background(), wshrpc.CommandDeleteFileData{Path: dir, Recursive: true}); err != nil {
+		t.Fatalf("RemoteFileDeleteCommand: %v", err)
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected %q to be deleted, stat err: %v", dir, err)
+	}
+}
+
+func TestFileDelete_RootGuard(t *testing.T) {
+	for _, path := range []string{"/", "/.", "/tmp/.."} {
+		if err := checkRecursiveDelete(path); err == nil {
+			t.Errorf("expected guard to refuse %q", path)
+		}
+	}
+	link := filepath.Join(t.TempDir(), "root-link")
+	if err := os.Symlink("/", link); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkRecursiveDelete(link); err == nil {
+		t.Errorf("expected guard to refuse symlink to root")
+	}
+	if err := checkRecursiveDelete(t.TempDir()); err != nil {
+		t.Errorf("unexpected guard error: %v", err)
+	}
+}
+
+func TestFileDelete_HomeGuard(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, "keep.txt"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	impl := &ServerImpl{}
+	for _, path := range []string{"~", home, home + "/"} {
+		err := impl.RemoteFileDeleteCommand(context.Background(), wshrpc.CommandDeleteFileData{Path: path, Recursive: true})
+		if err == nil || !strings.Contains(err.Error(), "home directory") {
+			t.Errorf("%q: expected home directory guard error, got %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(home, "keep.txt")); err != nil {
+		t.Errorf("home directory contents were deleted: %v", err)
+	}
+}
+
+func TestFileMove_CrossDevice(t *testing.T) {
+	// simulate rename failing across filesystems so the copy+delete fallback is used
+	origRename := osRename
+	osRename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	defer func() { osRename = origRename }()
+
+	srcDir := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(filepath.Join(srcDir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(srcDir, "sub", "run.sh")
+	if err := os.WriteFile(filePath, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filePath, 0751); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filePath, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub/run.sh", filepath.Join(srcDir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	destDir := filepath.Join(t.TempDir(), "dest")
+	impl := &ServerImpl{}
+	err := impl.RemoteFileMoveCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + srcDir,
+		DestUri: "wsh://local/" + destDir,
+		Opts:    &wshrpc.FileCopyOpts{Recursive: true},
+	})
+	if err != nil {
+		t.Fatalf("RemoteFileMoveCommand: %v", err)
+	}
+	if _, err := os.Stat(srcDir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected source to be removed, stat err: %v", err)
+	}
+	finfo, err := os.Stat(filepath.Join(destDir, "sub", "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finfo.Mode().Perm() != 0751 {
+		t.Errorf("mode: got %v, want 0751", finfo.Mode().Perm())
+	}
+	if !finfo.ModTime().Equal(mtime) {
+		t.Errorf("mtime: got %v, want %v", finfo.ModTime(), mtime)
+	}
+	if target, err := os.Readlink(filepath.Join(destDir, "link")); err != nil || target != "sub/run.sh" {
+		t.Errorf("symlink: got %q, %v", target, err)
+	}
+}
+
+// returns the contents of every regular file under root, keyed by slash-separated relative path.
+// withDirs also records the directories below root, with a "<dir>" value.
+func treeFiles(t *testing.T, root string, withDirs bool) map[string]string {
+	t.Helper()
+	rtn := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		isDir := withDirs && d.IsDir()
+		if !isDir && !d.Type().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if isDir {
+			rtn[filepath.ToSlash(relPath)] = "<dir>"
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		rtn[filepath.ToSlash(relPath)] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("cannot read tree %q: %v", root, err)
+	}
+	return rtn
+}
+
+func moveCmd(src string, dest string, opts *wshrpc.FileCopyOpts) error {
+	impl := &ServerImpl{}
+	return impl.RemoteFileMoveCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + src,
+		DestUri: "wsh://local/" + dest,
+		Opts:    opts,
+	})
+}
+
+func TestFileMove_OverwriteFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	dest := filepath.Join(dir, "dest.txt")
+	writeTestFile(t, src, "new")
+	writeTestFile(t, dest, "old")
+
+	if err := moveCmd(src, dest, nil); err == nil || !strings.Contains(err.Error(), "overwrite") {
+		t.Fatalf("expected overwrite required error, got %v", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "old" {
+		t.Fatalf("destination changed without overwrite: %q", data)
+	}
+	if err := moveCmd(src, dest, &wshrpc.FileCopyOpts{Overwrite: true}); err != nil {
+		t.Fatalf("move with overwrite: %v", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "new" {
+		t.Errorf("destination: got %q, expected %q", data, "new")
+	}
+	if _, err := os.Stat(src); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected source to be removed, stat err: %v", err)
+	}
+
+	// a file moved onto a directory goes inside it
+	writeTestFile(t, src, "inside")
+	destDir := filepath.Join(dir, "dir")
+	if err := os.Mkdir(destDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveCmd(src, destDir, nil); err != nil {
+		t.Fatalf("move into directory: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(destDir, "src.txt")); string(data) != "inside" {
+		t.Errorf("file in directory: got %q", data)
+	}
+}
+
+func moveStreamCmd(src string, dest string, opts *wshrpc.FileCopyOpts) ([]wshrpc.CommandRemoteFileCopyProgress, error) {
+	impl := &ServerImpl{}
+	var progress []wshrpc.CommandRemoteFileCopyProgress
+	var err error
+	for resp := range impl.RemoteFileMoveStreamCommand(context.Background(), wshrpc.CommandFileCopyData{
+		SrcUri:  "wsh://local/" + src,
+		DestUri: "wsh://local/" + dest,
+		Opts:    opts,
+	}) {
+		if resp.Error != nil {
+			err = resp.Error
+			continue
+		}
+		progress = append(progress, resp.Response)
+	}
+	return progress, err
+}
+
+func TestFileMoveStream_CrossDevice(t *testing.T) {
+	origRename := osRename
+	osRename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	defer func() { osRename = origRename }()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "big.bin")
+	dest := filepath.Join(dir, "dest.bin")
+	content := strings.Repeat("0123456789abcdef", (2*copyProgressIntervalBytes+1000)/16)
+	writeTestFile(t, src, content)
+	writeTestFile(t, dest, "old")
+
+	progress, err := moveStreamCmd(src, dest, nil)
+	if err == nil || !strings.Contains(err.Error(), "overwrite") {
+		t.Fatalf("expected overwrite required error, got %v", err)
+	}
+	if len(progress) != 0 {
+		t.Errorf("expected no progress for a failed move, got %v", progress)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "old" {
+		t.Fatalf("destination changed without overwrite: %q", data)
+	}
+
+	progress, err = moveStreamCmd(src, dest, &wshrpc.FileCopyOpts{Overwrite: true})
+	if err != nil {
+		t.Fatalf("move with overwrite: %v", err)
+	}
+	// at least one packet mid-file, then the file done and the final packet
+	if len(progress) < 3 {
+		t.Fatalf("expected progress during the copy, got %v", progress)
+	}
+	for i := 1; i < len(progress); i++ {
+		if progress[i].BytesDone < progress[i-1].BytesDone {
+			t.Errorf("progress went backwards: %v", progress)
+		}
+	}
+	last := progress[len(progress)-1]
+	if last.BytesDone != int64(len(content)) || last.TotalBytes != int64(len(content)) || last.FilesDone != 1 {
+		t.Errorf("final progress: got %+v, want %d bytes in 1 file", last, len(content))
+	}
+	if data, _ := os.ReadFile(dest); string(data) != content {
+		t.Errorf("destination was not replaced (%d bytes)", len(data))
+	}
+	if _, err := os.Stat(src); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected source to be removed, stat err: %v", err)
+	}
+}
+
+func TestMkdirTemp(t *testing.T) {
+	impl := &ServerImpl{}
+	base := t.TempDir()
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		info, err := impl.RemoteMkdirTempCommand(context.Background(), wshrpc.CommandRemoteMkdirTempData{Dir: base, Pattern: "scratch-*.d"})
+		if err != nil {
+			t.Fatalf("RemoteMkdirTempCommand: %v", err)
+		}
+		if !info.IsDir || filepath.Dir(info.Path) != base {
+			t.Fatalf("expected a directory in %q, got %+v", base, info)
+		}
+		if !strings.HasPrefix(info.Name, "scratch-") || !strings.HasSuffix(info.Name, ".d") || len(info.Name) <= len("scratch-.d") {
+			t.Errorf("name %q does not match the pattern", info.Name)
+		}
+		if seen[info.Name] {
+			t.Fatalf("duplicate temp dir %q", info.Name)
+		}
+		seen[info.Name] = true
+	}
+
+	info, err := impl.RemoteMkdirTempCommand(context.Background(), wshrpc.CommandRemoteMkdirTempData{})
+	if err != nil {
+		t.Fatalf("RemoteMkdirTempCommand with defaults: %v", err)
+	}
+	defer os.Remove(info.Path)
+	if !strings.HasPrefix(info.Name, "wsh-tmp-") || info.Mode.Perm() != 0700 {
+		t.Errorf("unexpected default temp dir %q (mode %v)", info.Name, info.Mode.Perm())
+	}
+
+	if _, err := impl.RemoteMkdirTempCommand(context.Background(), wshrpc.CommandRemoteMkdirTempData{Dir: filepath.Join(base, "missing")}); err == nil {
+		t.Errorf("expected error for a missing base dir")
+	}
+}
+
+func TestFileMove_MakeParents(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	dest := filepath.Join(dir, "a", "b", "dest.txt")
+	writeTestFile(t, src, "data")
+
+	if err := moveCmd(src, dest, nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not exist error without makeparents, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("parent created without makeparents, stat err: %v", err)
+	}
+	if err := moveCmd(src, dest, &wshrpc.FileCopyOpts{MakeParents: true}); err != nil {
+		t.Fatalf("move with makeparents: %v", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "data" {
+		t.Errorf("destination: got %q, expected %q", data, "data")
+	}
+
+	// the existing destination check still applies
+	writeTestFile(t, src, "new")
+	if err := moveCmd(src, dest, &wshrpc.FileCopyOpts{MakeParents: true}); err == nil || !strings.Contains(err.Error(), "overwrite") {
+		t.Fatalf("expected overwrite required error, got %v", err)
+	}
+}
+
+func TestFileMove_MergeDir(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dest := filepath.Join(dir, "dest")
+	writeTestFile(t, filepath.Join(src, "a.txt"), "a")
+	writeTestFile(t, filepath.Join(src, "sub", "b.txt"), "b")
+	writeTestFile(t, filepath.Join(dest, "keep.txt"), "keep")
+	writeTestFile(t, filepath.Join(dest, "sub", "c.txt"), "c")
+	recursive := &wshrpc.FileCopyOpts{Recursive: true}
+
+	if err := moveCmd(src, dest, recursive); err == nil || !strings.Contains(err.Error(), "merge") {
+		t.Fatalf("expected merge required error, got %v", err)
+	}
+	if err := moveCmd(src, dest, &wshrpc.FileCopyOpts{Recursive: true, Merge: true}); err != nil {
+		t.Fatalf("merge move: %v", err)
+	}
+	want := map[string]string{"a.txt": "a", "keep.txt": "keep", "sub/b.txt": "b", "sub/c.txt": "c"}
+	if got := treeFiles(t, dest, false); !maps.Equal(got, want) {
+		t.Errorf("merged tree: got %v, expected %v", got, want)
+	}
+	if _, err := os.Stat(src); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected source to be removed, stat err: %v", err)
+	}
+
+	// a conflicting file fails the merge before anything is moved
+	writeTestFile(t, filepath.Join(src, "new.txt"), "new")
+	writeTestFile(t, filepath.Join(src, "sub", "b.txt"), "b2")
+	if err := moveCmd(src, dest, &wshrpc.FileCopyOpts{Recursive: true, Merge: true}); err == nil {
+		t.Fatalf("expected conflict error")
+	}
+	if _, err := os.Stat(filepath.Join(dest, "new.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("conflicting merge moved new.txt anyway")
+	}
+
+	// overwrite replaces the destination directory instead of merging
+	if err := moveCmd(src, dest, &wshrpc.FileCopyOpts{Recursive: true, Overwrite: true}); err != nil {
+		t.Fatalf("overwrite move: %v", err)
+	}
+	want = map[string]string{"new.txt": "new", "sub/b.txt": "b2"}
+	if got := treeFiles(t, dest, false); !maps.Equal(got, want) {
+		t.Errorf("overwritten tree: got %v, expected %v", got, want)
+	}
+}
+
+func TestFileMove_SameFsRename(t *testing.T) {
+	renames := 0
+	origRename := osRename
+	osRename = func(oldpath, newpath string) error {
+		renames++
+		return origRename(oldpath, newpath)
+	}
+	defer func() { osRename = origRename }()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dest := filepath.Join(dir, "dest")
+	writeTestFile(t, filepath.Join(src, "big", "file.txt"), "data")
+	before, err := os.Stat(filepath.Join(src, "big", "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := moveCmd(src, dest, &wshrpc.FileCopyOpts{Recursive: true}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	after, err := os.Stat(filepath.Join(dest, "big", "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a single rename of the top directory, the file itself is never copied
+	if renames != 1 || !os.SameFile(before, after) {
+		t.Errorf("expected one atomic rename keeping the same file, got %d renames (same file: %v)", renames, os.SameFile(before, after))
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]int{
+		"a.txt":          100,
+		"sub/b.txt":      300,
+		"sub/deep/c.txt": 200,
+		"other/d.txt":    50,
+	}
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// symlinks aren't counted or followed, even when they point back up the tree
+	if err := os.Symlink(dir, filepath.Join(dir, "sub", "loop")); err != nil {
+		t.Fatal(err)
+	}
+	impl := &ServerImpl{}
+	rtn, err := impl.RemoteDiskUsageCommand(context.Background(), wshrpc.CommandRemoteDiskUsageData{Path: dir, TopN: 2})
+	if err != nil {
+		t.Fatalf("RemoteDiskUsageCommand: %v", err)
+	}
+	if rtn.TotalBytes != 650 || rtn.FileCount != 4 || rtn.DirCount != 3 {
+		t.Errorf("got total=%d files=%d dirs=%d, want 650/4/3", rtn.TotalBytes, rtn.FileCount, rtn.DirCount)
+	}
+	if len(rtn.LargestFiles) != 2 || rtn.LargestFiles[0].Name != "b.txt" || rtn.LargestFiles[1].Name != "c.txt" {
+		t.Errorf("unexpected largest files: %+v", rtn.LargestFiles)
+	}
+}
+
+func TestDiskUsage_Cancel(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	impl := &ServerImpl{}
+	_, err := impl.RemoteDiskUsageCommand(ctx, wshrpc.CommandRemoteDiskUsageData{Path: dir})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func grepLines(t *testing.T, path string, pattern string, opts *wshrpc.GrepOpts) []string {
+	t.Helper()
+	impl := &ServerImpl{}
+	var rtn []string
+	for resp := range impl.RemoteGrepCommand(context.Background(), wshrpc.CommandRemoteGrepData{Path: path, Pattern: pattern, Opts: opts}) {
+		if resp.Error != nil {
+			t.Fatalf("RemoteGrepCommand: %v", resp.Error)
+		}
+		rtn = append(rtn, fmt.Sprintf("%s:%d:%s", filepath.Base(resp.Response.Path), resp.Response.LineNum, resp.Response.Line))
+	}
+	slices.Sort(rtn)
+	return rtn
+}
+
+func TestGrep(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc Foo() {}\nfunc foobar() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "notes.txt"), []byte("call foo.Bar(1+2)\nnothing here\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// binary content that happens to contain the pattern
+	if err := os.WriteFile(filepath.Join(dir, "data.bin"), append([]byte{0x00, 0x01, 0x02, 0xff}, []byte("\nfoo\n")...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pattern string
+		opts    wshrpc.GrepOpts
+		want    string
+	}{
+		{"foo", wshrpc.GrepOpts{}, "main.go:4:func foobar() {},notes.txt:1:call foo.Bar(1+2)"},
+		{"foo", wshrpc.GrepOpts{IgnoreCase: true}, "main.go:3:func Foo() {},main.go:4:func foobar() {},notes.txt:1:call foo.Bar(1+2)"},
+		{"foo", wshrpc.GrepOpts{IgnoreCase: true, WholeWord: true}, "main.go:3:func Foo() {},notes.txt:1:call foo.Bar(1+2)"},
+		{"(1+2)", wshrpc.GrepOpts{}, "notes.txt:1:call foo.Bar(1+2)"},
+		{`^func \w+\(\)`, wshrpc.GrepOpts{Regex: true}, "main.go:3:func Foo() {},main.go:4:func foobar() {}"},
+		{"foo", wshrpc.GrepOpts{IgnoreCase: true, Glob: "*.go"}, "main.go:3:func Foo() {},main.go:4:func foobar() {}"},
+	}
+	for _, tc := range tests {
+		opts := tc.opts
+		got := strings.Join(grepLines(t, dir, tc.pattern, &opts), ",")
+		if got != tc.want {
+			t.Errorf("%q %+v: got %s, want %s", tc.pattern, tc.opts, got, tc.want)
+		}
+	}
+
+	if got := grepLines(t, dir, "func", &wshrpc.GrepOpts{MaxMatches: 1}); len(got) != 1 {
+		t.Errorf("expected max matches to limit results to 1, got %v", got)
+	}
+}
+
+func globPaths(t *testing.T, ctx context.Context, pattern string) ([]string, error) {
+	t.Helper()
+	impl := &ServerImpl{}
+	var paths []string
+	var err error
+	for resp := range impl.RemoteGlobCommand(ctx, pattern) {
+		if resp.Error != nil {
+			err = resp.Error
+			continue
+		}
+		for _, finfo := range resp.Response.FileInfo {
+			paths = append(paths, finfo.Path)
+		}
+	}
+	return paths, err
+}
+
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "main.go"), "package main")
+	writeTestFile(t, filepath.Join(dir, "a.txt"), "a")
+	writeTestFile(t, filepath.Join(dir, "b.txt"), "b")
+	writeTestFile(t, filepath.Join(dir, "c.txt"), "c")
+	writeTestFile(t, filepath.Join(dir, ".hidden.go"), "package hidden")
+	writeTestFile(t, filepath.Join(dir, "pkg", "util", "util.go"), "package util")
+	writeTestFile(t, filepath.Join(dir, "pkg", "util", "util_test.go"), "package util")
+	writeTestFile(t, filepath.Join(dir, ".git", "hooks", "hook.go"), "package hooks")
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"*.go", "main.go"},
+		{"*.txt", "a.txt,b.txt,c.txt"},
+		{"[ab].txt", "a.txt,b.txt"},
+		{"[!ab].txt", "c.txt"},
+		{"?.txt", "a.txt,b.txt,c.txt"},
+		{"**/*.go", "main.go,pkg/util/util.go,pkg/util/util_test.go"},
+		{"pkg/**", "pkg,pkg/util,pkg/util/util.go,pkg/util/util_test.go"},
+		{"*/*/*_test.go", "pkg/util/util_test.go"},
+		{".*.go", ".hidden.go"},
+		{".git/**/*.go", ".git/hooks/hook.go"},
+		{"main.go", "main.go"},
+		{"missing/*.go", ""},
+		{"*.rs", ""},
+	}
+	for _, tc := range tests {
+		paths, err := globPaths(t, context.Background(), filepath.Join(dir, tc.pattern))
+		if err != nil {
+			t.Errorf("%q: %v", tc.pattern, err)
+			continue
+		}
+		for i, p := range paths {
+			rel, _ := filepath.Rel(dir, p)
+			paths[i] = filepath.ToSlash(rel)
+		}
+		if got := strings.Join(paths, ","); got != tc.want {
+			t.Errorf("%q: got %s, want %s", tc.pattern, got, tc.want)
+		}
+	}
+
+	if _, err := globPaths(t, context.Background(), filepath.Join(dir, "[a.txt")); err == nil {
+		t.Errorf("expected error for malformed pattern")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := globPaths(t, ctx, filepath.Join(dir, "**/*.go")); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled error, got %v", err)
+	}
+}
+
+// tailState accumulates the followed content, resetting it when the file is reopened or truncated
+type tailState struct {
+	lock    sync.Mutex
+	content string
+	resets  int
+	err     error
+}
+
+func startTail(t *testing.T, path string, lines int) (*tailState, context.CancelFunc, chan struct{}) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	impl := &ServerImpl{}
+	ch := impl.RemoteTailFileCommand(ctx, wshrpc.CommandRemoteTailFileData{Path: path, Lines: lines, PollMs: 5})
+	state := &tailState{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for resp := range ch {
+			state.lock.Lock()
+			if resp.Error != nil {
+				state.err = resp.Error
+			} else if resp.Response.Info != nil {
+				state.content = ""
+				state.resets++
+			} else {
+				data, _ := base64.StdEncoding.DecodeString(resp.Response.Data64)
+				state.content += string(data)
+			}
+			state.lock.Unlock()
+		}
+	}()
+	return state, cancel, done
+}
+
+func (s *tailState) waitFor(t *testing.T, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.lock.Lock()
+		content, err := s.content, s.err
+		s.lock.Unlock()
+		if err != nil {
+			t.Fatalf("tail error: %v", err)
+		}
+		if content == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %q, have %q", want, content)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTailFile_Append(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("1\n2\n3\n4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state, cancel, done := startTail(t, path, 2)
+	state.waitFor(t, "3\n4\n")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString("5\n"); err != nil {
+		t.Fatal(err)
+	}
+	state.waitFor(t, "3\n4\n5\n")
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("channel not closed after cancel")
+	}
+}
+
+func TestTailFile_Truncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("old line 1\nold line 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state, cancel, done := startTail(t, path, 10)
+	defer func() { cancel(); <-done }()
+	state.waitFor(t, "old line 1\nold line 2\n")
+	if err := os.WriteFile(path, []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state.waitFor(t, "new\n")
+}
+
+func TestTailFile_Rotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("first\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state, cancel, done := startTail(t, path, 10)
+	defer func() { cancel(); <-done }()
+	state.waitFor(t, "first\n")
+	if err := os.Rename(path, filepath.Join(dir, "app.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("rotated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state.waitFor(t, "rotated\n")
+	state.lock.Lock()
+	resets := state.resets
+	state.lock.Unlock()
+	if resets != 2 {
+		t.Errorf("expected the file to be reopened once, got %d info packets", resets)
+	}
+}
+
+func TestTailStartOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	tests := []struct {
+		content string
+		lines   int
+		want    string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 2, "b\nc"},
+		{"a\nb\n", 5, "a\nb\n"},
+		{"", 3, ""},
+	}
+	for _, tc := range tests {
+		if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		fd, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offset, err := tailStartOffset(fd, int64(len(tc.content)), tc.lines)
+		fd.Close()
+		if err != nil {
+			t.Fatalf("tailStartOffset: %v", err)
+		}
+		if got := tc.content[offset:]; got != tc.want {
+			t.Errorf("%q last %d lines: got %q, want %q", tc.content, tc.lines, got, tc.want)
+		}
+	}
+}
+
+// readLinesCmd returns the data sent by RemoteReadLinesCommand, checking each chunk against its offset in content
+func readLinesCmd(t *testing.T, path string, content string, startLine int, endLine int) (string, int) {
+	t.Helper()
+	impl := &ServerImpl{}
+	var sb strings.Builder
+	totalLines := -1
+	for resp := range impl.RemoteReadLinesCommand(context.Background(), wshrpc.CommandRemoteReadLinesData{Path: path, StartLine: startLine, EndLine: endLine}) {
+		if resp.Error != nil {
+			t.Fatalf("lines %d-%d: %v", startLine, endLine, resp.Error)
+		}
+		if resp.Response.Info != nil {
+			continue
+		}
+		if resp.Response.At == nil {
+			totalLines = resp.Response.TotalLines
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(resp.Response.Data64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		at := resp.Response.At
+		if got := content[at.Offset : at.Offset+int64(at.Size)]; got != string(data) {
+			t.Errorf("lines %d-%d: chunk at offset %d is %q, file has %q", startLine, endLine, at.Offset, data, got)
+		}
+		sb.Write(data)
+	}
+	return sb.String(), totalLines
+}
+
+func TestReadLines(t *testing.T) {
+	dir := t.TempDir()
+	var sb strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	longLine := strings.Repeat("x", 3*wshrpc.FileChunkSize)
+	tests := []struct {
+		name       string
+		content    string
+		start, end int
+		want       string
+		totalLines int // -1 if the scan stops before EOF
+	}{
+		{"middle", sb.String(), 50, 52, "line 50\nline 51\nline 52\n", -1},
+		{"first line", sb.String(), 0, 1, "line 1\n", -1},
+		{"to eof", sb.String(), 99, 0, "line 99\nline 100\n", 100},
+		{"past eof", sb.String(), 99, 200, "line 99\nline 100\n", 100},
+		{"after eof", sb.String(), 101, 105, "", 100},
+		{"no final newline", "a\nb\nc", 2, 10, "b\nc", 3},
+		{"no final newline last line", "a\nb\nc", 3, 3, "c", 3},
+		{"long line", "a\n" + longLine + "\nc\n", 2, 2, longLine + "\n", -1},
+		{"after long line", "a\n" + longLine + "\nc\n", 3, 0, "c\n", 3},
+		{"empty file", "", 1, 0, "", 0},
+	}
+	for _, tc := range tests {
+		path := filepath.Join(dir, "lines.txt")
+		if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, totalLines := readLinesCmd(t, path, tc.content, tc.start, tc.end)
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, utilfn.EllipsisStr(got, 40), utilfn.EllipsisStr(tc.want, 40))
+		}
+		if totalLines != tc.totalLines {
+			t.Errorf("%s: got total lines %d, want %d", tc.name, totalLines, tc.totalLines)
+		}
+	}
+
+	impl := &ServerImpl{}
+	for resp := range impl.RemoteReadLinesCommand(context.Background(), wshrpc.CommandRemoteReadLinesData{Path: filepath.Join(dir, "lines.txt"), StartLine: 5, EndLine: 4}) {
+		if resp.Error == nil {
+			t.Errorf("expected error for an inverted line range")
+		}
+	}
+}
+
+func makeProjectTree(t *testing.T) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "project")
+	for _, name := range []string{
+		"main.go",
+		"README.md",
+		"node_modules/pkg/index.js",
+		"src/node_modules/other.js",
+		"src/app.go",
+		"src/app.o",
+		"build/out.tmp",
+		"build/keep.txt",
+		".git/config",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+var projectExcludes = []string{"node_modules", ".git/", "*.o", "build/*.tmp"}
+
+func TestTarStream_Exclude(t *testing.T) {
+	root := makeProjectTree(t)
+	impl := &ServerImpl{}
+	ch := impl.RemoteTarStreamCommand(context.Background(), wshrpc.CommandRemoteStreamTarData{Path: root, Opts: &wshrpc.FileCopyOpts{ExcludePatterns: projectExcludes}})
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	var names []string
+	err := tarcopy.TarCopyDest(ctx, cancel, ch, func(next *tar.Header, reader *tar.Reader, singleFile bool) error {
+		if next.Typeflag == tar.TypeReg {
+			names = append(names, next.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TarCopyDest: %v", err)
+	}
+	slices.Sort(names)
+	want := "project/README.md,project/build/keep.txt,project/main.go,project/src/app.go"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestExcludeWalkFunc_Prunes(t *testing.T) {
+	root := makeProjectTree(t)
+	excludeFn, err := excludeWalkFunc(root, projectExcludes, func(path string, info fs.FileInfo, err error) error {
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// record everything the walker offers, excluded directories must not be descended into
+	var visited []string
+	err = walkWithSymlinks(root, false, func(path string, info fs.FileInfo, err error) error {
+		relPath, _ := filepath.Rel(root, path)
+		visited = append(visited, filepath.ToSlash(relPath))
+		return excludeFn(path, info, err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range visited {
+		if strings.HasPrefix(path, "node_modules/") || strings.HasPrefix(path, "src/node_modules/") || strings.HasPrefix(path, ".git/") {
+			t.Errorf("walk descended into excluded directory: %s", path)
+		}
+	}
+	if !slices.Contains(visited, "node_modules") {
+		t.Errorf("expected the excluded directory itself to be visited, got %v", visited)
+	}
+
+	if _, err := excludeWalkFunc(root, []string{"[bad"}, nil); err == nil {
+		t.Errorf("expected error for invalid pattern")
+	}
+}
+
+func TestSymlinkCommand(t *testing.T) {
+	impl := &ServerImpl{}
+	dir := t.TempDir()
+	targetPath := filepath.Join(dir, "target.txt")
+	if err := os.WriteFile(targetPath, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// relative target, with parent creation
+	linkPath := filepath.Join(dir, "sub", "rel-link")
+	info, err := impl.RemoteSymlinkCommand(context.Background(), wshrpc.CommandRemoteLinkData{Path: linkPath, Target: "../target.txt", TargetMustExist: true, MakeParents: true})
+	if err != nil {
+		t.Fatalf("RemoteSymlinkCommand: %v", err)
+	}
+	if info.Mode&fs.ModeSymlink == 0 {
+		t.Errorf("expected symlink mode, got %v", info.Mode)
+	}
+	if target, _ := os.Readlink(linkPath); target != "../target.txt" {
+		t.Errorf("expected relative target to be stored as-is, got %q", target)
+	}
+	if data, err := os.ReadFile(linkPath); err != nil || string(data) != "hello" {
+		t.Errorf("reading through relative link: %q, %v", data, err)
+	}
+
+	// absolute target
+	absLink := filepath.Join(dir, "abs-link")
+	if _, err := impl.RemoteSymlinkCommand(context.Background(), wshrpc.CommandRemoteLinkData{Path: absLink, Target: targetPath}); err != nil {
+		t.Fatalf("RemoteSymlinkCommand: %v", err)
+	}
+	if data, err := os.ReadFile(absLink); err != nil || string(data) != "hello" {
+		t.Errorf("reading through absolute link: %q, %v", data, err)
+	}
+
+	// the link path must not exist
+	if _, err := impl.RemoteSymlinkCommand(context.Background(), wshrpc.CommandRemoteLinkData{Path: absLink, Target: targetPath}); err == nil {
+		t.Errorf("expected error when the link already exists")
+	}
+	// dangling links are allowed unless the target must exist
+	missingLink := filepath.Join(dir, "missing-link")
+	if _, err := impl.RemoteSymlinkCommand(context.Background(), wshrpc.CommandRemoteLinkData{Path: missingLink, Target: "nope", TargetMustExist: true}); err == nil {
+		t.Errorf("expected error for missing target")
+	}
+	if _, err := os.Lstat(missingLink); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("link should not have been created, lstat err: %v", err)
+	}
+	if _, err := impl.RemoteSymlinkCommand(context.Background(), wshrpc.CommandRemoteLinkData{Path: missingLink, Target: "nope"}); err != nil {
+		t.Errorf("dangling symlink: %v", err)
+	}
+}
+
+func TestHardlinkCommand(t *testing.T) {
+	impl := &ServerImpl{}
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src.txt")
+	if err := os.WriteFile(srcPath, []byte("hardlinked data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	linkPath := filepath.Join(dir, "a", "b", "link.txt")
+	if _, err := impl.RemoteHardlinkCommand(context.Background(), wshrpc.CommandRemoteLinkData{Path: linkPath, Target: srcPath}); err == nil {
+		t.Fatalf("expected error without MakeParents")
+	}
+	info, err := impl.RemoteHardlinkCommand(context.Background(), wshrpc.CommandRemoteLinkData{Path: linkPath, Target: srcPath, MakeParents: true})
+	if err != nil {
+		t.Fatalf("RemoteHardlinkCommand: %v", err)
+	}
+	if info.Size != int64(len("hardlinked data")) {
+		t.Errorf("expected size %d, got %d", len("hardlinked data"), info.Size)
+	}
+	srcInfo, _ := os.Stat(srcPath)
+	linkInfo, _ := os.Stat(linkPath)
+	if !os.SameFile(srcInfo, linkInfo) {
+		t.Errorf("expected link to share the source inode")
+	}
+	if _, err := impl.RemoteHardlinkCommand(context.Background(), wshrpc.CommandRemoteLinkData{Path: filepath.Join(dir, "x"), Target: filepath.Join(dir, "missing")}); err == nil {
+		t.Errorf("expected error for missing source")
+	}
+}
+
+func TestReadLink(t *testing.T) {
+	impl := &ServerImpl{}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"file-link":     "file.txt",
+		"dir-link":      filepath.Join(dir, "subdir"),
+		"dangling-link": "deleted.txt",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := impl.RemoteReadLinkCommand(context.Background(), filepath.Join(dir, name))
+		if err != nil || got != target {
+			t.Errorf("RemoteReadLinkCommand(%s) = %q, %v, expected %q", name, got, err, target)
+		}
+	}
+	if _, err := impl.RemoteReadLinkCommand(context.Background(), filepath.Join(dir, "file.txt")); err == nil {
+		t.Errorf("expected error reading a regular file as a link")
+	}
+
+	// FileInfo follows the link but reports its target
+	info, err := impl.RemoteFileInfoCommand(context.Background(), filepath.Join(dir, "dir-link"))
+	if err != nil {
+		t.Fatalf("RemoteFileInfoCommand: %v", err)
+	}
+	if !info.IsDir || info.LinkTarget != links["dir-link"] {
+		t.Errorf("dir-link: isdir=%v linktarget=%q", info.IsDir, info.LinkTarget)
+	}
+	info, err = impl.RemoteFileInfoCommand(context.Background(), filepath.Join(dir, "file.txt"))
+	if err != nil || info.LinkTarget != "" {
+		t.Errorf("regular file: linktarget=%q, err=%v", info.LinkTarget, err)
+	}
+
+	// listings lstat their entries, so every link (even a dangling one) carries its target
+	found := make(map[string]string)
+	for resp := range impl.RemoteListEntriesCommand(context.Background(), wshrpc.CommandRemoteListEntriesData{Path: dir, Opts: &wshrpc.FileListOpts{All: true}}) {
+		if resp.Error != nil {
+			t.Fatalf("RemoteListEntriesCommand: %v", resp.Error)
+		}
+		for _, finfo := range resp.Response.FileInfo {
+			found[finfo.Name] = finfo.LinkTarget
+		}
+	}
+	for name, target := range links {
+		if found[name] != target {
+			t.Errorf("listing %s: linktarget=%q, expected %q", name, found[name], target)
+		}
+	}
+}
+
+func TestRealPath(t *testing.T) {
+	dir := t.TempDir()
+	// the temp dir itself may be behind a symlink (e.g. /var on macos)
+	base, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "real", "sub", "file.txt"), "x")
+	if err := os.Symlink(filepath.Join(dir, "real"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	home, err := filepath.EvalSymlinks(wavebase.GetHomeDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path       string
+		want       string
+		wantExists bool
+	}{
+		{filepath.Join(dir, "real", "sub", "..", "sub"), filepath.Join(base, "real", "sub"), true},
+		{filepath.Join(dir, "link", "sub", "file.txt"), filepath.Join(base, "real", "sub", "file.txt"), true},
+		{filepath.Join(dir, "link"), filepath.Join(base, "real"), true},
+		{filepath.Join(dir, "link", "missing", "deeper"), filepath.Join(base, "real", "missing", "deeper"), false},
+		{filepath.Join(dir, "link", "missing", "..", "sub"), filepath.Join(base, "real", "sub"), true},
+		{filepath.Join(dir, "link", "sub", "file.txt", "child"), filepath.Join(base, "real", "sub", "file.txt", "child"), false},
+		{"~", home, true},
+	}
+	impl := &ServerImpl{}
+	for _, tc := range tests {
+		rtn, err := impl.RemoteRealPathCommand(context.Background(), tc.path)
+		if err != nil {
+			t.Errorf("%q: %v", tc.path, err)
+			continue
+		}
+		if rtn.Path != tc.want || rtn.Exists != tc.wantExists {
+			t.Errorf("%q: got %q (exists %v), expected %q (exists %v)", tc.path, rtn.Path, rtn.Exists, tc.want, tc.wantExists)
+		}
+	}
+
+	// relative paths are resolved against the working directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+	rtn, err := impl.RemoteRealPathCommand(context.Background(), "sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(base, "real", "sub"); rtn.Path != want || !rtn.Exists {
+		t.Errorf("relative: got %q (exists %v), expected %q", rtn.Path, rtn.Exists, want)
+	}
+}
+
+func TestFileExists(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "file.txt"), "data")
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub", filepath.Join(dir, "dirlink")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("missing.txt", filepath.Join(dir, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"file.txt", wshrpc.FileKind_File},
+		{"sub", wshrpc.FileKind_Dir},
+		{"dirlink", wshrpc.FileKind_Symlink},
+		{"dangling", wshrpc.FileKind_Symlink},
+		{"missing.txt", wshrpc.FileKind_Missing},
+		{"file.txt/child", wshrpc.FileKind_Missing},
+	}
+	impl := &ServerImpl{}
+	for _, tc := range tests {
+		kind, err := impl.RemoteFileExistsCommand(context.Background(), filepath.Join(dir, tc.name))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if kind != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, kind, tc.want)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	sockPath := filepath.Join(dir, "sock")
+	listener, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("cannot create unix socket: %v", err)
+	}
+	defer listener.Close()
+	if kind, err := impl.RemoteFileExistsCommand(context.Background(), sockPath); err != nil || kind != wshrpc.FileKind_Other {
+		t.Errorf("socket: got %q (err %v), want %q", kind, err, wshrpc.FileKind_Other)
+	}
+}
+
+func TestFileInfo_NumEntries(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "sub/c.txt"} {
+		writeTestFile(t, filepath.Join(dir, name), "x")
+	}
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	impl := &ServerImpl{}
+	for name, expected := range map[string]int{".": 4, "sub": 1, "empty": 0, "a.txt": 0} {
+		info, err := impl.RemoteFileInfoCommand(context.Background(), filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("RemoteFileInfoCommand %s: %v", name, err)
+		}
+		if info.NumEntries != expected {
+			t.Errorf("%s: expected %d entries, got %d", name, expected, info.NumEntries)
+		}
+	}
+
+	// the streamed directory is counted, the directories inside it are not
+	var entries []*wshrpc.FileInfo
+	for resp := range impl.RemoteStreamFileCommand(context.Background(), wshrpc.CommandRemoteStreamFileData{Path: dir}) {
+		if resp.Error != nil {
+			t.Fatal(resp.Error)
+		}
+		if resp.Response.Info != nil && resp.Response.Info.NumEntries != 4 {
+			t.Errorf("streamed dir: expected 4 entries, got %d", resp.Response.Info.NumEntries)
+		}
+		entries = append(entries, resp.Response.Entries...)
+	}
+	for _, entry := range entries {
+		if entry.IsDir && (entry.NumEntries != -1 || entry.Size != -1) {
+			t.Errorf("%s: expected uncounted subdirectory (-1/-1), got numentries %d size %d", entry.Name, entry.NumEntries, entry.Size)
+		}
+	}
+}
+
+func TestFileInfo_DanglingSymlink(t *testing.T) {
+	impl := &ServerImpl{}
+	dir := t.TempDir()
+	targetPath := filepath.Join(dir, "target.txt")
+	linkPath := filepath.Join(dir, "link")
+	if err := os.WriteFile(targetPath, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(targetPath, linkPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(targetPath); err != nil {
+		t.Fatal(err)
+	}
+	info, err := impl.RemoteFileInfoCommand(context.Background(), linkPath)
+	if err != nil {
+		t.Fatalf("RemoteFileInfoCommand: %v", err)
+	}
+	if info.NotFound {
+		t.Fatalf("dangling symlink reported as not found")
+	}
+	if info.Mode&fs.ModeSymlink == 0 || info.LinkTarget != targetPath || info.Name != "link" {
+		t.Errorf("unexpected info for dangling symlink: mode=%v linktarget=%q name=%q", info.Mode, info.LinkTarget, info.Name)
+	}
+	if err := impl.RemoteFileDeleteCommand(context.Background(), wshrpc.CommandDeleteFileData{Path: linkPath}); err != nil {
+		t.Fatalf("RemoteFileDeleteCommand: %v", err)
+	}
+	if _, err := os.Lstat(linkPath); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("dangling symlink was not removed: %v", err)
+	}
+	info, err = impl.RemoteFileInfoCommand(context.Background(), linkPath)
+	if err != nil || !info.NotFound {
+		t.Fatalf("expected NotFound after delete, got %+v, %v", info, err)
+	}
+}
+
+func TestFileTruncate(t *testing.T) {
+	impl := &ServerImpl{}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.txt")
+	if err := os.WriteFile(path, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	truncate := func(data wshrpc.CommandRemoteFileTruncateData) (int64, error) {
+		return impl.RemoteFileTruncateCommand(context.Background(), data)
+	}
+
+	size, err := truncate(wshrpc.CommandRemoteFileTruncateData{Path: path, Size: 4})
+	if err != nil || size != 4 {
+		t.Fatalf("shrink: got %d, %v", size, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "0123" {
+		t.Errorf("shrink: unexpected contents %q", data)
+	}
+
+	size, err = truncate(wshrpc.CommandRemoteFileTruncateData{Path: path, Size: 8})
+	if err != nil || size != 8 {
+		t.Fatalf("grow: got %d, %v", size, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "0123\x00\x00\x00\x00" {
+		t.Errorf("grow: expected zero fill, got %q", data)
+	}
+
+	if _, err = truncate(wshrpc.CommandRemoteFileTruncateData{Path: dir, Size: 0}); err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("expected directory error, got %v", err)
+	}
+
+	newPath := filepath.Join(dir, "new.bin")
+	if _, err = truncate(wshrpc.CommandRemoteFileTruncateData{Path: newPath, Size: 16}); err == nil {
+		t.Errorf("expected error for missing file without create")
+	}
+	size, err = truncate(wshrpc.CommandRemoteFileTruncateData{Path: newPath, Size: 16, Create: true})
+	if err != nil || size != 16 {
+		t.Fatalf("create: got %d, %v", size, err)
+	}
+	if _, err = truncate(wshrpc.CommandRemoteFileTruncateData{Path: path, Size: -1}); err == nil {
+		t.Errorf("expected error for negative size")
+	}
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func copyPlan(t *testing.T, src string, dest string, opts *wshrpc.FileCopyOpts) map[string]wshrpc.FileCopyPlanEntry {
+	t.Helper()
+	impl := &ServerImpl{}
+	plan := make(map[string]wshrpc.FileCopyPlanEntry)
+	for resp := range impl.RemoteFileCopyPlanCommand(context.Background(), wshrpc.CommandFileCopyData{SrcUri: "wsh://local/" + src, DestUri: "wsh://local/" + dest, Opts: opts}) {
+		if resp.Error != nil {
+			t.Fatalf("RemoteFileCopyPlanCommand: %v", resp.Error)
+		}
+		plan[resp.Response.Path] = resp.Response
+	}
+	return plan
+}
+
+func TestFileCopy_DryRunMerge(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dest := filepath.Join(dir, "dest")
+	// dest is an existing directory, so src is copied (merged) into dest/src
+	target := filepath.Join(dest, "src")
+	for path, content := range map[string]string{
+		"a.txt":       "new a",
+		"sub/b.txt":   "new b",
+		"other/c.txt": "new c",
+	} {
+		writeTestFile(t, filepath.Join(src, path), content)
+	}
+	writeTestFile(t, filepath.Join(target, "sub", "existing.txt"), "keep me")
+
+	before := treeFiles(t, dest, true)
+	plan := copyPlan(t, src, dest, &wshrpc.FileCopyOpts{Merge: true})
+	if after := treeFiles(t, dest, true); !maps.Equal(before, after) {
+		t.Fatalf("dry run modified the destination")
+	}
+	expected := map[string]string{
+		target:                                  wshrpc.FileCopyPlanAction_Skip,
+		filepath.Join(target, "a.txt"):          wshrpc.FileCopyPlanAction_Create,
+		filepath.Join(target, "sub"):            wshrpc.FileCopyPlanAction_Skip,
+		filepath.Join(target, "sub", "b.txt"):   wshrpc.FileCopyPlanAction_Create,
+		filepath.Join(target, "other"):          wshrpc.FileCopyPlanAction_Create,
+		filepath.Join(target, "other", "c.txt"): wshrpc.FileCopyPlanAction_Create,
+	}
+	for path, action := range expected {
+		if plan[path].Action != action {
+			t.Errorf("%s: planned %q, expected %q", path, plan[path].Action, action)
+		}
+	}
+	if len(plan) != len(expected) {
+		t.Errorf("unexpected plan entries: %+v", plan)
+	}
+	if plan[filepath.Join(target, "a.txt")].Size != int64(len("new a")) {
+		t.Errorf("expected planned size for a.txt, got %+v", plan[filepath.Join(target, "a.txt")])
+	}
+
+	// the real copy creates exactly the planned paths
+	impl := &ServerImpl{}
+	if _, err := impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{SrcUri: "wsh://local/" + src, DestUri: "wsh://local/" + dest, Opts: &wshrpc.FileCopyOpts{Merge: true}}); err != nil {
+		t.Fatalf("RemoteFileCopyCommand: %v", err)
+	}
+	after := treeFiles(t, dest, true)
+	for relPath := range after {
+		_, existed := before[relPath]
+		path := filepath.Join(dest, filepath.FromSlash(relPath))
+		entry, planned := plan[path]
+		if !existed && (!planned || entry.Action != wshrpc.FileCopyPlanAction_Create) {
+			t.Errorf("%s was created but planned as %+v", path, entry)
+		}
+		if existed && planned && entry.Action == wshrpc.FileCopyPlanAction_Create {
+			t.Errorf("%s was planned as create but already existed", path)
+		}
+	}
+	for path, entry := range plan {
+		relPath, err := filepath.Rel(dest, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := after[filepath.ToSlash(relPath)]; !ok {
+			t.Errorf("%s was planned as %q but does not exist after the copy", path, entry.Action)
+		}
+	}
+}
+
+func TestFileCopy_DryRunConflicts(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dest := filepath.Join(dir, "dest")
+	target := filepath.Join(dest, "src")
+	writeTestFile(t, filepath.Join(src, "a.txt"), "new a")
+	writeTestFile(t, filepath.Join(src, "b.txt"), "new b")
+	writeTestFile(t, filepath.Join(target, "a.txt"), "old a")
+
+	plan := copyPlan(t, src, dest, &wshrpc.FileCopyOpts{Merge: true})
+	conflict := plan[filepath.Join(target, "a.txt")]
+	if conflict.Action != wshrpc.FileCopyPlanAction_Conflict || conflict.Reason == "" {
+		t.Errorf("expected a conflict with a reason for a.txt, got %+v", conflict)
+	}
+	if plan[filepath.Join(target, "b.txt")].Action != wshrpc.FileCopyPlanAction_Create {
+		t.Errorf("expected the plan to continue past the conflict, got %+v", plan)
+	}
+	impl := &ServerImpl{}
+	if _, err := impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{SrcUri: "wsh://local/" + src, DestUri: "wsh://local/" + dest, Opts: &wshrpc.FileCopyOpts{Merge: true}}); err == nil {
+		t.Errorf("expected the real copy to fail on the planned conflict")
+	}
+
+	// without merge, the existing directory itself is the conflict
+	plan = copyPlan(t, src, dest, nil)
+	if plan[target].Action != wshrpc.FileCopyPlanAction_Conflict {
+		t.Errorf("expected a merge conflict for %s, got %+v", target, plan[target])
+	}
+
+	// overwriting the directory replaces everything under it
+	plan = copyPlan(t, src, dest, &wshrpc.FileCopyOpts{Overwrite: true})
+	if plan[target].Action != wshrpc.FileCopyPlanAction_Overwrite || plan[filepath.Join(target, "a.txt")].Action != wshrpc.FileCopyPlanAction_Create {
+		t.Errorf("expected the directory to be overwritten and a.txt recreated, got %+v", plan)
+	}
+
+	// overwriting a single file
+	srcFile := filepath.Join(src, "a.txt")
+	destFile := filepath.Join(target, "a.txt")
+	plan = copyPlan(t, srcFile, destFile, &wshrpc.FileCopyOpts{Overwrite: true})
+	if plan[destFile].Action != wshrpc.FileCopyPlanAction_Overwrite || len(plan) != 1 {
+		t.Errorf("expected a single overwrite, got %+v", plan)
+	}
+	if data, _ := os.ReadFile(destFile); string(data) != "old a" {
+		t.Errorf("dry run overwrote the destination: %q", data)
+	}
+}
+
+func TestMkdir_Mode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+	impl := &ServerImpl{}
+	dir := t.TempDir()
+	privateDir := filepath.Join(dir, "a", "private")
+	if err := impl.RemoteMkdirCommand(context.Background(), wshrpc.CommandRemoteMkdirData{Path: privateDir, Mode: 0700}); err != nil {
+		t.Fatalf("RemoteMkdirCommand: %v", err)
+	}
+	if finfo, err := os.Stat(privateDir); err != nil || finfo.Mode().Perm() != 0700 {
+		t.Fatalf("expected 0700 directory, got %v, %v", finfo.Mode(), err)
+	}
+
+	// an explicit mode is applied exactly, even where the umask would mask it
+	sharedDir := filepath.Join(dir, "shared")
+	if err := impl.RemoteMkdirCommand(context.Background(), wshrpc.CommandRemoteMkdirData{Path: sharedDir, Mode: 0777}); err != nil {
+		t.Fatalf("RemoteMkdirCommand: %v", err)
+	}
+	if finfo, _ := os.Stat(sharedDir); finfo.Mode().Perm() != 0777 {
+		t.Errorf("expected 0777 directory, got %v", finfo.Mode())
+	}
+
+	if err := impl.RemoteMkdirCommand(context.Background(), wshrpc.CommandRemoteMkdirData{Path: sharedDir}); err == nil {
+		t.Errorf("expected error for existing directory")
+	}
+}
+
+func TestEnsureDir(t *testing.T) {
+	impl := &ServerImpl{}
+	dir := t.TempDir()
+
+	// fresh create, parents included
+	newDir := filepath.Join(dir, "a", "b", "c")
+	if err := impl.RemoteEnsureDirCommand(context.Background(), wshrpc.CommandRemoteMkdirData{Path: newDir}); err != nil {
+		t.Fatalf("RemoteEnsureDirCommand: %v", err)
+	}
+	if finfo, err := os.Stat(newDir); err != nil || !finfo.IsDir() {
+		t.Fatalf("expected a new directory, got %v, %v", finfo, err)
+	}
+
+	// already exists: no error, the contents and mode are left alone
+	writeTestFile(t, filepath.Join(newDir, "keep.txt"), "keep me")
+	before, _ := os.Stat(newDir)
+	if err := impl.RemoteEnsureDirCommand(context.Background(), wshrpc.CommandRemoteMkdirData{Path: newDir, Mode: 0700}); err != nil {
+		t.Errorf("existing directory: %v", err)
+	}
+	if after, _ := os.Stat(newDir); after.Mode() != before.Mode() {
+		t.Errorf("existing directory mode changed from %v to %v", before.Mode(), after.Mode())
+	}
+	if _, err := os.Stat(filepath.Join(newDir, "keep.txt")); err != nil {
+		t.Errorf("existing directory contents changed: %v", err)
+	}
+
+	// a file in the way, at the path or at a parent
+	filePath := filepath.Join(dir, "file.txt")
+	writeTestFile(t, filePath, "not a dir")
+	for _, path := range []string{filePath, filepath.Join(filePath, "sub")} {
+		if err := impl.RemoteEnsureDirCommand(context.Background(), wshrpc.CommandRemoteMkdirData{Path: path}); err == nil {
+			t.Errorf("%q: expected an error with a file in the way", path)
+		}
+	}
+	if content, err := os.ReadFile(filePath); err != nil || string(content) != "not a dir" {
+		t.Errorf("file in the way was changed: %q, %v", content, err)
+	}
+
+	if runtime.GOOS != "windows" {
+		modeDir := filepath.Join(dir, "private")
+		if err := impl.RemoteEnsureDirCommand(context.Background(), wshrpc.CommandRemoteMkdirData{Path: modeDir, Mode: 0700}); err != nil {
+			t.Fatalf("RemoteEnsureDirCommand with mode: %v", err)
+		}
+		if finfo, err := os.Stat(modeDir); err != nil || finfo.Mode().Perm() != 0700 {
+			t.Errorf("expected 0700 directory, got %v, %v", finfo.Mode(), err)
+		}
+	}
+}
+
+func TestFileTouch(t *testing.T) {
+	impl := &ServerImpl{}
+	dir := t.TempDir()
+
+	newFile := filepath.Join(dir, "sub", "new.txt")
+	if err := impl.RemoteFileTouchCommand(context.Background(), wshrpc.CommandRemoteFileTouchData{Path: newFile}); err != nil {
+		t.Fatalf("touch new file: %v", err)
+	}
+	if finfo, err := os.Stat(newFile); err != nil || finfo.Size() != 0 {
+		t.Fatalf("expected empty new file, got %v, %v", finfo, err)
+	}
+
+	existing := filepath.Join(dir, "existing.txt")
+	writeTestFile(t, existing, "keep me")
+	old := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(existing, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if err := impl.RemoteFileTouchCommand(context.Background(), wshrpc.CommandRemoteFileTouchData{Path: existing}); err != nil {
+		t.Fatalf("touch existing file: %v", err)
+	}
+	finfo, err := os.Stat(existing)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if !finfo.ModTime().After(old.Add(time.Hour)) {
+		t.Errorf("expected mtime to be bumped, got %v", finfo.ModTime())
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "keep me" {
+		t.Errorf("touch changed contents: %q", data)
+	}
+
+	missing := filepath.Join(dir, "missing.txt")
+	if err := impl.RemoteFileTouchCommand(context.Background(), wshrpc.CommandRemoteFileTouchData{Path: missing, NoCreate: true}); err != nil {
+		t.Fatalf("touch with nocreate: %v", err)
+	}
+	if _, err := os.Stat(missing); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("nocreate should not create the file, stat err: %v", err)
+	}
+}
+
+// blocks ReadDir of blockDir until release is closed
+type blockingDirFS struct {
+	fs.ReadDirFS
+	blockDir string
+	release  chan struct{}
+}
+
+func (b blockingDirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == b.blockDir {
+		<-b.release
+	}
+	return b.ReadDirFS.ReadDir(name)
+}
+
+// removes paths under root right after the first ReadDir of root, so they are gone before they are stat'ed
+type removingDirFS struct {
+	fs.ReadDirFS
+	root   string
+	remove []string
+}
+
+func (r removingDirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := r.ReadDirFS.ReadDir(name)
+	if name == "." {
+		for _, path := range r.remove {
+			os.RemoveAll(filepath.Join(r.root, path))
+		}
+	}
+	return entries, err
+}
+
+func TestListEntries_EntryRemoved(t *testing.T) {
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		writeTestFile(t, filepath.Join(dir, "keep.txt"), "x")
+		writeTestFile(t, filepath.Join(dir, "gone.txt"), "x")
+		writeTestFile(t, filepath.Join(dir, "gonedir", "inner.txt"), "x")
+		writeTestFile(t, filepath.Join(dir, "sub", "nested.txt"), "x")
+		return dir
+	}
+	list := func(t *testing.T, dir string, opts *wshrpc.FileListOpts) ([]string, wshrpc.CommandRemoteListEntriesRtnData) {
+		impl := &ServerImpl{}
+		var names []string
+		var done wshrpc.CommandRemoteListEntriesRtnData
+		for resp := range impl.RemoteListEntriesCommand(context.Background(), wshrpc.CommandRemoteListEntriesData{Path: dir, Opts: opts}) {
+			if resp.Error != nil {
+				t.Fatalf("RemoteListEntriesCommand: %v", resp.Error)
+			}
+			for _, finfo := range resp.Response.FileInfo {
+				names = append(names, finfo.Name)
+			}
+			if resp.Response.Done {
+				done = resp.Response
+			}
+		}
+		if !done.Done {
+			t.Fatalf("listing did not complete")
+		}
+		slices.Sort(names)
+		return names, done
+	}
+	removeAfterReadDir := func(t *testing.T, dir string) {
+		t.Cleanup(func() { osReadDir = os.ReadDir })
+		osReadDir = func(name string) ([]os.DirEntry, error) {
+			entries, err := os.ReadDir(name)
+			os.RemoveAll(filepath.Join(dir, "gone.txt"))
+			os.RemoveAll(filepath.Join(dir, "gonedir"))
+			return entries, err
+		}
+	}
+
+	for name, opts := range map[string]*wshrpc.FileListOpts{"plain": {}, "sorted": {SortBy: wshrpc.FileListSortBy_Name}} {
+		t.Run(name, func(t *testing.T) {
+			dir := setup(t)
+			removeAfterReadDir(t, dir)
+			names, done := list(t, dir, opts)
+			if want := []string{"keep.txt", "sub"}; !slices.Equal(names, want) {
+				t.Errorf("got %v, expected %v", names, want)
+			}
+			if done.SkippedCount != 2 {
+				t.Errorf("expected 2 skipped entries, got %d", done.SkippedCount)
+			}
+		})
+	}
+
+	t.Run("all", func(t *testing.T) {
+		dir := setup(t)
+		t.Cleanup(func() { dirFS = os.DirFS })
+		dirFS = func(path string) fs.FS {
+			return removingDirFS{ReadDirFS: os.DirFS(path).(fs.ReadDirFS), root: path, remove: []string{"gone.txt", "gonedir"}}
+		}
+		names, done := list(t, dir, &wshrpc.FileListOpts{All: true})
+		// the walk carries on past the missing file and directory
+		if want := []string{"keep.txt", "nested.txt"}; !slices.Equal(names, want) {
+			t.Errorf("got %v, expected %v", names, want)
+		}
+		if done.SkippedCount != 2 || done.TotalCountIsMin {
+			t.Errorf("expected 2 skipped entries and an exact count, got %+v", done)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		_, done := list(t, setup(t), &wshrpc.FileListOpts{All: true})
+		if done.SkippedCount != 0 {
+			t.Errorf("expected no skipped entries, got %d", done.SkippedCount)
+		}
+	})
+}
+
+func TestListEntries_AllStreams(t *testing.T) {
+	dir := t.TempDir()
+	numFast := wshrpc.DirChunkSize + 5
+	for i := 0; i < numFast; i++ {
+		writeTestFile(t, filepath.Join(dir, "a", fmt.Sprintf("f%03d.txt", i)), "x")
+	}
+	writeTestFile(t, filepath.Join(dir, "b", "slow.txt"), "x")
+	release := make(chan struct{})
+	defer func() {
+		dirFS = os.DirFS
+	}()
+	dirFS = func(path string) fs.FS {
+		return blockingDirFS{ReadDirFS: os.DirFS(path).(fs.ReadDirFS), blockDir: "b", release: release}
+	}
+
+	impl := &ServerImpl{}
+	ch := impl.RemoteListEntriesCommand(context.Background(), wshrpc.CommandRemoteListEntriesData{Path: dir, Opts: &wshrpc.FileListOpts{All: true}})
+	// the walk is stuck on "b", so the first chunk must arrive before the walk completes
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			t.Fatalf("RemoteListEntriesCommand: %v", resp.Error)
+		}
+		if len(resp.Response.FileInfo) != wshrpc.DirChunkSize {
+			t.Fatalf("expected a full first chunk, got %d entries", len(resp.Response.FileInfo))
+		}
+		first := resp.Response.FileInfo[0]
+		if first.Path != filepath.Join(dir, "a", "f000.txt") {
+			t.Errorf("expected full path of nested entry, got %q", first.Path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no entries streamed before the walk completed")
+	}
+	close(release)
+	total := wshrpc.DirChunkSize
+	var sawSlow bool
+	for resp := range ch {
+		if resp.Error != nil {
+			t.Fatalf("RemoteListEntriesCommand: %v", resp.Error)
+		}
+		for _, finfo := range resp.Response.FileInfo {
+			total++
+			sawSlow = sawSlow || finfo.Name == "slow.txt"
+		}
+	}
+	if total != numFast+1 || !sawSlow {
+		t.Errorf("expected %d entries including slow.txt, got %d (slow=%v)", numFast+1, total, sawSlow)
+	}
+
+	// offset/limit still count walked entries (directories included)
+	names := listNames(t, dir, &wshrpc.FileListOpts{All: true, Offset: 2, Limit: 3})
+	if !slices.Equal(names, []string{"f000.txt", "f001.txt", "f002.txt"}) {
+		t.Errorf("offset/limit: got %v", names)
+	}
+}
+
+func TestListEntries_TotalCount(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "sub/d.txt", "sub/e.txt"} {
+		writeTestFile(t, filepath.Join(dir, name), "x")
+	}
+	impl := &ServerImpl{}
+	totalCount := func(opts *wshrpc.FileListOpts) (int, bool) {
+		t.Helper()
+		for resp := range impl.RemoteListEntriesCommand(context.Background(), wshrpc.CommandRemoteListEntriesData{Path: dir, Opts: opts}) {
+			if resp.Error != nil {
+				t.Fatalf("%+v: %v", opts, resp.Error)
+			}
+			if resp.Response.Done {
+				return resp.Response.TotalCount, resp.Response.TotalCountIsMin
+			}
+		}
+		t.Fatalf("%+v: no done response", opts)
+		return 0, false
+	}
+
+	for _, tc := range []struct {
+		opts     *wshrpc.FileListOpts
+		expected int
+	}{
+		{nil, 4},
+		{&wshrpc.FileListOpts{SortBy: wshrpc.FileListSortBy_Name, Offset: 1, Limit: 2}, 4},
+		{&wshrpc.FileListOpts{All: true}, 5},
+		{&wshrpc.FileListOpts{All: true, SortBy: wshrpc.FileListSortBy_Name, Offset: 3, Limit: 1}, 5},
+	} {
+		count, isMin := totalCount(tc.opts)
+		if count != tc.expected || isMin {
+			t.Errorf("%+v: expected exact count %d, got %d (min=%v)", tc.opts, tc.expected, count, isMin)
+		}
+	}
+
+	// the streamed walk stops after the page, so it can only report a lower bound
+	count, isMin := totalCount(&wshrpc.FileListOpts{All: true, Limit: 2})
+	if !isMin || count < 2 || count > 5 {
+		t.Errorf("expected a lower bound between 2 and 5, got %d (min=%v)", count, isMin)
+	}
+}
+
+func TestListEntries_DoneSentinel(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.txt"), "a")
+	writeTestFile(t, filepath.Join(dir, "sub", "b.txt"), "b")
+
+	impl := &ServerImpl{}
+	collect := func(path string, opts *wshrpc.FileListOpts) (numDone int, sawErr bool, lastDone bool) {
+		for resp := range impl.RemoteListEntriesCommand(context.Background(), wshrpc.CommandRemoteListEntriesData{Path: path, Opts: opts}) {
+			if resp.Error != nil {
+				sawErr = true
+				lastDone = false
+				continue
+			}
+			lastDone = resp.Response.Done
+			if resp.Response.Done {
+				numDone++
+				if len(resp.Response.FileInfo) != 0 {
+					t.Errorf("done sentinel should carry no entries, got %d", len(resp.Response.FileInfo))
+				}
+			}
+		}
+		return
+	}
+
+	for _, opts := range []*wshrpc.FileListOpts{
+		nil,
+		{All: true},
+		{SortBy: wshrpc.FileListSortBy_Name},
+	} {
+		numDone, sawErr, lastDone := collect(dir, opts)
+		if sawErr || numDone != 1 || !lastDone {
+			t.Errorf("%+v: expected exactly one trailing done, got done=%d last=%v err=%v", opts, numDone, lastDone, sawErr)
+		}
+	}
+
+	numDone, sawErr, _ := collect(filepath.Join(dir, "missing"), nil)
+	if !sawErr || numDone != 0 {
+		t.Errorf("missing dir: expected an error and no done, got done=%d err=%v", numDone, sawErr)
+	}
+	numDone, sawErr, _ = collect(dir, &wshrpc.FileListOpts{SortBy: "bogus"})
+	if !sawErr || numDone != 0 {
+		t.Errorf("bad sort: expected an error and no done, got done=%d err=%v", numDone, sawErr)
+	}
+}
+
+func TestTarEntryDestPath(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "dest")
+	outside := filepath.Join(dir, "outside")
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dest, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dest, "inner"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dest, "inner"), filepath.Join(dest, "inner-link")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"file.txt", false},
+		{"sub/dir/file.txt", false},
+		{"a..b.txt", false},
+		{"sub/../file.txt", false},
+		{"inner-link/file.txt", false},
+		{"../file.txt", true},
+		{"../../etc/passwd", true},
+		{"sub/../../file.txt", true},
+		{"/etc/passwd", true},
+		{"escape/file.txt", true},
+		{"escape/new/dir/file.txt", true},
+		{"", true},
+	}
+	for _, tc := range tests {
+		path, err := tarEntryDestPath(dest, tc.name)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got path %q", tc.name, path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if want := filepath.Join(dest, tc.name); path != want {
+			t.Errorf("%q: got %q, expected %q", tc.name, path, want)
+		}
+	}
+}
+
+func TestStripPathComponents(t *testing.T) {
+	tests := []struct {
+		name   string
+		n      int
+		want   string
+		wantOk bool
+	}{
+		{"project/src/main.go", 0, "project/src/main.go", true},
+		{"project/src/main.go", 1, "src/main.go", true},
+		{"project/src/main.go", 2, "main.go", true},
+		{"project/src/main.go", 3, "", false},
+		{"project/", 1, "", false},
+		{"./project//src/", 1, "src", true},
+		{"a/../../etc/passwd", 1, "../../etc/passwd", true},
+	}
+	for _, tc := range tests {
+		got, ok := stripPathComponents(tc.name, tc.n)
+		if got != tc.want || ok != tc.wantOk {
+			t.Errorf("stripPathComponents(%q, %d) = %q, %v, expected %q, %v", tc.name, tc.n, got, ok, tc.want, tc.wantOk)
+		}
+	}
+	// stripping must not let an entry slip past the traversal check
+	dest := t.TempDir()
+	stripped, _ := stripPathComponents("a/../../etc/passwd", 1)
+	if path, err := tarEntryDestPath(dest, stripped); err == nil {
+		t.Errorf("expected stripped traversal entry to be rejected, got %q", path)
+	}
+}
+
+func TestFileCopy_StripComponents(t *testing.T) {
+	srcDir := filepath.Join(t.TempDir(), "project")
+	writeTestFile(t, filepath.Join(srcDir, "top.txt"), "top")
+	writeTestFile(t, filepath.Join(srcDir, "src", "main.go"), "main")
+	writeTestFile(t, filepath.Join(srcDir, "src", "pkg", "util.go"), "util")
+
+	tests := []struct {
+		strip int
+		want  map[string]string
+	}{
+		{0, map[string]string{"project/top.txt": "top", "project/src/main.go": "main", "project/src/pkg/util.go": "util"}},
+		{1, map[string]string{"top.txt": "top", "src/main.go": "main", "src/pkg/util.go": "util"}},
+		// top.txt only has two segments, so it is stripped away entirely
+		{2, map[string]string{"main.go": "main", "pkg/util.go": "util"}},
+	}
+	impl := &ServerImpl{}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("strip%d", tc.strip), func(t *testing.T) {
+			destDir := t.TempDir()
+			_, err := impl.RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+				SrcUri:  "wsh://local/" + srcDir,
+				DestUri: "wsh://local/" + destDir,
+				Opts:    &wshrpc.FileCopyOpts{StripComponents: tc.strip},
+			})
+			if err != nil {
+				t.Fatalf("RemoteFileCopyCommand: %v", err)
+			}
+			got := make(map[string]string)
+			err = filepath.WalkDir(destDir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				rel, _ := filepath.Rel(destDir, path)
+				got[filepath.ToSlash(rel)] = string(data)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tc.want) {
+				t.Errorf("got files %v, expected %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStreamFile_LargerThanMaxFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "large.bin")
+	size := int64(wshrpc.MaxFileSize + 12345)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sparse, so the test doesn't have to write 50MB
+	if err := file.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteAt([]byte("tail"), size-4); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	impl := &ServerImpl{}
+	var total int64
+	var last []byte
+	for resp := range impl.RemoteStreamFileCommand(context.Background(), wshrpc.CommandRemoteStreamFileData{Path: path}) {
+		if resp.Error != nil {
+			t.Fatalf("RemoteStreamFileCommand: %v", resp.Error)
+		}
+		if resp.Response.At == nil {
+			continue
+		}
+		if resp.Response.At.Offset != total {
+			t.Fatalf("chunk at offset %d, expected %d", resp.Response.At.Offset, total)
+		}
+		data, err := base64.StdEncoding.DecodeString(resp.Response.Data64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += int64(len(data))
+		last = data
+	}
+	if total != size {
+		t.Errorf("streamed %d bytes, expected %d", total, size)
+	}
+	if !bytes.HasSuffix(last, []byte("tail")) {
+		t.Errorf("last chunk doesn't end with the file's tail")
+	}
+}
+
+func TestFileCompare(t *testing.T) {
+	dir := t.TempDir()
+	content := make([]byte, wshrpc.FileChunkSize*2+100)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	orig := write("orig.bin", content)
+	same := write("same.bin", content)
+	changed := slices.Clone(content)
+	changed[wshrpc.FileChunkSize+7] ^= 0xff
+	differ := write("differ.bin", changed)
+	shorter := write("shorter.bin", content[:len(content)-1])
+
+	impl := &ServerImpl{}
+	compare := func(path1, path2 string, quick bool) *wshrpc.CommandRemoteFileCompareRtnData {
+		t.Helper()
+		rtn, err := impl.RemoteFileCompareCommand(context.Background(), wshrpc.CommandRemoteFileCompareData{Path1: path1, Path2: path2, QuickCheck: quick})
+		if err != nil {
+			t.Fatalf("RemoteFileCompareCommand(%q, %q): %v", path1, path2, err)
+		}
+		return rtn
+	}
+
+	if rtn := compare(orig, same, false); rtn.Result != wshrpc.FileCompareResult_Identical {
+		t.Errorf("identical files: got %+v", rtn)
+	}
+	if rtn := compare(orig, differ, false); rtn.Result != wshrpc.FileCompareResult_Differ || rtn.DiffOffset != wshrpc.FileChunkSize+7 {
+		t.Errorf("differing files: got %+v, expected offset %d", rtn, wshrpc.FileChunkSize+7)
+	}
+	if rtn := compare(orig, shorter, false); rtn.Result != wshrpc.FileCompareResult_Differ || rtn.DiffOffset != -1 {
+		t.Errorf("size mismatch: got %+v", rtn)
+	}
+	missing := filepath.Join(dir, "missing.bin")
+	if rtn := compare(orig, missing, false); rtn.Result != wshrpc.FileCompareResult_Missing || rtn.MissingPath != missing {
+		t.Errorf("missing file: got %+v", rtn)
+	}
+
+	// quick check trusts a matching size and mtime
+	mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, path := range []string{orig, differ} {
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rtn := compare(orig, differ, true); rtn.Result != wshrpc.FileCompareResult_Identical {
+		t.Errorf("quick check: got %+v", rtn)
+	}
+
+	if _, err := impl.RemoteFileCompareCommand(context.Background(), wshrpc.CommandRemoteFileCompareData{Path1: dir, Path2: orig}); err == nil {
+		t.Errorf("expected error comparing a directory")
+	}
+}
+
+func TestZipStream_Dir(t *testing.T) {
+	srcDir := filepath.Join(t.TempDir(), "src")
+	writeTestFile(t, filepath.Join(srcDir, "a.txt"), strings.Repeat("hello ", 1000))
+	writeTestFile(t, filepath.Join(srcDir, "sub", "b.png"), "not really a png")
+	if err := os.Mkdir(filepath.Join(srcDir, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(srcDir, "a.txt"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(srcDir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	impl := &ServerImpl{}
+	var buf bytes.Buffer
+	for resp := range impl.RemoteZipStreamCommand(context.Background(), wshrpc.CommandRemoteStreamZipData{Path: srcDir}) {
+		if resp.Error != nil {
+			t.Fatalf("RemoteZipStreamCommand: %v", resp.Error)
+		}
+		buf.Write(resp.Response.Data)
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("cannot read zip: %v", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, file := range zipReader.File {
+		files[file.Name] = file
+	}
+	for _, name := range []string{"src/", "src/a.txt", "src/empty/", "src/link", "src/sub/", "src/sub/b.png"} {
+		if files[name] == nil {
+			t.Errorf("missing zip entry %q", name)
+		}
+	}
+	if t.Failed() {
+		t.FailNow()
+	}
+	readEntry := func(name string) string {
+		rc, err := files[name].Open()
+		if err != nil {
+			t.Fatalf("cannot open %q: %v", name, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("cannot read %q: %v", name, err)
+		}
+		return string(data)
+	}
+	if got := readEntry("src/a.txt"); got != strings.Repeat("hello ", 1000) {
+		t.Errorf("a.txt: got %d bytes", len(got))
+	}
+	if files["src/a.txt"].Method != zip.Deflate {
+		t.Errorf("a.txt should be deflated, method %d", files["src/a.txt"].Method)
+	}
+	if files["src/a.txt"].Mode().Perm() != 0750 {
+		t.Errorf("a.txt mode: got %v, want 0750", files["src/a.txt"].Mode().Perm())
+	}
+	if files["src/sub/b.png"].Method != zip.Store {
+		t.Errorf("b.png should be stored, method %d", files["src/sub/b.png"].Method)
+	}
+	if files["src/link"].Mode()&fs.ModeSymlink == 0 || readEntry("src/link") != "a.txt" {
+		t.Errorf("link: mode %v, target %q", files["src/link"].Mode(), readEntry("src/link"))
+	}
+	if !files["src/empty/"].Mode().IsDir() {
+		t.Errorf("empty dir: mode %v", files["src/empty/"].Mode())
+	}
+
+	buf.Reset()
+	for resp := range impl.RemoteZipStreamCommand(context.Background(), wshrpc.CommandRemoteStreamZipData{Path: srcDir, CompressionLevel: 42}) {
+		if resp.Error == nil {
+			t.Fatalf("expected error for invalid compression level")
+		}
+	}
+}
+
+func TestStreamFile_HeadOnly(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "file.txt"), "hello")
+	impl := &ServerImpl{}
+	for _, name := range []string{"file.txt", ".", "missing"} {
+		var resps []wshrpc.FileData
+		for resp := range impl.RemoteStreamFileCommand(context.Background(), wshrpc.CommandRemoteStreamFileData{Path: filepath.Join(dir, name), HeadOnly: true}) {
+			if resp.Error != nil {
+				t.Fatalf("%s: %v", name, resp.Error)
+			}
+			resps = append(resps, resp.Response)
+		}
+		if len(resps) != 1 || resps[0].Info == nil {
+			t.Fatalf("%s: expected a single info response, got %d responses", name, len(resps))
+		}
+		if resps[0].Data64 != "" || len(resps[0].Entries) > 0 {
+			t.Errorf("%s: expected no data or entries in head-only mode", name)
+		}
+		if name == "missing" && !resps[0].Info.NotFound {
+			t.Errorf("expected notfound for a missing file")
+		}
+		if name == "file.txt" && resps[0].Info.Size != 5 {
+			t.Errorf("expected size 5, got %d", resps[0].Info.Size)
+		}
+	}
+}
+
+func TestStreamFileRegular_SizeChanges(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), wshrpc.FileChunkSize/10*3)
+	impl := &ServerImpl{}
+
+	// a growing file is streamed as it was when opened
+	growPath := filepath.Join(t.TempDir(), "grow.log")
+	if err := os.WriteFile(growPath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var got []byte
+	err := impl.remoteStreamFileRegular(context.Background(), growPath, ByteRangeType{All: true}, wshrpc.FileChunkSize, func(fileInfo []*wshrpc.FileInfo, data []byte, byteRange ByteRangeType) {
+		if len(got) == 0 {
+			file, err := os.OpenFile(growPath, os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Write(bytes.Repeat([]byte("x"), wshrpc.FileChunkSize))
+			file.Close()
+		}
+		got = append(got, data...)
+	})
+	if err != nil {
+		t.Fatalf("growing file: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("growing file: got %d bytes, expected the original %d", len(got), len(content))
+	}
+
+	// a file truncated below the requested range is an error, not a short read
+	shrinkPath := filepath.Join(t.TempDir(), "shrink.log")
+	for _, rangeStr := range []string{"", "100-", fmt.Sprintf("0-%d", len(content)-10)} {
+		if err := os.WriteFile(shrinkPath, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		byteRange, err := parseByteRange(rangeStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := true
+		err = impl.remoteStreamFileRegular(context.Background(), shrinkPath, byteRange, wshrpc.FileChunkSize, func(fileInfo []*wshrpc.FileInfo, data []byte, byteRange ByteRangeType) {
+			if first {
+				first = false
+				if err := os.Truncate(shrinkPath, int64(wshrpc.FileChunkSize)+5); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+		if err == nil || !strings.Contains(err.Error(), "shrank while streaming") {
+			t.Errorf("range %q: expected shrink error, got %v", rangeStr, err)
+		}
+	}
+
+	// a range past the end of the file is just empty
+	if got := readRange(t, growPath, fmt.Sprintf("%d-%d", len(content)*10, len(content)*11)); len(got) != 0 {
+		t.Errorf("range past EOF: got %d bytes", len(got))
+	}
+}
+
+func TestTarStream_HeaderErrorClosesStream(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets")
+	}
+	srcDir := filepath.Join(t.TempDir(), "src")
+	writeTestFile(t, filepath.Join(srcDir, "a.txt"), "a")
+	// tar has no representation for sockets, so writing its header fails mid-stream
+	listener, err := net.Listen("unix", filepath.Join(srcDir, "sock"))
+	if err != nil {
+		t.Skipf("cannot create unix socket: %v", err)
+	}
+	defer listener.Close()
+
+	impl := &ServerImpl{}
+	ch := impl.RemoteTarStreamCommand(context.Background(), wshrpc.CommandRemoteStreamTarData{Path: srcDir})
+	var gotErr error
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case resp, ok := <-ch:
+			if !ok {
+				done = true
+				break
+			}
+			if resp.Error != nil {
+				gotErr = resp.Error
+			}
+		case <-timeout:
+			t.Fatalf("tar stream did not close after a header error")
+		}
+	}
+	if gotErr == nil || !strings.Contains(gotErr.Error(), "socket") {
+		t.Errorf("expected socket header error, got %v", gotErr)
+	}
+}
+
+func TestReadText(t *testing.T) {
+	impl := &ServerImpl{}
+	dir := t.TempDir()
+	readText := func(name string, byteRange string, encoding string) *wshrpc.CommandRemoteReadTextRtnData {
+		t.Helper()
+		rtn, err := impl.RemoteReadTextCommand(context.Background(), wshrpc.CommandRemoteReadTextData{Path: filepath.Join(dir, name), ByteRange: byteRange, Encoding: encoding})
+		if err != nil {
+			t.Fatalf("RemoteReadTextCommand %s: %v", name, err)
+		}
+		return rtn
+	}
+
+	// "é" is 2 bytes, a range ending in its middle leaves it for the next chunk
+	writeTestFile(t, filepath.Join(dir, "utf8.txt"), "café 日本")
+	rtn := readText("utf8.txt", "", "")
+	if rtn.Text != "café 日本" || rtn.Encoding != fileutil.TextEncoding_UTF8 || !rtn.EOF || rtn.BytesRead != 12 {
+		t.Errorf("unexpected utf-8 result %+v", rtn)
+	}
+	rtn = readText("utf8.txt", "0-4", "")
+	if rtn.Text != "caf" || rtn.BytesRead != 3 || rtn.EOF {
+		t.Errorf("expected split rune to be trimmed, got %+v", rtn)
+	}
+	rtn = readText("utf8.txt", fmt.Sprintf("%d-", rtn.BytesRead), rtn.Encoding)
+	if rtn.Text != "é 日本" || !rtn.EOF {
+		t.Errorf("expected the rest of the file, got %+v", rtn)
+	}
+
+	utf16Content := []byte{0xff, 0xfe}
+	for _, r := range "héllo \U0001f600" {
+		if r > 0xffff {
+			r1, r2 := utf16.EncodeRune(r)
+			utf16Content = append(utf16Content, byte(r1), byte(r1>>8), byte(r2), byte(r2>>8))
+		} else {
+			utf16Content = append(utf16Content, byte(r), byte(r>>8))
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "utf16.txt"), utf16Content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	rtn = readText("utf16.txt", "", "")
+	if rtn.Text != "héllo \U0001f600" || rtn.Encoding != fileutil.TextEncoding_UTF16LE || rtn.IsBinary {
+		t.Errorf("unexpected utf-16 result %+v", rtn)
+	}
+	// cut between the surrogates of the emoji
+	rtn = readText("utf16.txt", fmt.Sprintf("0-%d", len(utf16Content)-2), "")
+	if rtn.Text != "héllo " || rtn.BytesRead != int64(len(utf16Content)-4) {
+		t.Errorf("expected split surrogate pair to be trimmed, got %+v", rtn)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "latin1.txt"), []byte("caf\xe9"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rtn = readText("latin1.txt", "", "")
+	if rtn.Text != "café" || rtn.Encoding != fileutil.TextEncoding_Latin1 {
+		t.Errorf("unexpected latin-1 result %+v", rtn)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "data.bin"), []byte{0x7f, 'E', 'L', 'F', 2, 1, 1, 0, 0, 0, 0, 0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	rtn = readText("data.bin", "", "")
+	if !rtn.IsBinary || rtn.Text != "" || rtn.Encoding != "" {
+		t.Errorf("expected binary content to be flagged, got %+v", rtn)
+	}
+}
+
+func TestChunkSizeOverrides(t *testing.T) {
+	impl := &ServerImpl{}
+	dir := t.TempDir()
+	bigPath := filepath.Join(dir, "big.bin")
+	writeTestFile(t, bigPath, strings.Repeat("x", 20000))
+	listDir := filepath.Join(dir, "list")
+	for i := 0; i < 25; i++ {
+		writeTestFile(t, filepath.Join(listDir, fmt.Sprintf("f%02d", i)), "")
+	}
+
+	streamSizes := func(data wshrpc.CommandRemoteStreamFileData) []int {
+		t.Helper()
+		var sizes []int
+		for resp := range impl.RemoteStreamFileCommand(context.Background(), data) {
+			if resp.Error != nil {
+				t.Fatal(resp.Error)
+			}
+			if resp.Response.At != nil {
+				sizes = append(sizes, resp.Response.At.Size)
+			}
+			if len(resp.Response.Entries) > 0 {
+				sizes = append(sizes, len(resp.Response.Entries))
+			}
+		}
+		return sizes
+	}
+	listSizes := func(opts *wshrpc.FileListOpts) []int {
+		t.Helper()
+		var sizes []int
+		for resp := range impl.RemoteListEntriesCommand(context.Background(), wshrpc.CommandRemoteListEntriesData{Path: listDir, Opts: opts}) {
+			if resp.Error != nil {
+				t.Fatal(resp.Error)
+			}
+			if len(resp.Response.FileInfo) > 0 {
+				sizes = append(sizes, len(resp.Response.FileInfo))
+			}
+		}
+		return sizes
+	}
+	tests := []struct {
+		name string
+		got  []int
+		want []int
+	}{
+		{"file default", streamSizes(wshrpc.CommandRemoteStreamFileData{Path: bigPath}), []int{20000}},
+		{"file chunks", streamSizes(wshrpc.CommandRemoteStreamFileData{Path: bigPath, FileChunkSize: 8192}), []int{8192, 8192, 3616}},
+		{"file chunks clamped", streamSizes(wshrpc.CommandRemoteStreamFileData{Path: bigPath, FileChunkSize: 1}), []int{4096, 4096, 4096, 4096, 3616}},
+		{"file chunks per range", streamSizes(wshrpc.CommandRemoteStreamFileData{Path: bigPath, ByteRange: "0-5000,10000-20000", FileChunkSize: 4096}), []int{4096, 904, 4096, 4096, 1808}},
+		{"stream dir chunks", streamSizes(wshrpc.CommandRemoteStreamFileData{Path: listDir, DirChunkSize: 10}), []int{10, 10, 5}},
+		{"list default", listSizes(nil), []int{25}},
+		{"list chunks", listSizes(&wshrpc.FileListOpts{DirChunkSize: 10}), []int{10, 10, 5}},
+		{"list chunks clamped", listSizes(&wshrpc.FileListOpts{DirChunkSize: 3}), []int{8, 8, 8, 1}},
+		{"sorted list chunks", listSizes(&wshrpc.FileListOpts{DirChunkSize: 10, SortBy: wshrpc.FileListSortBy_Name}), []int{10, 10, 5}},
+		{"walk list chunks", listSizes(&wshrpc.FileListOpts{DirChunkSize: 10, All: true}), []int{10, 10, 5}},
+	}
+	for _, tc := range tests {
+		if !slices.Equal(tc.got, tc.want) {
+			t.Errorf("%s: got chunk sizes %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+
+	// the tar stream's packets are capped at the requested size
+	maxPacket := func(opts *wshrpc.FileCopyOpts) int {
+		t.Helper()
+		maxLen := 0
+		for resp := range impl.RemoteTarStreamCommand(context.Background(), wshrpc.CommandRemoteStreamTarData{Path: bigPath, Opts: opts}) {
+			if resp.Error != nil {
+				t.Fatal(resp.Error)
+			}
+			maxLen = max(maxLen, len(resp.Response.Data))
+		}
+		return maxLen
+	}
+	if got := maxPacket(&wshrpc.FileCopyOpts{FileChunkSize: 4096}); got != 4096 {
+		t.Errorf("tar stream with 4096 byte chunks: got packets up to %d bytes", got)
+	}
+	if got := maxPacket(nil); got <= 4096 {
+		t.Errorf("tar stream with default chunks: got packets up to %d bytes, want more than 4096", got)
+	}
+}
+
+func TestFileCopy_Dedupe(t *testing.T) {
+	srcDir := filepath.Join(t.TempDir(), "vendor")
+	writeTestFile(t, filepath.Join(srcDir, "a", "LICENSE"), "same license text")
+	writeTestFile(t, filepath.Join(srcDir, "b", "LICENSE"), "same license text")
+	writeTestFile(t, filepath.Join(srcDir, "c", "deep", "LICENSE"), "same license text")
+	writeTestFile(t, filepath.Join(srcDir, "d", "LICENSE"), "other license text")
+	writeTestFile(t, filepath.Join(srcDir, "e", "run.sh"), "same license text")
+	if err := os.Chmod(filepath.Join(srcDir, "e", "run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(srcDir, "empty1"), "")
+	writeTestFile(t, filepath.Join(srcDir, "empty2"), "")
+
+	copyTree := func(t *testing.T, dedupe bool) string {
+		destDir := filepath.Join(t.TempDir(), "vendor")
+		_, err := (&ServerImpl{}).RemoteFileCopyCommand(context.Background(), wshrpc.CommandFileCopyData{
+			SrcUri:  "wsh://local/" + srcDir,
+			DestUri: "wsh://local/" + destDir,
+			Opts:    &wshrpc.FileCopyOpts{Dedupe: dedupe},
+		})
+		if err != nil {
+			t.Fatalf("RemoteFileCopyCommand: %v", err)
+		}
+		return destDir
+	}
+	sameFile := func(t *testing.T, destDir string, a string, b string) bool {
+		t.Helper()
+		infoA, err := os.Stat(filepath.Join(destDir, a))
+		if err != nil {
+			t.Fatal(err)
+		}
+		infoB, err := os.Stat(filepath.Join(destDir, b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return os.SameFile(infoA, infoB)
+	}
+
+	destDir := copyTree(t, true)
+	for _, dup := range []string{"b/LICENSE", "c/deep/LICENSE"} {
+		if !sameFile(t, destDir, "a/LICENSE", dup) {
+			t.Errorf("%s: expected a hardlink to a/LICENSE", dup)
+		}
+		if data, err := os.ReadFile(filepath.Join(destDir, dup)); err != nil || string(data) != "same license text" {
+			t.Errorf("%s: got %q, %v", dup, data, err)
+		}
+	}
+	// different content, a different mode, and empty files are left as separate files
+	for _, pair := range [][2]string{{"a/LICENSE", "d/LICENSE"}, {"a/LICENSE", "e/run.sh"}, {"empty1", "empty2"}} {
+		if sameFile(t, destDir, pair[0], pair[1]) {
+			t.Errorf("%s and %s should not be linked", pair[0], pair[1])
+		}
+	}
+	if entries, err := os.ReadDir(filepath.Join(destDir, "b")); err != nil || len(entries) != 1 {
+		t.Errorf("expected no temp files left in b, got %d entries (%v)", len(entries), err)
+	}
+
+	destDir = copyTree(t, false)
+	if sameFile(t, destDir, "a/LICENSE", "b/LICENSE") {
+		t.Error("files should not be linked without Dedupe")
+	}
+}
@@ -0,0 +1,10 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux && !darwin
+
+package wshremote
+
+func getXattrs(path string, isLink bool) map[string]string {
+	return nil
+}
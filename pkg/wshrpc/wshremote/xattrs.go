@@ -0,0 +1,64 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin
+
+package wshremote
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+const maxXattrValueSize = 64 * 1024
+
+// readXattrBuf calls fn with a growing buffer until the result fits (the size can change between calls)
+func readXattrBuf(fn func(dest []byte) (int, error)) ([]byte, error) {
+	size, err := fn(nil)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if size > maxXattrValueSize {
+			return nil, unix.E2BIG
+		}
+		buf := make([]byte, size)
+		n, err := fn(buf)
+		if errors.Is(err, unix.ERANGE) {
+			size *= 2
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+// getXattrs returns nil if the file has no extended attributes or they can't be read.
+// symlinks are not followed when isLink is set.  values over 64k are skipped.
+func getXattrs(path string, isLink bool) map[string]string {
+	listFn, getFn := unix.Listxattr, unix.Getxattr
+	if isLink {
+		listFn, getFn = unix.Llistxattr, unix.Lgetxattr
+	}
+	names, err := readXattrBuf(func(dest []byte) (int, error) { return listFn(path, dest) })
+	if err != nil || len(names) == 0 {
+		return nil
+	}
+	rtn := make(map[string]string)
+	for _, name := range bytes.Split(bytes.TrimRight(names, "\x00"), []byte{0}) {
+		attr := string(name)
+		value, err := readXattrBuf(func(dest []byte) (int, error) { return getFn(path, attr, dest) })
+		if err != nil {
+			continue
+		}
+		rtn[attr] = string(value)
+	}
+	if len(rtn) == 0 {
+		return nil
+	}
+	return rtn
+}
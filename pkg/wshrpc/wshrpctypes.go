@@ -28,8 +28,43 @@ const (
 	MaxDirSize = 1024
 	// FileChunkSize is the size of the file chunk to read
 	FileChunkSize = 64 * 1024
+	// MinFileChunkSize and MaxFileChunkSize bound a per-request file chunk size
+	MinFileChunkSize = 4 * 1024
+	MaxFileChunkSize = 1024 * 1024
+	// MaxReadAtSize is the largest read RemoteReadAtCommand does
+	MaxReadAtSize = 4 * 1024 * 1024
 	// DirChunkSize is the size of the directory chunk to read
 	DirChunkSize = 128
+	// MinDirChunkSize and MaxDirChunkSize bound a per-request directory chunk size
+	MinDirChunkSize = 8
+	MaxDirChunkSize = 1024
+	// MaxSortedDirSize is the maximum number of entries gathered (and sorted) before paging a sorted listing
+	MaxSortedDirSize = 10000
+	// MaxGrepMatches is the maximum number of matches returned by a grep
+	MaxGrepMatches = 1000
+	// MaxGlobMatches is the maximum number of paths returned by a glob
+	MaxGlobMatches = 1000
+)
+
+const (
+	FileListSortBy_Name    = "name"
+	FileListSortBy_Size    = "size"
+	FileListSortBy_ModTime = "modtime"
+)
+
+const (
+	FileCompareResult_Identical = "identical"
+	FileCompareResult_Differ    = "differ"
+	FileCompareResult_Missing   = "missing" // one (or both) of the files does not exist
+)
+
+// returned by RemoteFileExistsCommand
+const (
+	FileKind_Missing = "missing"
+	FileKind_File    = "file"
+	FileKind_Dir     = "dir"
+	FileKind_Symlink = "symlink" // the link itself, it is not followed (the target may be missing)
+	FileKind_Other   = "other"   // sockets, named pipes, devices
 )
 
 const LocalConnName = "local"
@@ -227,16 +262,53 @@ type WshRpcInterface interface {
 
 	// remotes
 	RemoteStreamFileCommand(ctx context.Context, data CommandRemoteStreamFileData) chan RespOrErrorUnion[FileData]
+	RemoteReadFileRangeCommand(ctx context.Context, data CommandRemoteReadFileRangeData) <-chan RespOrErrorUnion[iochantypes.Packet]
 	RemoteTarStreamCommand(ctx context.Context, data CommandRemoteStreamTarData) <-chan RespOrErrorUnion[iochantypes.Packet]
+	RemoteZipStreamCommand(ctx context.Context, data CommandRemoteStreamZipData) <-chan RespOrErrorUnion[iochantypes.Packet]
 	RemoteFileCopyCommand(ctx context.Context, data CommandFileCopyData) (bool, error)
+	RemoteFileCopyStreamCommand(ctx context.Context, data CommandFileCopyData) chan RespOrErrorUnion[CommandRemoteFileCopyProgress]
+	RemoteFileCopyPlanCommand(ctx context.Context, data CommandFileCopyData) chan RespOrErrorUnion[FileCopyPlanEntry]
 	RemoteListEntriesCommand(ctx context.Context, data CommandRemoteListEntriesData) chan RespOrErrorUnion[CommandRemoteListEntriesRtnData]
 	RemoteFileInfoCommand(ctx context.Context, path string) (*FileInfo, error)
-	RemoteFileTouchCommand(ctx context.Context, path string) error
+	RemoteFileExistsCommand(ctx context.Context, path string) (string, error)
+	RemoteRealPathCommand(ctx context.Context, path string) (*CommandRemoteRealPathRtnData, error)
+	RemoteReadLinkCommand(ctx context.Context, path string) (string, error)
+	RemoteFileTouchCommand(ctx context.Context, data CommandRemoteFileTouchData) error
 	RemoteFileMoveCommand(ctx context.Context, data CommandFileCopyData) error
+	RemoteFileMoveStreamCommand(ctx context.Context, data CommandFileCopyData) chan RespOrErrorUnion[CommandRemoteFileCopyProgress]
 	RemoteFileDeleteCommand(ctx context.Context, data CommandDeleteFileData) error
 	RemoteWriteFileCommand(ctx context.Context, data FileData) error
+	RemoteFileAppendCommand(ctx context.Context, data FileData) (*FileInfo, error)
+	RemoteFileTruncateCommand(ctx context.Context, data CommandRemoteFileTruncateData) (int64, error)
 	RemoteFileJoinCommand(ctx context.Context, paths []string) (*FileInfo, error)
-	RemoteMkdirCommand(ctx context.Context, path string) error
+	RemoteMkdirCommand(ctx context.Context, data CommandRemoteMkdirData) error
+	RemoteEnsureDirCommand(ctx context.Context, data CommandRemoteMkdirData) error
+	RemoteMkdirTempCommand(ctx context.Context, data CommandRemoteMkdirTempData) (*FileInfo, error)
+	RemoteFileChownCommand(ctx context.Context, data CommandRemoteFileChownData) error
+	RemoteSymlinkCommand(ctx context.Context, data CommandRemoteLinkData) (*FileInfo, error)
+	RemoteHardlinkCommand(ctx context.Context, data CommandRemoteLinkData) (*FileInfo, error)
+	RemoteDiskUsageCommand(ctx context.Context, data CommandRemoteDiskUsageData) (*CommandRemoteDiskUsageRtnData, error)
+	RemoteFileCompareCommand(ctx context.Context, data CommandRemoteFileCompareData) (*CommandRemoteFileCompareRtnData, error)
+	RemoteReadTextCommand(ctx context.Context, data CommandRemoteReadTextData) (*CommandRemoteReadTextRtnData, error)
+	RemoteFileSystemStatsCommand(ctx context.Context, path string) (*FileSystemStats, error)
+	RemoteStatVFSCommand(ctx context.Context, path string) (*StatVFSInfo, error)
+	RemoteFilePreviewCommand(ctx context.Context, data CommandRemoteFilePreviewData) (*CommandRemoteFilePreviewRtnData, error)
+	RemoteGrepCommand(ctx context.Context, data CommandRemoteGrepData) chan RespOrErrorUnion[GrepMatch]
+	RemoteSedCommand(ctx context.Context, data CommandRemoteSedData) (*CommandRemoteSedRtnData, error)
+	RemoteManifestCommand(ctx context.Context, data CommandRemoteManifestData) chan RespOrErrorUnion[ManifestEntry]
+	RemoteSyncCommand(ctx context.Context, data CommandRemoteSyncData) chan RespOrErrorUnion[CommandRemoteSyncProgress]
+	RemoteDownloadCommand(ctx context.Context, data CommandRemoteDownloadData) chan RespOrErrorUnion[CommandRemoteDownloadProgress]
+	RemoteGlobCommand(ctx context.Context, pattern string) chan RespOrErrorUnion[CommandRemoteListEntriesRtnData]
+	RemoteTailFileCommand(ctx context.Context, data CommandRemoteTailFileData) chan RespOrErrorUnion[FileData]
+	RemoteReadLinesCommand(ctx context.Context, data CommandRemoteReadLinesData) chan RespOrErrorUnion[FileData]
+	RemoteFileUploadOpenCommand(ctx context.Context, data CommandRemoteFileUploadOpenData) (string, error)
+	RemoteFileUploadWriteCommand(ctx context.Context, data CommandRemoteFileUploadWriteData) error
+	RemoteFileUploadCloseCommand(ctx context.Context, data CommandRemoteFileUploadCloseData) (*FileInfo, error)
+	RemoteOpenFileCommand(ctx context.Context, path string) (*CommandRemoteOpenFileRtnData, error)
+	RemoteReadAtCommand(ctx context.Context, data CommandRemoteReadAtData) (*FileData, error)
+	RemoteCloseFileCommand(ctx context.Context, handleId string) error
+	RemoteFileLockCommand(ctx context.Context, data CommandRemoteFileLockData) (*CommandRemoteFileLockRtnData, error)
+	RemoteFileUnlockCommand(ctx context.Context, handleId string) error
 	RemoteStreamCpuDataCommand(ctx context.Context) chan RespOrErrorUnion[TimeSeriesData]
 	RemoteGetInfoCommand(ctx context.Context) (RemoteInfo, error)
 	RemoteInstallRcFilesCommand(ctx context.Context) error
@@ -407,23 +479,32 @@ type FileData struct {
 	Data64  string      `json:"data64,omitempty"`
 	Entries []*FileInfo `json:"entries,omitempty"`
 	At      *FileDataAt `json:"at,omitempty"` // if set, this turns read/write ops to ReadAt/WriteAt ops (len is only used for ReadAt)
+
+	TotalLines int `json:"totallines,omitempty"` // RemoteReadLinesCommand only, set on the final packet when the scan reached EOF
 }
 
 type FileInfo struct {
-	Path          string      `json:"path"`          // cleaned path (may have "~")
-	Dir           string      `json:"dir,omitempty"` // returns the directory part of the path (if this is a a directory, it will be equal to Path).  "~" will be expanded, and separators will be normalized to "/"
-	Name          string      `json:"name,omitempty"`
-	NotFound      bool        `json:"notfound,omitempty"`
-	Opts          *FileOpts   `json:"opts,omitempty"`
-	Size          int64       `json:"size,omitempty"`
-	Meta          *FileMeta   `json:"meta,omitempty"`
-	Mode          os.FileMode `json:"mode,omitempty"`
-	ModeStr       string      `json:"modestr,omitempty"`
-	ModTime       int64       `json:"modtime,omitempty"`
-	IsDir         bool        `json:"isdir,omitempty"`
-	SupportsMkdir bool        `json:"supportsmkdir,omitempty"`
-	MimeType      string      `json:"mimetype,omitempty"`
-	ReadOnly      bool        `json:"readonly,omitempty"` // this is not set for fileinfo's returned from directory listings
+	Path          string            `json:"path"`          // cleaned path (may have "~")
+	Dir           string            `json:"dir,omitempty"` // returns the directory part of the path (if this is a a directory, it will be equal to Path).  "~" will be expanded, and separators will be normalized to "/"
+	Name          string            `json:"name,omitempty"`
+	NotFound      bool              `json:"notfound,omitempty"`
+	Opts          *FileOpts         `json:"opts,omitempty"`
+	Size          int64             `json:"size,omitempty"`       // -1 for directories
+	NumEntries    int               `json:"numentries,omitempty"` // directories only, the number of entries (not recursive), -1 if not counted (directories inside listings)
+	Meta          *FileMeta         `json:"meta,omitempty"`
+	Mode          os.FileMode       `json:"mode,omitempty"`
+	ModeStr       string            `json:"modestr,omitempty"`
+	ModTime       int64             `json:"modtime,omitempty"`
+	IsDir         bool              `json:"isdir,omitempty"`
+	SupportsMkdir bool              `json:"supportsmkdir,omitempty"`
+	MimeType      string            `json:"mimetype,omitempty"`
+	ReadOnly      bool              `json:"readonly,omitempty"`   // this is not set for fileinfo's returned from directory listings
+	LinkTarget    string            `json:"linktarget,omitempty"` // set for symlinks, the (unresolved) target of the link
+	Uid           int               `json:"uid,omitempty"`        // unix only (uid/gid 0 are omitted, Owner/Group are still set)
+	Gid           int               `json:"gid,omitempty"`
+	Owner         string            `json:"owner,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Xattrs        map[string]string `json:"xattrs,omitempty"` // extended attributes, only for extended stats (linux and macos)
 }
 
 type FileOpts struct {
@@ -433,6 +514,13 @@ type FileOpts struct {
 	IJsonBudget int   `json:"ijsonbudget,omitempty"`
 	Truncate    bool  `json:"truncate,omitempty"`
 	Append      bool  `json:"append,omitempty"`
+	Atomic      bool  `json:"atomic,omitempty"` // write to a temp file and rename it over the destination
+
+	// if set, writes fail with a conflict error unless the existing file still matches (0 means don't check)
+	ExpectedModTime int64 `json:"expectedmodtime,omitempty"` // unix millis, as returned in FileInfo.ModTime
+	ExpectedSize    int64 `json:"expectedsize,omitempty"`
+
+	Sha256 string `json:"sha256,omitempty"` // hex sha256 of the data being written, the write fails (without touching the file) if the data doesn't match
 }
 
 type FileMeta = map[string]any
@@ -445,9 +533,15 @@ type FileListData struct {
 }
 
 type FileListOpts struct {
-	All    bool `json:"all,omitempty"`
-	Offset int  `json:"offset,omitempty"`
-	Limit  int  `json:"limit,omitempty"`
+	All              bool   `json:"all,omitempty"`
+	Offset           int    `json:"offset,omitempty"`
+	Limit            int    `json:"limit,omitempty"`
+	SortBy           string `json:"sortby,omitempty"` // one of FileListSortBy_*, sorting is applied before Offset/Limit
+	SortDesc         bool   `json:"sortdesc,omitempty"`
+	DirsFirst        bool   `json:"dirsfirst,omitempty"`
+	RespectGitignore bool   `json:"respectgitignore,omitempty"` // skip entries ignored by .gitignore files
+	ShowHidden       *bool  `json:"showhidden,omitempty"`       // defaults to true, false skips dotfiles (and files with the hidden attribute on windows), All listings don't descend into hidden dirs
+	DirChunkSize     int    `json:"dirchunksize,omitempty"`     // entries per response, 0 for DirChunkSize, clamped to MinDirChunkSize-MaxDirChunkSize
 }
 
 type FileCreateData struct {
@@ -539,21 +633,182 @@ type CommandFileCopyData struct {
 	Opts    *FileCopyOpts `json:"opts,omitempty"`
 }
 
+type CommandRemoteFileCopyProgress struct {
+	FilesDone   int    `json:"filesdone"`
+	BytesDone   int64  `json:"bytesdone"`
+	CurrentFile string `json:"currentfile,omitempty"`
+	TotalBytes  int64  `json:"totalbytes,omitempty"` // only set when known up front (single file copies)
+}
+
+type CommandRemoteDownloadData struct {
+	Url       string `json:"url"` // http or https only
+	Path      string `json:"path"`
+	Sha256    string `json:"sha256,omitempty"` // expected hex digest of the body, the download fails (and Path is untouched) if it doesn't match
+	Overwrite bool   `json:"overwrite,omitempty"`
+	Timeout   int64  `json:"timeout,omitempty"` // ms for the whole download, 0 for no limit beyond the rpc's own
+}
+
+// CommandRemoteDownloadProgress is sent periodically while downloading, the last one has Done set along with the result
+type CommandRemoteDownloadProgress struct {
+	BytesDone   int64     `json:"bytesdone"`
+	TotalBytes  int64     `json:"totalbytes,omitempty"` // the Content-Length, when the server sends one
+	Done        bool      `json:"done,omitempty"`
+	ContentType string    `json:"contenttype,omitempty"` // set with Done
+	Sha256      string    `json:"sha256,omitempty"`      // set with Done, hex digest of the body
+	Info        *FileInfo `json:"info,omitempty"`        // set with Done
+}
+
+const (
+	FileCopyPlanAction_Create    = "create"
+	FileCopyPlanAction_Overwrite = "overwrite"
+	FileCopyPlanAction_Skip      = "skip"     // an existing directory that is merged into
+	FileCopyPlanAction_Conflict  = "conflict" // the real copy would fail here, see Reason
+)
+
+type FileCopyPlanEntry struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	IsDir  bool   `json:"isdir,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
 type CommandRemoteStreamTarData struct {
 	Path string        `json:"path"`
 	Opts *FileCopyOpts `json:"opts,omitempty"`
 }
 
+type CommandRemoteStreamZipData struct {
+	Path             string        `json:"path"`
+	Opts             *FileCopyOpts `json:"opts,omitempty"`             // only the timeout, buffer depth, exclude, gitignore and follow symlink opts apply
+	CompressionLevel int           `json:"compressionlevel,omitempty"` // deflate level 1-9, 0 uses the default, -1 stores without compressing
+}
+
 type FileCopyOpts struct {
-	Overwrite bool  `json:"overwrite,omitempty"`
-	Recursive bool  `json:"recursive,omitempty"` // only used for move, always true for copy
-	Merge     bool  `json:"merge,omitempty"`
-	Timeout   int64 `json:"timeout,omitempty"`
+	Overwrite          bool     `json:"overwrite,omitempty"`
+	Recursive          bool     `json:"recursive,omitempty"`   // only used for move, always true for copy
+	MakeParents        bool     `json:"makeparents,omitempty"` // only used for move, create missing parent directories of the destination
+	Merge              bool     `json:"merge,omitempty"`
+	Timeout            int64    `json:"timeout,omitempty"`
+	PreserveTimestamps bool     `json:"preservetimestamps,omitempty"`
+	PreserveMode       bool     `json:"preservemode,omitempty"`     // set the exact source permissions, otherwise new files get the source mode filtered through the umask (like cp without -p)
+	PreserveOwner      bool     `json:"preserveowner,omitempty"`    // copy the source uid/gid, needs root, falls back to the current user without failing
+	FollowSymlinks     bool     `json:"followsymlinks,omitempty"`   // copy the symlink targets instead of the symlinks themselves
+	Hardlink           bool     `json:"hardlink,omitempty"`         // hardlink instead of copying when the source and destination are on the same filesystem
+	Dedupe             bool     `json:"dedupe,omitempty"`           // hardlink destination files whose content (and mode) matches a file already written by this copy
+	RespectGitignore   bool     `json:"respectgitignore,omitempty"` // skip files ignored by .gitignore files in the source tree
+	ExcludePatterns    []string `json:"excludepatterns,omitempty"`  // globs to skip, matched against each name (or the relative path if the pattern has a "/")
+	Resume             bool     `json:"resume,omitempty"`           // resume a partial single file copy, appending to the existing destination file
+	ResumeOffset       int64    `json:"resumeoffset,omitempty"`     // set by the destination when resuming, number of bytes it already has
+	ResumeChecksum     string   `json:"resumechecksum,omitempty"`   // set by the destination when resuming, hex sha256 of the bytes it already has
+	DryRun             bool     `json:"dryrun,omitempty"`           // don't touch the destination, see RemoteFileCopyPlanCommand
+	BufferDepth        int      `json:"bufferdepth,omitempty"`      // chunks the source reads ahead of the destination, 0 for the default (see iochan.ReaderChanOpts)
+	ParallelWrites     int      `json:"parallelwrites,omitempty"`   // number of small files the destination writes concurrently (max 32), 0 or 1 writes them one at a time
+	StripComponents    int      `json:"stripcomponents,omitempty"`  // like tar --strip-components, drop this many leading path segments from each entry of a directory copy, entries left with nothing are skipped
+	FileChunkSize      int      `json:"filechunksize,omitempty"`    // bytes per packet of the tar/zip stream, 0 for FileChunkSize, clamped to MinFileChunkSize-MaxFileChunkSize
 }
 
 type CommandRemoteStreamFileData struct {
 	Path      string `json:"path"`
-	ByteRange string `json:"byterange,omitempty"`
+	ByteRange string `json:"byterange,omitempty"` // "start-end", "start-", "-N", or a comma-separated list of these (files only)
+	HeadOnly  bool   `json:"headonly,omitempty"`  // only send the FileInfo, no file data or directory entries
+
+	// bytes per file data response and entries per directory response, 0 for the FileChunkSize/DirChunkSize defaults.
+	// bigger chunks cut the per-packet overhead on fast links, smaller ones keep slow links responsive.
+	FileChunkSize int `json:"filechunksize,omitempty"` // clamped to MinFileChunkSize-MaxFileChunkSize
+	DirChunkSize  int `json:"dirchunksize,omitempty"`  // clamped to MinDirChunkSize-MaxDirChunkSize
+}
+
+type CommandRemoteReadFileRangeData struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset,omitempty"`
+	Size   int64  `json:"size"` // number of bytes to read, the range is cut short at EOF
+}
+
+type CommandRemoteReadLinesData struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"startline,omitempty"` // 1-based, defaults to 1
+	EndLine   int    `json:"endline,omitempty"`   // inclusive, 0 reads to EOF
+}
+
+type CommandRemoteTailFileData struct {
+	Path   string `json:"path"`
+	Lines  int    `json:"lines,omitempty"`  // number of trailing lines to send before following, defaults to 10
+	PollMs int    `json:"pollms,omitempty"` // how often to check for new data, defaults to 250ms
+}
+
+type CommandRemoteFileUploadOpenData struct {
+	Path string      `json:"path"`
+	Mode os.FileMode `json:"mode,omitempty"` // defaults to 0644
+}
+
+type CommandRemoteFileUploadWriteData struct {
+	UploadId string             `json:"uploadid"`
+	Packet   iochantypes.Packet `json:"packet"` // a data packet (the checksum is sent on close)
+}
+
+type CommandRemoteFileUploadCloseData struct {
+	UploadId string `json:"uploadid"`
+	Checksum []byte `json:"checksum,omitempty"` // sha256 of all the uploaded data, required unless canceling
+	Cancel   bool   `json:"cancel,omitempty"`   // abort the upload, leaving the destination untouched
+}
+
+type CommandRemoteOpenFileRtnData struct {
+	HandleId string    `json:"handleid"` // closed by RemoteCloseFileCommand, or after it goes unused for a while
+	Info     *FileInfo `json:"info"`
+}
+
+type CommandRemoteReadAtData struct {
+	HandleId string `json:"handleid"`
+	Offset   int64  `json:"offset"`
+	Size     int    `json:"size"` // at most MaxReadAtSize, fewer bytes are returned at EOF
+}
+
+type CommandRemoteFileLockData struct {
+	Path    string `json:"path"`
+	Shared  bool   `json:"shared,omitempty"`  // a read lock that other shared locks can hold too, exclusive by default
+	TryLock bool   `json:"trylock,omitempty"` // return right away (with Locked false) if the lock is held, instead of waiting
+}
+
+type CommandRemoteFileLockRtnData struct {
+	Locked   bool   `json:"locked"`
+	HandleId string `json:"handleid,omitempty"` // set when locked, released by RemoteFileUnlockCommand or when the owning connection drops
+}
+
+type CommandRemoteFileTruncateData struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`             // growing a file zero-fills (sparse where the filesystem supports it)
+	Create bool   `json:"create,omitempty"` // create the file if it doesn't exist
+}
+
+type CommandRemoteFileTouchData struct {
+	Path     string `json:"path"`
+	NoCreate bool   `json:"nocreate,omitempty"` // only update the times of an existing file, like touch -c
+}
+
+type CommandRemoteMkdirData struct {
+	Path string      `json:"path"`
+	Mode os.FileMode `json:"mode,omitempty"` // defaults to 0755 (subject to umask), an explicit mode is applied exactly to the final directory
+}
+
+type CommandRemoteMkdirTempData struct {
+	Dir     string `json:"dir,omitempty"`     // parent directory, defaults to the system temp dir
+	Pattern string `json:"pattern,omitempty"` // name pattern as in os.MkdirTemp (the last "*" is replaced by the random part), defaults to "wsh-tmp-*"
+}
+
+type CommandRemoteFileChownData struct {
+	Path      string `json:"path"`
+	Owner     string `json:"owner,omitempty"`     // user name or numeric uid, empty leaves the owner unchanged
+	Group     string `json:"group,omitempty"`     // group name or numeric gid, empty leaves the group unchanged
+	Recursive bool   `json:"recursive,omitempty"` // symlinks are never followed, the links themselves are changed
+	Strict    bool   `json:"strict,omitempty"`    // stop at the first path that can't be changed instead of collecting failures
+}
+
+type CommandRemoteLinkData struct {
+	Path            string `json:"path"`                      // the link to create
+	Target          string `json:"target"`                    // symlinks: stored as-is (relative targets are relative to the link's directory); hardlinks: the existing file
+	TargetMustExist bool   `json:"targetmustexist,omitempty"` // symlinks only, fail instead of creating a dangling link
+	MakeParents     bool   `json:"makeparents,omitempty"`     // create missing parent directories of Path
 }
 
 type CommandRemoteListEntriesData struct {
@@ -563,6 +818,207 @@ type CommandRemoteListEntriesData struct {
 
 type CommandRemoteListEntriesRtnData struct {
 	FileInfo []*FileInfo `json:"fileinfo,omitempty"`
+	Done     bool        `json:"done,omitempty"` // set on the final (empty) response when the listing completed without error
+
+	// set on the done response, the number of entries in the listing before Offset/Limit are applied.
+	// plain listings count every entry, All listings count files only (directories aren't listed).  the streamed All walk
+	// stops once it has sent Limit entries, and sorted listings stop gathering at MaxSortedDirSize, in which case
+	// TotalCountIsMin is set and TotalCount is only what was seen so far.
+	TotalCount      int  `json:"totalcount,omitempty"`
+	TotalCountIsMin bool `json:"totalcountismin,omitempty"`
+
+	// set on the done response, entries that were left out because they couldn't be read, most likely because they
+	// were removed (or changed permissions) during the listing.  the listing is still complete otherwise, a UI may want to refresh.
+	SkippedCount int `json:"skippedcount,omitempty"`
+}
+
+type CommandRemoteRealPathRtnData struct {
+	Path   string `json:"path"`             // absolute, with symlinks resolved as far as the path exists
+	Exists bool   `json:"exists,omitempty"` // false when some trailing components don't exist, they are only cleaned
+}
+
+type CommandRemoteDiskUsageData struct {
+	Path      string `json:"path"`
+	TopN      int    `json:"topn,omitempty"`      // if set, also return the N largest files
+	TimeoutMs int    `json:"timeoutms,omitempty"` // if set, stop walking after this long and return the totals so far with Truncated set
+}
+
+type CommandRemoteDiskUsageRtnData struct {
+	TotalBytes   int64       `json:"totalbytes"`
+	FileCount    int         `json:"filecount"`
+	DirCount     int         `json:"dircount"`
+	LargestFiles []*FileInfo `json:"largestfiles,omitempty"` // largest first
+	Truncated    bool        `json:"truncated,omitempty"`    // the walk hit TimeoutMs, the totals only cover part of the tree
+}
+
+type CommandRemoteReadTextData struct {
+	Path      string `json:"path"`
+	ByteRange string `json:"byterange,omitempty"` // a single "start-end", "start-" or "-N" range, open-ended ranges read up to 64k
+	Encoding  string `json:"encoding,omitempty"`  // skips detection, e.g. to read later chunks with the encoding detected for the first one
+}
+
+type CommandRemoteReadTextRtnData struct {
+	Text      string `json:"text"`
+	Encoding  string `json:"encoding,omitempty"` // one of the fileutil.TextEncoding_ constants, empty for binary content
+	IsBinary  bool   `json:"isbinary,omitempty"` // no text is returned for binary content
+	BytesRead int64  `json:"bytesread"`          // can be short of the range when a character split at its end was left out, continue reading from start+bytesread
+	EOF       bool   `json:"eof,omitempty"`
+}
+
+const (
+	PreviewFormat_Jpeg = "jpeg"
+	PreviewFormat_Png  = "png"
+)
+
+type CommandRemoteFilePreviewData struct {
+	Path    string `json:"path"`
+	MaxSize int    `json:"maxsize,omitempty"` // max width/height of the thumbnail, defaults to 256, images are never scaled up
+	Format  string `json:"format,omitempty"`  // one of the PreviewFormat_ constants, defaults to jpeg for opaque images and png otherwise
+}
+
+type CommandRemoteFilePreviewRtnData struct {
+	Data64     string `json:"data64,omitempty"`
+	MimeType   string `json:"mimetype"` // mimetype of the thumbnail, or of the file itself when there is no preview
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	OrigWidth  int    `json:"origwidth,omitempty"`
+	OrigHeight int    `json:"origheight,omitempty"`
+	NoPreview  bool   `json:"nopreview,omitempty"` // set for files that are not decodable images
+}
+
+type CommandRemoteFileCompareData struct {
+	Path1      string `json:"path1"`
+	Path2      string `json:"path2"`
+	QuickCheck bool   `json:"quickcheck,omitempty"` // treat files with the same size and mtime as identical without reading them (like rsync)
+}
+
+type CommandRemoteFileCompareRtnData struct {
+	Result      string `json:"result"`                // one of the FileCompareResult_ constants
+	DiffOffset  int64  `json:"diffoffset"`            // offset of the first differing byte, -1 if the files differ only in size (not read) or are not compared
+	MissingPath string `json:"missingpath,omitempty"` // set for FileCompareResult_Missing
+}
+
+type FileSystemStats struct {
+	Path      string `json:"path"`
+	Total     int64  `json:"total"`
+	Used      int64  `json:"used"`
+	Available int64  `json:"available"` // bytes available to the current user (may be less than Total-Used)
+	FsType    string `json:"fstype,omitempty"`
+}
+
+type StatVFSInfo struct {
+	Path       string `json:"path"`                 // the existing path that was checked (the nearest parent for a missing path)
+	MountPoint string `json:"mountpoint,omitempty"` // the volume root on windows
+	FsType     string `json:"fstype,omitempty"`     // e.g. "ext4", "apfs", "nfs4", "NTFS"
+	ReadOnly   bool   `json:"readonly,omitempty"`
+	Network    bool   `json:"network,omitempty"` // a network mount, expect slower file operations
+}
+
+type CommandRemoteGrepData struct {
+	Path    string    `json:"path"`
+	Pattern string    `json:"pattern"`
+	Opts    *GrepOpts `json:"opts,omitempty"`
+}
+
+type GrepOpts struct {
+	Regex      bool   `json:"regex,omitempty"` // pattern is a regular expression, otherwise it is matched literally
+	IgnoreCase bool   `json:"ignorecase,omitempty"`
+	WholeWord  bool   `json:"wholeword,omitempty"`
+	MaxMatches int    `json:"maxmatches,omitempty"` // defaults to (and is capped at) MaxGrepMatches
+	Glob       string `json:"glob,omitempty"`       // only search files whose name matches this glob
+}
+
+type GrepMatch struct {
+	Path    string `json:"path"`
+	LineNum int    `json:"linenum"`
+	Line    string `json:"line"`
+}
+
+type CommandRemoteSedData struct {
+	Path        string   `json:"path"`
+	Pattern     string   `json:"pattern"`
+	Replacement string   `json:"replacement"` // with Regex, $1 / ${name} expand to the submatches
+	Opts        *SedOpts `json:"opts,omitempty"`
+}
+
+type SedOpts struct {
+	Regex      bool   `json:"regex,omitempty"` // pattern is a regular expression, otherwise it is matched literally
+	IgnoreCase bool   `json:"ignorecase,omitempty"`
+	WholeWord  bool   `json:"wholeword,omitempty"`
+	Glob       string `json:"glob,omitempty"`   // only edit files whose name matches this glob
+	DryRun     bool   `json:"dryrun,omitempty"` // report the replacements without writing any files
+}
+
+type SedFileResult struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`           // replacements made (or that would be made for a dry run)
+	Error string `json:"error,omitempty"` // the file could not be rewritten, it is unchanged
+}
+
+type CommandRemoteSedRtnData struct {
+	Files        []SedFileResult `json:"files,omitempty"` // only files with at least one match
+	TotalCount   int             `json:"totalcount"`
+	SkippedCount int             `json:"skippedcount,omitempty"` // binary, unreadable or oversized files
+	DryRun       bool            `json:"dryrun,omitempty"`
+}
+
+type CommandRemoteManifestData struct {
+	Path string        `json:"path"`
+	Opts *ManifestOpts `json:"opts,omitempty"`
+}
+
+type ManifestOpts struct {
+	Hash            bool     `json:"hash,omitempty"`            // sha256 the content of every file (the expensive part), directories then get a hash over their entries
+	ExcludePatterns []string `json:"excludepatterns,omitempty"` // same matching as FileCopyOpts.ExcludePatterns
+}
+
+// ManifestEntry is one entry of a RemoteManifestCommand stream.  directories are sent after their contents and the
+// root itself (Path ".") comes last, so a stream that ends without it was cut short.
+type ManifestEntry struct {
+	Path       string      `json:"path"` // relative to the root, "/" separated
+	Size       int64       `json:"size,omitempty"`
+	ModTime    int64       `json:"modtime"` // unix ms
+	Mode       os.FileMode `json:"mode"`
+	IsDir      bool        `json:"isdir,omitempty"`
+	LinkTarget string      `json:"linktarget,omitempty"` // symlinks are listed, never followed
+	Sha256     string      `json:"sha256,omitempty"`     // with Hash: the file content, the link target, or for directories their entries' names and hashes
+}
+
+// CommandRemoteSyncData makes the destination directory a copy of the source directory, like rsync "src/" "dest".
+// it runs on the destination host, the source may be on another connection.
+type CommandRemoteSyncData struct {
+	SrcUri  string    `json:"srcuri"`
+	DestUri string    `json:"desturi"`
+	Opts    *SyncOpts `json:"opts,omitempty"`
+}
+
+type SyncOpts struct {
+	Delete          bool     `json:"delete,omitempty"`          // remove destination entries that are not in the source
+	Checksum        bool     `json:"checksum,omitempty"`        // compare files by content hash instead of size and mtime (reads every file on both sides)
+	ExcludePatterns []string `json:"excludepatterns,omitempty"` // excluded paths are neither copied nor deleted
+	DryRun          bool     `json:"dryrun,omitempty"`          // report the actions without changing anything
+	Timeout         int64    `json:"timeout,omitempty"`         // per file copy, in ms
+}
+
+const (
+	SyncAction_Copy   = "copy"   // a new or changed file
+	SyncAction_Mkdir  = "mkdir"  // a new directory
+	SyncAction_Link   = "link"   // a new or changed symlink
+	SyncAction_Chmod  = "chmod"  // a directory whose permissions changed
+	SyncAction_Delete = "delete" // an extra destination entry (with Delete), or one in the way of an entry of another kind
+)
+
+type CommandRemoteSyncProgress struct {
+	Path      string `json:"path,omitempty"`   // the entry just synced, relative to the root
+	Action    string `json:"action,omitempty"` // one of the SyncAction_ constants, empty for progress within a large file
+	FilesDone int    `json:"filesdone"`        // files and links copied so far
+	BytesDone int64  `json:"bytesdone"`
+
+	// set on the final response
+	Done      bool `json:"done,omitempty"`
+	Deleted   int  `json:"deleted,omitempty"`
+	Unchanged int  `json:"unchanged,omitempty"` // files and links that were already up to date
+	DryRun    bool `json:"dryrun,omitempty"`
 }
 
 type ConnRequest struct {
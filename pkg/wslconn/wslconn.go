@@ -238,6 +238,14 @@ func (conn *WslConn) getWshPath() string {
 	return wavebase.RemoteFullWshBinPath
 }
 
+func (conn *WslConn) getJailRoot() string {
+	config, ok := conn.getConnectionConfig()
+	if !ok {
+		return ""
+	}
+	return config.ConnJailRoot
+}
+
 func (conn *WslConn) GetConfigShellPath() string {
 	config, ok := conn.getConnectionConfig()
 	if !ok {
@@ -277,6 +285,9 @@ func (conn *WslConn) StartConnServer(ctx context.Context, afterUpdate bool) (boo
 		conn.cancelFn = cancelFn
 	})
 	cmdStr := fmt.Sprintf(ConnServerCmdTemplate, wshPath, wshPath)
+	if jailRoot := conn.getJailRoot(); jailRoot != "" {
+		cmdStr += " --jailroot " + shellutil.HardQuote(jailRoot)
+	}
 	shWrappedCmdStr := fmt.Sprintf("sh -c %s", shellutil.HardQuote(cmdStr))
 	cmd := client.WslCommand(connServerCtx, shWrappedCmdStr)
 	pipeRead, pipeWrite := io.Pipe()